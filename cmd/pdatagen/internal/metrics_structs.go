@@ -17,6 +17,7 @@ package internal
 var metricsFile = &File{
 	Name: "metrics",
 	imports: []string{
+		`"go.opentelemetry.io/collector/internal/data"`,
 		`otlpmetrics "go.opentelemetry.io/collector/internal/data/protogen/metrics/v1"`,
 	},
 	testImports: []string{
@@ -360,6 +361,8 @@ var intExemplar = &messageValueStruct{
 			originFieldName: "FilteredLabels",
 			returnSlice:     stringMap,
 		},
+		traceIDField,
+		spanIDField,
 	},
 }
 
@@ -383,6 +386,8 @@ var doubleExemplar = &messageValueStruct{
 			originFieldName: "FilteredLabels",
 			returnSlice:     stringMap,
 		},
+		traceIDField,
+		spanIDField,
 	},
 }
 
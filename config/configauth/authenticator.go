@@ -24,9 +24,10 @@ import (
 )
 
 var (
-	errNoOIDCProvided   = errors.New("no OIDC information provided")
-	errMetadataNotFound = errors.New("no request metadata found")
-	defaultAttribute    = "authorization"
+	errNoAuthenticationProvided = errors.New("one of oidc, bearer_token or basic must be provided")
+	errOIDCAndStaticProvided    = errors.New("oidc cannot be combined with bearer_token or basic")
+	errMetadataNotFound         = errors.New("no request metadata found")
+	defaultAttribute            = "authorization"
 )
 
 // Authenticator will authenticate the incoming request/RPC
@@ -52,15 +53,20 @@ type streamInterceptorFunc func(srv interface{}, stream grpc.ServerStream, info
 
 // NewAuthenticator creates an authenticator based on the given configuration
 func NewAuthenticator(cfg Authentication) (Authenticator, error) {
-	if cfg.OIDC == nil {
-		return nil, errNoOIDCProvided
-	}
-
 	if len(cfg.Attribute) == 0 {
 		cfg.Attribute = defaultAttribute
 	}
 
-	return newOIDCAuthenticator(cfg)
+	static := cfg.BearerToken != "" || cfg.Basic != nil
+	switch {
+	case cfg.OIDC != nil && static:
+		return nil, errOIDCAndStaticProvided
+	case cfg.OIDC != nil:
+		return newOIDCAuthenticator(cfg)
+	case static:
+		return newStaticAuthenticator(cfg)
+	}
+	return nil, errNoAuthenticationProvided
 }
 
 func defaultUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler, authenticate authenticateFunc) (interface{}, error) {
@@ -38,13 +38,31 @@ func TestNewAuthenticator(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestMissingOIDC(t *testing.T) {
+func TestMissingAuthentication(t *testing.T) {
 	// test
 	p, err := NewAuthenticator(Authentication{})
 
 	// verify
 	assert.Nil(t, p)
-	assert.Equal(t, errNoOIDCProvided, err)
+	assert.Equal(t, errNoAuthenticationProvided, err)
+}
+
+func TestOIDCAndStaticCredentials(t *testing.T) {
+	oidc := &OIDC{
+		Audience:  "some-audience",
+		IssuerURL: "http://example.com",
+	}
+	for _, cfg := range []Authentication{
+		{OIDC: oidc, BearerToken: "some-token"},
+		{OIDC: oidc, Basic: &BasicAuth{Username: "user", Password: "secret"}},
+	} {
+		// test
+		p, err := NewAuthenticator(cfg)
+
+		// verify
+		assert.Nil(t, p)
+		assert.Equal(t, errOIDCAndStaticProvided, err)
+	}
 }
 
 func TestDefaultUnaryInterceptorAuthSucceeded(t *testing.T) {
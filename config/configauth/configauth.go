@@ -26,8 +26,26 @@ type Authentication struct {
 	Attribute string `mapstructure:"attribute"`
 
 	// OIDC configures this receiver to use the given OIDC provider as the backend for the authentication mechanism.
-	// Required.
+	// One of OIDC, BearerToken or Basic is required.
 	OIDC *OIDC `mapstructure:"oidc"`
+
+	// BearerToken configures this receiver to accept only requests carrying this static token as "Bearer <token>".
+	// Cannot be combined with OIDC.
+	BearerToken string `mapstructure:"bearer_token"`
+
+	// Basic configures this receiver to accept only requests carrying the given credentials using basic auth.
+	// Cannot be combined with OIDC or BearerToken.
+	Basic *BasicAuth `mapstructure:"basic"`
+}
+
+// BasicAuth defines the credentials accepted by the basic auth mechanism
+type BasicAuth struct {
+	// Username expected in the requests.
+	// Required.
+	Username string `mapstructure:"username"`
+
+	// Password expected in the requests.
+	Password string `mapstructure:"password"`
 }
 
 // OIDC defines the OpenID Connect properties for this processor
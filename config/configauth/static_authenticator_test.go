@@ -0,0 +1,77 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticBearerTokenAuthentication(t *testing.T) {
+	// prepare
+	p, err := NewAuthenticator(Authentication{BearerToken: "some-token"})
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background()))
+	defer p.Close()
+
+	// test and verify
+	_, err = p.Authenticate(context.Background(), map[string][]string{"authorization": {"Bearer some-token"}})
+	assert.NoError(t, err)
+
+	_, err = p.Authenticate(context.Background(), map[string][]string{"authorization": {"Bearer other-token"}})
+	assert.Equal(t, errNotAuthenticated, err)
+
+	_, err = p.Authenticate(context.Background(), map[string][]string{})
+	assert.Equal(t, errNotAuthenticated, err)
+}
+
+func TestStaticBasicAuthentication(t *testing.T) {
+	// prepare
+	p, err := NewAuthenticator(Authentication{
+		Attribute: "x-auth",
+		Basic:     &BasicAuth{Username: "jdoe", Password: "secret"},
+	})
+	require.NoError(t, err)
+
+	// test and verify
+	assert.Equal(t, "Basic amRvZTpzZWNyZXQ=", BasicAuthHeader("jdoe", "secret"))
+	_, err = p.Authenticate(context.Background(), map[string][]string{"x-auth": {BasicAuthHeader("jdoe", "secret")}})
+	assert.NoError(t, err)
+
+	_, err = p.Authenticate(context.Background(), map[string][]string{"x-auth": {BasicAuthHeader("jdoe", "wrong")}})
+	assert.Equal(t, errNotAuthenticated, err)
+
+	_, err = p.Authenticate(context.Background(), map[string][]string{"authorization": {BasicAuthHeader("jdoe", "secret")}})
+	assert.Equal(t, errNotAuthenticated, err)
+}
+
+func TestStaticAuthenticationInvalidConfig(t *testing.T) {
+	// test
+	p, err := NewAuthenticator(Authentication{BearerToken: "some-token", Basic: &BasicAuth{Username: "jdoe"}})
+
+	// verify
+	assert.Nil(t, p)
+	assert.Equal(t, errBearerTokenAndBasicProvided, err)
+
+	// test
+	p, err = NewAuthenticator(Authentication{Basic: &BasicAuth{Password: "secret"}})
+
+	// verify
+	assert.Nil(t, p)
+	assert.Equal(t, errNoUsernameProvided, err)
+}
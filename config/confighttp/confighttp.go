@@ -120,6 +120,14 @@ type HTTPServerSettings struct {
 	// CORS needs to be enabled first by providing a non-empty list in CorsOrigins
 	// A wildcard (*) can be used to match any header.
 	CorsHeaders []string `mapstructure:"cors_allowed_headers"`
+
+	// ReadTimeout is the maximum duration for reading an entire request, including the
+	// body. Slow clients are disconnected when it expires. Zero means no timeout.
+	ReadTimeout time.Duration `mapstructure:"read_timeout,omitempty"`
+
+	// IdleTimeout is the maximum duration to wait for the next request on a keep-alive
+	// connection. Zero means ReadTimeout is used.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout,omitempty"`
 }
 
 func (hss *HTTPServerSettings) ToListener() (net.Listener, error) {
@@ -171,6 +179,8 @@ func (hss *HTTPServerSettings) ToServer(handler http.Handler, opts ...ToServerOp
 		middleware.WithErrorHandler(serverOpts.errorHandler),
 	)
 	return &http.Server{
-		Handler: handler,
+		Handler:     handler,
+		ReadTimeout: hss.ReadTimeout,
+		IdleTimeout: hss.IdleTimeout,
 	}
 }
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -442,6 +443,35 @@ func ExampleHTTPServerSettings() {
 	}
 }
 
+func TestHTTPServerTimeouts(t *testing.T) {
+	hss := &HTTPServerSettings{
+		Endpoint:    "localhost:0",
+		ReadTimeout: 100 * time.Millisecond,
+		IdleTimeout: time.Minute,
+	}
+	ln, err := hss.ToListener()
+	require.NoError(t, err)
+	s := hss.ToServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	assert.Equal(t, 100*time.Millisecond, s.ReadTimeout)
+	assert.Equal(t, time.Minute, s.IdleTimeout)
+	go func() {
+		_ = s.Serve(ln)
+	}()
+	defer s.Close()
+
+	// A client which never completes its request is disconnected after the read timeout.
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	start := time.Now()
+	_, err = ioutil.ReadAll(conn)
+	assert.NoError(t, err, "connection was not closed by the server")
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
 func TestHttpHeaders(t *testing.T) {
 	tests := []struct {
 		name    string
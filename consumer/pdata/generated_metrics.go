@@ -18,6 +18,7 @@
 package pdata
 
 import (
+	"go.opentelemetry.io/collector/internal/data"
 	otlpmetrics "go.opentelemetry.io/collector/internal/data/protogen/metrics/v1"
 )
 
@@ -2113,11 +2114,33 @@ func (ms IntExemplar) FilteredLabels() StringMap {
 	return newStringMap(&(*ms.orig).FilteredLabels)
 }
 
+// TraceID returns the traceid associated with this IntExemplar.
+func (ms IntExemplar) TraceID() TraceID {
+	return TraceID((*ms.orig).TraceId)
+}
+
+// SetTraceID replaces the traceid associated with this IntExemplar.
+func (ms IntExemplar) SetTraceID(v TraceID) {
+	(*ms.orig).TraceId = data.TraceID(v)
+}
+
+// SpanID returns the spanid associated with this IntExemplar.
+func (ms IntExemplar) SpanID() SpanID {
+	return SpanID((*ms.orig).SpanId)
+}
+
+// SetSpanID replaces the spanid associated with this IntExemplar.
+func (ms IntExemplar) SetSpanID(v SpanID) {
+	(*ms.orig).SpanId = data.SpanID(v)
+}
+
 // CopyTo copies all properties from the current struct to the dest.
 func (ms IntExemplar) CopyTo(dest IntExemplar) {
 	dest.SetTimestamp(ms.Timestamp())
 	dest.SetValue(ms.Value())
 	ms.FilteredLabels().CopyTo(dest.FilteredLabels())
+	dest.SetTraceID(ms.TraceID())
+	dest.SetSpanID(ms.SpanID())
 }
 
 // DoubleExemplarSlice logically represents a slice of DoubleExemplar.
@@ -2279,9 +2302,31 @@ func (ms DoubleExemplar) FilteredLabels() StringMap {
 	return newStringMap(&(*ms.orig).FilteredLabels)
 }
 
+// TraceID returns the traceid associated with this DoubleExemplar.
+func (ms DoubleExemplar) TraceID() TraceID {
+	return TraceID((*ms.orig).TraceId)
+}
+
+// SetTraceID replaces the traceid associated with this DoubleExemplar.
+func (ms DoubleExemplar) SetTraceID(v TraceID) {
+	(*ms.orig).TraceId = data.TraceID(v)
+}
+
+// SpanID returns the spanid associated with this DoubleExemplar.
+func (ms DoubleExemplar) SpanID() SpanID {
+	return SpanID((*ms.orig).SpanId)
+}
+
+// SetSpanID replaces the spanid associated with this DoubleExemplar.
+func (ms DoubleExemplar) SetSpanID(v SpanID) {
+	(*ms.orig).SpanId = data.SpanID(v)
+}
+
 // CopyTo copies all properties from the current struct to the dest.
 func (ms DoubleExemplar) CopyTo(dest DoubleExemplar) {
 	dest.SetTimestamp(ms.Timestamp())
 	dest.SetValue(ms.Value())
 	ms.FilteredLabels().CopyTo(dest.FilteredLabels())
+	dest.SetTraceID(ms.TraceID())
+	dest.SetSpanID(ms.SpanID())
 }
@@ -187,14 +187,13 @@ func (be *baseExporter) Start(ctx context.Context, host component.Host) error {
 	}
 
 	// If no error then start the queuedRetrySender.
-	be.qrSender.start()
-	return nil
+	return be.qrSender.start()
 }
 
 // Shutdown all senders and exporter and is invoked during service shutdown.
 func (be *baseExporter) Shutdown(ctx context.Context) error {
 	// First shutdown the queued retry sender
-	be.qrSender.shutdown()
+	be.qrSender.shutdown(ctx)
 	// Last shutdown the wrapped exporter itself.
 	return be.Component.Shutdown(ctx)
 }
@@ -22,6 +22,9 @@ import (
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/jaegertracing/jaeger/pkg/queue"
+	"go.opencensus.io/metric"
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/metric/metricproducer"
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -30,6 +33,26 @@ import (
 	"go.opentelemetry.io/collector/obsreport"
 )
 
+var (
+	queueMetricsRegistry = metric.NewRegistry()
+
+	queueSizeGauge *metric.Int64DerivedGauge
+)
+
+func init() {
+	var err error
+	queueSizeGauge, err = queueMetricsRegistry.AddInt64DerivedGauge(
+		obsreport.ExporterKey+"/queue_size",
+		metric.WithDescription("Current size of the retry queue (in batches)"),
+		metric.WithLabelKeys(obsreport.ExporterKey),
+		metric.WithUnit(metricdata.UnitDimensionless))
+	if err != nil {
+		// The gauge is registered once in a fresh registry, so this is a programming error.
+		panic(fmt.Sprintf("failed to register the exporter queue size gauge: %v", err))
+	}
+	metricproducer.GlobalManager().AddProducer(queueMetricsRegistry)
+}
+
 // QueueSettings defines configuration for queueing batches before sending to the consumerSender.
 type QueueSettings struct {
 	// Enabled indicates whether to not enqueue batches before sending to the consumerSender.
@@ -79,6 +102,7 @@ func DefaultRetrySettings() RetrySettings {
 }
 
 type queuedRetrySender struct {
+	fullName        string
 	cfg             QueueSettings
 	consumerSender  requestSender
 	queue           *queue.BoundedQueue
@@ -111,7 +135,8 @@ func newQueuedRetrySender(fullName string, qCfg QueueSettings, rCfg RetrySetting
 	sampledLogger := createSampledLogger(logger)
 	traceAttr := trace.StringAttribute(obsreport.ExporterKey, fullName)
 	return &queuedRetrySender{
-		cfg: qCfg,
+		fullName: fullName,
+		cfg:      qCfg,
 		consumerSender: &retrySender{
 			traceAttribute: traceAttr,
 			cfg:            rCfg,
@@ -127,11 +152,23 @@ func newQueuedRetrySender(fullName string, qCfg QueueSettings, rCfg RetrySetting
 }
 
 // start is invoked during service startup.
-func (qrs *queuedRetrySender) start() {
+func (qrs *queuedRetrySender) start() error {
 	qrs.queue.StartConsumers(qrs.cfg.NumConsumers, func(item interface{}) {
 		req := item.(request)
 		_, _ = qrs.consumerSender.send(req)
 	})
+
+	// Start reporting queue length metric
+	if qrs.cfg.Enabled {
+		err := queueSizeGauge.UpsertEntry(func() int64 {
+			return int64(qrs.queue.Size())
+		}, metricdata.NewLabelValue(qrs.fullName))
+		if err != nil {
+			return fmt.Errorf("failed to create retry queue size metric: %v", err)
+		}
+	}
+
+	return nil
 }
 
 // send implements the requestSender interface
@@ -165,14 +202,57 @@ func (qrs *queuedRetrySender) send(req request) (int, error) {
 	return 0, nil
 }
 
-// shutdown is invoked during service shutdown.
-func (qrs *queuedRetrySender) shutdown() {
+// queueDrainInterval is how often shutdown checks whether the queue is drained.
+const queueDrainInterval = 10 * time.Millisecond
+
+// shutdown is invoked during service shutdown. It gives up draining the queue and
+// waiting for the requests in progress once ctx is done.
+func (qrs *queuedRetrySender) shutdown(ctx context.Context) {
+	// Cleanup queue metrics reporting
+	if qrs.cfg.Enabled {
+		_ = queueSizeGauge.UpsertEntry(func() int64 {
+			return int64(0)
+		}, metricdata.NewLabelValue(qrs.fullName))
+	}
+
 	// First stop the retry goroutines, so that unblocks the queue workers.
 	close(qrs.retryStopCh)
 
-	// Stop the queued sender, this will drain the queue and will call the retry (which is stopped) that will only
-	// try once every request.
-	qrs.queue.Stop()
+	// Wait for the queue workers to take the remaining requests, they call the retry (which is stopped) that will
+	// only try once every request. Stopping the queue stops the workers without draining it.
+	if qrs.cfg.NumConsumers > 0 {
+		ticker := time.NewTicker(queueDrainInterval)
+		defer ticker.Stop()
+	drain:
+		for qrs.queue.Size() > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				qrs.logger.Error(
+					"Shutdown timed out before the sending_queue was drained. Dropping data.",
+					zap.Int("dropped_requests", qrs.queue.Size()),
+				)
+				break drain
+			}
+		}
+	}
+
+	qrs.stopQueue(ctx)
+}
+
+// stopQueue stops the queued sender and waits for the requests in progress until ctx
+// is done.
+func (qrs *queuedRetrySender) stopQueue(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		qrs.queue.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		qrs.logger.Error("Shutdown timed out while requests were in progress.")
+	}
 }
 
 // TODO: Clean this by forcing all exporters to return an internal error type that always include the information about retries.
@@ -262,7 +342,8 @@ func (rs *retrySender) send(req request) (int, error) {
 			return req.count(), err
 		}
 
-		if throttleErr, isThrottle := err.(*throttleRetry); isThrottle {
+		var throttleErr *throttleRetry
+		if errors.As(err, &throttleErr) {
 			backoffDelay = max(backoffDelay, throttleErr.delay)
 		}
 
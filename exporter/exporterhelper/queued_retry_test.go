@@ -17,6 +17,7 @@ package exporterhelper
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opencensus.io/metric/metricproducer"
 	"go.uber.org/zap"
 
 	"go.opentelemetry.io/collector/component/componenttest"
@@ -140,13 +142,36 @@ func TestQueuedRetry_StopWhileWaiting(t *testing.T) {
 
 	assert.NoError(t, be.Shutdown(context.Background()))
 
-	// TODO: Ensure that queue is drained, and uncomment the next 3 lines.
-	//  https://github.com/jaegertracing/jaeger/pull/2349
 	firstMockR.checkNumRequests(t, 1)
-	// secondMockR.checkNumRequests(t, 1)
-	// ocs.checkSendItemsCount(t, 3)
+	secondMockR.checkNumRequests(t, 1)
+	ocs.checkSendItemsCount(t, 3)
 	ocs.checkDroppedItemsCount(t, 2)
-	// require.Zero(t, be.qrSender.queue.Size())
+	require.Zero(t, be.qrSender.queue.Size())
+}
+
+func TestQueuedRetry_ShutdownWhileConsumerBlocked(t *testing.T) {
+	qCfg := DefaultQueueSettings()
+	qCfg.NumConsumers = 1
+	rCfg := DefaultRetrySettings()
+	be := newBaseExporter(defaultExporterCfg, zap.NewNop(), WithRetry(rCfg), WithQueue(qCfg))
+	require.NoError(t, be.Start(context.Background(), componenttest.NewNopHost()))
+
+	// The first request blocks the only consumer, so the second one stays in the queue.
+	unblock := make(chan struct{})
+	defer close(unblock)
+	blockingR := &mockBlockingRequest{baseRequest: baseRequest{ctx: context.Background()}, started: make(chan struct{}), unblock: unblock}
+	_, err := be.sender.send(blockingR)
+	require.NoError(t, err)
+	<-blockingR.started
+	_, err = be.sender.send(newMockRequest(context.Background(), 3, nil))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.NoError(t, be.Shutdown(ctx))
+	assert.Less(t, time.Since(start).Seconds(), 5.0)
+	assert.Equal(t, 1, be.qrSender.queue.Size())
 }
 
 func TestQueuedRetry_DoNotPreserveCancellation(t *testing.T) {
@@ -253,6 +278,40 @@ func TestQueuedRetry_ThrottleError(t *testing.T) {
 	require.Zero(t, be.qrSender.queue.Size())
 }
 
+func TestQueuedRetry_WrappedThrottleError(t *testing.T) {
+	qCfg := DefaultQueueSettings()
+	qCfg.NumConsumers = 1
+	rCfg := DefaultRetrySettings()
+	rCfg.InitialInterval = 10 * time.Millisecond
+	be := newBaseExporter(defaultExporterCfg, zap.NewNop(), WithRetry(rCfg), WithQueue(qCfg))
+	ocs := newObservabilityConsumerSender(be.qrSender.consumerSender)
+	be.qrSender.consumerSender = ocs
+	require.NoError(t, be.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() {
+		assert.NoError(t, be.Shutdown(context.Background()))
+	})
+
+	// Exporters usually wrap the error returned by the client.
+	retry := NewThrottleRetry(errors.New("throttle error"), 100*time.Millisecond)
+	mockR := newMockRequest(context.Background(), 2, fmt.Errorf("failed to push data: %w", retry))
+	start := time.Now()
+	ocs.run(func() {
+		// This is asynchronous so it should just enqueue, no errors expected.
+		droppedItems, err := be.sender.send(mockR)
+		require.NoError(t, err)
+		assert.Equal(t, 0, droppedItems)
+	})
+	ocs.awaitAsyncProcessing()
+
+	// The initial backoff is 10ms, but because of the throttle this should wait at least 100ms.
+	assert.True(t, 100*time.Millisecond < time.Since(start))
+
+	mockR.checkNumRequests(t, 2)
+	ocs.checkSendItemsCount(t, 2)
+	ocs.checkDroppedItemsCount(t, 0)
+	require.Zero(t, be.qrSender.queue.Size())
+}
+
 func TestQueuedRetry_RetryOnError(t *testing.T) {
 	qCfg := DefaultQueueSettings()
 	qCfg.NumConsumers = 1
@@ -338,6 +397,23 @@ func TestQueuedRetryHappyPath(t *testing.T) {
 	ocs.checkDroppedItemsCount(t, 0)
 }
 
+func TestQueuedRetry_QueueMetricsReported(t *testing.T) {
+	qCfg := DefaultQueueSettings()
+	qCfg.NumConsumers = 0 // to make every request go straight to the queue
+	rCfg := DefaultRetrySettings()
+	be := newBaseExporter(defaultExporterCfg, zap.NewNop(), WithRetry(rCfg), WithQueue(qCfg))
+	require.NoError(t, be.Start(context.Background(), componenttest.NewNopHost()))
+
+	for i := 0; i < 7; i++ {
+		_, err := be.sender.send(newErrorRequest(context.Background()))
+		require.NoError(t, err)
+	}
+	checkValueForProducer(t, queueMetricsRegistry, defaultExporterCfg.Name(), int64(7))
+
+	assert.NoError(t, be.Shutdown(context.Background()))
+	checkValueForProducer(t, queueMetricsRegistry, defaultExporterCfg.Name(), int64(0))
+}
+
 func TestNoCancellationContext(t *testing.T) {
 	deadline := time.Now().Add(1 * time.Second)
 	ctx, cancelFunc := context.WithDeadline(context.Background(), deadline)
@@ -376,6 +452,27 @@ func newErrorRequest(ctx context.Context) request {
 	}
 }
 
+// mockBlockingRequest blocks its export until unblock is closed.
+type mockBlockingRequest struct {
+	baseRequest
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (m *mockBlockingRequest) export(context.Context) (int, error) {
+	close(m.started)
+	<-m.unblock
+	return 0, nil
+}
+
+func (m *mockBlockingRequest) onPartialError(consumererror.PartialError) request {
+	return m
+}
+
+func (m *mockBlockingRequest) count() int {
+	return 1
+}
+
 type mockRequest struct {
 	baseRequest
 	cnt          int
@@ -460,3 +557,21 @@ func (ocs *observabilityConsumerSender) checkSendItemsCount(t *testing.T, want i
 func (ocs *observabilityConsumerSender) checkDroppedItemsCount(t *testing.T, want int) {
 	assert.EqualValues(t, want, atomic.LoadInt64(&ocs.droppedItemsCount))
 }
+
+// checkValueForProducer checks that the queue size gauge of the given exporter has the
+// wanted value.
+func checkValueForProducer(t *testing.T, producer metricproducer.Producer, exporterName string, want int64) {
+	for _, m := range producer.Read() {
+		if m.Descriptor.Name != "exporter/queue_size" {
+			continue
+		}
+		for _, ts := range m.TimeSeries {
+			if len(ts.LabelValues) == 1 && ts.LabelValues[0].Value == exporterName {
+				require.Len(t, ts.Points, 1)
+				assert.Equal(t, want, ts.Points[0].Value)
+				return
+			}
+		}
+	}
+	assert.Fail(t, "Queue size metric not found.", "exporter %q", exporterName)
+}
@@ -39,4 +39,15 @@ type Config struct {
 	// The compression key for supported compression types within
 	// collector. Currently the only supported mode is `gzip`.
 	Compression string `mapstructure:"compression"`
+
+	// The encoding of the request body, either EncodingProto or EncodingJSON.
+	// If empty EncodingProto is used.
+	Encoding string `mapstructure:"encoding"`
 }
+
+const (
+	// EncodingProto encodes requests as binary Protobuf with Content-Type application/x-protobuf.
+	EncodingProto = "proto"
+	// EncodingJSON encodes requests as JSON with Content-Type application/json.
+	EncodingJSON = "json"
+)
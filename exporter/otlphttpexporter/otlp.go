@@ -27,6 +27,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gogo/protobuf/jsonpb"
+	gogoproto "github.com/gogo/protobuf/proto"
 	"go.uber.org/zap"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/proto"
@@ -36,6 +38,10 @@ import (
 	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/internal"
+	otlplogscol "go.opentelemetry.io/collector/internal/data/protogen/collector/logs/v1"
+	otlpmetricscol "go.opentelemetry.io/collector/internal/data/protogen/collector/metrics/v1"
+	otlptracecol "go.opentelemetry.io/collector/internal/data/protogen/collector/trace/v1"
 	"go.opentelemetry.io/collector/internal/middleware"
 )
 
@@ -78,6 +84,12 @@ func newExporter(cfg configmodels.Exporter, logger *zap.Logger) (*exporterImp, e
 		}
 	}
 
+	switch oCfg.Encoding {
+	case "", EncodingProto, EncodingJSON:
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", oCfg.Encoding)
+	}
+
 	return &exporterImp{
 		config: oCfg,
 		client: client,
@@ -86,7 +98,13 @@ func newExporter(cfg configmodels.Exporter, logger *zap.Logger) (*exporterImp, e
 }
 
 func (e *exporterImp) pushTraceData(ctx context.Context, traces pdata.Traces) (int, error) {
-	request, err := traces.ToOtlpProtoBytes()
+	var request []byte
+	var err error
+	if e.config.Encoding == EncodingJSON {
+		request, err = marshalJSON(&otlptracecol.ExportTraceServiceRequest{ResourceSpans: pdata.TracesToOtlp(traces)})
+	} else {
+		request, err = traces.ToOtlpProtoBytes()
+	}
 	if err != nil {
 		return traces.SpanCount(), consumererror.Permanent(err)
 	}
@@ -100,7 +118,13 @@ func (e *exporterImp) pushTraceData(ctx context.Context, traces pdata.Traces) (i
 }
 
 func (e *exporterImp) pushMetricsData(ctx context.Context, metrics pdata.Metrics) (int, error) {
-	request, err := metrics.ToOtlpProtoBytes()
+	var request []byte
+	var err error
+	if e.config.Encoding == EncodingJSON {
+		request, err = marshalJSON(&otlpmetricscol.ExportMetricsServiceRequest{ResourceMetrics: pdata.MetricsToOtlp(metrics)})
+	} else {
+		request, err = metrics.ToOtlpProtoBytes()
+	}
 	if err != nil {
 		return metrics.MetricCount(), consumererror.Permanent(err)
 	}
@@ -114,7 +138,13 @@ func (e *exporterImp) pushMetricsData(ctx context.Context, metrics pdata.Metrics
 }
 
 func (e *exporterImp) pushLogData(ctx context.Context, logs pdata.Logs) (int, error) {
-	request, err := logs.ToOtlpProtoBytes()
+	var request []byte
+	var err error
+	if e.config.Encoding == EncodingJSON {
+		request, err = marshalJSON(&otlplogscol.ExportLogsServiceRequest{ResourceLogs: internal.LogsToOtlp(logs.InternalRep())})
+	} else {
+		request, err = logs.ToOtlpProtoBytes()
+	}
 	if err != nil {
 		return logs.LogRecordCount(), consumererror.Permanent(err)
 	}
@@ -133,7 +163,11 @@ func (e *exporterImp) export(ctx context.Context, url string, request []byte) er
 	if err != nil {
 		return consumererror.Permanent(err)
 	}
-	req.Header.Set("Content-Type", "application/x-protobuf")
+	if e.config.Encoding == EncodingJSON {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	}
 
 	resp, err := e.client.Do(req)
 	if err != nil {
@@ -189,6 +223,15 @@ func (e *exporterImp) export(ctx context.Context, url string, request []byte) er
 	return formattedErr
 }
 
+// marshalJSON encodes the request using the OTLP JSON mapping of Protobuf.
+func marshalJSON(request gogoproto.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&buf, request); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // Read the response and decode the status.Status from the body.
 // Returns nil if the response is empty or cannot be decoded.
 func readResponse(resp *http.Response) *status.Status {
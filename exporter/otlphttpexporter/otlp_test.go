@@ -185,6 +185,76 @@ func TestCompressionOptions(t *testing.T) {
 	}
 }
 
+func TestEncodingOptions(t *testing.T) {
+	addr := testutil.GetAvailableLocalAddress(t)
+	baseURL := fmt.Sprintf("http://%s", addr)
+	params := component.ExporterCreateParams{Logger: zap.NewNop()}
+
+	for _, encoding := range []string{"", EncodingProto, EncodingJSON} {
+		t.Run("encoding="+encoding, func(t *testing.T) {
+			factory := NewFactory()
+
+			tracesSink := new(consumertest.TracesSink)
+			startTraceReceiver(t, addr, tracesSink)
+			tracesCfg := createExporterConfig(baseURL, factory.CreateDefaultConfig())
+			tracesCfg.Encoding = encoding
+			tracesExp, err := factory.CreateTracesExporter(context.Background(), params, tracesCfg)
+			require.NoError(t, err)
+			startAndCleanup(t, tracesExp)
+
+			td := testdata.GenerateTraceDataTwoSpansSameResource()
+			assert.NoError(t, tracesExp.ConsumeTraces(context.Background(), td))
+			require.Eventually(t, func() bool {
+				return tracesSink.SpansCount() > 0
+			}, 1*time.Second, 10*time.Millisecond)
+			require.Len(t, tracesSink.AllTraces(), 1)
+			assert.EqualValues(t, td, tracesSink.AllTraces()[0])
+
+			metricsAddr := testutil.GetAvailableLocalAddress(t)
+			metricsSink := new(consumertest.MetricsSink)
+			startMetricsReceiver(t, metricsAddr, metricsSink)
+			metricsCfg := createExporterConfig(fmt.Sprintf("http://%s", metricsAddr), factory.CreateDefaultConfig())
+			metricsCfg.Encoding = encoding
+			metricsExp, err := factory.CreateMetricsExporter(context.Background(), params, metricsCfg)
+			require.NoError(t, err)
+			startAndCleanup(t, metricsExp)
+
+			md := testdata.GenerateMetricsOneMetric()
+			assert.NoError(t, metricsExp.ConsumeMetrics(context.Background(), md))
+			require.Eventually(t, func() bool {
+				return metricsSink.MetricsCount() > 0
+			}, 1*time.Second, 10*time.Millisecond)
+			require.Len(t, metricsSink.AllMetrics(), 1)
+			assert.EqualValues(t, md, metricsSink.AllMetrics()[0])
+
+			logsAddr := testutil.GetAvailableLocalAddress(t)
+			logsSink := new(consumertest.LogsSink)
+			startLogsReceiver(t, logsAddr, logsSink)
+			logsCfg := createExporterConfig(fmt.Sprintf("http://%s", logsAddr), factory.CreateDefaultConfig())
+			logsCfg.Encoding = encoding
+			logsExp, err := factory.CreateLogsExporter(context.Background(), params, logsCfg)
+			require.NoError(t, err)
+			startAndCleanup(t, logsExp)
+
+			ld := testdata.GenerateLogDataOneLog()
+			assert.NoError(t, logsExp.ConsumeLogs(context.Background(), ld))
+			require.Eventually(t, func() bool {
+				return logsSink.LogRecordsCount() > 0
+			}, 1*time.Second, 10*time.Millisecond)
+			require.Len(t, logsSink.AllLogs(), 1)
+			assert.EqualValues(t, ld, logsSink.AllLogs()[0])
+		})
+	}
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		factory := NewFactory()
+		cfg := createExporterConfig(baseURL, factory.CreateDefaultConfig())
+		cfg.Encoding = "xml"
+		_, err := factory.CreateTracesExporter(context.Background(), params, cfg)
+		assert.Error(t, err)
+	})
+}
+
 func TestMetricsError(t *testing.T) {
 	addr := testutil.GetAvailableLocalAddress(t)
 
@@ -19,11 +19,13 @@ import (
 
 	"go.uber.org/zap"
 
+	"go.opentelemetry.io/collector/config/configtelemetry"
 	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/internal/processor/filterconfig"
 	"go.opentelemetry.io/collector/internal/processor/filtermatcher"
 	"go.opentelemetry.io/collector/internal/processor/filtermetric"
 	"go.opentelemetry.io/collector/internal/processor/filterset"
+	"go.opentelemetry.io/collector/obsreport"
 	"go.opentelemetry.io/collector/processor/processorhelper"
 )
 
@@ -34,6 +36,7 @@ type filterMetricProcessor struct {
 	exclude          filtermetric.Matcher
 	excludeAttribute filtermatcher.AttributesMatcher
 	logger           *zap.Logger
+	obsrep           *obsreport.Processor
 }
 
 func newFilterMetricProcessor(logger *zap.Logger, cfg *Config) (*filterMetricProcessor, error) {
@@ -89,6 +92,7 @@ func newFilterMetricProcessor(logger *zap.Logger, cfg *Config) (*filterMetricPro
 		exclude:          exc,
 		excludeAttribute: excludeAttr,
 		logger:           logger,
+		obsrep:           obsreport.NewProcessor(configtelemetry.GetMetricsLevelFlagValue(), cfg.Name()),
 	}, nil
 }
 
@@ -114,7 +118,9 @@ func createMatcher(mp *filtermetric.MatchProperties) (filtermetric.Matcher, filt
 }
 
 // ProcessMetrics filters the given metrics based off the filterMetricProcessor's filters.
-func (fmp *filterMetricProcessor) ProcessMetrics(_ context.Context, pdm pdata.Metrics) (pdata.Metrics, error) {
+// The data points of the metrics which are filtered out are reported as dropped.
+func (fmp *filterMetricProcessor) ProcessMetrics(ctx context.Context, pdm pdata.Metrics) (pdata.Metrics, error) {
+	_, numPoints := pdm.MetricAndDataPointCount()
 	rms := pdm.ResourceMetrics()
 	idx := newMetricIndex()
 	for i := 0; i < rms.Len(); i++ {
@@ -141,9 +147,14 @@ func (fmp *filterMetricProcessor) ProcessMetrics(_ context.Context, pdm pdata.Me
 		}
 	}
 	if idx.isEmpty() {
+		fmp.obsrep.MetricsDropped(ctx, numPoints)
 		return pdm, processorhelper.ErrSkipProcessingData
 	}
-	return idx.extract(pdm), nil
+	filtered := idx.extract(pdm)
+	if _, keptPoints := filtered.MetricAndDataPointCount(); keptPoints < numPoints {
+		fmp.obsrep.MetricsDropped(ctx, numPoints-keptPoints)
+	}
+	return filtered, nil
 }
 
 func (fmp *filterMetricProcessor) shouldKeepMetric(metric pdata.Metric) (bool, error) {
@@ -34,6 +34,7 @@ import (
 	"go.opentelemetry.io/collector/internal/goldendataset"
 	"go.opentelemetry.io/collector/internal/processor/filterconfig"
 	"go.opentelemetry.io/collector/internal/processor/filtermetric"
+	"go.opentelemetry.io/collector/obsreport/obsreporttest"
 	"go.opentelemetry.io/collector/translator/internaldata"
 )
 
@@ -361,6 +362,39 @@ func TestFilterMetricProcessor(t *testing.T) {
 	}
 }
 
+func TestFilterMetricProcessorRecordsDrops(t *testing.T) {
+	doneFn, err := obsreporttest.SetupRecordedMetricsTest()
+	require.NoError(t, err)
+	defer doneFn()
+
+	cfg := &Config{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+			NameVal: typeStr,
+		},
+		Metrics: MetricFilters{
+			Exclude: &filtermetric.MatchProperties{
+				MatchType:   filtermetric.Strict,
+				MetricNames: []string{"dropped_a", "dropped_b"},
+			},
+		},
+	}
+	next := new(consumertest.MetricsSink)
+	fmp, err := NewFactory().CreateMetricsProcessor(context.Background(), component.ProcessorCreateParams{Logger: zap.NewNop()}, cfg, next)
+	require.NoError(t, err)
+
+	// One metric data point is kept, two are filtered out.
+	md := internaldata.OCToMetrics(internaldata.MetricsData{Metrics: metricsWithName([]string{"kept", "dropped_a", "dropped_b"})})
+	require.NoError(t, fmp.ConsumeMetrics(context.Background(), md))
+	obsreporttest.CheckProcessorMetricsViews(t, typeStr, 0, 0, 2)
+
+	// All data points are filtered out and the request is skipped.
+	md = internaldata.OCToMetrics(internaldata.MetricsData{Metrics: metricsWithName([]string{"dropped_a"})})
+	require.NoError(t, fmp.ConsumeMetrics(context.Background(), md))
+	obsreporttest.CheckProcessorMetricsViews(t, typeStr, 0, 0, 3)
+	assert.Equal(t, 1, next.MetricsCount())
+}
+
 func metricsWithName(names []string) []*metricspb.Metric {
 	ret := make([]*metricspb.Metric, len(names))
 	now := time.Now()
@@ -40,7 +40,7 @@ var (
 
 // Flags adds flags related to basic building of the collector application to the given flagset.
 func Flags(flags *flag.FlagSet) {
-	configFile = flags.String(configCfg, "", "Path to the config file")
+	configFile = flags.String(configCfg, "", "Path or http(s) URL of the config file")
 	memBallastSize = flags.Uint(memBallastFlag, 0,
 		fmt.Sprintf("Flag to specify size of memory (MiB) ballast to set. Ballast is not used when this is not specified. "+
 			"default settings: 0"))
@@ -82,6 +82,30 @@ var viewSysMem = &view.View{
 	TagKeys:     nil,
 }
 
+var mRuntimeTotalGCPause = stats.Float64(
+	"process/runtime/total_gc_pause_seconds",
+	"Cumulative time spent in GC stop-the-world pauses (see 'go doc runtime.MemStats.PauseTotalNs')",
+	stats.UnitSeconds)
+var viewTotalGCPause = &view.View{
+	Name:        mRuntimeTotalGCPause.Name(),
+	Description: mRuntimeTotalGCPause.Description(),
+	Measure:     mRuntimeTotalGCPause,
+	Aggregation: view.LastValue(),
+	TagKeys:     nil,
+}
+
+var mRuntimeNumGC = stats.Int64(
+	"process/runtime/num_gc",
+	"Number of completed GC cycles (see 'go doc runtime.MemStats.NumGC')",
+	stats.UnitDimensionless)
+var viewNumGC = &view.View{
+	Name:        mRuntimeNumGC.Name(),
+	Description: mRuntimeNumGC.Description(),
+	Measure:     mRuntimeNumGC,
+	Aggregation: view.LastValue(),
+	TagKeys:     nil,
+}
+
 var mCPUSeconds = stats.Float64(
 	"process/cpu_seconds",
 	"Total CPU user and system time in seconds",
@@ -112,7 +136,7 @@ func NewProcessMetricsViews(ballastSizeBytes uint64) (*ProcessMetricsViews, erro
 	pmv := &ProcessMetricsViews{
 		prevTimeUnixNano: time.Now().UnixNano(),
 		ballastSizeBytes: ballastSizeBytes,
-		views:            []*view.View{viewProcessUptime, viewAllocMem, viewTotalAllocMem, viewSysMem, viewCPUSeconds, viewRSSMemory},
+		views:            []*view.View{viewProcessUptime, viewAllocMem, viewTotalAllocMem, viewSysMem, viewTotalGCPause, viewNumGC, viewCPUSeconds, viewRSSMemory},
 		done:             make(chan struct{}),
 	}
 
@@ -163,6 +187,8 @@ func (pmv *ProcessMetricsViews) updateViews() {
 	stats.Record(context.Background(), mRuntimeAllocMem.M(int64(ms.Alloc)))
 	stats.Record(context.Background(), mRuntimeTotalAllocMem.M(int64(ms.TotalAlloc)))
 	stats.Record(context.Background(), mRuntimeSysMem.M(int64(ms.Sys)))
+	stats.Record(context.Background(), mRuntimeTotalGCPause.M(float64(ms.PauseTotalNs)/1e9))
+	stats.Record(context.Background(), mRuntimeNumGC.M(int64(ms.NumGC)))
 
 	if pmv.proc != nil {
 		if times, err := pmv.proc.Times(); err == nil {
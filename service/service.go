@@ -27,7 +27,9 @@ import (
 	"path"
 	"runtime"
 	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -115,8 +117,13 @@ func FileLoaderConfigFactory(v *viper.Viper, cmd *cobra.Command, factories compo
 		return nil, errors.New("config file not specified")
 	}
 	// first load the config file
-	v.SetConfigFile(file)
-	err := v.ReadInConfig()
+	var err error
+	if strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+		err = readRemoteConfig(v, file)
+	} else {
+		v.SetConfigFile(file)
+		err = v.ReadInConfig()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error loading config file %q: %v", file, err)
 	}
@@ -128,6 +135,24 @@ func FileLoaderConfigFactory(v *viper.Viper, cmd *cobra.Command, factories compo
 	return config.Load(v, factories)
 }
 
+// remoteConfigTimeout limits the time to fetch the config from an http(s) URL.
+const remoteConfigTimeout = 30 * time.Second
+
+// readRemoteConfig reads the YAML config served at the http(s) URL into v.
+func readRemoteConfig(v *viper.Viper, url string) error {
+	client := &http.Client{Timeout: remoteConfigTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status %q", resp.Status)
+	}
+	v.SetConfigType("yaml")
+	return v.ReadConfig(resp.Body)
+}
+
 // New creates and returns a new instance of Application.
 func New(params Parameters) (*Application, error) {
 	app := &Application{
@@ -144,8 +169,9 @@ func New(params Parameters) (*Application, error) {
 	}
 
 	rootCmd := &cobra.Command{
-		Use:  params.ApplicationStartInfo.ExeName,
-		Long: params.ApplicationStartInfo.LongName,
+		Use:     params.ApplicationStartInfo.ExeName,
+		Long:    params.ApplicationStartInfo.LongName,
+		Version: params.ApplicationStartInfo.Version,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := app.init(params.LoggingOptions)
 			if err != nil {
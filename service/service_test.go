@@ -17,11 +17,14 @@ package service
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"sort"
 	"strconv"
 	"strings"
@@ -110,6 +113,23 @@ func (tel *mockAppTelemetry) shutdown() error {
 	return errors.New("err1")
 }
 
+func TestApplication_Version(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	startInfo := component.ApplicationStartInfo{ExeName: "otelcol", LongName: "OpenTelemetry Collector", Version: "v1.2.3"}
+	app, err := New(Parameters{Factories: factories, ApplicationStartInfo: startInfo})
+	require.NoError(t, err)
+
+	out := new(bytes.Buffer)
+	app.rootCmd.SetOut(out)
+	app.rootCmd.SetArgs([]string{"--version"})
+	require.NoError(t, app.Run())
+	assert.Equal(t, "otelcol version v1.2.3\n", out.String())
+	// The collector is not started.
+	assert.Empty(t, app.GetStateChannel())
+}
+
 func TestApplication_ReportError(t *testing.T) {
 	// use a mock AppTelemetry struct to return an error on shutdown
 	preservedAppTelemetry := applicationTelemetry
@@ -617,6 +637,37 @@ func TestSetFlag_component_does_not_exist(t *testing.T) {
 	require.NotNil(t, cfg)
 }
 
+func TestFileLoaderConfigFactory_remoteConfig(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+	configData, err := ioutil.ReadFile("testdata/otelcol-config.yaml")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/otelcol-config.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(configData)
+	}))
+	defer server.Close()
+
+	app, err := New(Parameters{Factories: factories})
+	require.NoError(t, err)
+	require.NoError(t, app.rootCmd.ParseFlags([]string{"--config=" + server.URL + "/otelcol-config.yaml"}))
+	cfg, err := FileLoaderConfigFactory(app.v, app.rootCmd, factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"attributes", "batch"}, cfg.Service.Pipelines["traces"].Processors)
+
+	app, err = New(Parameters{Factories: factories})
+	require.NoError(t, err)
+	require.NoError(t, app.rootCmd.ParseFlags([]string{"--config=" + server.URL + "/missing.yaml"}))
+	_, err = FileLoaderConfigFactory(app.v, app.rootCmd, factories)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
 func constructMimumalOpConfig(t *testing.T, factories component.Factories) *configmodels.Config {
 	configStr := `
 receivers:
@@ -0,0 +1,113 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.opentelemetry.io/collector/service/defaultcomponents"
+	"go.opentelemetry.io/collector/testbed/correctness"
+	"go.opentelemetry.io/collector/testbed/testbed"
+)
+
+var correctnessResults testbed.TestResultsSummary = &testbed.CorrectnessResults{}
+
+func TestMain(m *testing.M) {
+	testbed.DoTestMain(m, correctnessResults)
+}
+
+func TestLogsCorrectness(t *testing.T) {
+	processors := map[string]string{
+		"batch": `
+  batch:
+    send_batch_size: 1024
+`,
+	}
+	testWithLogCorrectnessData(t, processors, nil)
+}
+
+func TestLogsCorrectnessAttributesProcessor(t *testing.T) {
+	// The attributes processor modifies every record, the validator is told to expect it.
+	processors := map[string]string{
+		"attributes": `
+  attributes:
+    actions:
+      - action: insert
+        key: "env"
+        value: "test"
+      - action: delete
+        key: "seq_even"
+`,
+	}
+	testWithLogCorrectnessData(t, processors, func(record pdata.LogRecord) {
+		record.Attributes().InsertString("env", "test")
+		record.Attributes().Delete("seq_even")
+	})
+}
+
+func testWithLogCorrectnessData(
+	t *testing.T,
+	processors map[string]string,
+	expectedTransform func(record pdata.LogRecord),
+) {
+	sender := testbed.NewOTLPLogsDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond: 1024,
+		ItemsPerBatch:      8,
+	}
+	dataProvider := testbed.NewLogCorrectnessDataProvider(options)
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err, "default components resulted in: %v", err)
+	runner := testbed.NewInProcessCollector(factories)
+	validator := testbed.NewLogCorrectnessTestValidator(expectedTransform)
+	config := correctness.CreateConfigYaml(sender, receiver, processors, "logs")
+	configCleanup, cfgErr := runner.PrepareConfig(config)
+	require.NoError(t, cfgErr, "collector configuration resulted in: %v", cfgErr)
+	defer configCleanup()
+	tc := testbed.NewTestCase(
+		t,
+		dataProvider,
+		sender,
+		receiver,
+		runner,
+		validator,
+		correctnessResults,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent("--metrics-level=NONE")
+
+	tc.StartLoad(options)
+
+	duration := time.Second
+	tc.Sleep(duration)
+
+	tc.StopLoad()
+
+	tc.WaitForN(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		duration*3, "all data items received")
+
+	tc.StopAgent()
+
+	tc.ValidateData()
+}
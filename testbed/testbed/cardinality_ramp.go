@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// CardinalityRamp describes how to ramp up the cardinality of the generated data while
+// the load runs, to find the cardinality at which the memory of the agent crosses a
+// threshold.
+type CardinalityRamp struct {
+	// Steps are the cardinalities to ramp through, in increasing order.
+	Steps []int
+	// StepDuration is how long the load is generated with each cardinality before the
+	// memory is measured.
+	StepDuration time.Duration
+	// ThresholdMiB is the RSS in MiB whose crossing ends the ramp.
+	ThresholdMiB uint32
+}
+
+// CardinalityRampStep holds the memory measured at the end of one step of a
+// CardinalityRamp.
+type CardinalityRampStep struct {
+	Cardinality int    `json:"cardinality"`
+	RAMMiB      uint32 `json:"ram_mib"`
+}
+
+// CardinalityRampResult holds the results of CardinalityRamp.Run.
+type CardinalityRampResult struct {
+	ThresholdMiB uint32                `json:"threshold_mib"`
+	Steps        []CardinalityRampStep `json:"steps"`
+	// CrossingCardinality is the cardinality of the first step whose memory exceeded
+	// ThresholdMiB, or 0 if the threshold was not crossed.
+	CrossingCardinality int `json:"crossing_cardinality"`
+}
+
+// Run ramps through the steps, calling setCardinality at the start of each step, wait
+// for the StepDuration and then ramMiB to measure the memory. The ramp stops after the
+// first step whose memory exceeds ThresholdMiB. wait is usually TestCase.Sleep and
+// ramMiB returns the RSS of the agent, see TestCase.AgentMemoryInfo.
+func (r CardinalityRamp) Run(
+	setCardinality func(cardinality int),
+	wait func(d time.Duration),
+	ramMiB func() (uint32, error),
+) (CardinalityRampResult, error) {
+	result := CardinalityRampResult{ThresholdMiB: r.ThresholdMiB}
+	for _, cardinality := range r.Steps {
+		setCardinality(cardinality)
+		wait(r.StepDuration)
+		ram, err := ramMiB()
+		if err != nil {
+			return result, fmt.Errorf("cannot measure memory at cardinality %d: %s", cardinality, err.Error())
+		}
+		result.Steps = append(result.Steps, CardinalityRampStep{Cardinality: cardinality, RAMMiB: ram})
+		log.Printf("Cardinality %d: RAM %d MiB", cardinality, ram)
+		if ram > r.ThresholdMiB {
+			result.CrossingCardinality = cardinality
+			log.Printf("RAM crossed %d MiB at cardinality %d.", r.ThresholdMiB, cardinality)
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinalityRamp(t *testing.T) {
+	ramp := CardinalityRamp{
+		Steps:        []int{100, 1_000, 10_000, 100_000},
+		StepDuration: time.Second,
+		ThresholdMiB: 500,
+	}
+
+	// Simulate an agent whose memory grows by 1 MiB per 100 series above 50 MiB.
+	var cardinality int
+	var waited time.Duration
+	setCardinality := func(c int) { cardinality = c }
+	wait := func(d time.Duration) { waited += d }
+	ramMiB := func() (uint32, error) { return uint32(50 + cardinality/100), nil }
+
+	result, err := ramp.Run(setCardinality, wait, ramMiB)
+	require.NoError(t, err)
+	assert.Equal(t, CardinalityRampResult{
+		ThresholdMiB: 500,
+		Steps: []CardinalityRampStep{
+			{Cardinality: 100, RAMMiB: 51},
+			{Cardinality: 1_000, RAMMiB: 60},
+			{Cardinality: 10_000, RAMMiB: 150},
+			{Cardinality: 100_000, RAMMiB: 1050},
+		},
+		CrossingCardinality: 100_000,
+	}, result)
+	assert.Equal(t, 4*time.Second, waited)
+
+	// The ramp stops at the crossing and reports 0 if the threshold is not crossed.
+	ramp.ThresholdMiB = 100
+	result, err = ramp.Run(setCardinality, wait, ramMiB)
+	require.NoError(t, err)
+	assert.Len(t, result.Steps, 3)
+	assert.Equal(t, 10_000, result.CrossingCardinality)
+
+	ramp.ThresholdMiB = 2000
+	result, err = ramp.Run(setCardinality, wait, ramMiB)
+	require.NoError(t, err)
+	assert.Len(t, result.Steps, 4)
+	assert.Zero(t, result.CrossingCardinality)
+
+	_, err = ramp.Run(setCardinality, wait, func() (uint32, error) { return 0, errors.New("process exited") })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot measure memory at cardinality 100")
+}
@@ -0,0 +1,197 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/atomic"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupParentEnvVarName is the environment variable set to the delegated cgroup v2
+// directory below which memory cgroups are created, see memoryCgroup.
+const cgroupParentEnvVarName = "TESTBED_CGROUP"
+
+// cgroupSeqNum makes the names of the cgroups created by the test process unique.
+var cgroupSeqNum atomic.Uint64
+
+// memoryCgroup is a cgroup with a hard memory limit which processes can be run in. Both
+// the v1 memory controller and the unified v2 hierarchy are supported. With v1 the
+// cgroup is created below the memory cgroup of the test process, which must be
+// writable. With v2 the memory controller cannot be enabled for the children of the
+// cgroup of the test process, because a cgroup with processes cannot delegate
+// controllers. The cgroup is created below the directory set by the TESTBED_CGROUP
+// environment variable instead, which must be a writable cgroup without processes with
+// the memory controller enabled in its cgroup.subtree_control, e.g. prepared by an
+// administrator or delegated by systemd. The setup is never changed by the testbed.
+type memoryCgroup struct {
+	dir string
+	v2  bool
+}
+
+// newMemoryCgroup creates a cgroup limiting the memory of its processes, including
+// swap, to limitBytes.
+func newMemoryCgroup(limitBytes uint64) (*memoryCgroup, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("cgroups are only supported on Linux")
+	}
+	parent, v2, err := findMemoryCgroupDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cg := &memoryCgroup{
+		dir: filepath.Join(parent, fmt.Sprintf("testbed-%d-%d", os.Getpid(), cgroupSeqNum.Inc())),
+		v2:  v2,
+	}
+	if err = os.Mkdir(cg.dir, 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	limit := strconv.FormatUint(limitBytes, 10)
+	if v2 {
+		err = cg.write("memory.max", limit)
+		if err == nil {
+			// Without swap the process is killed instead of swapping once the limit is hit.
+			// The file is missing if swap accounting is disabled.
+			_ = cg.write("memory.swap.max", "0")
+		}
+	} else {
+		err = cg.write("memory.limit_in_bytes", limit)
+		if err == nil {
+			// Must not be lower than memory.limit_in_bytes, so it is written afterwards.
+			_ = cg.write("memory.memsw.limit_in_bytes", limit)
+		}
+	}
+	if err != nil {
+		_ = cg.remove()
+		return nil, fmt.Errorf("cannot set memory limit of cgroup %s: %s", cg.dir, err.Error())
+	}
+	return cg, nil
+}
+
+// CheckMemoryLimitSupport returns an error if processes cannot be run with a memory
+// limit, see ChildProcess.MemoryLimitMiB, e.g. to skip tests which require it.
+func CheckMemoryLimitSupport() error {
+	cg, err := newMemoryCgroup(64 * 1024 * 1024)
+	if err != nil {
+		return err
+	}
+	return cg.remove()
+}
+
+// findMemoryCgroupDir returns the directory below which memory cgroups are created and
+// whether it is in the unified v2 hierarchy, see memoryCgroup.
+func findMemoryCgroupDir() (dir string, v2 bool, err error) {
+	if dir = os.Getenv(cgroupParentEnvVarName); dir != "" {
+		subtree, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+		if err != nil {
+			return "", false, fmt.Errorf("%s=%s is not a cgroup v2 directory: %s", cgroupParentEnvVarName, dir, err.Error())
+		}
+		if !containsField(string(subtree), "memory") {
+			return "", false, fmt.Errorf("the memory controller is not enabled in the cgroup.subtree_control of %s=%s",
+				cgroupParentEnvVarName, dir)
+		}
+		return dir, true, nil
+	}
+
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", false, err
+	}
+
+	// Every line has the format "hierarchy-ID:controller-list:cgroup-path". The v2
+	// hierarchy has ID 0 and an empty controller list.
+	var v2Path string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			v2Path = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == "memory" {
+				return filepath.Join(cgroupRoot, "memory", fields[2]), false, nil
+			}
+		}
+	}
+
+	if v2Path != "" {
+		return "", false, fmt.Errorf("cgroup v2 requires a delegated cgroup with the memory controller enabled, set %s",
+			cgroupParentEnvVarName)
+	}
+	return "", false, errors.New("no cgroup memory controller found")
+}
+
+func containsField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (cg *memoryCgroup) write(file, value string) error {
+	return ioutil.WriteFile(filepath.Join(cg.dir, file), []byte(value), 0644)
+}
+
+// procsFile returns the file a process ID is written to to move the process into the
+// cgroup.
+func (cg *memoryCgroup) procsFile() string {
+	return filepath.Join(cg.dir, "cgroup.procs")
+}
+
+// oomKills returns the number of processes in the cgroup which were killed by the OOM
+// killer because the memory limit was exceeded.
+func (cg *memoryCgroup) oomKills() (uint64, error) {
+	file := "memory.oom_control"
+	if cg.v2 {
+		file = "memory.events"
+	}
+	data, err := ioutil.ReadFile(filepath.Join(cg.dir, file))
+	if err != nil {
+		return 0, err
+	}
+
+	// Both files have one "key value" pair per line.
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no oom_kill counter in %s", file)
+}
+
+// remove deletes the cgroup, which must not contain any processes anymore.
+func (cg *memoryCgroup) remove() error {
+	return os.Remove(cg.dir)
+}
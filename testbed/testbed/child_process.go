@@ -21,19 +21,29 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"text/template"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/process"
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
+	"gopkg.in/yaml.v2"
 )
 
 // ResourceSpec is a resource consumption specification.
@@ -50,6 +60,13 @@ type ResourceSpec struct {
 	// the test result.
 	ExpectedMaxRAM uint32
 
+	// Maximum number of OS threads the process is expected to use. Test is aborted
+	// and failed if the thread count exceeds this number, e.g. because goroutines
+	// blocked in syscalls or cgo calls keep spawning threads. The thread count is only
+	// sampled on Linux, elsewhere this is ignored. If 0 the thread count does not
+	// affect the test result.
+	ExpectedMaxThreads uint32
+
 	// Period during which CPU and RAM of the process are measured.
 	// Bigger numbers will result in more averaging of short spikes.
 	ResourceCheckPeriod time.Duration
@@ -58,7 +75,7 @@ type ResourceSpec struct {
 // isSpecified returns true if any part of ResourceSpec is specified,
 // i.e. has non-zero value.
 func (rs *ResourceSpec) isSpecified() bool {
-	return rs != nil && (rs.ExpectedMaxCPU != 0 || rs.ExpectedMaxRAM != 0)
+	return rs != nil && (rs.ExpectedMaxCPU != 0 || rs.ExpectedMaxRAM != 0 || rs.ExpectedMaxThreads != 0)
 }
 
 // ChildProcess implements the OtelcolRunner interface as a child process on the same machine executing
@@ -66,15 +83,85 @@ func (rs *ResourceSpec) isSpecified() bool {
 type ChildProcess struct {
 	// Path to agent executable. If unset the default executable in
 	// bin/otelcol_{{.GOOS}}_{{.GOARCH}} will be used.
-	// Can be set for example to use the unstable executable for a specific test.
+	// Can be set for example to use the unstable executable for a specific test, or
+	// the executable of a custom collector distribution.
 	AgentExePath string
 
+	// Components lists the component types, e.g. "otlp" or "batch", which the
+	// executable at AgentExePath is built with. If set PrepareConfig fails for configs
+	// using other components. If empty the config is not validated.
+	Components []string
+
+	// Env specifies additional environment variables to set for the process, on top
+	// of the environment of the test. See also SetGOMAXPROCS and SetGOGC.
+	Env map[string]string
+
+	// ErrorLogPattern matches the lines of the process output which are reported by
+	// AssertNoErrorLogs. If nil DefaultErrorLogPattern is used.
+	ErrorLogPattern *regexp.Regexp
+
+	// AllowedLogPatterns match lines which AssertNoErrorLogs ignores even though they
+	// match ErrorLogPattern, e.g. warnings which are expected in the test.
+	AllowedLogPatterns []*regexp.Regexp
+
+	// MetricsPort is the port the process serves its internal metrics on, passed via
+	// --metrics-addr. If not 0 the exporter queue size and the counters of lost data
+	// items are scraped from the internal metrics on every resource check and before
+	// the process is stopped gracefully, see ExporterQueueSizes and LostItems.
+	MetricsPort int
+
+	// CPUAffinity lists the CPU cores the process is pinned to, to reduce the noise of
+	// CPU measurements caused by moving between cores. Pinning uses taskset and is only
+	// supported on Linux, elsewhere or if taskset is not installed the process runs
+	// unpinned. If empty the process is not pinned.
+	CPUAffinity []int
+
+	// MemoryLimitMiB is the hard memory limit of a cgroup the process is run in, to
+	// reproduce the conditions of a container. When the process exceeds the limit it is
+	// killed by the OOM killer, which is reported distinctly from other unexpected exits,
+	// see OOMKilled. Only supported on Linux with a writable cgroup memory controller,
+	// elsewhere starting the process fails, see CheckMemoryLimitSupport. With cgroup v2
+	// the TESTBED_CGROUP environment variable must be set to a delegated cgroup. If 0 the
+	// memory is not limited.
+	MemoryLimitMiB uint32
+
+	// ServeConfigOverHTTP makes the test process serve the config on a local HTTP server
+	// and pass its URL via --config instead of the path of the config file, to exercise
+	// the startup with a remote config source. The time to fetch the config is part of
+	// the time to first item, see also ConfigFetchLatency.
+	ServeConfigOverHTTP bool
+
+	// FeatureGates lists the feature gates passed to the process via --feature-gates,
+	// e.g. "example.gate" to enable a gate or "-example.gate" to disable it, to compare
+	// the performance with and without a gated feature. The gates are reported in the
+	// results. The otelcol of this repository has no --feature-gates flag, so this
+	// requires an AgentExePath supporting it. If empty the flag is not passed.
+	FeatureGates []string
+
 	// Descriptive name of the process
 	name string
 
+	// Cores the process was pinned to, see CPUAffinity.
+	pinnedCPUs []int
+
+	// Cgroup the process runs in if MemoryLimitMiB is set and supported.
+	memCgroup *memoryCgroup
+
+	// Whether the process was killed by the OOM killer of memCgroup, set when it exits.
+	oomKilled bool
+
 	// Config file name
 	configFileName string
 
+	// Server of the config if ServeConfigOverHTTP is set, and when the config was
+	// first fetched from it.
+	configServer      *http.Server
+	configFetchMutex  sync.Mutex
+	configFetchedTime time.Time
+
+	// File the output of the process is written to.
+	logFilePath string
+
 	// Command to execute
 	cmd *exec.Cmd
 
@@ -87,6 +174,13 @@ type ChildProcess struct {
 	isStopped  bool
 	doneSignal chan struct{}
 
+	// exitSignal is closed when the process exits, exitErr is the result of waiting for it.
+	exitSignal chan struct{}
+	exitErr    error
+
+	// Last part of the standard error of the process, reported if the process crashes.
+	stderrTail *tailBuffer
+
 	// Resource specification that must be monitored for.
 	resourceSpec *ResourceSpec
 
@@ -119,6 +213,115 @@ type ChildProcess struct {
 
 	// Maximum RAM seen
 	ramMiBMax uint32
+
+	// Current and maximum number of OS threads, only sampled on Linux.
+	threadsCur atomic.Uint32
+	threadsMax uint32
+
+	// Version reported by the executable, fetched on first use.
+	agentVersion string
+
+	// Exporter queue sizes scraped on resource checks if MetricsPort is set.
+	queueSizeMutex sync.Mutex
+	queueSizes     []QueueSizeSample
+
+	// Counters of lost data items from the last scrape of the internal metrics.
+	lostItemsMutex sync.Mutex
+	lostItems      LostItemCounts
+
+	// CPU usage measured on every resource check.
+	cpuSampleMutex sync.Mutex
+	cpuSamples     []CPUSample
+
+	// RAM usage measured on every resource check.
+	ramSampleMutex sync.Mutex
+	ramSamples     []RAMSample
+
+	// Runtime memory statistics scraped from the internal metrics if MetricsPort is set.
+	// The agent refreshes them periodically, so repeated values are not stored.
+	gcSampleMutex sync.Mutex
+	gcSamples     []gcSample
+}
+
+// RAMSample is the resident set size of the process at a point of time.
+type RAMSample struct {
+	// Time since the start of the process.
+	Elapsed time.Duration
+	// Resident set size in MiBs.
+	MiB float64
+}
+
+// CPUSample is the CPU usage of the process during one resource check period.
+type CPUSample struct {
+	// Time since the start of the process at the end of the period.
+	Elapsed time.Duration
+	// Percentage of one core used during the period.
+	Percent float64
+}
+
+// QueueSizeSample is the total size of the exporter queues of the process at a point
+// of time.
+type QueueSizeSample struct {
+	// Time since the start of the process.
+	Elapsed time.Duration
+	// Number of batches in all exporter queues.
+	Size int64
+}
+
+// exporterQueueSizeMetric is the name of the internal metric reporting the number of
+// batches in the sending queue of each exporter.
+const exporterQueueSizeMetric = "otelcol_exporter_queue_size"
+
+// LostItemCounts are the numbers of data items which the agent reported as lost in its
+// internal metrics, summed over all components and signals.
+type LostItemCounts struct {
+	// Data items refused by receivers, e.g. because the memory limiter refused them.
+	Refused uint64
+	// Data items dropped by processors, e.g. because a filter processor removed them.
+	Dropped uint64
+	// Data items which exporters failed to send. Every failed attempt is counted, so
+	// items which were eventually sent after a retry are counted too.
+	SendFailed uint64
+}
+
+// Total returns the number of data items reported as lost.
+func (c LostItemCounts) Total() uint64 {
+	return c.Refused + c.Dropped + c.SendFailed
+}
+
+// lostItemMetricPrefixes are the prefixes of the internal metrics counting lost data
+// items, followed by the signal, e.g. "spans" or "metric_points".
+var lostItemMetricPrefixes = []string{
+	"otelcol_receiver_refused_",
+	"otelcol_processor_dropped_",
+	"otelcol_exporter_send_failed_",
+}
+
+// Names of the internal process metrics from which GCStats are derived.
+const (
+	processUptimeMetric       = "otelcol_process_uptime"
+	processTotalAllocMetric   = "otelcol_process_runtime_total_alloc_bytes"
+	processNumGCMetric        = "otelcol_process_runtime_num_gc"
+	processTotalGCPauseMetric = "otelcol_process_runtime_total_gc_pause_seconds"
+)
+
+// gcSample are the cumulative runtime memory statistics of the agent at a point of time.
+type gcSample struct {
+	uptimeSeconds  float64
+	totalAlloc     float64
+	numGC          float64
+	gcPauseSeconds float64
+}
+
+// GCStats summarize the heap allocations and garbage collections of the agent process
+// between the first and the last scrape of its internal metrics.
+type GCStats struct {
+	// Bytes allocated for heap objects per second.
+	AllocBytesPerSec float64
+	// Number of completed GC cycles.
+	NumGC uint64
+	// Total time spent in GC stop-the-world pauses.
+	GCPause time.Duration
 }
 
 type StartParams struct {
@@ -133,12 +336,45 @@ type ResourceConsumption struct {
 	CPUPercentMax float64
 	RAMMiBAvg     uint32
 	RAMMiBMax     uint32
+	// Peak number of OS threads, 0 if the thread count was not sampled.
+	ThreadsMax uint32
+}
+
+// SetGOMAXPROCS sets the GOMAXPROCS environment variable of the process.
+func (cp *ChildProcess) SetGOMAXPROCS(procs int) {
+	cp.setEnv("GOMAXPROCS", strconv.Itoa(procs))
+}
+
+// SetGOGC sets the GOGC environment variable of the process. Use "off" to disable
+// garbage collection.
+func (cp *ChildProcess) SetGOGC(gogc string) {
+	cp.setEnv("GOGC", gogc)
+}
+
+func (cp *ChildProcess) setEnv(key, value string) {
+	if cp.Env == nil {
+		cp.Env = map[string]string{}
+	}
+	cp.Env[key] = value
+}
+
+// envList returns the additional environment variables in KEY=value form, sorted by key.
+func (cp *ChildProcess) envList() []string {
+	env := make([]string, 0, len(cp.Env))
+	for k, v := range cp.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
 }
 
 func (cp *ChildProcess) PrepareConfig(configStr string) (configCleanup func(), err error) {
 	configCleanup = func() {
 		// NoOp
 	}
+	if err = validateConfigComponents(configStr, cp.Components); err != nil {
+		return configCleanup, err
+	}
 	var file *os.File
 	file, err = ioutil.TempFile("", "agent*.yaml")
 	if err != nil {
@@ -164,6 +400,126 @@ func (cp *ChildProcess) PrepareConfig(configStr string) (configCleanup func(), e
 	return configCleanup, err
 }
 
+// validateConfigComponents returns an error listing the components used by the config
+// which are not in supported. Nothing is validated if supported is empty.
+func validateConfigComponents(configStr string, supported []string) error {
+	if len(supported) == 0 {
+		return nil
+	}
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal([]byte(configStr), &cfg); err != nil {
+		return fmt.Errorf("cannot parse config: %v", err)
+	}
+
+	supportedSet := make(map[string]bool, len(supported))
+	for _, typ := range supported {
+		supportedSet[typ] = true
+	}
+	var unsupported []string
+	for _, kind := range []string{"receivers", "processors", "exporters", "extensions"} {
+		components, _ := cfg[kind].(map[interface{}]interface{})
+		for name := range components {
+			// The type is the part of the name before the optional "/".
+			typ := strings.SplitN(fmt.Sprint(name), "/", 2)[0]
+			if !supportedSet[typ] {
+				unsupported = append(unsupported, fmt.Sprintf("%s %q", kind, name))
+			}
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("the agent executable does not support the %s used by the config",
+			strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// AgentVersion returns the version reported by "--version" of the executable which is
+// or will be started, or "unknown" if the executable does not report it.
+func (cp *ChildProcess) AgentVersion() string {
+	if cp.agentVersion != "" {
+		return cp.agentVersion
+	}
+	cp.agentVersion = "unknown"
+	out, err := exec.Command(cp.agentExeAbsPath(), "--version").Output()
+	if err != nil {
+		return cp.agentVersion
+	}
+	// The output has the form "otelcol version latest".
+	fields := strings.Fields(string(out))
+	if len(fields) >= 3 && fields[len(fields)-2] == "version" {
+		cp.agentVersion = fields[len(fields)-1]
+	}
+	return cp.agentVersion
+}
+
+// DefaultErrorLogPattern matches the warn and more severe lines of the collector log.
+var DefaultErrorLogPattern = regexp.MustCompile(`\t(WARN|ERROR|DPANIC|PANIC|FATAL)\t`)
+
+// ErrorLogLines returns the lines of the output of the process which match
+// ErrorLogPattern and none of AllowedLogPatterns, in the order they were written.
+func (cp *ChildProcess) ErrorLogLines() ([]string, error) {
+	if cp.logFilePath == "" {
+		return nil, errors.New("the process was not started")
+	}
+	data, err := ioutil.ReadFile(cp.logFilePath)
+	if err != nil {
+		return nil, err
+	}
+	pattern := cp.ErrorLogPattern
+	if pattern == nil {
+		pattern = DefaultErrorLogPattern
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if pattern.MatchString(line) && !cp.isAllowedLogLine(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func (cp *ChildProcess) isAllowedLogLine(line string) bool {
+	for _, allowed := range cp.AllowedLogPatterns {
+		if allowed.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertNoErrorLogs fails the test if the output of the process contains lines returned
+// by ErrorLogLines, listing them. Returns true if there were none.
+func (cp *ChildProcess) AssertNoErrorLogs(t TestingT) bool {
+	lines, err := cp.ErrorLogLines()
+	if err != nil {
+		return assert.Fail(t, "Cannot read the "+cp.name+" log.", "%v", err)
+	}
+	if len(lines) > 0 {
+		return assert.Fail(t, fmt.Sprintf("%s logged %d unexpected error lines.", cp.name, len(lines)),
+			"%s", strings.Join(lines, "\n"))
+	}
+	return true
+}
+
+// agentExeAbsPath returns the absolute path of the executable which is or will be started.
+func (cp *ChildProcess) agentExeAbsPath() string {
+	exePath := cp.AgentExePath
+	if exePath == "" {
+		exePath = GlobalConfig.DefaultAgentExeRelativeFile
+	}
+	exePath = expandExeFileName(exePath)
+	if absPath, err := filepath.Abs(exePath); err == nil {
+		return absPath
+	}
+	return exePath
+}
+
+// hasCustomAgentExe returns true if the executable is not the default one.
+func (cp *ChildProcess) hasCustomAgentExe() bool {
+	return cp.AgentExePath != "" && cp.AgentExePath != GlobalConfig.DefaultAgentExeRelativeFile
+}
+
 func expandExeFileName(exeName string) string {
 	cfgTemplate, err := template.New("").Parse(exeName)
 	if err != nil {
@@ -202,6 +558,8 @@ func (cp *ChildProcess) Start(params StartParams) error {
 
 	cp.name = params.Name
 	cp.doneSignal = make(chan struct{})
+	cp.exitSignal = make(chan struct{})
+	cp.stderrTail = newTailBuffer(stderrTailSize)
 	cp.resourceSpec = params.resourceSpec
 
 	if cp.AgentExePath == "" {
@@ -221,6 +579,7 @@ func (cp *ChildProcess) Start(params StartParams) error {
 		return fmt.Errorf("cannot create %s: %s", params.LogFilePath, err.Error())
 	}
 	log.Printf("Writing %s log to %s", cp.name, params.LogFilePath)
+	cp.logFilePath = params.LogFilePath
 
 	// Prepare to start the process.
 	// #nosec
@@ -233,10 +592,32 @@ func (cp *ChildProcess) Start(params StartParams) error {
 				return err
 			}
 		}
+		configSource := cp.configFileName
+		if cp.ServeConfigOverHTTP {
+			if configSource, err = cp.serveConfig(); err != nil {
+				return err
+			}
+		}
 		args = append(args, "--config")
-		args = append(args, cp.configFileName)
+		args = append(args, configSource)
+	}
+	if cp.MetricsPort != 0 {
+		args = append(args, "--metrics-addr", fmt.Sprintf("%s:%d", DefaultHost, cp.MetricsPort))
+	}
+	if len(cp.FeatureGates) > 0 {
+		args = append(args, "--feature-gates="+strings.Join(cp.FeatureGates, ","))
+		log.Printf("%s feature gates: %s", cp.name, strings.Join(cp.FeatureGates, ","))
+	}
+	cp.cmd = cp.command(exePath, args)
+	if cp.MemoryLimitMiB != 0 {
+		if err = cp.limitMemory(); err != nil {
+			return err
+		}
+	}
+	if len(cp.Env) > 0 {
+		cp.cmd.Env = append(os.Environ(), cp.envList()...)
+		log.Printf("%s environment: %s", cp.name, strings.Join(cp.envList(), " "))
 	}
-	cp.cmd = exec.Command(exePath, args...)
 
 	// Capture standard output and standard error.
 	stdoutIn, err := cp.cmd.StdoutPipe()
@@ -267,14 +648,189 @@ func (cp *ChildProcess) Start(params StartParams) error {
 		cp.outputWG.Done()
 	}()
 	go func() {
-		_, _ = io.Copy(logFile, stderrIn)
+		_, _ = io.Copy(io.MultiWriter(logFile, cp.stderrTail), stderrIn)
 		cp.outputWG.Done()
 	}()
 
+	// Wait for the process to exit, whether it is stopped or crashes.
+	go func() {
+		// Wait for output to be fully copied.
+		cp.outputWG.Wait()
+		cp.exitErr = cp.cmd.Wait()
+		if cp.memCgroup != nil {
+			cp.releaseMemoryCgroup()
+		}
+		if cp.configServer != nil {
+			_ = cp.configServer.Close()
+		}
+		close(cp.exitSignal)
+	}()
+
 	return err
 }
 
+// serveConfig starts serving the config file on a local HTTP server and returns its URL.
+func (cp *ChildProcess) serveConfig() (string, error) {
+	config, err := ioutil.ReadFile(cp.configFileName)
+	if err != nil {
+		return "", err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(DefaultHost, "0"))
+	if err != nil {
+		return "", fmt.Errorf("cannot serve config of %s: %s", cp.name, err.Error())
+	}
+
+	configPath := "/" + filepath.Base(cp.configFileName)
+	mux := http.NewServeMux()
+	mux.HandleFunc(configPath, func(w http.ResponseWriter, _ *http.Request) {
+		cp.configFetchMutex.Lock()
+		if cp.configFetchedTime.IsZero() {
+			cp.configFetchedTime = time.Now()
+		}
+		cp.configFetchMutex.Unlock()
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(config)
+	})
+	cp.configServer = &http.Server{Handler: mux}
+	go func() {
+		_ = cp.configServer.Serve(listener)
+	}()
+
+	url := "http://" + listener.Addr().String() + configPath
+	log.Printf("Serving %s config at %s", cp.name, url)
+	return url, nil
+}
+
+// ConfigFetchLatency returns the time from the start of the process until it fetched
+// its config, if ServeConfigOverHTTP is set. Returns 0 if the config was not fetched.
+func (cp *ChildProcess) ConfigFetchLatency() time.Duration {
+	cp.configFetchMutex.Lock()
+	defer cp.configFetchMutex.Unlock()
+	if cp.configFetchedTime.IsZero() || cp.configFetchedTime.Before(cp.startTime) {
+		return 0
+	}
+	return cp.configFetchedTime.Sub(cp.startTime)
+}
+
+// command creates the command to run the executable, pinned to the CPUAffinity cores
+// if supported.
+func (cp *ChildProcess) command(exePath string, args []string) *exec.Cmd {
+	cp.pinnedCPUs = nil
+	if len(cp.CPUAffinity) == 0 {
+		return exec.Command(exePath, args...)
+	}
+	taskset, err := exec.LookPath("taskset")
+	if runtime.GOOS != "linux" || err != nil {
+		log.Printf("Cannot pin %s to CPUs %s, CPU affinity is only supported on Linux with taskset installed",
+			cp.name, formatCPUList(cp.CPUAffinity))
+		return exec.Command(exePath, args...)
+	}
+	cp.pinnedCPUs = cp.CPUAffinity
+	log.Printf("Pinning %s to CPUs %s", cp.name, formatCPUList(cp.pinnedCPUs))
+	// taskset executes the process in place so its pid is the one of the process.
+	return exec.Command(taskset, append([]string{"-c", formatCPUList(cp.pinnedCPUs), exePath}, args...)...)
+}
+
+// limitMemory makes the command join a cgroup limited to MemoryLimitMiB before it
+// executes the process, so that all memory of the process is accounted.
+func (cp *ChildProcess) limitMemory() error {
+	cg, err := newMemoryCgroup(uint64(cp.MemoryLimitMiB) * 1024 * 1024)
+	if err != nil {
+		return fmt.Errorf("cannot limit memory of %s to %d MiB: %s", cp.name, cp.MemoryLimitMiB, err.Error())
+	}
+	cp.memCgroup = cg
+	log.Printf("Limiting memory of %s to %d MiB in cgroup %s", cp.name, cp.MemoryLimitMiB, cg.dir)
+
+	// The shell executes the process in place so its pid is the one of the process.
+	script := `echo $$ > "$0" && exec "$@"`
+	cp.cmd = exec.Command("/bin/sh", append([]string{"-c", script, cg.procsFile(), cp.cmd.Path}, cp.cmd.Args[1:]...)...)
+	return nil
+}
+
+// releaseMemoryCgroup records whether the exited process was killed by the OOM killer
+// and removes its cgroup.
+func (cp *ChildProcess) releaseMemoryCgroup() {
+	kills, err := cp.memCgroup.oomKills()
+	if err != nil {
+		log.Printf("Cannot read OOM kills of %s: %s", cp.name, err.Error())
+	}
+	cp.oomKilled = kills > 0
+	if err = cp.memCgroup.remove(); err != nil {
+		log.Printf("Cannot remove cgroup %s: %s", cp.memCgroup.dir, err.Error())
+	}
+}
+
+// OOMKilled returns whether the process was killed because it exceeded MemoryLimitMiB.
+// Only valid after the process exited.
+func (cp *ChildProcess) OOMKilled() bool {
+	return cp.oomKilled
+}
+
+// MemoryLimited returns whether the process runs in a cgroup limited to MemoryLimitMiB.
+func (cp *ChildProcess) MemoryLimited() bool {
+	return cp.memCgroup != nil
+}
+
+// PinnedCPUs returns the CPU cores the process was pinned to when it was started, or
+// nil if it was not pinned, see CPUAffinity.
+func (cp *ChildProcess) PinnedCPUs() []int {
+	return cp.pinnedCPUs
+}
+
+// formatCPUList formats CPU core numbers like the list format of taskset, e.g. "0,2".
+func formatCPUList(cpus []int) string {
+	list := make([]string, len(cpus))
+	for i, cpu := range cpus {
+		list[i] = strconv.Itoa(cpu)
+	}
+	return strings.Join(list, ",")
+}
+
 func (cp *ChildProcess) Stop() (stopped bool, err error) {
+	return cp.stop(syscall.SIGTERM)
+}
+
+// Kill stops the process immediately with SIGKILL, without giving it a chance to
+// shut down gracefully, e.g. to flush its queues. The returned error reports the
+// killed exit status.
+func (cp *ChildProcess) Kill() (stopped bool, err error) {
+	return cp.stop(syscall.SIGKILL)
+}
+
+// DumpGoroutines stops the process with SIGQUIT, which makes the Go runtime write the
+// stack traces of all goroutines to the standard error before exiting, and returns
+// the output written by the process after the signal was sent, e.g. to diagnose a hang.
+// The process cannot be used anymore afterwards.
+func (cp *ChildProcess) DumpGoroutines() (string, error) {
+	if !cp.isStarted || cp.isStopped {
+		return "", fmt.Errorf("%s is not running", cp.name)
+	}
+
+	// The output copied to the log file before the signal is not part of the dump.
+	var offset int64
+	if fi, err := os.Stat(cp.logFilePath); err == nil {
+		offset = fi.Size()
+	}
+
+	// The process is expected to exit with an error status.
+	_, _ = cp.stop(syscall.SIGQUIT)
+
+	f, err := os.Open(cp.logFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	dump, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(dump), nil
+}
+
+func (cp *ChildProcess) stop(sig syscall.Signal) (stopped bool, err error) {
 	if !cp.isStarted || cp.isStopped {
 		return false, nil
 	}
@@ -287,17 +843,34 @@ func (cp *ChildProcess) Stop() (stopped bool, err error) {
 
 		cp.isStopped = true
 
-		log.Printf("Gracefully terminating %s pid=%d, sending SIGTEM...", cp.name, cp.cmd.Process.Pid)
+		// Scrape the internal metrics a last time so that the counters include all
+		// data processed before the agent shuts down.
+		if cp.MetricsPort != 0 && sig == syscall.SIGTERM {
+			cp.fetchInternalMetrics()
+		}
 
 		// Notify resource monitor to stop.
 		close(cp.doneSignal)
 
-		// Gracefully signal process to stop.
-		if err = cp.cmd.Process.Signal(syscall.SIGTERM); err != nil {
-			log.Printf("Cannot send SIGTEM: %s", err.Error())
-		}
+		switch sig {
+		case syscall.SIGKILL:
+			log.Printf("Killing %s pid=%d, sending SIGKILL...", cp.name, cp.cmd.Process.Pid)
+			if err = cp.cmd.Process.Signal(syscall.SIGKILL); err != nil {
+				log.Printf("Cannot send SIGKILL: %s", err.Error())
+			}
+		case syscall.SIGQUIT:
+			log.Printf("Dumping goroutines of %s pid=%d, sending SIGQUIT...", cp.name, cp.cmd.Process.Pid)
+			if err = cp.cmd.Process.Signal(syscall.SIGQUIT); err != nil {
+				log.Printf("Cannot send SIGQUIT: %s", err.Error())
+			}
+		default:
+			log.Printf("Gracefully terminating %s pid=%d, sending SIGTEM...", cp.name, cp.cmd.Process.Pid)
 
-		finished := make(chan struct{})
+			// Gracefully signal process to stop.
+			if err = cp.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+				log.Printf("Cannot send SIGTEM: %s", err.Error())
+			}
+		}
 
 		// Setup a goroutine to wait a while for process to finish and send kill signal
 		// to the process if it doesn't finish.
@@ -309,26 +882,22 @@ func (cp *ChildProcess) Stop() (stopped bool, err error) {
 				// Time is out. Kill the process.
 				log.Printf("%s pid=%d is not responding to SIGTERM. Sending SIGKILL to kill forcedly.",
 					cp.name, cp.cmd.Process.Pid)
-				if err = cp.cmd.Process.Signal(syscall.SIGKILL); err != nil {
+				if err := cp.cmd.Process.Signal(syscall.SIGKILL); err != nil {
 					log.Printf("Cannot send SIGKILL: %s", err.Error())
 				}
-			case <-finished:
+			case <-cp.exitSignal:
 				// Process is successfully finished.
 			}
 		}()
 
-		// Wait for output to be fully copied.
-		cp.outputWG.Wait()
-
 		// Wait for process to terminate
-		err = cp.cmd.Wait()
-
-		// Let goroutine know process is finished.
-		close(finished)
+		<-cp.exitSignal
+		err = cp.exitErr
 
 		// Set resource consumption stats to 0
 		cp.ramMiBCur.Store(0)
 		cp.cpuPercentX1000Cur.Store(0)
+		cp.threadsCur.Store(0)
 
 		log.Printf("%s process stopped, exit code=%d", cp.name, cp.cmd.ProcessState.ExitCode())
 
@@ -340,10 +909,18 @@ func (cp *ChildProcess) Stop() (stopped bool, err error) {
 	return stopped, err
 }
 
+// WatchResourceConsumption monitors the process until it is stopped. It returns an
+// error if the resource consumption exceeds the ResourceSpec or if the process exits
+// without Stop being called, e.g. because it crashed or was killed.
 func (cp *ChildProcess) WatchResourceConsumption() error {
 	if !cp.resourceSpec.isSpecified() {
-		// Resource monitoring is not enabled.
-		return nil
+		// Resource monitoring is not enabled, only watch for unexpected exit.
+		select {
+		case <-cp.exitSignal:
+			return cp.unexpectedExitError()
+		case <-cp.doneSignal:
+			return nil
+		}
 	}
 
 	var err error
@@ -354,6 +931,7 @@ func (cp *ChildProcess) WatchResourceConsumption() error {
 	}
 
 	cp.fetchRAMUsage()
+	cp.fetchThreadCount()
 
 	// Begin measuring elapsed and process CPU times.
 	cp.lastElapsedTime = time.Now()
@@ -372,12 +950,19 @@ func (cp *ChildProcess) WatchResourceConsumption() error {
 		case <-ticker.C:
 			cp.fetchRAMUsage()
 			cp.fetchCPUUsage()
+			cp.fetchThreadCount()
+			if cp.MetricsPort != 0 {
+				cp.fetchInternalMetrics()
+			}
 
 			if err := cp.checkAllowedResourceUsage(); err != nil {
 				cp.Stop()
 				return err
 			}
 
+		case <-cp.exitSignal:
+			return cp.unexpectedExitError()
+
 		case <-cp.doneSignal:
 			log.Printf("Stopping process monitor.")
 			return nil
@@ -385,6 +970,61 @@ func (cp *ChildProcess) WatchResourceConsumption() error {
 	}
 }
 
+// unexpectedExitError returns an error describing the exit of the process, or nil if the
+// process exited because Stop was called.
+func (cp *ChildProcess) unexpectedExitError() error {
+	select {
+	case <-cp.doneSignal:
+		// Stop was called before the process exited.
+		return nil
+	default:
+	}
+
+	errMsg := fmt.Sprintf("%s process exited unexpectedly, exit code=%d", cp.name, cp.cmd.ProcessState.ExitCode())
+	if cp.oomKilled {
+		errMsg = fmt.Sprintf("%s process was OOM-killed, exceeded the memory limit of %d MiB", cp.name, cp.MemoryLimitMiB)
+	}
+	if cp.exitErr != nil {
+		errMsg += fmt.Sprintf(" (%s)", cp.exitErr.Error())
+	}
+	if tail := cp.stderrTail.String(); tail != "" {
+		errMsg += ", last output:\n" + tail
+	}
+	log.Print(errMsg)
+	return errors.New(errMsg)
+}
+
+// stderrTailSize is the number of trailing bytes of the standard error of the process
+// to report if the process crashes.
+const stderrTailSize = 4096
+
+// tailBuffer is an io.Writer which keeps the last max bytes written to it.
+type tailBuffer struct {
+	mutex sync.Mutex
+	buf   []byte
+	max   int
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (tb *tailBuffer) Write(p []byte) (int, error) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.buf = append(tb.buf, p...)
+	if len(tb.buf) > tb.max {
+		tb.buf = tb.buf[len(tb.buf)-tb.max:]
+	}
+	return len(p), nil
+}
+
+func (tb *tailBuffer) String() string {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	return string(bytes.TrimSpace(tb.buf))
+}
+
 func (cp *ChildProcess) GetProcessMon() *process.Process {
 	return cp.processMon
 }
@@ -410,6 +1050,58 @@ func (cp *ChildProcess) fetchRAMUsage() {
 
 	// Store current usage.
 	cp.ramMiBCur.Store(ramMiBCur)
+
+	cp.ramSampleMutex.Lock()
+	defer cp.ramSampleMutex.Unlock()
+	cp.ramSamples = append(cp.ramSamples, RAMSample{
+		Elapsed: time.Since(cp.startTime),
+		MiB:     float64(mi.RSS) / mibibyte,
+	})
+}
+
+// RAMSamples returns the RSS measured on every resource check, in the order it was
+// measured. Returns nil if resource consumption is not monitored.
+func (cp *ChildProcess) RAMSamples() []RAMSample {
+	cp.ramSampleMutex.Lock()
+	defer cp.ramSampleMutex.Unlock()
+	return append([]RAMSample(nil), cp.ramSamples...)
+}
+
+// fetchThreadCount samples the number of OS threads of the process. Only supported on
+// Linux, where it is read from /proc/<pid>/status.
+func (cp *ChildProcess) fetchThreadCount() {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	threads, err := readThreadCount(int(cp.processMon.Pid))
+	if err != nil {
+		log.Printf("cannot get thread count of %d: %s", cp.processMon.Pid, err.Error())
+		return
+	}
+	cp.threadsCur.Store(threads)
+	if threads > cp.threadsMax {
+		cp.threadsMax = threads
+	}
+}
+
+// readThreadCount returns the number of threads of the process with the given pid from
+// the Threads line of /proc/<pid>/status.
+func readThreadCount(pid int) (uint32, error) {
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "Threads:") {
+			continue
+		}
+		threads, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Threads:")), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid thread count %q: %s", line, err.Error())
+		}
+		return uint32(threads), nil
+	}
+	return 0, errors.New("no thread count in process status")
 }
 
 func (cp *ChildProcess) fetchCPUUsage() {
@@ -443,6 +1135,152 @@ func (cp *ChildProcess) fetchCPUUsage() {
 
 	// Store current usage.
 	cp.cpuPercentX1000Cur.Store(curCPUPercentageX1000)
+
+	cp.cpuSampleMutex.Lock()
+	defer cp.cpuSampleMutex.Unlock()
+	cp.cpuSamples = append(cp.cpuSamples, CPUSample{
+		Elapsed: now.Sub(cp.startTime),
+		Percent: cpuPercent,
+	})
+}
+
+// CPUSamples returns the CPU usage measured on every resource check, in the order it
+// was measured. Returns nil if resource consumption is not monitored.
+func (cp *ChildProcess) CPUSamples() []CPUSample {
+	cp.cpuSampleMutex.Lock()
+	defer cp.cpuSampleMutex.Unlock()
+	return append([]CPUSample(nil), cp.cpuSamples...)
+}
+
+func (cp *ChildProcess) fetchInternalMetrics() {
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s:%d/metrics", DefaultHost, cp.MetricsPort))
+	if err != nil {
+		log.Printf("cannot scrape internal metrics of %s: %s", cp.name, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		log.Printf("cannot parse internal metrics of %s: %s", cp.name, err.Error())
+		return
+	}
+
+	// Sum the queues of all exporters. The metric is missing if no exporter has a queue.
+	var size int64
+	if family, ok := families[exporterQueueSizeMetric]; ok {
+		for _, m := range family.GetMetric() {
+			size += int64(m.GetGauge().GetValue())
+		}
+	}
+
+	cp.queueSizeMutex.Lock()
+	cp.queueSizes = append(cp.queueSizes, QueueSizeSample{
+		Elapsed: time.Since(cp.startTime),
+		Size:    size,
+	})
+	cp.queueSizeMutex.Unlock()
+
+	// The counters are missing until a component reports its first data items.
+	var lost LostItemCounts
+	counters := []*uint64{&lost.Refused, &lost.Dropped, &lost.SendFailed}
+	for name, family := range families {
+		for i, prefix := range lostItemMetricPrefixes {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				*counters[i] += uint64(m.GetCounter().GetValue())
+			}
+		}
+	}
+
+	cp.lostItemsMutex.Lock()
+	cp.lostItems = lost
+	cp.lostItemsMutex.Unlock()
+
+	cp.recordGCSample(families)
+}
+
+// recordGCSample stores the runtime memory statistics of the scraped internal metrics
+// if they were refreshed by the agent since the previous scrape.
+func (cp *ChildProcess) recordGCSample(families map[string]*dto.MetricFamily) {
+	value := func(name string) (float64, bool) {
+		family, ok := families[name]
+		if !ok || len(family.GetMetric()) == 0 {
+			return 0, false
+		}
+		m := family.GetMetric()[0]
+		if m.GetCounter() != nil {
+			return m.GetCounter().GetValue(), true
+		}
+		return m.GetGauge().GetValue(), true
+	}
+
+	var sample gcSample
+	var ok bool
+	// The metrics are missing until the agent refreshes them for the first time.
+	if sample.uptimeSeconds, ok = value(processUptimeMetric); !ok {
+		return
+	}
+	if sample.totalAlloc, ok = value(processTotalAllocMetric); !ok {
+		return
+	}
+	// Older agents do not report the GC metrics.
+	sample.numGC, _ = value(processNumGCMetric)
+	sample.gcPauseSeconds, _ = value(processTotalGCPauseMetric)
+
+	cp.gcSampleMutex.Lock()
+	defer cp.gcSampleMutex.Unlock()
+	if n := len(cp.gcSamples); n > 0 && cp.gcSamples[n-1].uptimeSeconds == sample.uptimeSeconds {
+		return
+	}
+	cp.gcSamples = append(cp.gcSamples, sample)
+}
+
+// GCStats returns the allocation rate and the garbage collections of the agent scraped
+// from its internal metrics. The agent refreshes these metrics every 5 seconds, so ok
+// is false unless MetricsPort is set and the agent ran long enough to refresh them at
+// least twice.
+func (cp *ChildProcess) GCStats() (stats GCStats, ok bool) {
+	cp.gcSampleMutex.Lock()
+	defer cp.gcSampleMutex.Unlock()
+	return computeGCStats(cp.gcSamples)
+}
+
+func computeGCStats(samples []gcSample) (GCStats, bool) {
+	if len(samples) < 2 {
+		return GCStats{}, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	elapsed := last.uptimeSeconds - first.uptimeSeconds
+	if elapsed <= 0 {
+		return GCStats{}, false
+	}
+	return GCStats{
+		AllocBytesPerSec: (last.totalAlloc - first.totalAlloc) / elapsed,
+		NumGC:            uint64(last.numGC - first.numGC),
+		GCPause:          time.Duration((last.gcPauseSeconds - first.gcPauseSeconds) * float64(time.Second)),
+	}, true
+}
+
+// ExporterQueueSizes returns the exporter queue sizes scraped from the internal metrics
+// on every resource check, in the order they were scraped. Returns nil if MetricsPort
+// is not set.
+func (cp *ChildProcess) ExporterQueueSizes() []QueueSizeSample {
+	cp.queueSizeMutex.Lock()
+	defer cp.queueSizeMutex.Unlock()
+	return append([]QueueSizeSample(nil), cp.queueSizes...)
+}
+
+// LostItems returns the counters of lost data items from the last scrape of the internal
+// metrics. All counters are 0 if MetricsPort is not set.
+func (cp *ChildProcess) LostItems() LostItemCounts {
+	cp.lostItemsMutex.Lock()
+	defer cp.lostItemsMutex.Unlock()
+	return cp.lostItems
 }
 
 func (cp *ChildProcess) checkAllowedResourceUsage() error {
@@ -459,6 +1297,12 @@ func (cp *ChildProcess) checkAllowedResourceUsage() error {
 			cp.ramMiBCur.String(), cp.resourceSpec.ExpectedMaxRAM)
 	}
 
+	// Check if current thread count exceeds expected.
+	if cp.resourceSpec.ExpectedMaxThreads != 0 && cp.threadsCur.Load() > cp.resourceSpec.ExpectedMaxThreads {
+		errMsg = fmt.Sprintf("Thread count is %d, max expected is %d",
+			cp.threadsCur.Load(), cp.resourceSpec.ExpectedMaxThreads)
+	}
+
 	if errMsg == "" {
 		return nil
 	}
@@ -501,6 +1345,7 @@ func (cp *ChildProcess) GetTotalConsumption() *ResourceConsumption {
 			rc.RAMMiBAvg = uint32(cp.ramMiBTotal / uint64(cp.memProbeCount))
 		}
 		rc.RAMMiBMax = cp.ramMiBMax
+		rc.ThreadsMax = cp.threadsMax
 	}
 
 	return rc
@@ -0,0 +1,329 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/shirou/gopsutil/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startScript starts cp running a shell script with the given body.
+func startScript(t *testing.T, cp *ChildProcess, dir string, body string) {
+	exePath := filepath.Join(dir, "agent.sh")
+	require.NoError(t, ioutil.WriteFile(exePath, []byte("#!/bin/sh\n"+body+"\n"), 0700))
+
+	cp.AgentExePath = exePath
+	require.NoError(t, cp.Start(StartParams{
+		Name:         "Agent",
+		LogFilePath:  filepath.Join(dir, "agent.log"),
+		CmdArgs:      []string{"--config", "unused.yaml"},
+		resourceSpec: &ResourceSpec{},
+	}))
+}
+
+func TestChildProcessUnexpectedExit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cp := &ChildProcess{}
+	startScript(t, cp, dir, "echo 'fatal error: out of memory' >&2\nexit 3")
+
+	err = cp.WatchResourceConsumption()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited unexpectedly, exit code=3")
+	assert.Contains(t, err.Error(), "fatal error: out of memory")
+
+	// Stopping an already exited process must not hang.
+	cp.Stop()
+}
+
+func TestChildProcessIntentionalStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cp := &ChildProcess{}
+	startScript(t, cp, dir, "exec sleep 30")
+
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- cp.WatchResourceConsumption()
+	}()
+
+	stopped, _ := cp.Stop()
+	assert.True(t, stopped)
+
+	select {
+	case err := <-watchErr:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("process watcher did not return after Stop")
+	}
+}
+
+func TestChildProcessEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cp := &ChildProcess{}
+	cp.SetGOMAXPROCS(1)
+	cp.SetGOGC("50")
+	assert.Equal(t, []string{"GOGC=50", "GOMAXPROCS=1"}, cp.envList())
+
+	startScript(t, cp, dir, "echo GOMAXPROCS=$GOMAXPROCS GOGC=$GOGC")
+	<-cp.exitSignal
+	cp.Stop()
+
+	output, err := ioutil.ReadFile(filepath.Join(dir, "agent.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "GOMAXPROCS=1 GOGC=50\n", string(output))
+}
+
+func TestChildProcessFeatureGates(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cp := &ChildProcess{FeatureGates: []string{"example.gate", "-other.gate"}}
+	startScript(t, cp, dir, `echo "$@"`)
+	<-cp.exitSignal
+	cp.Stop()
+
+	output, err := ioutil.ReadFile(filepath.Join(dir, "agent.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "--config unused.yaml --feature-gates=example.gate,-other.gate\n", string(output))
+
+	// The flag is not passed without gates.
+	cp = &ChildProcess{}
+	startScript(t, cp, dir, `echo "$@"`)
+	<-cp.exitSignal
+	cp.Stop()
+
+	output, err = ioutil.ReadFile(filepath.Join(dir, "agent.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "--config unused.yaml\n", string(output))
+}
+
+func TestChildProcessThreadCount(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("thread count is only sampled on Linux")
+	}
+	// The test process runs several threads, sample it instead of a child process.
+	cp := &ChildProcess{resourceSpec: &ResourceSpec{ExpectedMaxThreads: 1}}
+	var err error
+	cp.processMon, err = process.NewProcess(int32(os.Getpid()))
+	require.NoError(t, err)
+
+	cp.fetchThreadCount()
+	threads := cp.threadsCur.Load()
+	assert.Greater(t, threads, uint32(1))
+	assert.Equal(t, threads, cp.GetTotalConsumption().ThreadsMax)
+
+	err = cp.checkAllowedResourceUsage()
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf("Thread count is %d, max expected is 1", threads), err.Error())
+
+	cp.resourceSpec.ExpectedMaxThreads = 10_000
+	assert.NoError(t, cp.checkAllowedResourceUsage())
+
+	_, err = readThreadCount(-1)
+	assert.Error(t, err)
+}
+
+func TestChildProcessCPUAffinity(t *testing.T) {
+	if _, err := exec.LookPath("taskset"); runtime.GOOS != "linux" || err != nil {
+		t.Skip("CPU affinity requires Linux with taskset installed")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cp := &ChildProcess{CPUAffinity: []int{0}}
+	startScript(t, cp, dir, "grep Cpus_allowed_list /proc/self/status")
+	<-cp.exitSignal
+	cp.Stop()
+
+	assert.Equal(t, []int{0}, cp.PinnedCPUs())
+	output, err := ioutil.ReadFile(filepath.Join(dir, "agent.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "Cpus_allowed_list:\t0\n", string(output))
+}
+
+func TestChildProcessMemoryLimitOOMKill(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory limits require Linux")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	if err = CheckMemoryLimitSupport(); err != nil {
+		t.Skip(err.Error())
+	}
+
+	// tail keeps the whole input in memory because it contains no line break.
+	cp := &ChildProcess{MemoryLimitMiB: 16}
+	startScript(t, cp, dir, "head -c 256m /dev/zero | tail")
+	require.True(t, cp.MemoryLimited())
+
+	err = cp.WatchResourceConsumption()
+	require.Error(t, err)
+	assert.True(t, cp.OOMKilled())
+	assert.Contains(t, err.Error(), "Agent process was OOM-killed, exceeded the memory limit of 16 MiB")
+	_, statErr := os.Stat(cp.memCgroup.dir)
+	assert.True(t, os.IsNotExist(statErr), "cgroup was not removed")
+}
+
+func TestChildProcessMemoryLimitUnsupported(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory limits require Linux")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The directory is not a cgroup.
+	require.NoError(t, os.Setenv(cgroupParentEnvVarName, dir))
+	defer os.Unsetenv(cgroupParentEnvVarName)
+	assert.Error(t, CheckMemoryLimitSupport())
+
+	exePath := filepath.Join(dir, "agent.sh")
+	require.NoError(t, ioutil.WriteFile(exePath, []byte("#!/bin/sh\nsleep 10\n"), 0700))
+	cp := &ChildProcess{AgentExePath: exePath, MemoryLimitMiB: 16}
+	err = cp.Start(StartParams{
+		Name:         "Agent",
+		LogFilePath:  filepath.Join(dir, "agent.log"),
+		CmdArgs:      []string{"--config", "unused.yaml"},
+		resourceSpec: &ResourceSpec{},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot limit memory of Agent to 16 MiB")
+	assert.False(t, cp.MemoryLimited())
+}
+
+func TestChildProcessValidateComponents(t *testing.T) {
+	config := `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+exporters:
+  otlphttp/backend:
+    endpoint: "http://localhost:4318"
+processors:
+  batch:
+extensions:
+  pprof:
+`
+	cp := &ChildProcess{}
+	cleanup, err := cp.PrepareConfig(config)
+	require.NoError(t, err, "config must not be validated if components are not specified")
+	cleanup()
+
+	cp.Components = []string{"otlp", "otlphttp", "batch", "pprof"}
+	cleanup, err = cp.PrepareConfig(config)
+	require.NoError(t, err)
+	cleanup()
+
+	cp.Components = []string{"otlp", "pprof"}
+	_, err = cp.PrepareConfig(config)
+	require.Error(t, err)
+	assert.Equal(t, `the agent executable does not support the exporters "otlphttp/backend", processors "batch" used by the config`,
+		err.Error())
+}
+
+func TestChildProcessAgentVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "childprocess")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exePath := filepath.Join(dir, "custom-otelcol")
+	require.NoError(t, ioutil.WriteFile(exePath, []byte("#!/bin/sh\necho custom-otelcol version v1.2.3\n"), 0700))
+	cp := &ChildProcess{AgentExePath: exePath}
+	assert.True(t, cp.hasCustomAgentExe())
+	assert.Equal(t, "v1.2.3", cp.AgentVersion())
+
+	cp = &ChildProcess{AgentExePath: filepath.Join(dir, "missing")}
+	assert.Equal(t, "unknown", cp.AgentVersion())
+
+	assert.False(t, (&ChildProcess{}).hasCustomAgentExe())
+}
+
+func TestChildProcessGCStats(t *testing.T) {
+	scrape := func(uptime, totalAlloc, numGC, gcPause float64) map[string]*dto.MetricFamily {
+		text := fmt.Sprintf("# TYPE otelcol_process_uptime counter\notelcol_process_uptime %g\n"+
+			"# TYPE otelcol_process_runtime_total_alloc_bytes gauge\notelcol_process_runtime_total_alloc_bytes %g\n"+
+			"# TYPE otelcol_process_runtime_num_gc gauge\notelcol_process_runtime_num_gc %g\n"+
+			"# TYPE otelcol_process_runtime_total_gc_pause_seconds gauge\notelcol_process_runtime_total_gc_pause_seconds %g\n",
+			uptime, totalAlloc, numGC, gcPause)
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(strings.NewReader(text))
+		require.NoError(t, err)
+		return families
+	}
+
+	cp := &ChildProcess{}
+	_, ok := cp.GCStats()
+	assert.False(t, ok)
+
+	// The metrics are missing until the agent refreshes them for the first time.
+	cp.recordGCSample(map[string]*dto.MetricFamily{})
+	cp.recordGCSample(scrape(5, 100e6, 10, 0.01))
+	_, ok = cp.GCStats()
+	assert.False(t, ok)
+
+	// Unchanged metrics do not count as a new sample.
+	cp.recordGCSample(scrape(5, 100e6, 10, 0.01))
+	_, ok = cp.GCStats()
+	assert.False(t, ok)
+
+	cp.recordGCSample(scrape(10, 150e6, 14, 0.012))
+	cp.recordGCSample(scrape(15, 200e6, 20, 0.015))
+	stats, ok := cp.GCStats()
+	require.True(t, ok)
+	assert.InDelta(t, 10e6, stats.AllocBytesPerSec, 1e-6)
+	assert.EqualValues(t, 10, stats.NumGC)
+	assert.InDelta(t, 5*time.Millisecond, stats.GCPause, float64(time.Microsecond))
+}
@@ -0,0 +1,132 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// componentFuncRegexp matches the names of functions in the package of a collector
+// component and captures the component kind and the package name. Shared helper
+// packages like processorhelper are not components.
+var componentFuncRegexp = regexp.MustCompile(`^go\.opentelemetry\.io/collector/(receiver|processor|exporter|extension)/([^/.]+)`)
+
+// ComponentCPUShare is the share of the CPU time of the agent spent in one component.
+type ComponentCPUShare struct {
+	// Component is the kind and the package name of the component, for example
+	// "processor/batchprocessor".
+	Component string
+	// Percent of the CPU time of the whole profile spent in the component.
+	Percent float64
+}
+
+func (s ComponentCPUShare) String() string {
+	return fmt.Sprintf("%s %.1f%%", s.Component, s.Percent)
+}
+
+// FindComponentCPUShares attributes the CPU time recorded in the CPU profile at
+// profilePath, which the agent writes with the pprof extension, to the receivers,
+// processors, exporters and extensions of the collector. The internal metrics of the
+// agent do not contain timings, so the profile is the only source of the breakdown.
+// A sample is attributed to the innermost component in its stack, so the time a
+// processor spends in the next consumer of the pipeline is not counted for it. Time
+// outside of any component, like the garbage collector, is not attributed. The shares
+// are ordered by decreasing percentage.
+func FindComponentCPUShares(profilePath string) ([]ComponentCPUShare, error) {
+	f, err := os.Open(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prof, err := profile.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse CPU profile %s: %s", profilePath, err.Error())
+	}
+	return componentCPUShares(prof)
+}
+
+func componentCPUShares(prof *profile.Profile) ([]ComponentCPUShare, error) {
+	valueIndex := -1
+	for i, st := range prof.SampleType {
+		if st.Type == "cpu" {
+			valueIndex = i
+		}
+	}
+	if valueIndex < 0 {
+		return nil, fmt.Errorf("not a CPU profile, sample types are %v", prof.SampleType)
+	}
+
+	var total int64
+	perComponent := make(map[string]int64)
+	for _, sample := range prof.Sample {
+		value := sample.Value[valueIndex]
+		total += value
+		if component := innermostComponent(sample); component != "" {
+			perComponent[component] += value
+		}
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	shares := make([]ComponentCPUShare, 0, len(perComponent))
+	for component, value := range perComponent {
+		shares = append(shares, ComponentCPUShare{
+			Component: component,
+			Percent:   100 * float64(value) / float64(total),
+		})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Percent != shares[j].Percent {
+			return shares[i].Percent > shares[j].Percent
+		}
+		return shares[i].Component < shares[j].Component
+	})
+	return shares, nil
+}
+
+// innermostComponent returns the component of the innermost stack frame of sample
+// which belongs to a component, "" if there is none.
+func innermostComponent(sample *profile.Sample) string {
+	// Locations are ordered from the leaf to the root and the lines of a location from
+	// the innermost inlined function to the function it was inlined into.
+	for _, loc := range sample.Location {
+		for _, line := range loc.Line {
+			if line.Function == nil {
+				continue
+			}
+			if m := componentFuncRegexp.FindStringSubmatch(line.Function.Name); m != nil && !strings.HasSuffix(m[2], "helper") {
+				return m[1] + "/" + m[2]
+			}
+		}
+	}
+	return ""
+}
+
+// formatComponentCPUShares formats shares as a comma separated list.
+func formatComponentCPUShares(shares []ComponentCPUShare) string {
+	parts := make([]string, len(shares))
+	for i, share := range shares {
+		parts[i] = share.String()
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindComponentCPUShares(t *testing.T) {
+	var functions []*profile.Function
+	var locations []*profile.Location
+	loc := func(names ...string) *profile.Location {
+		l := &profile.Location{ID: uint64(len(locations) + 1)}
+		for _, name := range names {
+			fn := &profile.Function{ID: uint64(len(functions) + 1), Name: name}
+			functions = append(functions, fn)
+			l.Line = append(l.Line, profile.Line{Function: fn})
+		}
+		locations = append(locations, l)
+		return l
+	}
+
+	gc := loc("runtime.gcBgMarkWorker")
+	marshal := loc("github.com/gogo/protobuf/proto.Marshal")
+	batch := loc("go.opentelemetry.io/collector/processor/batchprocessor.(*batchProcessor).ConsumeTraces")
+	attrs := loc(
+		"go.opentelemetry.io/collector/internal/processor/attraction.(*AttrProc).Process",
+		"go.opentelemetry.io/collector/processor/attributesprocessor.(*tracesProcessor).ProcessTraces",
+	)
+	helper := loc("go.opentelemetry.io/collector/processor/processorhelper.(*tracesProcessor).ConsumeTraces")
+	receiver := loc("go.opentelemetry.io/collector/receiver/otlpreceiver/trace.(*Receiver).Export")
+	exporter := loc("go.opentelemetry.io/collector/exporter/otlpexporter.(*exporterImp).pushTraceData")
+
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		Sample: []*profile.Sample{
+			// Leaf first: the time in the batch processor called by the attributes processor
+			// belongs to the batch processor.
+			{Location: []*profile.Location{batch, attrs, receiver}, Value: []int64{1, 30}},
+			{Location: []*profile.Location{attrs, receiver}, Value: []int64{1, 10}},
+			// The helper of the attributes processor is not a component of its own.
+			{Location: []*profile.Location{helper, attrs, receiver}, Value: []int64{1, 10}},
+			{Location: []*profile.Location{marshal, exporter}, Value: []int64{1, 20}},
+			{Location: []*profile.Location{receiver}, Value: []int64{1, 10}},
+			{Location: []*profile.Location{gc}, Value: []int64{1, 20}},
+		},
+		Location: locations,
+		Function: functions,
+	}
+
+	dir, err := ioutil.TempDir("", "componentcpu")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cpu.prof")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, prof.Write(f))
+	require.NoError(t, f.Close())
+
+	shares, err := FindComponentCPUShares(path)
+	require.NoError(t, err)
+	assert.Equal(t, []ComponentCPUShare{
+		{Component: "processor/batchprocessor", Percent: 30},
+		{Component: "exporter/otlpexporter", Percent: 20},
+		{Component: "processor/attributesprocessor", Percent: 20},
+		{Component: "receiver/otlpreceiver", Percent: 10},
+	}, shares)
+	assert.Equal(t, "processor/batchprocessor 30.0%, exporter/otlpexporter 20.0%", formatComponentCPUShares(shares[:2]))
+
+	_, err = FindComponentCPUShares(filepath.Join(dir, "missing.prof"))
+	assert.True(t, os.IsNotExist(err))
+}
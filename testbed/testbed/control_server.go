@@ -0,0 +1,256 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// controlServerFlag and controlServerEnvVarName enable the ControlServer in
+// DoTestMainWithControlServer, their value is the endpoint to listen on.
+const (
+	controlServerFlag       = "testbed.control_server"
+	controlServerEnvVarName = "TESTBED_CONTROL_SERVER"
+)
+
+// ControlServerPath is the path to which scenario specs are posted.
+const ControlServerPath = "/scenarios"
+
+// MaxScenarioDuration is the longest duration a ScenarioSpec may request.
+const MaxScenarioDuration = 10 * time.Minute
+
+// ScenarioSpec describes a scenario to run by the ControlServer, for example
+// {"sender":"otlp","receiver":"otlp","items_per_second":10000,"duration":"10s"}. The
+// fields correspond to ScenarioParams, Duration is in time.ParseDuration format and at
+// most MaxScenarioDuration. Fields which are not set keep the defaults of the
+// ControlServer.
+type ScenarioSpec struct {
+	Sender             string `json:"sender"`
+	Receiver           string `json:"receiver"`
+	DataItemsPerSecond int    `json:"items_per_second"`
+	ItemsPerBatch      int    `json:"items_per_batch"`
+	Duration           string `json:"duration"`
+}
+
+// params returns defaults with the fields set in the spec overridden.
+func (spec ScenarioSpec) params(defaults ScenarioParams) (ScenarioParams, error) {
+	params := defaults
+	if spec.Sender != "" {
+		params.Sender = spec.Sender
+	}
+	if spec.Receiver != "" {
+		params.Receiver = spec.Receiver
+	}
+	if spec.DataItemsPerSecond < 0 || spec.ItemsPerBatch < 0 {
+		return defaults, fmt.Errorf("items_per_second and items_per_batch must not be negative")
+	}
+	if spec.DataItemsPerSecond > 0 {
+		params.DataItemsPerSecond = spec.DataItemsPerSecond
+	}
+	if spec.ItemsPerBatch > 0 {
+		params.ItemsPerBatch = spec.ItemsPerBatch
+	}
+	if spec.Duration != "" {
+		duration, err := time.ParseDuration(spec.Duration)
+		if err != nil {
+			return defaults, fmt.Errorf("invalid duration %q: %s", spec.Duration, err.Error())
+		}
+		if duration <= 0 || duration > MaxScenarioDuration {
+			return defaults, fmt.Errorf("duration must be positive and at most %s", MaxScenarioDuration)
+		}
+		params.Duration = duration
+	}
+	return params, nil
+}
+
+// ScenarioRunner runs a scenario with params, writing its logs and results to the
+// "results/<name>" directory, and returns its results, which must be encodable as JSON.
+// Cancelling ctx should end the scenario early.
+type ScenarioRunner func(ctx context.Context, name string, params ScenarioParams) (interface{}, error)
+
+// ScenarioResponse is the JSON response of the ControlServer to a scenario spec.
+type ScenarioResponse struct {
+	// Name of the run, which is also the name of its results directory.
+	Name string `json:"name"`
+	// Spec holds all parameters the scenario was run with, including the defaults.
+	Spec    ScenarioSpec `json:"spec"`
+	Results interface{}  `json:"results,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// ControlServer is an HTTP server which runs the scenarios described by ScenarioSpecs
+// posted as JSON to ControlServerPath and responds with a ScenarioResponse once the
+// scenario completed, so that the testbed can be used as a service by other tools.
+// Scenarios run one at a time, a spec posted while another scenario runs is rejected
+// with status 409 Conflict. Invalid specs are rejected with status 400 Bad Request, and
+// failed scenarios are reported with status 500 Internal Server Error together with
+// their partial results.
+type ControlServer struct {
+	endpoint string
+	defaults ScenarioParams
+	run      ScenarioRunner
+
+	server   *http.Server
+	listener net.Listener
+	running  atomic.Bool
+	runs     atomic.Uint64
+}
+
+// NewControlServer creates a ControlServer listening on endpoint, which runs the
+// scenarios with run. Fields not set in a spec keep the value in defaults.
+func NewControlServer(endpoint string, defaults ScenarioParams, run ScenarioRunner) *ControlServer {
+	cs := &ControlServer{
+		endpoint: endpoint,
+		defaults: defaults,
+		run:      run,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ControlServerPath, cs.handleScenario)
+	cs.server = &http.Server{Handler: mux}
+	return cs
+}
+
+// Start starts listening and serving requests in the background.
+func (cs *ControlServer) Start() error {
+	listener, err := net.Listen("tcp", cs.endpoint)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %s", cs.endpoint, err.Error())
+	}
+	cs.listener = listener
+	go func() {
+		if err := cs.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Control server failed: %s", err.Error())
+		}
+	}()
+	log.Printf("Control server listening on %s", listener.Addr().String())
+	return nil
+}
+
+// Addr returns the address the server listens on, e.g. to find the port if the endpoint
+// has port 0. Must be called after Start.
+func (cs *ControlServer) Addr() string {
+	return cs.listener.Addr().String()
+}
+
+// Shutdown stops the server, waiting for running scenarios until ctx is done.
+func (cs *ControlServer) Shutdown(ctx context.Context) error {
+	return cs.server.Shutdown(ctx)
+}
+
+func (cs *ControlServer) handleScenario(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "scenario specs must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var spec ScenarioSpec
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		http.Error(w, fmt.Sprintf("invalid scenario spec: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	params, err := spec.params(cs.defaults)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid scenario spec: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if !cs.running.CAS(false, true) {
+		http.Error(w, "another scenario is running", http.StatusConflict)
+		return
+	}
+	defer cs.running.Store(false)
+
+	response := ScenarioResponse{
+		Name: "ControlServer/scenario_" + strconv.FormatUint(cs.runs.Inc(), 10),
+		Spec: ScenarioSpec{
+			Sender:             params.Sender,
+			Receiver:           params.Receiver,
+			DataItemsPerSecond: params.DataItemsPerSecond,
+			ItemsPerBatch:      params.ItemsPerBatch,
+			Duration:           params.Duration.String(),
+		},
+	}
+	log.Printf("Running scenario %s with %+v", response.Name, params)
+	status := http.StatusOK
+	response.Results, err = cs.run(r.Context(), response.Name, params)
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Cannot write scenario response: %s", err.Error())
+	}
+}
+
+// controlServerEndpoint returns the endpoint of the control server set by the command
+// line flag or the environment variable, or "" if it is not enabled.
+func controlServerEndpoint() string {
+	if f := flag.Lookup(controlServerFlag); f != nil && f.Value.String() != "" {
+		return f.Value.String()
+	}
+	return os.Getenv(controlServerEnvVarName)
+}
+
+// serveControl runs a ControlServer on endpoint until the process is interrupted and
+// returns the exit code of the process.
+func serveControl(endpoint string, run ScenarioRunner) int {
+	defaults, err := ReadScenarioParams(ScenarioParams{
+		DataItemsPerSecond: 10_000,
+		ItemsPerBatch:      100,
+		Duration:           15 * time.Second,
+		Sender:             "otlp",
+		Receiver:           "otlp",
+	})
+	if err != nil {
+		log.Printf("Invalid scenario parameters: %s", err.Error())
+		return 1
+	}
+	cs := NewControlServer(endpoint, defaults, run)
+	if err := cs.Start(); err != nil {
+		log.Print(err.Error())
+		return 1
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	<-interrupted
+	log.Printf("Stopping control server.")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := cs.Shutdown(ctx); err != nil {
+		log.Printf("Cannot stop control server: %s", err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlServer(t *testing.T) {
+	defaults := ScenarioParams{
+		DataItemsPerSecond: 10_000,
+		ItemsPerBatch:      100,
+		Duration:           15 * time.Second,
+		Sender:             "otlp",
+		Receiver:           "otlp",
+	}
+	var runParams []ScenarioParams
+	release := make(chan struct{})
+	run := func(ctx context.Context, name string, params ScenarioParams) (interface{}, error) {
+		runParams = append(runParams, params)
+		if params.Sender == "blocking" {
+			<-release
+		}
+		if params.Receiver == "failing" {
+			return map[string]uint64{"data_items_sent": 10}, errors.New("data items lost")
+		}
+		return map[string]interface{}{"name": name, "data_items_sent": 1000}, nil
+	}
+	cs := NewControlServer("localhost:0", defaults, run)
+	require.NoError(t, cs.Start())
+	defer cs.Shutdown(context.Background())
+	url := "http://" + cs.Addr() + ControlServerPath
+
+	post := func(spec string) (*http.Response, ScenarioResponse) {
+		resp, err := http.Post(url, "application/json", strings.NewReader(spec))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var response ScenarioResponse
+		if resp.Header.Get("Content-Type") == "application/json" {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		}
+		return resp, response
+	}
+
+	resp, response := post(`{"sender":"jaeger","items_per_second":5000,"duration":"2s"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ControlServer/scenario_1", response.Name)
+	assert.Equal(t, ScenarioSpec{
+		Sender:             "jaeger",
+		Receiver:           "otlp",
+		DataItemsPerSecond: 5000,
+		ItemsPerBatch:      100,
+		Duration:           "2s",
+	}, response.Spec)
+	assert.Equal(t, map[string]interface{}{"name": "ControlServer/scenario_1", "data_items_sent": 1000.0}, response.Results)
+	assert.Empty(t, response.Error)
+	require.Len(t, runParams, 1)
+	assert.Equal(t, 2*time.Second, runParams[0].Duration)
+
+	resp, response = post(`{"receiver":"failing"}`)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "ControlServer/scenario_2", response.Name)
+	assert.Equal(t, map[string]interface{}{"data_items_sent": 10.0}, response.Results)
+	assert.Equal(t, "data items lost", response.Error)
+
+	for _, spec := range []string{`{"duration":"soon"}`, `{"duration":"10h"}`, `{"items_per_second":-1}`, `{"rate":1000}`, `[]`} {
+		resp, _ = post(spec)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, spec)
+	}
+	assert.Len(t, runParams, 2)
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// Scenarios run one at a time.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := http.Post(url, "application/json", strings.NewReader(`{"sender":"blocking"}`))
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+	}()
+	require.Eventually(t, func() bool { return cs.running.Load() }, 5*time.Second, 10*time.Millisecond)
+	resp, _ = post(`{}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	close(release)
+	<-done
+}
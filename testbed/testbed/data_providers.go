@@ -16,10 +16,15 @@ package testbed
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/jsonpb"
@@ -32,8 +37,10 @@ import (
 	otlplogscol "go.opentelemetry.io/collector/internal/data/protogen/collector/logs/v1"
 	otlpmetricscol "go.opentelemetry.io/collector/internal/data/protogen/collector/metrics/v1"
 	otlptracecol "go.opentelemetry.io/collector/internal/data/protogen/collector/trace/v1"
+	otlplogs "go.opentelemetry.io/collector/internal/data/protogen/logs/v1"
 	otlptrace "go.opentelemetry.io/collector/internal/data/protogen/trace/v1"
 	"go.opentelemetry.io/collector/internal/goldendataset"
+	"go.opentelemetry.io/collector/translator/conventions"
 )
 
 // DataProvider defines the interface for generators of test data used to drive various end-to-end tests.
@@ -57,13 +64,39 @@ type PerfTestDataProvider struct {
 	options            LoadOptions
 	batchesGenerated   *atomic.Uint64
 	dataItemsGenerated *atomic.Uint64
+	// Number of batches with ResourceAttributeValues generated.
+	resourceBatches atomic.Uint64
+
+	// Guards ResourceAttributeCardinality of options, see
+	// SetResourceAttributeCardinality.
+	cardinalityMutex sync.RWMutex
+
+	// Cycle of log record severities, see LoadOptions.SeverityDistribution.
+	severityCycle []pdata.SeverityNumber
+
+	// Average size in bytes of the generated spans, metrics and log records, see
+	// packBatch.
+	spanBytes   atomic.Int64
+	metricBytes atomic.Int64
+	logBytes    atomic.Int64
+
+	// State of the cumulative counters, see counterState.
+	counterMutex     sync.Mutex
+	metricBatches    uint64
+	counterStartTime time.Time
+	counterBase      uint64
 }
 
 // NewPerfTestDataProvider creates an instance of PerfTestDataProvider which generates test data based on the sizes
 // specified in the supplied LoadOptions.
 func NewPerfTestDataProvider(options LoadOptions) *PerfTestDataProvider {
+	if len(options.SummaryQuantiles) > 0 {
+		options.SummaryQuantiles = append([]float64(nil), options.SummaryQuantiles...)
+		sort.Float64s(options.SummaryQuantiles)
+	}
 	return &PerfTestDataProvider{
-		options: options,
+		options:       options,
+		severityCycle: severityCycle(options.SeverityDistribution),
 	}
 }
 
@@ -72,41 +105,185 @@ func (dp *PerfTestDataProvider) SetLoadGeneratorCounters(batchesGenerated *atomi
 	dp.dataItemsGenerated = dataItemsGenerated
 }
 
+// SpanSeqNumAttribute is the name of the span attribute which holds the sequence number
+// of the span generated by PerfTestDataProvider.
+const SpanSeqNumAttribute = "load_generator.span_seq_num"
+
 func (dp *PerfTestDataProvider) GenerateTraces() (pdata.Traces, bool) {
 
 	traceData := pdata.NewTraces()
 	traceData.ResourceSpans().Resize(1)
+	dp.addResourceAttributeValues(traceData.ResourceSpans().At(0).Resource().Attributes())
 	ilss := traceData.ResourceSpans().At(0).InstrumentationLibrarySpans()
 	ilss.Resize(1)
 	spans := ilss.At(0).Spans()
-	spans.Resize(dp.options.ItemsPerBatch)
 
 	traceID := dp.batchesGenerated.Inc()
-	for i := 0; i < dp.options.ItemsPerBatch; i++ {
+	dp.packBatch(&dp.spanBytes, traceData.Size, func(n int) {
+		first := spans.Len()
+		spans.Resize(first + n)
+		for i := first; i < first+n; i++ {
+			startTime := time.Now()
+			endTime := startTime.Add(time.Millisecond)
 
-		startTime := time.Now()
-		endTime := startTime.Add(time.Millisecond)
+			spanID := dp.dataItemsGenerated.Inc()
 
-		spanID := dp.dataItemsGenerated.Inc()
+			span := spans.At(i)
 
-		span := spans.At(i)
+			// Create a span.
+			span.SetTraceID(GenerateSequentialTraceID(traceID))
+			span.SetSpanID(GenerateSequentialSpanID(spanID))
+			span.SetName("load-generator-span")
+			span.SetKind(pdata.SpanKindCLIENT)
+			attrs := span.Attributes()
+			attrs.UpsertInt(SpanSeqNumAttribute, int64(spanID))
+			attrs.UpsertInt("load_generator.trace_seq_num", int64(traceID))
+			// Additional attributes.
+			for k, v := range dp.options.Attributes {
+				attrs.UpsertString(k, v)
+			}
+			dp.addTypedAttributes(attrs, int64(spanID))
+			dp.addNestedAttribute(attrs, int64(spanID))
+			dp.addSpanLinks(span.Links(), traceID, spanID)
+			dp.setSpanStatus(span.Status(), spanID)
+			startTime, endTime = dp.skewSpanTimes(startTime, endTime, spanID)
+			span.SetStartTime(pdata.TimestampFromTime(startTime))
+			span.SetEndTime(pdata.TimestampFromTime(endTime))
+		}
+	})
+	return traceData, false
+}
 
-		// Create a span.
-		span.SetTraceID(GenerateSequentialTraceID(traceID))
-		span.SetSpanID(GenerateSequentialSpanID(spanID))
-		span.SetName("load-generator-span")
-		span.SetKind(pdata.SpanKindCLIENT)
-		attrs := span.Attributes()
-		attrs.UpsertInt("load_generator.span_seq_num", int64(spanID))
-		attrs.UpsertInt("load_generator.trace_seq_num", int64(traceID))
-		// Additional attributes.
-		for k, v := range dp.options.Attributes {
-			attrs.UpsertString(k, v)
+// packBatch generates the data items of a batch by calling generate, which appends n
+// items to the batch. If LoadOptions.MaxBatchBytes is set, items are appended while the
+// batch, whose size size returns, stays within it. Every round appends half of the
+// items estimated to still fit, by the size of the items appended in the previous
+// round, so that the estimate does not overshoot as items grow, e.g. with their
+// sequence numbers. The estimate is kept in bytesPerItem for the next batch. A batch has
+// at least one item. Otherwise ItemsPerBatch items are generated. For metrics an item
+// is a metric with all its data points.
+func (dp *PerfTestDataProvider) packBatch(bytesPerItem *atomic.Int64, size func() int, generate func(n int)) {
+	if dp.options.MaxBatchBytes <= 0 {
+		generate(dp.options.ItemsPerBatch)
+		return
+	}
+	batchSize := size()
+	itemSize := int(bytesPerItem.Load())
+	items := 0
+	for {
+		n := 0
+		if itemSize > 0 {
+			n = ((dp.options.MaxBatchBytes-batchSize)/itemSize + 1) / 2
 		}
-		span.SetStartTime(pdata.TimestampFromTime(startTime))
-		span.SetEndTime(pdata.TimestampFromTime(endTime))
+		if n < 1 {
+			if items > 0 {
+				break
+			}
+			n = 1
+		}
+		generate(n)
+		items += n
+		prevSize := batchSize
+		batchSize = size()
+		itemSize = (batchSize - prevSize + n - 1) / n
 	}
-	return traceData, false
+	bytesPerItem.Store(int64(itemSize))
+}
+
+// addResourceAttributeValues adds the ResourceAttributeValues for the next generated
+// batch to attrs.
+func (dp *PerfTestDataProvider) addResourceAttributeValues(attrs pdata.AttributeMap) {
+	dp.cardinalityMutex.RLock()
+	defer dp.cardinalityMutex.RUnlock()
+	if len(dp.options.ResourceAttributeValues) == 0 && len(dp.options.ResourceAttributeCardinality) == 0 {
+		return
+	}
+	batch := dp.resourceBatches.Inc() - 1
+	for k, values := range dp.options.ResourceAttributeValues {
+		if len(values) > 0 {
+			attrs.UpsertString(k, values[batch%uint64(len(values))])
+		}
+	}
+	for k, cardinality := range dp.options.ResourceAttributeCardinality {
+		if cardinality > 0 {
+			value := batch % uint64(cardinality)
+			attrs.UpsertString(k, strings.ReplaceAll(k, ".", "-")+"-"+strconv.FormatUint(value, 10))
+		}
+	}
+}
+
+// SetResourceAttributeCardinality replaces LoadOptions.ResourceAttributeCardinality of
+// the batches generated from now on, e.g. to ramp up the cardinality while the load is
+// generated.
+func (dp *PerfTestDataProvider) SetResourceAttributeCardinality(cardinality map[string]int) {
+	copied := make(map[string]int, len(cardinality))
+	for k, v := range cardinality {
+		copied[k] = v
+	}
+	dp.cardinalityMutex.Lock()
+	defer dp.cardinalityMutex.Unlock()
+	dp.options.ResourceAttributeCardinality = copied
+}
+
+// DeploymentResourceAttributes returns ResourceAttributeCardinality for the resource
+// attributes of services deployed on Kubernetes with the given number of pods. Every 10
+// pods share a service, deployment, container name and host, and every 50 pods share a
+// namespace.
+func DeploymentResourceAttributes(pods int) map[string]int {
+	atLeastOne := func(n int) int {
+		if n < 1 {
+			return 1
+		}
+		return n
+	}
+	return map[string]int{
+		conventions.AttributeServiceName:     atLeastOne(pods / 10),
+		conventions.AttributeServiceInstance: pods,
+		conventions.AttributeHostName:        atLeastOne(pods / 10),
+		conventions.AttributeK8sNamespace:    atLeastOne(pods / 50),
+		conventions.AttributeK8sDeployment:   atLeastOne(pods / 10),
+		conventions.AttributeK8sPod:          pods,
+		conventions.AttributeK8sContainer:    atLeastOne(pods / 10),
+	}
+}
+
+// setSpanStatus sets the status of the span with the given sequence number according
+// to SpanErrorRate. Error spans are spread evenly over the sequence numbers so that
+// the fraction of error spans matches the rate in every stretch of generated spans.
+func (dp *PerfTestDataProvider) setSpanStatus(status pdata.SpanStatus, spanID uint64) {
+	rate := dp.options.SpanErrorRate
+	if rate <= 0 {
+		return
+	}
+	if math.Floor(float64(spanID)*rate) > math.Floor(float64(spanID-1)*rate) {
+		status.SetCode(pdata.StatusCodeError)
+		status.SetMessage("load generator error " + strconv.FormatUint(spanID, 10))
+	} else {
+		status.SetCode(pdata.StatusCodeOk)
+	}
+}
+
+// ClockSkewFutureOffset is how far in the future the timestamps of spans skewed into the
+// future by LoadOptions.ClockSkewedSpanRate are.
+const ClockSkewFutureOffset = time.Hour
+
+// skewSpanTimes returns the start and end time of the span with the given sequence number
+// distorted according to ClockSkewedSpanRate. Skewed spans are spread evenly over the
+// sequence numbers like error spans, and every other one has its end before its start
+// while the others are moved into the future.
+func (dp *PerfTestDataProvider) skewSpanTimes(start, end time.Time, spanID uint64) (time.Time, time.Time) {
+	rate := dp.options.ClockSkewedSpanRate
+	if rate <= 0 {
+		return start, end
+	}
+	skewed := math.Floor(float64(spanID) * rate)
+	if skewed <= math.Floor(float64(spanID-1)*rate) {
+		return start, end
+	}
+	if int64(skewed)%2 == 1 {
+		return end, start
+	}
+	return start.Add(ClockSkewFutureOffset), end.Add(ClockSkewFutureOffset)
 }
 
 func GenerateSequentialTraceID(id uint64) pdata.TraceID {
@@ -121,14 +298,23 @@ func GenerateSequentialSpanID(id uint64) pdata.SpanID {
 	return pdata.NewSpanID(spanID)
 }
 
+// defaultDataPointsPerMetric is the number of data points per generated metric if
+// LoadOptions.DataPointsPerMetric is not set.
+const defaultDataPointsPerMetric = 7
+
 func (dp *PerfTestDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
 
-	// Generate 7 data points per metric.
-	const dataPointsPerMetric = 7
+	dataPointsPerMetric := dp.options.DataPointsPerMetric
+	if dataPointsPerMetric <= 0 {
+		dataPointsPerMetric = defaultDataPointsPerMetric
+	}
 
 	md := pdata.NewMetrics()
 	md.ResourceMetrics().Resize(1)
 	md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().Resize(1)
+	il := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).InstrumentationLibrary()
+	il.SetName(dp.options.InstrumentationLibraryName)
+	il.SetVersion(dp.options.InstrumentationLibraryVersion)
 	if dp.options.Attributes != nil {
 		attrs := md.ResourceMetrics().At(0).Resource().Attributes()
 		attrs.InitEmptyWithCapacity(len(dp.options.Attributes))
@@ -136,33 +322,277 @@ func (dp *PerfTestDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
 			attrs.UpsertString(k, v)
 		}
 	}
+	dp.addTypedAttributes(md.ResourceMetrics().At(0).Resource().Attributes(), int64(dp.batchesGenerated.Load()))
+	dp.addResourceAttributeValues(md.ResourceMetrics().At(0).Resource().Attributes())
 	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
-	metrics.Resize(dp.options.ItemsPerBatch)
+	counterStartTime, counterBase := dp.counterState()
 
-	for i := 0; i < dp.options.ItemsPerBatch; i++ {
-		metric := metrics.At(i)
-		metric.SetName("load_generator_" + strconv.Itoa(i))
-		metric.SetDescription("Load Generator Counter #" + strconv.Itoa(i))
+	dp.packBatch(&dp.metricBytes, md.Size, func(n int) {
+		first := metrics.Len()
+		metrics.Resize(first + n)
+		for i := first; i < first+n; i++ {
+			metric := metrics.At(i)
+			metric.SetName("load_generator_" + strconv.Itoa(i))
+			dp.setMetricMetadata(metric, i)
+
+			batchIndex := dp.batchesGenerated.Inc()
+
+			switch edgeCase := dp.edgeCaseMetric(batchIndex); edgeCase {
+			case EdgeCaseMetricNaNGauge, EdgeCaseMetricInfGauge, EdgeCaseMetricInconsistentHistogram:
+				dp.fillEdgeCaseMetric(metric, edgeCase, batchIndex, dataPointsPerMetric)
+				continue
+			case EdgeCaseMetricEmptyName:
+				metric.SetName("")
+			}
+
+			if len(dp.options.SummaryQuantiles) > 0 {
+				dp.fillSummaryMetric(metric, batchIndex, dataPointsPerMetric, counterStartTime)
+				continue
+			}
+
+			dataType := dp.metricDataType(i)
+			if dataType == pdata.MetricDataTypeDoubleGauge || dataType == pdata.MetricDataTypeDoubleSum {
+				dp.fillDoubleMetric(metric, dataType, batchIndex, dataPointsPerMetric, counterStartTime, counterBase)
+				continue
+			}
+
+			var dps pdata.IntDataPointSlice
+			if dataType == pdata.MetricDataTypeIntSum {
+				metric.SetDataType(pdata.MetricDataTypeIntSum)
+				sum := metric.IntSum()
+				sum.SetIsMonotonic(true)
+				sum.SetAggregationTemporality(dp.sumTemporality())
+				dps = sum.DataPoints()
+			} else {
+				metric.SetDataType(pdata.MetricDataTypeIntGauge)
+				dps = metric.IntGauge().DataPoints()
+			}
+			// Generate data points for the metric.
+			dps.Resize(dataPointsPerMetric)
+			for j := 0; j < dataPointsPerMetric; j++ {
+				dataPoint := dps.At(j)
+				dataPoint.SetStartTime(dataPointStartTime(counterStartTime))
+				value := dp.dataItemsGenerated.Inc()
+				dataPoint.SetValue(int64(value - counterBase))
+				dataPoint.LabelsMap().InitFromMap(dp.dataPointLabels(j, batchIndex))
+				dp.addExemplars(dataPoint.Exemplars(), batchIndex, value)
+			}
+		}
+	})
+	return md, false
+}
+
+// metricDataType returns the data type of the i-th metric of a batch, see
+// LoadOptions.MetricDataTypes.
+func (dp *PerfTestDataProvider) metricDataType(i int) pdata.MetricDataType {
+	if types := dp.options.MetricDataTypes; len(types) > 0 {
+		return types[i%len(types)]
+	}
+	if dp.options.ExemplarsPerDataPoint > 0 || dp.options.MetricTemporality != "" || dp.options.CounterResetInterval > 0 {
+		return pdata.MetricDataTypeIntSum
+	}
+	return pdata.MetricDataTypeIntGauge
+}
+
+// sumTemporality returns the aggregation temporality of generated sums, see
+// LoadOptions.MetricTemporality.
+func (dp *PerfTestDataProvider) sumTemporality() pdata.AggregationTemporality {
+	if dp.options.MetricTemporality == MetricTemporalityDelta {
+		return pdata.AggregationTemporalityDelta
+	}
+	return pdata.AggregationTemporalityCumulative
+}
+
+// dataPointStartTime returns the start time of a generated data point, the current
+// time if counterStartTime is zero.
+func dataPointStartTime(counterStartTime time.Time) pdata.Timestamp {
+	if counterStartTime.IsZero() {
+		return pdata.TimestampFromTime(time.Now())
+	}
+	return pdata.TimestampFromTime(counterStartTime)
+}
+
+// dataPointLabels returns the labels of the j-th data point of a generated metric.
+func (dp *PerfTestDataProvider) dataPointLabels(j int, batchIndex uint64) map[string]string {
+	labels := map[string]string{"item_index": "item_" + strconv.Itoa(j)}
+	if dp.options.CounterResetInterval <= 0 {
+		labels["batch_index"] = "batch_" + strconv.Itoa(int(batchIndex))
+	}
+	return labels
+}
+
+// fillDoubleMetric generates the data points of a DoubleGauge or DoubleSum metric with
+// the same values and labels as the Int variants.
+func (dp *PerfTestDataProvider) fillDoubleMetric(metric pdata.Metric, dataType pdata.MetricDataType, batchIndex uint64, dataPoints int, counterStartTime time.Time, counterBase uint64) {
+	metric.SetDataType(dataType)
+	var dps pdata.DoubleDataPointSlice
+	if dataType == pdata.MetricDataTypeDoubleSum {
+		sum := metric.DoubleSum()
+		sum.SetIsMonotonic(true)
+		sum.SetAggregationTemporality(dp.sumTemporality())
+		dps = sum.DataPoints()
+	} else {
+		dps = metric.DoubleGauge().DataPoints()
+	}
+	dps.Resize(dataPoints)
+	for j := 0; j < dataPoints; j++ {
+		dataPoint := dps.At(j)
+		dataPoint.SetStartTime(dataPointStartTime(counterStartTime))
+		value := dp.dataItemsGenerated.Inc()
+		dataPoint.SetValue(float64(value - counterBase))
+		dataPoint.LabelsMap().InitFromMap(dp.dataPointLabels(j, batchIndex))
+	}
+}
+
+// setMetricMetadata sets the unit and description of the i-th metric of a batch, see
+// LoadOptions.MetricUnits and LoadOptions.MetricDescriptions.
+func (dp *PerfTestDataProvider) setMetricMetadata(metric pdata.Metric, i int) {
+	if units := dp.options.MetricUnits; len(units) > 0 {
+		metric.SetUnit(units[i%len(units)])
+	} else {
 		metric.SetUnit("1")
-		metric.SetDataType(pdata.MetricDataTypeIntGauge)
+	}
+	if descriptions := dp.options.MetricDescriptions; len(descriptions) > 0 {
+		metric.SetDescription(descriptions[i%len(descriptions)])
+	} else {
+		metric.SetDescription("Load Generator Counter #" + strconv.Itoa(i))
+	}
+}
 
-		batchIndex := dp.batchesGenerated.Inc()
+// fillSummaryMetric generates the data points of a summary with SummaryQuantiles. The
+// values of the quantiles of a data point grow linearly from the sequence number of the
+// data point for quantile 0 to twice that for quantile 1.
+func (dp *PerfTestDataProvider) fillSummaryMetric(metric pdata.Metric, batchIndex uint64, dataPoints int, startTime time.Time) {
+	if startTime.IsZero() {
+		startTime = time.Now()
+	}
+	metric.SetDataType(pdata.MetricDataTypeDoubleSummary)
+	dps := metric.DoubleSummary().DataPoints()
+	dps.Resize(dataPoints)
+	for j := 0; j < dataPoints; j++ {
+		dataPoint := dps.At(j)
+		dataPoint.SetStartTime(pdata.TimestampFromTime(startTime))
+		value := float64(dp.dataItemsGenerated.Inc())
+		dataPoint.SetCount(uint64(len(dp.options.SummaryQuantiles)))
+		quantileValues := dataPoint.QuantileValues()
+		quantileValues.Resize(len(dp.options.SummaryQuantiles))
+		var sum float64
+		for k, quantile := range dp.options.SummaryQuantiles {
+			quantileValues.At(k).SetQuantile(quantile)
+			quantileValues.At(k).SetValue(value * (1 + quantile))
+			sum += value * (1 + quantile)
+		}
+		dataPoint.SetSum(sum)
+		dataPoint.LabelsMap().InitFromMap(map[string]string{
+			"item_index":  "item_" + strconv.Itoa(j),
+			"batch_index": "batch_" + strconv.Itoa(int(batchIndex)),
+		})
+	}
+}
 
-		dps := metric.IntGauge().DataPoints()
-		// Generate data points for the metric.
-		dps.Resize(dataPointsPerMetric)
-		for j := 0; j < dataPointsPerMetric; j++ {
+// edgeCaseMetric returns the kind of edge case metric to generate in place of the
+// metric with the given sequence number according to EdgeCaseMetricRate, or "" if a
+// regular metric must be generated. Edge cases are spread evenly like error spans, see
+// setSpanStatus.
+func (dp *PerfTestDataProvider) edgeCaseMetric(seqNum uint64) string {
+	rate := dp.options.EdgeCaseMetricRate
+	if rate <= 0 || len(dp.options.EdgeCaseMetrics) == 0 {
+		return ""
+	}
+	edgeCases := math.Floor(float64(seqNum) * rate)
+	if edgeCases == math.Floor(float64(seqNum-1)*rate) {
+		return ""
+	}
+	return dp.options.EdgeCaseMetrics[(uint64(edgeCases)-1)%uint64(len(dp.options.EdgeCaseMetrics))]
+}
+
+// fillEdgeCaseMetric generates the data points of an edge case metric which is not a
+// regular int metric, i.e. of a NaN or Inf gauge or an inconsistent histogram.
+func (dp *PerfTestDataProvider) fillEdgeCaseMetric(metric pdata.Metric, edgeCase string, batchIndex uint64, dataPoints int) {
+	now := pdata.TimestampFromTime(time.Now())
+	labelsAt := func(j int) map[string]string {
+		return map[string]string{
+			"item_index":  "item_" + strconv.Itoa(j),
+			"batch_index": "batch_" + strconv.Itoa(int(batchIndex)),
+		}
+	}
+	switch edgeCase {
+	case EdgeCaseMetricNaNGauge, EdgeCaseMetricInfGauge:
+		metric.SetDataType(pdata.MetricDataTypeDoubleGauge)
+		dps := metric.DoubleGauge().DataPoints()
+		dps.Resize(dataPoints)
+		for j := 0; j < dataPoints; j++ {
 			dataPoint := dps.At(j)
-			dataPoint.SetStartTime(pdata.TimestampFromTime(time.Now()))
+			dataPoint.SetStartTime(now)
+			value := math.NaN()
+			if edgeCase == EdgeCaseMetricInfGauge {
+				value = math.Inf(1 - 2*int(dp.dataItemsGenerated.Inc()%2))
+			} else {
+				dp.dataItemsGenerated.Inc()
+			}
+			dataPoint.SetValue(value)
+			dataPoint.LabelsMap().InitFromMap(labelsAt(j))
+		}
+	case EdgeCaseMetricInconsistentHistogram:
+		metric.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+		histogram := metric.DoubleHistogram()
+		histogram.SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+		dps := histogram.DataPoints()
+		dps.Resize(dataPoints)
+		for j := 0; j < dataPoints; j++ {
+			dataPoint := dps.At(j)
+			dataPoint.SetStartTime(now)
 			value := dp.dataItemsGenerated.Inc()
-			dataPoint.SetValue(int64(value))
-			dataPoint.LabelsMap().InitFromMap(map[string]string{
-				"item_index":  "item_" + strconv.Itoa(j),
-				"batch_index": "batch_" + strconv.Itoa(int(batchIndex)),
-			})
+			dataPoint.SetExplicitBounds([]float64{1, 10})
+			dataPoint.SetBucketCounts([]uint64{value, value, value})
+			dataPoint.SetCount(1)
+			dataPoint.SetSum(-float64(value))
+			dataPoint.LabelsMap().InitFromMap(labelsAt(j))
 		}
 	}
-	return md, false
+}
+
+// counterState returns the start time and the value offset of the data points of the
+// next generated metrics batch. The start time is zero if neither MetricStartTime nor
+// CounterResetInterval is set. Every CounterResetInterval batches the counters are reset:
+// the start time advances to the current time and the values start again from 1.
+func (dp *PerfTestDataProvider) counterState() (time.Time, uint64) {
+	dp.counterMutex.Lock()
+	defer dp.counterMutex.Unlock()
+	batch := dp.metricBatches
+	dp.metricBatches++
+	if dp.counterStartTime.IsZero() {
+		dp.counterStartTime = dp.options.MetricStartTime
+		if dp.counterStartTime.IsZero() && dp.options.CounterResetInterval > 0 {
+			dp.counterStartTime = time.Now()
+		}
+	}
+	if interval := uint64(dp.options.CounterResetInterval); interval > 0 && batch > 0 && batch%interval == 0 {
+		dp.counterStartTime = time.Now()
+		dp.counterBase = dp.dataItemsGenerated.Load()
+	}
+	return dp.counterStartTime, dp.counterBase
+}
+
+// addExemplars adds ExemplarsPerDataPoint exemplars to the data point with the given
+// value. Exemplars reference sequentially generated trace and span IDs.
+func (dp *PerfTestDataProvider) addExemplars(exemplars pdata.IntExemplarSlice, batchIndex uint64, value uint64) {
+	count := dp.options.ExemplarsPerDataPoint
+	if count <= 0 {
+		return
+	}
+	now := pdata.TimestampFromTime(time.Now())
+	exemplars.Resize(count)
+	for k := 0; k < count; k++ {
+		exemplar := exemplars.At(k)
+		exemplar.SetTimestamp(now)
+		exemplar.SetValue(int64(value))
+		exemplar.SetTraceID(GenerateSequentialTraceID(batchIndex))
+		exemplar.SetSpanID(GenerateSequentialSpanID(value*uint64(count) + uint64(k)))
+		exemplar.FilteredLabels().InitFromMap(map[string]string{
+			"exemplar_index": "exemplar_" + strconv.Itoa(k),
+		})
+	}
 }
 
 func (dp *PerfTestDataProvider) GetGeneratedSpan(pdata.TraceID, pdata.SpanID) *otlptrace.Span {
@@ -181,34 +611,240 @@ func (dp *PerfTestDataProvider) GenerateLogs() (pdata.Logs, bool) {
 			attrs.UpsertString(k, v)
 		}
 	}
+	dp.addResourceAttributeValues(logs.ResourceLogs().At(0).Resource().Attributes())
 	logRecords := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
-	logRecords.Resize(dp.options.ItemsPerBatch)
 
 	now := pdata.TimestampFromTime(time.Now())
 
 	batchIndex := dp.batchesGenerated.Inc()
 
-	for i := 0; i < dp.options.ItemsPerBatch; i++ {
-		itemIndex := dp.dataItemsGenerated.Inc()
-		record := logRecords.At(i)
-		record.SetSeverityNumber(pdata.SeverityNumberINFO3)
-		record.SetSeverityText("INFO3")
-		record.SetName("load_generator_" + strconv.Itoa(i))
-		record.Body().SetStringVal("Load Generator Counter #" + strconv.Itoa(i))
-		record.SetFlags(uint32(2))
-		record.SetTimestamp(now)
-
-		attrs := record.Attributes()
-		attrs.UpsertString("batch_index", "batch_"+strconv.Itoa(int(batchIndex)))
-		attrs.UpsertString("item_index", "item_"+strconv.Itoa(int(itemIndex)))
-		attrs.UpsertString("a", "test")
-		attrs.UpsertDouble("b", 5.0)
-		attrs.UpsertInt("c", 3)
-		attrs.UpsertBool("d", true)
-	}
+	dp.packBatch(&dp.logBytes, logs.SizeBytes, func(n int) {
+		first := logRecords.Len()
+		logRecords.Resize(first + n)
+		for i := first; i < first+n; i++ {
+			itemIndex := dp.dataItemsGenerated.Inc()
+			record := logRecords.At(i)
+			dp.setSeverity(record, itemIndex)
+			record.SetName("load_generator_" + strconv.Itoa(i))
+			record.Body().SetStringVal(dp.genLogBody(i))
+			record.SetFlags(uint32(2))
+			record.SetTimestamp(now)
+
+			attrs := record.Attributes()
+			attrs.UpsertString("batch_index", "batch_"+strconv.Itoa(int(batchIndex)))
+			attrs.UpsertString("item_index", "item_"+strconv.Itoa(int(itemIndex)))
+			attrs.UpsertString("a", "test")
+			attrs.UpsertDouble("b", 5.0)
+			attrs.UpsertInt("c", 3)
+			attrs.UpsertBool("d", true)
+			dp.addTypedAttributes(attrs, int64(itemIndex))
+			dp.setLogTraceContext(record, batchIndex, itemIndex)
+		}
+	})
 	return logs, false
 }
 
+// setSeverity sets the severity of the log record with the given sequence number
+// according to SeverityDistribution.
+func (dp *PerfTestDataProvider) setSeverity(record pdata.LogRecord, itemIndex uint64) {
+	severity := pdata.SeverityNumberINFO3
+	if len(dp.severityCycle) > 0 {
+		severity = dp.severityCycle[itemIndex%uint64(len(dp.severityCycle))]
+	}
+	record.SetSeverityNumber(severity)
+	record.SetSeverityText(strings.TrimPrefix(otlplogs.SeverityNumber(severity).String(), "SEVERITY_NUMBER_"))
+}
+
+// severityCycle returns a cycle of severities with the proportions of the weights in
+// distribution, interleaved by smooth weighted round-robin so that the severities of
+// consecutive records are mixed.
+func severityCycle(distribution map[pdata.SeverityNumber]int) []pdata.SeverityNumber {
+	var severities []pdata.SeverityNumber
+	total := 0
+	for severity, weight := range distribution {
+		if weight > 0 {
+			severities = append(severities, severity)
+			total += weight
+		}
+	}
+	sort.Slice(severities, func(i, j int) bool { return severities[i] < severities[j] })
+
+	cycle := make([]pdata.SeverityNumber, 0, total)
+	current := make([]int, len(severities))
+	for len(cycle) < total {
+		best := 0
+		for i, severity := range severities {
+			current[i] += distribution[severity]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		cycle = append(cycle, severities[best])
+	}
+	return cycle
+}
+
+// setLogTraceContext sets the trace ID and span ID of a fraction of the log records
+// according to LogTraceCorrelationRate, spread evenly like setSpanStatus spreads errors.
+func (dp *PerfTestDataProvider) setLogTraceContext(record pdata.LogRecord, batchIndex uint64, itemIndex uint64) {
+	rate := dp.options.LogTraceCorrelationRate
+	if rate <= 0 {
+		return
+	}
+	if math.Floor(float64(itemIndex)*rate) > math.Floor(float64(itemIndex-1)*rate) {
+		record.SetTraceID(GenerateSequentialTraceID(batchIndex))
+		record.SetSpanID(GenerateSequentialSpanID(itemIndex))
+	}
+}
+
+// attributeValueTypesOrder is the order in which addTypedAttributes adds attributes
+// of each value type, to keep the generated data deterministic.
+var attributeValueTypesOrder = []pdata.AttributeValueType{
+	pdata.AttributeValueSTRING,
+	pdata.AttributeValueINT,
+	pdata.AttributeValueDOUBLE,
+	pdata.AttributeValueBOOL,
+	pdata.AttributeValueMAP,
+	pdata.AttributeValueARRAY,
+}
+
+// addTypedAttributes adds the attributes requested by LoadOptions.AttributeValueTypes.
+// Attributes are named "load_generator.<type>_<n>" and their values are derived from seqNum.
+func (dp *PerfTestDataProvider) addTypedAttributes(attrs pdata.AttributeMap, seqNum int64) {
+	for _, valueType := range attributeValueTypesOrder {
+		count := dp.options.AttributeValueTypes[valueType]
+		prefix := "load_generator." + strings.ToLower(valueType.String()) + "_"
+		for i := 0; i < count; i++ {
+			attrs.Upsert(prefix+strconv.Itoa(i), genAttributeValue(valueType, seqNum+int64(i)))
+		}
+	}
+}
+
+func genAttributeValue(valueType pdata.AttributeValueType, n int64) pdata.AttributeValue {
+	switch valueType {
+	case pdata.AttributeValueINT:
+		return pdata.NewAttributeValueInt(n)
+	case pdata.AttributeValueDOUBLE:
+		return pdata.NewAttributeValueDouble(float64(n) + 0.5)
+	case pdata.AttributeValueBOOL:
+		return pdata.NewAttributeValueBool(n%2 == 0)
+	case pdata.AttributeValueMAP:
+		value := pdata.NewAttributeValueMap()
+		value.MapVal().UpsertString("string", "value_"+strconv.FormatInt(n, 10))
+		value.MapVal().UpsertInt("int", n)
+		return value
+	case pdata.AttributeValueARRAY:
+		value := pdata.NewAttributeValueArray()
+		value.ArrayVal().Append(pdata.NewAttributeValueString("value_" + strconv.FormatInt(n, 10)))
+		value.ArrayVal().Append(pdata.NewAttributeValueInt(n))
+		return value
+	default:
+		return pdata.NewAttributeValueString("value_" + strconv.FormatInt(n, 10))
+	}
+}
+
+// addSpanLinks adds the links configured by SpanLinksPerSpan to the span with the given
+// trace and span sequence numbers.
+func (dp *PerfTestDataProvider) addSpanLinks(links pdata.SpanLinkSlice, traceID, spanID uint64) {
+	for j := 1; j <= dp.options.SpanLinksPerSpan; j++ {
+		offset := uint64(j * dp.options.ItemsPerBatch)
+		if uint64(j) >= traceID || offset >= spanID {
+			return
+		}
+		link := pdata.NewSpanLink()
+		link.SetTraceID(GenerateSequentialTraceID(traceID - uint64(j)))
+		link.SetSpanID(GenerateSequentialSpanID(spanID - offset))
+		links.Append(link)
+	}
+}
+
+// addNestedAttribute adds the attribute of nested maps and arrays configured by
+// NestedAttributeDepth and NestedAttributeWidth to attrs.
+func (dp *PerfTestDataProvider) addNestedAttribute(attrs pdata.AttributeMap, seqNum int64) {
+	if dp.options.NestedAttributeDepth <= 0 {
+		return
+	}
+	width := dp.options.NestedAttributeWidth
+	if width <= 0 {
+		width = 2
+	}
+	attrs.Upsert("load_generator.nested", genNestedAttributeValue(0, dp.options.NestedAttributeDepth, width, seqNum))
+}
+
+// genNestedAttributeValue generates the value of the given level of a nested attribute,
+// a map on even levels, an array on odd levels and a string leaf on the last level.
+func genNestedAttributeValue(level, depth, width int, n int64) pdata.AttributeValue {
+	if level == depth {
+		return pdata.NewAttributeValueString("value_" + strconv.FormatInt(n, 10))
+	}
+	if level%2 == 0 {
+		value := pdata.NewAttributeValueMap()
+		for i := 0; i < width; i++ {
+			value.MapVal().Insert("key_"+strconv.Itoa(i), genNestedAttributeValue(level+1, depth, width, n))
+		}
+		return value
+	}
+	value := pdata.NewAttributeValueArray()
+	for i := 0; i < width; i++ {
+		value.ArrayVal().Append(genNestedAttributeValue(level+1, depth, width, n))
+	}
+	return value
+}
+
+// genLogBody generates the body of the i-th log record of a batch according to
+// the LogBodyBytes and LogBodyFormat options.
+func (dp *PerfTestDataProvider) genLogBody(i int) string {
+	msg := "Load Generator Counter #" + strconv.Itoa(i)
+
+	switch dp.options.LogBodyFormat {
+	case LogBodyFormatJSON:
+		body := map[string]interface{}{"message": msg, "index": i, "padding": ""}
+		b, _ := json.Marshal(body)
+		if dp.options.LogBodyBytes == 0 {
+			return string(b)
+		}
+		if pad := dp.options.LogBodyBytes - len(b); pad > 0 {
+			body["padding"] = strings.Repeat("x", pad)
+			b, _ = json.Marshal(body)
+		}
+		return string(b[:dp.options.LogBodyBytes])
+	case LogBodyFormatMultiline:
+		var b strings.Builder
+		b.WriteString(msg)
+		for line := 1; line <= 3 || b.Len() < dp.options.LogBodyBytes; line++ {
+			sep := "\n"
+			if line%2 == 0 {
+				sep = "\r\n"
+			}
+			b.WriteString(sep + "\tat frame " + strconv.Itoa(line))
+		}
+		if dp.options.LogBodyBytes > 0 {
+			return b.String()[:dp.options.LogBodyBytes]
+		}
+		return b.String()
+	case LogBodyFormatBinary:
+		size := dp.options.LogBodyBytes
+		if size == 0 {
+			size = 256
+		}
+		// Every byte value starting at the record index, so that bodies differ.
+		b := make([]byte, size)
+		for j := range b {
+			b[j] = byte(i + j)
+		}
+		return string(b)
+	}
+
+	if dp.options.LogBodyBytes == 0 {
+		return msg
+	}
+	if pad := dp.options.LogBodyBytes - len(msg); pad > 0 {
+		return msg + strings.Repeat(" ", pad)
+	}
+	return msg[:dp.options.LogBodyBytes]
+}
+
 // GoldenDataProvider is an implementation of DataProvider for use in correctness tests.
 // Provided data from the "Golden" dataset generated using pairwise combinatorial testing techniques.
 type GoldenDataProvider struct {
@@ -312,16 +948,110 @@ func traceIDAndSpanIDToString(traceID pdata.TraceID, spanID pdata.SpanID) string
 	return fmt.Sprintf("%s-%s", traceID.HexString(), spanID.HexString())
 }
 
+// LogSeqNumAttribute is the name of the log record attribute which holds the sequence
+// number of the record generated by LogCorrectnessDataProvider.
+const LogSeqNumAttribute = "load_generator.log_seq_num"
+
+// logSeverities are the severities cycled through by GenerateSeqNumLogRecord.
+var logSeverities = []struct {
+	number pdata.SeverityNumber
+	text   string
+}{
+	{pdata.SeverityNumberDEBUG, "DEBUG"},
+	{pdata.SeverityNumberINFO, "INFO"},
+	{pdata.SeverityNumberWARN, "WARN"},
+	{pdata.SeverityNumberERROR, "ERROR"},
+}
+
+// GenerateSeqNumLogRecord fills the log record with data which is derived from the
+// sequence number only, so that the expected content of a received record can be
+// reconstructed from its sequence number.
+func GenerateSeqNumLogRecord(seqNum int64, record pdata.LogRecord) {
+	severity := logSeverities[seqNum%int64(len(logSeverities))]
+	record.SetSeverityNumber(severity.number)
+	record.SetSeverityText(severity.text)
+	record.SetName("load_generator_" + strconv.FormatInt(seqNum, 10))
+	record.Body().SetStringVal("Log record #" + strconv.FormatInt(seqNum, 10))
+
+	attrs := record.Attributes()
+	attrs.UpsertInt(LogSeqNumAttribute, seqNum)
+	attrs.UpsertString("seq_str", "seq_"+strconv.FormatInt(seqNum, 10))
+	attrs.UpsertDouble("seq_double", float64(seqNum)/2)
+	attrs.UpsertBool("seq_even", seqNum%2 == 0)
+}
+
+// LogCorrectnessDataProvider in an implementation of the DataProvider for use in log
+// correctness tests. Every generated log record carries its sequence number and the
+// rest of its content is derived from it, see GenerateSeqNumLogRecord.
+type LogCorrectnessDataProvider struct {
+	options            LoadOptions
+	batchesGenerated   *atomic.Uint64
+	dataItemsGenerated *atomic.Uint64
+}
+
+// NewLogCorrectnessDataProvider creates an instance of LogCorrectnessDataProvider.
+func NewLogCorrectnessDataProvider(options LoadOptions) *LogCorrectnessDataProvider {
+	return &LogCorrectnessDataProvider{options: options}
+}
+
+func (dp *LogCorrectnessDataProvider) SetLoadGeneratorCounters(batchesGenerated *atomic.Uint64, dataItemsGenerated *atomic.Uint64) {
+	dp.batchesGenerated = batchesGenerated
+	dp.dataItemsGenerated = dataItemsGenerated
+}
+
+func (dp *LogCorrectnessDataProvider) GenerateTraces() (pdata.Traces, bool) {
+	return pdata.NewTraces(), true
+}
+
+func (dp *LogCorrectnessDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
+	return pdata.NewMetrics(), true
+}
+
+func (dp *LogCorrectnessDataProvider) GenerateLogs() (pdata.Logs, bool) {
+	logs := pdata.NewLogs()
+	logs.ResourceLogs().Resize(1)
+	logs.ResourceLogs().At(0).InstrumentationLibraryLogs().Resize(1)
+	logRecords := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+	logRecords.Resize(dp.options.ItemsPerBatch)
+
+	dp.batchesGenerated.Inc()
+	for i := 0; i < dp.options.ItemsPerBatch; i++ {
+		GenerateSeqNumLogRecord(int64(dp.dataItemsGenerated.Inc()), logRecords.At(i))
+	}
+	return logs, false
+}
+
+func (dp *LogCorrectnessDataProvider) GetGeneratedSpan(pdata.TraceID, pdata.SpanID) *otlptrace.Span {
+	// Nothing to do. This function is only used by data providers used in correctness tests for traces.
+	return nil
+}
+
 // FileDataProvider in an implementation of the DataProvider for use in performance tests.
-// The data to send is loaded from a file. The file should contain one JSON-encoded
-// Export*ServiceRequest Protobuf message. The file can be recorded using the "file"
-// exporter (note: "file" exporter writes one JSON message per line, FileDataProvider
-// expects just a single JSON message in the entire file).
+// The data to send is loaded from a file. The file should contain one or more JSON-encoded
+// Export*ServiceRequest Protobuf messages, for example as recorded by the "file" exporter
+// which writes one JSON message per line. Each message is sent as one batch and the
+// messages are replayed in a loop.
 type FileDataProvider struct {
 	batchesGenerated   *atomic.Uint64
 	dataItemsGenerated *atomic.Uint64
-	message            proto.Message
+	messages           []proto.Message
 	ItemsPerBatch      int
+
+	// PreserveTiming makes the provider send each batch at the same offset from the
+	// first batch as recorded in the file (based on the first timestamp found in each
+	// batch) instead of at the rate configured in LoadOptions. The load generator rate
+	// should be set at least as high as the recorded rate since batches are never sent
+	// earlier than the rate allows. If the file has no timestamps the configured rate
+	// is used.
+	PreserveTiming bool
+	// TimeScale speeds up (values > 1) or slows down (values < 1) the replay when
+	// PreserveTiming is set. Zero is treated as 1.
+	TimeScale float64
+
+	mutex      sync.Mutex
+	nextIndex  int
+	offsets    []time.Duration
+	replayFrom time.Time
 }
 
 // NewFileDataProvider creates an instance of FileDataProvider which generates test data
@@ -331,65 +1061,301 @@ func NewFileDataProvider(filePath string, dataType configmodels.DataType) (*File
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
-	var message proto.Message
+	var messages []proto.Message
+	var timestamps []pdata.Timestamp
 	var dataPointCount int
 
-	// Load the message from the file and count the data points.
+	// Load the messages from the file and count the data points.
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		switch dataType {
+		case configmodels.TracesDataType:
+			var msg otlptracecol.ExportTraceServiceRequest
+			if err := protobufJSONUnmarshaler.UnmarshalNext(dec, &msg); err != nil {
+				return nil, err
+			}
+			messages = append(messages, &msg)
 
-	switch dataType {
-	case configmodels.TracesDataType:
-		var msg otlptracecol.ExportTraceServiceRequest
-		if err := protobufJSONUnmarshaler.Unmarshal(file, &msg); err != nil {
-			return nil, err
-		}
-		message = &msg
+			td := pdata.TracesFromOtlp(msg.ResourceSpans)
+			dataPointCount += td.SpanCount()
+			timestamps = append(timestamps, getFirstSpanTimestamp(td))
 
-		md := pdata.TracesFromOtlp(msg.ResourceSpans)
-		dataPointCount = md.SpanCount()
+		case configmodels.MetricsDataType:
+			var msg otlpmetricscol.ExportMetricsServiceRequest
+			if err := protobufJSONUnmarshaler.UnmarshalNext(dec, &msg); err != nil {
+				return nil, err
+			}
+			messages = append(messages, &msg)
 
-	case configmodels.MetricsDataType:
-		var msg otlpmetricscol.ExportMetricsServiceRequest
-		if err := protobufJSONUnmarshaler.Unmarshal(file, &msg); err != nil {
-			return nil, err
-		}
-		message = &msg
+			md := pdata.MetricsFromOtlp(msg.ResourceMetrics)
+			_, count := md.MetricAndDataPointCount()
+			dataPointCount += count
+			timestamps = append(timestamps, getFirstMetricTimestamp(md))
+
+		case configmodels.LogsDataType:
+			var msg otlplogscol.ExportLogsServiceRequest
+			if err := protobufJSONUnmarshaler.UnmarshalNext(dec, &msg); err != nil {
+				return nil, err
+			}
+			messages = append(messages, &msg)
 
-		md := pdata.MetricsFromOtlp(msg.ResourceMetrics)
-		_, dataPointCount = md.MetricAndDataPointCount()
+			ld := pdata.LogsFromInternalRep(internal.LogsFromOtlp(msg.ResourceLogs))
+			dataPointCount += ld.LogRecordCount()
+			timestamps = append(timestamps, getFirstLogTimestamp(ld))
 
-	case configmodels.LogsDataType:
-		var msg otlplogscol.ExportLogsServiceRequest
-		if err := protobufJSONUnmarshaler.Unmarshal(file, &msg); err != nil {
-			return nil, err
+		default:
+			return nil, fmt.Errorf("unsupported data type %q", dataType)
 		}
-		message = &msg
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no data found in %s", filePath)
+	}
 
-		md := pdata.LogsFromInternalRep(internal.LogsFromOtlp(msg.ResourceLogs))
-		dataPointCount = md.LogRecordCount()
+	itemsPerBatch := dataPointCount / len(messages)
+	if itemsPerBatch == 0 {
+		itemsPerBatch = 1
 	}
 
 	return &FileDataProvider{
-		message:       message,
-		ItemsPerBatch: dataPointCount,
+		messages:      messages,
+		ItemsPerBatch: itemsPerBatch,
+		offsets:       replayOffsets(timestamps),
 	}, nil
 }
 
+// replayOffsets returns the offset of each batch from the first batch, or nil
+// if any of the batches has no timestamp.
+func replayOffsets(timestamps []pdata.Timestamp) []time.Duration {
+	offsets := make([]time.Duration, len(timestamps))
+	for i, ts := range timestamps {
+		if ts == 0 {
+			return nil
+		}
+		offsets[i] = time.Duration(ts) - time.Duration(timestamps[0])
+	}
+	return offsets
+}
+
+func getFirstSpanTimestamp(td pdata.Traces) pdata.Timestamp {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if ts := spans.At(k).StartTime(); ts != 0 {
+					return ts
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func getFirstMetricTimestamp(md pdata.Metrics) pdata.Timestamp {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		ilms := rms.At(i).InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			metrics := ilms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				if ts := getFirstDataPointTimestamp(metrics.At(k)); ts != 0 {
+					return ts
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func getFirstDataPointTimestamp(metric pdata.Metric) pdata.Timestamp {
+	var first pdata.Timestamp
+	forEachDataPoint(metric, func(dp dataPoint) {
+		if first == 0 {
+			first = dp.Timestamp()
+		}
+	})
+	return first
+}
+
+// dataPoint is implemented by the data points of all metric types.
+type dataPoint interface {
+	LabelsMap() pdata.StringMap
+	Timestamp() pdata.Timestamp
+}
+
+// forEachDataPoint calls fn with every data point of metric, in the order of the data
+// points.
+func forEachDataPoint(metric pdata.Metric, fn func(dp dataPoint)) {
+	retainDataPoints(metric, func(_ int, dp dataPoint) bool {
+		fn(dp)
+		return true
+	})
+}
+
+// retainDataPoints calls keep with the index and every data point of metric, in the
+// order of the data points, and removes the data points for which it returns false.
+func retainDataPoints(metric pdata.Metric, keep func(i int, dp dataPoint) bool) {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeIntGauge:
+		dps := metric.IntGauge().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	case pdata.MetricDataTypeDoubleGauge:
+		dps := metric.DoubleGauge().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	case pdata.MetricDataTypeIntSum:
+		dps := metric.IntSum().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	case pdata.MetricDataTypeDoubleSum:
+		dps := metric.DoubleSum().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	case pdata.MetricDataTypeIntHistogram:
+		dps := metric.IntHistogram().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	case pdata.MetricDataTypeDoubleHistogram:
+		dps := metric.DoubleHistogram().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	case pdata.MetricDataTypeDoubleSummary:
+		dps := metric.DoubleSummary().DataPoints()
+		n := 0
+		for i := 0; i < dps.Len(); i++ {
+			if keep(i, dps.At(i)) {
+				if n != i {
+					dps.At(i).CopyTo(dps.At(n))
+				}
+				n++
+			}
+		}
+		dps.Resize(n)
+	}
+}
+
+func getFirstLogTimestamp(ld pdata.Logs) pdata.Timestamp {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		ills := rls.At(i).InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			logs := ills.At(j).Logs()
+			for k := 0; k < logs.Len(); k++ {
+				if ts := logs.At(k).Timestamp(); ts != 0 {
+					return ts
+				}
+			}
+		}
+	}
+	return 0
+}
+
 func (dp *FileDataProvider) SetLoadGeneratorCounters(batchesGenerated *atomic.Uint64, dataItemsGenerated *atomic.Uint64) {
 	dp.batchesGenerated = batchesGenerated
 	dp.dataItemsGenerated = dataItemsGenerated
+	if dp.PreserveTiming && dp.offsets == nil {
+		log.Printf("File data has no timestamps, ignoring PreserveTiming and using the configured rate")
+	}
 }
 
 // Marshaler configuration used for marhsaling Protobuf to JSON. Use default config.
 var protobufJSONUnmarshaler = &jsonpb.Unmarshaler{}
 
+// nextMessage returns the next message to send. If PreserveTiming is set it
+// blocks until the recorded offset of the message (scaled by TimeScale) has
+// elapsed since the replay started.
+func (dp *FileDataProvider) nextMessage() proto.Message {
+	dp.mutex.Lock()
+	index := dp.nextIndex
+	dp.nextIndex = (dp.nextIndex + 1) % len(dp.messages)
+
+	if !dp.PreserveTiming || dp.offsets == nil {
+		dp.mutex.Unlock()
+		return dp.messages[index]
+	}
+
+	if index == 0 {
+		dp.replayFrom = time.Now()
+		dp.mutex.Unlock()
+		return dp.messages[index]
+	}
+
+	scale := dp.TimeScale
+	if scale <= 0 {
+		scale = 1
+	}
+	sendAt := dp.replayFrom.Add(time.Duration(float64(dp.offsets[index]) / scale))
+	// Sleep without holding the mutex so that concurrent callers can claim the
+	// following messages and wait for their own offsets meanwhile.
+	dp.mutex.Unlock()
+	if wait := time.Until(sendAt); wait > 0 {
+		time.Sleep(wait)
+	}
+	return dp.messages[index]
+}
+
 func (dp *FileDataProvider) GenerateTraces() (pdata.Traces, bool) {
-	// TODO: implement similar to GenerateMetrics.
-	return pdata.NewTraces(), true
+	td := pdata.TracesFromOtlp(dp.nextMessage().(*otlptracecol.ExportTraceServiceRequest).ResourceSpans)
+	dp.batchesGenerated.Inc()
+	dp.dataItemsGenerated.Add(uint64(td.SpanCount()))
+	return td, false
 }
 
 func (dp *FileDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
-	md := pdata.MetricsFromOtlp(dp.message.(*otlpmetricscol.ExportMetricsServiceRequest).ResourceMetrics)
+	md := pdata.MetricsFromOtlp(dp.nextMessage().(*otlpmetricscol.ExportMetricsServiceRequest).ResourceMetrics)
 	dp.batchesGenerated.Inc()
 	_, dataPointCount := md.MetricAndDataPointCount()
 	dp.dataItemsGenerated.Add(uint64(dataPointCount))
@@ -402,6 +1368,74 @@ func (dp *FileDataProvider) GetGeneratedSpan(pdata.TraceID, pdata.SpanID) *otlpt
 }
 
 func (dp *FileDataProvider) GenerateLogs() (pdata.Logs, bool) {
-	// TODO: implement similar to GenerateMetrics.
-	return pdata.NewLogs(), true
+	ld := pdata.LogsFromInternalRep(internal.LogsFromOtlp(dp.nextMessage().(*otlplogscol.ExportLogsServiceRequest).ResourceLogs))
+	dp.batchesGenerated.Inc()
+	dp.dataItemsGenerated.Add(uint64(ld.LogRecordCount()))
+	return ld, false
+}
+
+// SentTracesRecorder records the traces generated by a DataProvider, see
+// NewRecordingDataProvider.
+type SentTracesRecorder interface {
+	RecordSentTraces(td pdata.Traces)
+}
+
+// SentMetricsRecorder records the metrics generated by a DataProvider, see
+// NewRecordingDataProvider.
+type SentMetricsRecorder interface {
+	RecordSentMetrics(md pdata.Metrics)
+}
+
+// SentLogsRecorder records the logs generated by a DataProvider, see
+// NewRecordingDataProvider.
+type SentLogsRecorder interface {
+	RecordSentLogs(ld pdata.Logs)
+}
+
+// recordingDataProvider passes the data generated by the wrapped DataProvider to a
+// recorder, see NewRecordingDataProvider.
+type recordingDataProvider struct {
+	DataProvider
+	recorder interface{}
+	mutex    sync.Mutex
+}
+
+// NewRecordingDataProvider returns a DataProvider which generates the same data as
+// dataProvider and passes every generated batch to recorder before it is sent, if
+// recorder is a SentTracesRecorder, SentMetricsRecorder or SentLogsRecorder for the type
+// of the data. This is how validators which compare the received data with the sent
+// data, e.g. LogBodyValidator, learn what was sent. The calls to recorder are serialized.
+// The returned DataProvider must be used by the test case instead of dataProvider.
+func NewRecordingDataProvider(dataProvider DataProvider, recorder interface{}) DataProvider {
+	return &recordingDataProvider{DataProvider: dataProvider, recorder: recorder}
+}
+
+func (dp *recordingDataProvider) GenerateTraces() (pdata.Traces, bool) {
+	td, done := dp.DataProvider.GenerateTraces()
+	if recorder, ok := dp.recorder.(SentTracesRecorder); ok {
+		dp.mutex.Lock()
+		defer dp.mutex.Unlock()
+		recorder.RecordSentTraces(td)
+	}
+	return td, done
+}
+
+func (dp *recordingDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
+	md, done := dp.DataProvider.GenerateMetrics()
+	if recorder, ok := dp.recorder.(SentMetricsRecorder); ok {
+		dp.mutex.Lock()
+		defer dp.mutex.Unlock()
+		recorder.RecordSentMetrics(md)
+	}
+	return md, done
+}
+
+func (dp *recordingDataProvider) GenerateLogs() (pdata.Logs, bool) {
+	ld, done := dp.DataProvider.GenerateLogs()
+	if recorder, ok := dp.recorder.(SentLogsRecorder); ok {
+		dp.mutex.Lock()
+		defer dp.mutex.Unlock()
+		recorder.RecordSentLogs(ld)
+	}
+	return ld, done
 }
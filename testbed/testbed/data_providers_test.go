@@ -15,12 +15,26 @@
 package testbed
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 
+	"go.opentelemetry.io/collector/config/configmodels"
 	"go.opentelemetry.io/collector/consumer/pdata"
+	otlpmetricscol "go.opentelemetry.io/collector/internal/data/protogen/collector/metrics/v1"
+	"go.opentelemetry.io/collector/translator/conventions"
 )
 
 const metricsPictPairsFile = "../../internal/goldendataset/testdata/generated_pict_pairs_metrics.txt"
@@ -38,3 +52,740 @@ func TestGoldenDataProvider(t *testing.T) {
 	}
 	require.Equal(t, len(dp.metricsGenerated), len(ms))
 }
+
+func TestPerfTestDataProviderLogBody(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch: 10,
+		LogBodyBytes:  4096,
+		LogBodyFormat: LogBodyFormatJSON,
+	}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	logs, done := dp.GenerateLogs()
+	require.False(t, done)
+	require.Equal(t, 10, logs.LogRecordCount())
+
+	records := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+	for i := 0; i < records.Len(); i++ {
+		body := records.At(i).Body().StringVal()
+		assert.Len(t, body, 4096)
+		var parsed map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(body), &parsed))
+		assert.Equal(t, "Load Generator Counter #"+strconv.Itoa(i), parsed["message"])
+	}
+
+	// Bodies too small for the JSON object are cut like those of the other formats.
+	options.LogBodyBytes = 16
+	dp = NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	logs, _ = dp.GenerateLogs()
+	records = logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+	for i := 0; i < records.Len(); i++ {
+		assert.Len(t, records.At(i).Body().StringVal(), 16)
+	}
+}
+
+func TestPerfTestDataProviderLogBodyFormats(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 2, LogBodyFormat: LogBodyFormatBinary})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	logs, _ := dp.GenerateLogs()
+	records := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+	for i := 0; i < records.Len(); i++ {
+		body := records.At(i).Body().StringVal()
+		require.Len(t, body, 256)
+		assert.EqualValues(t, i, body[0])
+		assert.False(t, utf8.ValidString(body))
+		assert.Contains(t, body, "\x00")
+	}
+
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1, LogBodyFormat: LogBodyFormatMultiline})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	logs, _ = dp.GenerateLogs()
+	assert.Equal(t, "Load Generator Counter #0\n\tat frame 1\r\n\tat frame 2\n\tat frame 3",
+		logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(0).Body().StringVal())
+
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1, LogBodyFormat: LogBodyFormatMultiline, LogBodyBytes: 1024})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	logs, _ = dp.GenerateLogs()
+	assert.Len(t, logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(0).Body().StringVal(), 1024)
+}
+
+func TestSplitMetricsKeepsEachDataPoint(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	ilm := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics()
+	ilm.Resize(1)
+	metrics := ilm.At(0).Metrics()
+	metrics.Resize(2)
+	metrics.At(0).SetDataType(pdata.MetricDataTypeIntGauge)
+	metrics.At(0).IntGauge().DataPoints().Resize(3)
+	metrics.At(1).SetDataType(pdata.MetricDataTypeDoubleSummary)
+	metrics.At(1).DoubleSummary().DataPoints().Resize(2)
+	n := 0
+	for i := 0; i < metrics.Len(); i++ {
+		forEachDataPoint(metrics.At(i), func(dp dataPoint) {
+			n++
+			dp.LabelsMap().Insert("index", strconv.Itoa(n))
+		})
+	}
+	assert.Equal(t, 3, metricDataPointCount(metrics.At(0)))
+	assert.Equal(t, 2, metricDataPointCount(metrics.At(1)))
+
+	split := splitMetrics(md)
+	require.Len(t, split, 5)
+	for i, single := range split {
+		metric := single.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0)
+		require.Equal(t, 1, metricDataPointCount(metric))
+		forEachDataPoint(metric, func(dp dataPoint) {
+			value, _ := dp.LabelsMap().Get("index")
+			assert.Equal(t, strconv.Itoa(i+1), value)
+		})
+	}
+}
+
+func TestFileDataProviderPreserveTiming(t *testing.T) {
+	// Three batches recorded 0ms, 200ms and 600ms after the first one.
+	start := time.Now()
+	offsets := []time.Duration{0, 200 * time.Millisecond, 600 * time.Millisecond}
+	var lines []string
+	for _, offset := range offsets {
+		md := pdata.NewMetrics()
+		md.ResourceMetrics().Resize(1)
+		ilm := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics()
+		ilm.Resize(1)
+		metrics := ilm.At(0).Metrics()
+		metrics.Resize(1)
+		metric := metrics.At(0)
+		metric.SetName("load_generator.gauge")
+		metric.SetDataType(pdata.MetricDataTypeIntGauge)
+		metric.IntGauge().DataPoints().Resize(1)
+		metric.IntGauge().DataPoints().At(0).SetTimestamp(pdata.TimestampFromTime(start.Add(offset)))
+
+		msg := otlpmetricscol.ExportMetricsServiceRequest{ResourceMetrics: pdata.MetricsToOtlp(md)}
+		line, err := (&jsonpb.Marshaler{}).MarshalToString(&msg)
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+
+	dir, err := ioutil.TempDir("", "filedataprovider")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "metrics.json")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(strings.Join(lines, "\n")), 0600))
+
+	dp, err := NewFileDataProvider(filePath, configmodels.MetricsDataType)
+	require.NoError(t, err)
+	dp.PreserveTiming = true
+	dp.TimeScale = 2
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	var sentAt []time.Time
+	for range offsets {
+		_, done := dp.GenerateMetrics()
+		require.False(t, done)
+		sentAt = append(sentAt, time.Now())
+	}
+
+	// The lower bounds allow for the timer granularity. The upper bounds are generous
+	// for loaded machines but still fail if TimeScale is ignored.
+	const tolerance = 10 * time.Millisecond
+	for i := 1; i < len(offsets); i++ {
+		expected := offsets[i] / 2
+		actual := sentAt[i].Sub(sentAt[0])
+		assert.GreaterOrEqual(t, int64(actual), int64(expected-tolerance))
+		assert.Less(t, int64(actual), int64(expected+offsets[i]/4+100*time.Millisecond))
+	}
+}
+
+func TestFileDataProviderPreserveTimingWithoutTimestamps(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	msg := otlpmetricscol.ExportMetricsServiceRequest{ResourceMetrics: pdata.MetricsToOtlp(md)}
+	line, err := (&jsonpb.Marshaler{}).MarshalToString(&msg)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "filedataprovider")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "metrics.json")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(line+"\n"+line), 0600))
+
+	dp, err := NewFileDataProvider(filePath, configmodels.MetricsDataType)
+	require.NoError(t, err)
+	dp.PreserveTiming = true
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	// Without timestamps batches are returned immediately.
+	start := time.Now()
+	dp.GenerateMetrics()
+	dp.GenerateMetrics()
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+}
+
+func TestPerfTestDataProviderAttributeValueTypes(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch: 5,
+		AttributeValueTypes: map[pdata.AttributeValueType]int{
+			pdata.AttributeValueSTRING: 1,
+			pdata.AttributeValueINT:    1,
+			pdata.AttributeValueDOUBLE: 2,
+			pdata.AttributeValueBOOL:   1,
+			pdata.AttributeValueMAP:    1,
+			pdata.AttributeValueARRAY:  1,
+		},
+	}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	countTypes := func(attrs pdata.AttributeMap) map[pdata.AttributeValueType]int {
+		counts := map[pdata.AttributeValueType]int{}
+		attrs.ForEach(func(k string, v pdata.AttributeValue) {
+			if strings.HasPrefix(k, "load_generator.") && !strings.HasSuffix(k, "_seq_num") {
+				counts[v.Type()]++
+			}
+		})
+		return counts
+	}
+
+	traces, _ := dp.GenerateTraces()
+	assert.Equal(t, 5, traces.SpanCount())
+	spans := traces.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		assert.Equal(t, options.AttributeValueTypes, countTypes(spans.At(i).Attributes()))
+	}
+
+	metrics, _ := dp.GenerateMetrics()
+	assert.Equal(t, options.AttributeValueTypes, countTypes(metrics.ResourceMetrics().At(0).Resource().Attributes()))
+
+	logs, _ := dp.GenerateLogs()
+	assert.Equal(t, 5, logs.LogRecordCount())
+	records := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+	for i := 0; i < records.Len(); i++ {
+		assert.Equal(t, options.AttributeValueTypes, countTypes(records.At(i).Attributes()))
+	}
+}
+
+func TestPerfTestDataProviderNestedAttributes(t *testing.T) {
+	options := LoadOptions{ItemsPerBatch: 3, NestedAttributeDepth: 3, NestedAttributeWidth: 2}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	traces, _ := dp.GenerateTraces()
+	assert.Equal(t, 3, traces.SpanCount())
+	spans := traces.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		nested, ok := spans.At(i).Attributes().Get("load_generator.nested")
+		require.True(t, ok)
+		leaf := "value_" + strconv.Itoa(i+1)
+
+		// Level 0 is a map of arrays of maps of string leaves.
+		require.Equal(t, pdata.AttributeValueMAP, nested.Type())
+		require.Equal(t, 2, nested.MapVal().Len())
+		nested.MapVal().ForEach(func(k string, array pdata.AttributeValue) {
+			assert.Contains(t, []string{"key_0", "key_1"}, k)
+			require.Equal(t, pdata.AttributeValueARRAY, array.Type())
+			require.Equal(t, 2, array.ArrayVal().Len())
+			for j := 0; j < array.ArrayVal().Len(); j++ {
+				m := array.ArrayVal().At(j)
+				require.Equal(t, pdata.AttributeValueMAP, m.Type())
+				require.Equal(t, 2, m.MapVal().Len())
+				m.MapVal().ForEach(func(k string, v pdata.AttributeValue) {
+					assert.Equal(t, pdata.AttributeValueSTRING, v.Type())
+					assert.Equal(t, leaf, v.StringVal())
+				})
+			}
+		})
+	}
+
+	// Without a depth no nested attribute is added.
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1, NestedAttributeWidth: 2})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	traces, _ = dp.GenerateTraces()
+	_, ok := traces.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).Attributes().Get("load_generator.nested")
+	assert.False(t, ok)
+}
+
+func TestPerfTestDataProviderSeverityDistribution(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch: 25,
+		SeverityDistribution: map[pdata.SeverityNumber]int{
+			pdata.SeverityNumberINFO:  70,
+			pdata.SeverityNumberWARN:  20,
+			pdata.SeverityNumberERROR: 10,
+		},
+	}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	counts := map[pdata.SeverityNumber]int{}
+	texts := map[pdata.SeverityNumber]string{}
+	for i := 0; i < 40; i++ {
+		logs, _ := dp.GenerateLogs()
+		records := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+		for j := 0; j < records.Len(); j++ {
+			counts[records.At(j).SeverityNumber()]++
+			texts[records.At(j).SeverityNumber()] = records.At(j).SeverityText()
+		}
+
+		// The severities are mixed, every batch approximates the distribution.
+		if i == 0 {
+			for severity, weight := range options.SeverityDistribution {
+				assert.InDelta(t, float64(weight)/100, float64(counts[severity])/25, 0.05, texts[severity])
+			}
+		}
+	}
+
+	// Every full cycle of 100 records matches the distribution exactly.
+	assert.Equal(t, map[pdata.SeverityNumber]int{
+		pdata.SeverityNumberINFO:  700,
+		pdata.SeverityNumberWARN:  200,
+		pdata.SeverityNumberERROR: 100,
+	}, counts)
+	assert.Equal(t, map[pdata.SeverityNumber]string{
+		pdata.SeverityNumberINFO:  "INFO",
+		pdata.SeverityNumberWARN:  "WARN",
+		pdata.SeverityNumberERROR: "ERROR",
+	}, texts)
+
+	// Without a distribution all records have the default severity.
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	logs, _ := dp.GenerateLogs()
+	record := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(0)
+	assert.Equal(t, pdata.SeverityNumberINFO3, record.SeverityNumber())
+	assert.Equal(t, "INFO3", record.SeverityText())
+}
+
+func TestPerfTestDataProviderExemplars(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch:         2,
+		ExemplarsPerDataPoint: 3,
+	}
+	dp := NewPerfTestDataProvider(options)
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	md, _ := dp.GenerateMetrics()
+	_, dataPoints := md.MetricAndDataPointCount()
+	assert.EqualValues(t, dataPoints, dataItemsGenerated.Load())
+
+	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	require.Equal(t, 2, metrics.Len())
+	for i := 0; i < metrics.Len(); i++ {
+		metric := metrics.At(i)
+		require.Equal(t, pdata.MetricDataTypeIntSum, metric.DataType())
+		dps := metric.IntSum().DataPoints()
+		for j := 0; j < dps.Len(); j++ {
+			exemplars := dps.At(j).Exemplars()
+			require.Equal(t, 3, exemplars.Len())
+			for k := 0; k < exemplars.Len(); k++ {
+				exemplar := exemplars.At(k)
+				assert.False(t, exemplar.TraceID().IsEmpty())
+				assert.False(t, exemplar.SpanID().IsEmpty())
+				assert.Equal(t, dps.At(j).Value(), exemplar.Value())
+				assert.Equal(t, 1, exemplar.FilteredLabels().Len())
+			}
+		}
+	}
+
+	// Without exemplars gauges are generated as before.
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	md, _ = dp.GenerateMetrics()
+	metric := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0)
+	assert.Equal(t, pdata.MetricDataTypeIntGauge, metric.DataType())
+	assert.Equal(t, 0, metric.IntGauge().DataPoints().At(0).Exemplars().Len())
+}
+
+func TestPerfTestDataProviderMetricTemporality(t *testing.T) {
+	tests := []struct {
+		temporality string
+		expected    pdata.AggregationTemporality
+	}{
+		{MetricTemporalityCumulative, pdata.AggregationTemporalityCumulative},
+		{MetricTemporalityDelta, pdata.AggregationTemporalityDelta},
+	}
+	for _, test := range tests {
+		t.Run(test.temporality, func(t *testing.T) {
+			dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 2, MetricTemporality: test.temporality})
+			dataItemsGenerated := atomic.NewUint64(0)
+			dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+			md, _ := dp.GenerateMetrics()
+			_, dataPoints := md.MetricAndDataPointCount()
+			assert.EqualValues(t, dataPoints, dataItemsGenerated.Load())
+
+			metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+			for i := 0; i < metrics.Len(); i++ {
+				require.Equal(t, pdata.MetricDataTypeIntSum, metrics.At(i).DataType())
+				assert.Equal(t, test.expected, metrics.At(i).IntSum().AggregationTemporality())
+			}
+		})
+	}
+}
+
+func TestPerfTestDataProviderMetricDataTypes(t *testing.T) {
+	dataTypes := []pdata.MetricDataType{
+		pdata.MetricDataTypeIntGauge,
+		pdata.MetricDataTypeIntSum,
+		pdata.MetricDataTypeDoubleGauge,
+		pdata.MetricDataTypeDoubleSum,
+	}
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:     8,
+		MetricDataTypes:   dataTypes,
+		MetricTemporality: MetricTemporalityDelta,
+	})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	md, _ := dp.GenerateMetrics()
+	_, dataPoints := md.MetricAndDataPointCount()
+	assert.Equal(t, 8*defaultDataPointsPerMetric, dataPoints)
+	assert.EqualValues(t, dataPoints, dataItemsGenerated.Load())
+
+	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		metric := metrics.At(i)
+		require.Equal(t, dataTypes[i%len(dataTypes)], metric.DataType(), metric.Name())
+		switch metric.DataType() {
+		case pdata.MetricDataTypeIntGauge:
+			assert.Equal(t, defaultDataPointsPerMetric, metric.IntGauge().DataPoints().Len())
+		case pdata.MetricDataTypeIntSum:
+			assert.Equal(t, pdata.AggregationTemporalityDelta, metric.IntSum().AggregationTemporality())
+			assert.Equal(t, defaultDataPointsPerMetric, metric.IntSum().DataPoints().Len())
+		case pdata.MetricDataTypeDoubleGauge:
+			dps := metric.DoubleGauge().DataPoints()
+			require.Equal(t, defaultDataPointsPerMetric, dps.Len())
+			assert.NotZero(t, dps.At(0).Value())
+			assert.NotZero(t, dps.At(0).StartTime())
+		case pdata.MetricDataTypeDoubleSum:
+			assert.True(t, metric.DoubleSum().IsMonotonic())
+			assert.Equal(t, pdata.AggregationTemporalityDelta, metric.DoubleSum().AggregationTemporality())
+			assert.Equal(t, defaultDataPointsPerMetric, metric.DoubleSum().DataPoints().Len())
+		}
+	}
+}
+
+func TestPerfTestDataProviderDataPointsPerMetric(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 3, DataPointsPerMetric: 50})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	md, _ := dp.GenerateMetrics()
+	metricCount, dataPoints := md.MetricAndDataPointCount()
+	assert.Equal(t, 3, metricCount)
+	assert.Equal(t, 150, dataPoints)
+	assert.EqualValues(t, 150, dataItemsGenerated.Load())
+
+	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		dps := metrics.At(i).IntGauge().DataPoints()
+		require.Equal(t, 50, dps.Len())
+		labelSets := make(map[string]struct{})
+		for j := 0; j < dps.Len(); j++ {
+			var key strings.Builder
+			dps.At(j).LabelsMap().Sort().ForEach(func(k string, v string) {
+				key.WriteString(k + "=" + v + ",")
+			})
+			labelSets[key.String()] = struct{}{}
+		}
+		assert.Len(t, labelSets, 50)
+	}
+
+	// Without the option every metric carries the default number of data points.
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 3})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	md, _ = dp.GenerateMetrics()
+	_, dataPoints = md.MetricAndDataPointCount()
+	assert.Equal(t, 3*defaultDataPointsPerMetric, dataPoints)
+}
+
+func TestPerfTestDataProviderCounterReset(t *testing.T) {
+	startTime := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:        2,
+		DataPointsPerMetric:  1,
+		MetricStartTime:      startTime,
+		CounterResetInterval: 2,
+	})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	type point struct {
+		startTime pdata.Timestamp
+		value     int64
+	}
+	var batches [][]point
+	for i := 0; i < 3; i++ {
+		md, _ := dp.GenerateMetrics()
+		var points []point
+		metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			require.Equal(t, pdata.MetricDataTypeIntSum, metrics.At(j).DataType())
+			require.Equal(t, pdata.AggregationTemporalityCumulative, metrics.At(j).IntSum().AggregationTemporality())
+			dataPoint := metrics.At(j).IntSum().DataPoints().At(0)
+			_, ok := dataPoint.LabelsMap().Get("batch_index")
+			assert.False(t, ok, "batch_index must not split the series")
+			points = append(points, point{dataPoint.StartTime(), dataPoint.Value()})
+		}
+		batches = append(batches, points)
+	}
+
+	// The counters are reset before the third batch.
+	configured := pdata.TimestampFromTime(startTime)
+	assert.Equal(t, []point{{configured, 1}, {configured, 2}}, batches[0])
+	assert.Equal(t, []point{{configured, 3}, {configured, 4}}, batches[1])
+	require.Len(t, batches[2], 2)
+	assert.Greater(t, uint64(batches[2][0].startTime), uint64(configured))
+	assert.Equal(t, int64(1), batches[2][0].value)
+	assert.Equal(t, int64(2), batches[2][1].value)
+	// Resets do not affect counting.
+	assert.EqualValues(t, 6, dataItemsGenerated.Load())
+}
+
+func TestPerfTestDataProviderResourceAttributeCardinality(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:                1,
+		ResourceAttributeCardinality: DeploymentResourceAttributes(20),
+	})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	values := make(map[string]map[string]struct{})
+	for i := 0; i < 40; i++ {
+		td, _ := dp.GenerateTraces()
+		td.ResourceSpans().At(0).Resource().Attributes().ForEach(func(k string, v pdata.AttributeValue) {
+			if values[k] == nil {
+				values[k] = make(map[string]struct{})
+			}
+			values[k][v.StringVal()] = struct{}{}
+		})
+	}
+
+	assert.Len(t, values, 7)
+	assert.Len(t, values[conventions.AttributeK8sPod], 20)
+	assert.Len(t, values[conventions.AttributeServiceName], 2)
+	assert.Len(t, values[conventions.AttributeK8sNamespace], 1)
+	assert.Contains(t, values[conventions.AttributeK8sPod], "k8s-pod-name-19")
+	// Resource attributes are not counted as data items.
+	assert.EqualValues(t, 40, dataItemsGenerated.Load())
+}
+
+func TestPerfTestDataProviderSetResourceAttributeCardinality(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1, ResourceAttributeCardinality: map[string]int{"k8s.pod.name": 2}})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	distinctPods := func() int {
+		pods := map[string]bool{}
+		for i := 0; i < 10; i++ {
+			md, _ := dp.GenerateMetrics()
+			pod, ok := md.ResourceMetrics().At(0).Resource().Attributes().Get("k8s.pod.name")
+			require.True(t, ok)
+			pods[pod.StringVal()] = true
+		}
+		return len(pods)
+	}
+	assert.Equal(t, 2, distinctPods())
+	dp.SetResourceAttributeCardinality(map[string]int{"k8s.pod.name": 5})
+	assert.Equal(t, 5, distinctPods())
+}
+
+func TestPerfTestDataProviderSpanErrorRate(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10, SpanErrorRate: 0.2})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	errorSpans := 0
+	for i := 0; i < 100; i++ {
+		td, _ := dp.GenerateTraces()
+		spans := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+		for j := 0; j < spans.Len(); j++ {
+			status := spans.At(j).Status()
+			switch status.Code() {
+			case pdata.StatusCodeError:
+				errorSpans++
+				assert.NotEmpty(t, status.Message())
+			case pdata.StatusCodeOk:
+				assert.Empty(t, status.Message())
+			default:
+				t.Fatalf("unexpected status code %v", status.Code())
+			}
+		}
+	}
+	assert.EqualValues(t, 1000, dataItemsGenerated.Load())
+	assert.InDelta(t, 0.2, float64(errorSpans)/1000, 0.02)
+}
+
+func TestPerfTestDataProviderClockSkewedSpanRate(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10, ClockSkewedSpanRate: 0.2})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	skews := map[ClockSkew]int{}
+	for i := 0; i < 100; i++ {
+		td, _ := dp.GenerateTraces()
+		now := time.Now()
+		spans := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+		for j := 0; j < spans.Len(); j++ {
+			skews[SpanClockSkew(spans.At(j), now)]++
+		}
+	}
+	assert.EqualValues(t, 1000, dataItemsGenerated.Load())
+	assert.Equal(t, map[ClockSkew]int{
+		ClockSkewNone:             800,
+		ClockSkewNegativeDuration: 100,
+		ClockSkewFuture:           100,
+	}, skews)
+}
+
+func TestPerfTestDataProviderEdgeCaseMetrics(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:       8,
+		DataPointsPerMetric: 2,
+		EdgeCaseMetrics: []string{
+			EdgeCaseMetricNaNGauge,
+			EdgeCaseMetricInfGauge,
+			EdgeCaseMetricInconsistentHistogram,
+			EdgeCaseMetricEmptyName,
+		},
+		EdgeCaseMetricRate: 0.5,
+	})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	md, _ := dp.GenerateMetrics()
+	_, dataPoints := md.MetricAndDataPointCount()
+	assert.Equal(t, 16, dataPoints)
+	assert.EqualValues(t, 16, dataItemsGenerated.Load())
+
+	// Every second metric is an edge case, cycling through the kinds.
+	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	for _, i := range []int{0, 2, 4, 6} {
+		assert.Equal(t, pdata.MetricDataTypeIntGauge, metrics.At(i).DataType())
+		assert.NotEmpty(t, metrics.At(i).Name())
+	}
+
+	nanGauge := metrics.At(1).DoubleGauge().DataPoints()
+	require.Equal(t, 2, nanGauge.Len())
+	assert.True(t, math.IsNaN(nanGauge.At(0).Value()))
+
+	infGauge := metrics.At(3).DoubleGauge().DataPoints()
+	require.Equal(t, 2, infGauge.Len())
+	assert.True(t, math.IsInf(infGauge.At(0).Value(), 0))
+	assert.Equal(t, -infGauge.At(0).Value(), infGauge.At(1).Value())
+
+	histogram := metrics.At(5).DoubleHistogram().DataPoints()
+	require.Equal(t, 2, histogram.Len())
+	assert.Less(t, histogram.At(0).Sum(), float64(0))
+	assert.Less(t, histogram.At(0).Count(), histogram.At(0).BucketCounts()[0])
+
+	assert.Equal(t, pdata.MetricDataTypeIntGauge, metrics.At(7).DataType())
+	assert.Empty(t, metrics.At(7).Name())
+}
+
+func TestPerfTestDataProviderSummaryQuantiles(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:       2,
+		DataPointsPerMetric: 3,
+		SummaryQuantiles:    []float64{0.99, 0, 0.5, 0.9, 1},
+	})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	md, _ := dp.GenerateMetrics()
+	_, dataPoints := md.MetricAndDataPointCount()
+	assert.Equal(t, 6, dataPoints)
+	assert.EqualValues(t, 6, dataItemsGenerated.Load())
+
+	metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		require.Equal(t, pdata.MetricDataTypeDoubleSummary, metrics.At(i).DataType())
+		dps := metrics.At(i).DoubleSummary().DataPoints()
+		require.Equal(t, 3, dps.Len())
+		for j := 0; j < dps.Len(); j++ {
+			quantileValues := dps.At(j).QuantileValues()
+			require.Equal(t, 5, quantileValues.Len())
+			var quantiles []float64
+			sum := 0.0
+			for k := 0; k < quantileValues.Len(); k++ {
+				quantiles = append(quantiles, quantileValues.At(k).Quantile())
+				sum += quantileValues.At(k).Value()
+				if k > 0 {
+					assert.Greater(t, quantileValues.At(k).Value(), quantileValues.At(k-1).Value())
+				}
+			}
+			assert.Equal(t, []float64{0, 0.5, 0.9, 0.99, 1}, quantiles)
+			assert.Equal(t, 2*quantileValues.At(0).Value(), quantileValues.At(4).Value())
+			assert.EqualValues(t, 5, dps.At(j).Count())
+			assert.InDelta(t, sum, dps.At(j).Sum(), 1e-9)
+		}
+	}
+}
+
+func TestPerfTestDataProviderLogTraceCorrelation(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10, LogTraceCorrelationRate: 0.3})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	correlated := 0
+	for batch := uint64(1); batch <= 10; batch++ {
+		ld, _ := dp.GenerateLogs()
+		records := ld.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs()
+		require.Equal(t, 10, records.Len())
+		for i := 0; i < records.Len(); i++ {
+			record := records.At(i)
+			if record.TraceID().IsEmpty() {
+				assert.True(t, record.SpanID().IsEmpty())
+				continue
+			}
+			correlated++
+			itemIndex, ok := record.Attributes().Get("item_index")
+			require.True(t, ok)
+			seqNum, err := strconv.ParseUint(strings.TrimPrefix(itemIndex.StringVal(), "item_"), 10, 64)
+			require.NoError(t, err)
+			assert.Equal(t, GenerateSequentialTraceID(batch), record.TraceID())
+			assert.Equal(t, GenerateSequentialSpanID(seqNum), record.SpanID())
+		}
+	}
+	assert.Equal(t, 30, correlated)
+	assert.EqualValues(t, 100, dataItemsGenerated.Load())
+}
+
+func TestPerfTestDataProviderMaxBatchBytes(t *testing.T) {
+	const maxBatchBytes = 4 << 20
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10, MaxBatchBytes: maxBatchBytes})
+	dataItemsGenerated := atomic.NewUint64(0)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), dataItemsGenerated)
+
+	var sentItems int
+	for i := 0; i < 3; i++ {
+		td, _ := dp.GenerateTraces()
+		data, err := td.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		assert.InDelta(t, maxBatchBytes, len(data), 0.01*maxBatchBytes)
+		assert.Greater(t, td.SpanCount(), 10)
+		sentItems += td.SpanCount()
+
+		md, _ := dp.GenerateMetrics()
+		data, err = md.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		assert.InDelta(t, maxBatchBytes, len(data), 0.01*maxBatchBytes)
+		_, dataPoints := md.MetricAndDataPointCount()
+		sentItems += dataPoints
+
+		ld, _ := dp.GenerateLogs()
+		data, err = ld.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		assert.InDelta(t, maxBatchBytes, len(data), 0.01*maxBatchBytes)
+		sentItems += ld.LogRecordCount()
+	}
+	assert.EqualValues(t, sentItems, dataItemsGenerated.Load())
+
+	// A batch has at least one item even if it exceeds MaxBatchBytes.
+	dp = NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10, MaxBatchBytes: 1})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	td, _ := dp.GenerateTraces()
+	assert.Equal(t, 1, td.SpanCount())
+}
@@ -23,6 +23,9 @@ import (
 
 	"go.uber.org/atomic"
 	"golang.org/x/text/message"
+
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/consumer/pdata"
 )
 
 var printer = message.NewPrinter(message.MatchLanguage("en"))
@@ -39,14 +42,41 @@ type LoadGenerator struct {
 	// Number of data items (spans or metric data points) sent.
 	dataItemsSent atomic.Uint64
 
+	// Number of failed attempts to send a batch.
+	sendErrors atomic.Uint64
+
+	// Number of send attempts which failed because LoadOptions.ExportTimeout expired.
+	cancelledExports atomic.Uint64
+
+	// Number of requests without data items sent because of LoadOptions.EmptyRequestRate,
+	// and number of requests with data items they are interleaved with.
+	emptyRequestsSent atomic.Uint64
+	dataRequestsSent  atomic.Uint64
+
 	stopOnce   sync.Once
 	stopWait   sync.WaitGroup
 	stopSignal chan struct{}
 
+	// Number of data items to send before stopping, 0 if unlimited.
+	totalItems uint64
+	// Serializes generation of batches when sending a limited number of data items.
+	countMutex   sync.Mutex
+	countReached atomic.Bool
+
+	// Times the load was started and stopped, used to compute the active duration.
+	startTime time.Time
+	stopTime  time.Time
+
 	options LoadOptions
 
 	// Record information about previous errors to avoid flood of error messages.
 	prevErr error
+
+	// Records the generated batches if RecordSession was called, nil otherwise.
+	recorder *sessionRecorder
+
+	// The process generating the load if StartProcess was called, nil otherwise.
+	process *loadGeneratorProcess
 }
 
 // LoadOptions defines the options to use for generating the load.
@@ -60,11 +90,253 @@ type LoadOptions struct {
 	// of batches generated per second will be DataItemsPerSecond/ItemsPerBatch.
 	ItemsPerBatch int
 
+	// MaxBatchBytes specifies the size in bytes of the generated batches if greater than
+	// zero. Instead of ItemsPerBatch items, as many items are packed into each batch as
+	// fit into MaxBatchBytes in OTLP protobuf encoding, at least one, so that the batches
+	// end up close to MaxBatchBytes, e.g. to exercise the max_recv_msg_size_mib limit of
+	// the receiver. Batches are still generated at the rate
+	// DataItemsPerSecond/ItemsPerBatch, so ItemsPerBatch should be set to the expected
+	// number of items per batch to keep the configured rate.
+	MaxBatchBytes int
+
 	// Attributes to add to each generated data item. Can be empty.
 	Attributes map[string]string
 
+	// ResourceAttributeValues specifies resource attributes to add to each generated
+	// batch. For each key the batches cycle through the listed values, e.g. with values
+	// "a" and "b" every second batch gets "a". Can be used to exercise routing by a
+	// resource attribute. Can be empty.
+	ResourceAttributeValues map[string][]string
+
+	// ResourceAttributeCardinality specifies resource attributes to add to each
+	// generated batch and how many distinct values each of them has. The values are
+	// generated from the key, e.g. "k8s-pod-name-3" for "k8s.pod.name", and cycled
+	// through like ResourceAttributeValues, so attributes with the same cardinality
+	// always appear with the same combination of values. DeploymentResourceAttributes
+	// returns a realistic set of attributes. Can be empty.
+	ResourceAttributeCardinality map[string]int
+
+	// AttributeValueTypes specifies how many additional attributes of each value
+	// type to add to each generated span and log record, and to the resource of
+	// generated metrics (metric labels only support strings). The ratio between the
+	// counts controls the proportion of the value types. Supported types are
+	// pdata.AttributeValueSTRING, INT, DOUBLE, BOOL, MAP and ARRAY. Can be empty.
+	AttributeValueTypes map[pdata.AttributeValueType]int
+
+	// ExemplarsPerDataPoint specifies how many exemplars to add to each generated
+	// metric data point. If greater than zero monotonic sums are generated instead of
+	// gauges. Exemplars are not counted as data items.
+	ExemplarsPerDataPoint int
+
+	// DataPointsPerMetric specifies how many data points with distinct label sets each
+	// generated metric carries. Every data point counts as one data item. If 0 each
+	// metric carries 7 data points.
+	DataPointsPerMetric int
+
+	// MetricTemporality specifies the aggregation temporality of generated metrics, one
+	// of MetricTemporalityCumulative or MetricTemporalityDelta. If set monotonic sums
+	// are generated instead of gauges. If empty and sums are generated they are
+	// cumulative.
+	MetricTemporality string
+
+	// MetricDataTypes specifies the data types of generated metrics, any of
+	// pdata.MetricDataTypeIntGauge, IntSum, DoubleGauge and DoubleSum, so that both the
+	// legacy Int variants and the Double variants are exercised. The i-th metric of every
+	// batch gets element i modulo the length of the slice. Sums are monotonic with the
+	// temporality set by MetricTemporality and exemplars are only added to Int data
+	// points. If empty the data type is chosen as described for ExemplarsPerDataPoint,
+	// MetricTemporality and CounterResetInterval. Ignored if SummaryQuantiles is set.
+	MetricDataTypes []pdata.MetricDataType
+
+	// SummaryQuantiles specifies the quantiles, between 0 and 1, of the summaries which
+	// are generated instead of gauges if it is not empty. The quantiles are generated
+	// in ascending order and the values of every data point increase with the quantile.
+	// Every summary data point counts as one data item.
+	SummaryQuantiles []float64
+
+	// MetricUnits and MetricDescriptions specify the units and descriptions of generated
+	// metrics. The i-th metric of every batch gets element i modulo the length of the
+	// slice so that metrics with the same name always have the same metadata. If empty
+	// the unit "1" and the description "Load Generator Counter #i" are used.
+	MetricUnits        []string
+	MetricDescriptions []string
+
+	// InstrumentationLibraryName and InstrumentationLibraryVersion specify the name and
+	// version of the instrumentation library of generated metrics. If empty the
+	// instrumentation library is left unset.
+	InstrumentationLibraryName    string
+	InstrumentationLibraryVersion string
+
+	// MetricStartTime specifies the start timestamp of generated metric data points. If
+	// zero each data point gets the time it was generated, unless CounterResetInterval is
+	// set in which case the time of the first generated batch is used.
+	MetricStartTime time.Time
+
+	// CounterResetInterval specifies after how many generated metrics batches the
+	// counters are reset, i.e. the start timestamp advances to the current time and the
+	// values drop and start again from 1. If greater than zero monotonic cumulative sums
+	// are generated, and the batch_index label is omitted so that every batch continues
+	// the same series. Resets do not change the number of generated data items.
+	CounterResetInterval int
+
+	// EdgeCaseMetrics lists the kinds of semantically odd but valid metrics to generate
+	// in place of regular ones, see EdgeCaseMetricNaNGauge and the other EdgeCaseMetric
+	// constants. The generated edge case metrics cycle through the list. Edge case
+	// metrics have the same number of data points as regular ones so the counts of data
+	// items are unaffected. Can be empty.
+	EdgeCaseMetrics []string
+
+	// EdgeCaseMetricRate specifies the fraction of generated metrics which are replaced
+	// by EdgeCaseMetrics, between 0 and 1. The edge cases are spread evenly over the
+	// generated metrics.
+	EdgeCaseMetricRate float64
+
+	// SpanErrorRate specifies the fraction of generated spans which get status
+	// StatusCodeError with an error message, between 0 and 1. If greater than 0 the
+	// remaining spans get StatusCodeOk, otherwise the status is left unset.
+	SpanErrorRate float64
+
+	// ClockSkewedSpanRate specifies the fraction of generated spans with timestamps
+	// distorted as if by clock skew, between 0 and 1. The skewed spans alternate between
+	// an end timestamp before the start timestamp and timestamps ClockSkewFutureOffset in
+	// the future. They are spread evenly over the generated spans and counted like
+	// regular spans, see ClockSkewValidator.
+	ClockSkewedSpanRate float64
+
+	// NestedAttributeDepth adds the attribute "load_generator.nested" to each generated
+	// span if greater than 0. Its value is nested NestedAttributeDepth levels deep, the
+	// levels alternate between maps, starting at the top, and arrays of
+	// NestedAttributeWidth values each, so it has NestedAttributeWidth^NestedAttributeDepth
+	// string leaves. NestedAttributeWidth defaults to 2.
+	NestedAttributeDepth int
+	NestedAttributeWidth int
+
+	// SpanLinksPerSpan specifies how many links to other traces each generated span
+	// carries. The j-th link references the span at the same position of the batch
+	// generated j batches earlier, by the IDs derived from its sequence numbers. Links
+	// to batches before the first one are omitted. Links are not counted as data items.
+	SpanLinksPerSpan int
+
+	// IdlePattern alternates windows in which the load is generated with idle windows
+	// in which nothing is sent. If not set the load is generated continuously.
+	IdlePattern IdlePattern
+
 	// Parallel specifies how many goroutines to send from.
 	Parallel int
+
+	// DisableClientBatching makes the generator send each generated data item in its
+	// own request instead of accumulating ItemsPerBatch items into one request, i.e.
+	// it simulates sending without a batch processor in the client SDK. Items are
+	// still generated at the same rate so sent counts are unaffected.
+	DisableClientBatching bool
+
+	// LogBodyBytes specifies the size in bytes of the body of each generated log
+	// record. If 0 a short body identifying the record is generated.
+	LogBodyBytes int
+
+	// LogBodyFormat specifies the structure of generated log record bodies, one of
+	// LogBodyFormatPlain (the default), LogBodyFormatJSON, LogBodyFormatMultiline or
+	// LogBodyFormatBinary.
+	LogBodyFormat string
+
+	// SeverityDistribution specifies the proportions of the severities of generated log
+	// records as weights, for example {SeverityNumberINFO: 90, SeverityNumberERROR: 10}.
+	// The severities are interleaved in a fixed cycle over the record sequence numbers,
+	// so every stretch of records as long as the sum of the weights has exactly the
+	// configured proportions. If empty all records have severity INFO3.
+	SeverityDistribution map[pdata.SeverityNumber]int
+
+	// LogTraceCorrelationRate specifies the fraction of generated log records which
+	// carry a trace ID and span ID, between 0 and 1. The IDs are generated like those of
+	// generated spans, from the batch and the record sequence numbers, so that logs can
+	// be correlated with traces. The correlated records are spread evenly over the
+	// generated records.
+	LogTraceCorrelationRate float64
+
+	// ExportTimeout specifies the deadline of each request sent to the agent. Requests
+	// which are still in flight when it expires are cancelled by the client and counted
+	// as cancelled exports. If 0 requests have no deadline.
+	ExportTimeout time.Duration
+
+	// EmptyRequestRate specifies the fraction of sent requests which carry no data
+	// items, between 0 and 1 (exclusive), to measure the fixed cost per request apart
+	// from the cost per data item. The empty requests are sent in addition to and spread
+	// evenly between the requests with data, so the rate of data items is unaffected.
+	// They are not counted as batches, see EmptyRequestsSent.
+	EmptyRequestRate float64
+}
+
+const (
+	// LogBodyFormatPlain generates plain text log bodies.
+	LogBodyFormatPlain = "plain"
+	// LogBodyFormatJSON generates log bodies containing a JSON object, padded to
+	// LogBodyBytes. If LogBodyBytes is too small for the object the body is cut to
+	// LogBodyBytes and is no longer valid JSON.
+	LogBodyFormatJSON = "json"
+	// LogBodyFormatMultiline generates log bodies spanning several lines, like stack
+	// traces, separated by both "\n" and "\r\n".
+	LogBodyFormatMultiline = "multiline"
+	// LogBodyFormatBinary generates log bodies containing arbitrary bytes, including NUL
+	// bytes and invalid UTF-8 sequences. If LogBodyBytes is 0 the bodies are 256 bytes
+	// long so that they contain every byte value.
+	LogBodyFormatBinary = "binary"
+)
+
+const (
+	// MetricTemporalityCumulative generates metrics with cumulative aggregation temporality.
+	MetricTemporalityCumulative = "cumulative"
+	// MetricTemporalityDelta generates metrics with delta aggregation temporality.
+	MetricTemporalityDelta = "delta"
+)
+
+const (
+	// EdgeCaseMetricNaNGauge generates double gauges with NaN values.
+	EdgeCaseMetricNaNGauge = "nan_gauge"
+	// EdgeCaseMetricInfGauge generates double gauges with alternating +Inf and -Inf values.
+	EdgeCaseMetricInfGauge = "inf_gauge"
+	// EdgeCaseMetricInconsistentHistogram generates histograms with a negative sum and
+	// a count lower than the sum of the bucket counts. Counts are unsigned in OTLP so
+	// this is the closest to a negative count the protocol can carry.
+	EdgeCaseMetricInconsistentHistogram = "inconsistent_histogram"
+	// EdgeCaseMetricEmptyName generates regular metrics with an empty name.
+	EdgeCaseMetricEmptyName = "empty_name"
+)
+
+// IdlePattern describes alternating active and idle windows of a bursty load. The
+// pattern starts with an active window when the load is started. It is used only if
+// both durations are greater than zero.
+type IdlePattern struct {
+	// Active is the duration of the windows in which the load is generated.
+	Active time.Duration
+	// Idle is the duration of the windows in which nothing is sent.
+	Idle time.Duration
+}
+
+func (p IdlePattern) enabled() bool {
+	return p.Active > 0 && p.Idle > 0
+}
+
+// isIdle returns true if the time elapsed since the start of the load falls into an
+// idle window.
+func (p IdlePattern) isIdle(elapsed time.Duration) bool {
+	if !p.enabled() {
+		return false
+	}
+	return elapsed%(p.Active+p.Idle) >= p.Active
+}
+
+// activeDuration returns how much of the time elapsed since the start of the load fell
+// into active windows.
+func (p IdlePattern) activeDuration(elapsed time.Duration) time.Duration {
+	if !p.enabled() {
+		return elapsed
+	}
+	period := p.Active + p.Idle
+	active := elapsed / period * p.Active
+	if rem := elapsed % period; rem < p.Active {
+		return active + rem
+	}
+	return active + p.Active
 }
 
 // NewLoadGenerator creates a load generator that sends data using specified sender.
@@ -82,6 +354,36 @@ func NewLoadGenerator(dataProvider DataProvider, sender DataSender) (*LoadGenera
 	return lg, nil
 }
 
+// RecordSession makes the load generator write every generated batch together with
+// its offset from the start of the load to a session file at path, which
+// NewSessionDataProvider replays. The file is complete once the load is stopped. Must
+// be called before the load is started.
+func (lg *LoadGenerator) RecordSession(path string) error {
+	recorder, err := newSessionRecorder(path)
+	if err != nil {
+		return err
+	}
+	lg.recorder = recorder
+	return nil
+}
+
+// recordBatch records a generated batch if RecordSession was called.
+func (lg *LoadGenerator) recordBatch(signal configmodels.DataType, marshal func() ([]byte, error)) {
+	if lg.recorder != nil {
+		lg.recorder.record(time.Since(lg.startTime), signal, marshal)
+	}
+}
+
+// closeRecorder completes the session file if RecordSession was called.
+func (lg *LoadGenerator) closeRecorder() {
+	if lg.recorder == nil {
+		return
+	}
+	if err := lg.recorder.close(); err != nil {
+		log.Printf("Cannot record session: %v", err)
+	}
+}
+
 // Start the load.
 func (lg *LoadGenerator) Start(options LoadOptions) {
 	lg.options = options
@@ -92,6 +394,10 @@ func (lg *LoadGenerator) Start(options LoadOptions) {
 	}
 
 	log.Printf("Starting load generator at %d items/sec.", lg.options.DataItemsPerSecond)
+	if lg.options.IdlePattern.enabled() {
+		log.Printf("Alternating %v of load with %v of idle.", lg.options.IdlePattern.Active, lg.options.IdlePattern.Idle)
+	}
+	lg.startTime = time.Now()
 
 	// Indicate that generation is in progress.
 	lg.stopWait.Add(1)
@@ -100,14 +406,96 @@ func (lg *LoadGenerator) Start(options LoadOptions) {
 	go lg.generate()
 }
 
+// StartProcess starts the load like Start but generates it in a separate process, so
+// that the cost of generating and sending the data does not compete with the agent for
+// CPU. The process is the current executable, which must call DoTestMain from its
+// TestMain, and its standard error is written to logFilePath. The process uses a
+// PerfTestDataProvider with options instead of the DataProvider of the LoadGenerator
+// and recreates the DataSender from its type and endpoint, so only senders configured
+// solely by host and port are supported. The counters of the LoadGenerator are updated
+// with the counters reported by the process, and ProcessResourceConsumption reports its
+// resource consumption.
+func (lg *LoadGenerator) StartProcess(options LoadOptions, logFilePath string) error {
+	host, port, err := splitEndpoint(lg.sender.GetEndpoint())
+	if err != nil {
+		return err
+	}
+	spec := loadGeneratorProcessSpec{
+		Sender:  fmt.Sprintf("%T", lg.sender),
+		Host:    host,
+		Port:    port,
+		Options: options,
+	}
+	if _, err = newProcessDataSender(spec.Sender, host, port); err != nil {
+		return err
+	}
+
+	lg.options = options
+	log.Printf("Starting load generator process at %d items/sec.", options.DataItemsPerSecond)
+	lg.startTime = time.Now()
+	lg.process, err = startLoadGeneratorProcess(spec, logFilePath, lg.applyProcessStats)
+	return err
+}
+
+// applyProcessStats updates the counters with the counters reported by the load
+// generator process.
+func (lg *LoadGenerator) applyProcessStats(stats loadGeneratorProcessStats) {
+	lg.dataItemsSent.Store(stats.DataItemsSent)
+	lg.batchesSent.Store(stats.BatchesSent)
+	lg.sendErrors.Store(stats.SendErrors)
+	lg.cancelledExports.Store(stats.CancelledExports)
+	lg.emptyRequestsSent.Store(stats.EmptyRequestsSent)
+}
+
+// processStats returns the counters to report from the load generator process.
+func (lg *LoadGenerator) processStats() loadGeneratorProcessStats {
+	return loadGeneratorProcessStats{
+		DataItemsSent:     lg.DataItemsSent(),
+		BatchesSent:       lg.BatchesSent(),
+		SendErrors:        lg.SendErrors(),
+		CancelledExports:  lg.CancelledExports(),
+		EmptyRequestsSent: lg.EmptyRequestsSent(),
+	}
+}
+
+// ProcessResourceConsumption returns the resource consumption of the load generator
+// process, or nil if the load was not started with StartProcess.
+func (lg *LoadGenerator) ProcessResourceConsumption() *ResourceConsumption {
+	if lg.process == nil {
+		return nil
+	}
+	return lg.process.totalConsumption()
+}
+
+// StartCount starts the load like Start but stops generating once exactly totalItems
+// data items were sent. The last batch is truncated if needed and its remaining items
+// are sent individually. Use CountReached to check whether all items were sent.
+func (lg *LoadGenerator) StartCount(options LoadOptions, totalItems uint64) {
+	lg.totalItems = totalItems
+	lg.Start(options)
+}
+
+// CountReached returns true if the load was started with StartCount and the requested
+// number of data items was sent.
+func (lg *LoadGenerator) CountReached() bool {
+	return lg.countReached.Load()
+}
+
 // Stop the load.
 func (lg *LoadGenerator) Stop() {
 	lg.stopOnce.Do(func() {
-		// Signal generate() to stop.
-		close(lg.stopSignal)
+		if lg.process != nil {
+			if err := lg.process.stop(); err != nil {
+				log.Printf("Load generator process failed: %s", err.Error())
+			}
+		} else {
+			// Signal generate() to stop.
+			close(lg.stopSignal)
 
-		// Wait for it to stop.
-		lg.stopWait.Wait()
+			// Wait for it to stop.
+			lg.stopWait.Wait()
+		}
+		lg.stopTime = time.Now()
 
 		// Print stats.
 		log.Printf("Stopped generator. %s", lg.GetStats())
@@ -116,13 +504,58 @@ func (lg *LoadGenerator) Stop() {
 
 // GetStats returns the stats as a printable string.
 func (lg *LoadGenerator) GetStats() string {
-	return fmt.Sprintf("Sent:%10d items", lg.DataItemsSent())
+	stats := fmt.Sprintf("Sent:%10d items", lg.DataItemsSent())
+	if cancelled := lg.CancelledExports(); cancelled > 0 {
+		stats += fmt.Sprintf(", %d cancelled exports", cancelled)
+	}
+	if rc := lg.ProcessResourceConsumption(); rc != nil {
+		stats += fmt.Sprintf(", generator CPU max:%4.1f%%, RAM max:%4d MiB", rc.CPUPercentMax, rc.RAMMiBMax)
+	}
+	return stats
 }
 
 func (lg *LoadGenerator) DataItemsSent() uint64 {
 	return lg.dataItemsSent.Load()
 }
 
+// BatchesSent returns the number of batches sent. For traces generated by
+// PerfTestDataProvider this is the number of traces.
+func (lg *LoadGenerator) BatchesSent() uint64 {
+	return lg.batchesSent.Load()
+}
+
+// ActiveDuration returns how long the load was generated, excluding the idle windows of
+// LoadOptions.IdlePattern. Until the load is stopped it is measured up to now.
+func (lg *LoadGenerator) ActiveDuration() time.Duration {
+	if lg.startTime.IsZero() {
+		return 0
+	}
+	end := lg.stopTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return lg.options.IdlePattern.activeDuration(end.Sub(lg.startTime))
+}
+
+// SendErrors returns the number of batches which the sender failed to send, e.g.
+// because the agent was not accepting connections.
+func (lg *LoadGenerator) SendErrors() uint64 {
+	return lg.sendErrors.Load()
+}
+
+// CancelledExports returns the number of requests which were cancelled by the client
+// because they did not complete within LoadOptions.ExportTimeout. They are included in
+// SendErrors.
+func (lg *LoadGenerator) CancelledExports() uint64 {
+	return lg.cancelledExports.Load()
+}
+
+// EmptyRequestsSent returns the number of requests without data items sent because of
+// LoadOptions.EmptyRequestRate.
+func (lg *LoadGenerator) EmptyRequestsSent() uint64 {
+	return lg.emptyRequestsSent.Load()
+}
+
 // IncDataItemsSent is used when a test bypasses the LoadGenerator and sends data
 // directly via TestCases's Sender. This is necessary so that the total number of sent
 // items in the end is correct, because the reports are printed from LoadGenerator's
@@ -136,6 +569,7 @@ func (lg *LoadGenerator) IncDataItemsSent() {
 func (lg *LoadGenerator) generate() {
 	// Indicate that generation is done at the end
 	defer lg.stopWait.Done()
+	defer lg.closeRecorder()
 
 	if lg.options.DataItemsPerSecond == 0 {
 		return
@@ -167,7 +601,17 @@ func (lg *LoadGenerator) generate() {
 			for {
 				select {
 				case <-t.C:
+					if lg.CountReached() {
+						return
+					}
+					if lg.options.IdlePattern.isIdle(time.Since(lg.startTime)) {
+						continue
+					}
 					switch lg.sender.(type) {
+					case allSignalsDataSender:
+						lg.generateTrace()
+						lg.generateMetrics()
+						lg.generateLog()
 					case TraceDataSender:
 						lg.generateTrace()
 					case MetricDataSender:
@@ -190,53 +634,288 @@ func (lg *LoadGenerator) generate() {
 	lg.sender.Flush()
 }
 
+// allSignalsDataSender is implemented by senders of all signals, e.g. OTLPDataSender.
+type allSignalsDataSender interface {
+	TraceDataSender
+	MetricDataSender
+	LogDataSender
+}
+
 func (lg *LoadGenerator) generateTrace() {
 	traceSender := lg.sender.(TraceDataSender)
 
+	if lg.totalItems > 0 {
+		lg.countMutex.Lock()
+		defer lg.countMutex.Unlock()
+		if lg.CountReached() {
+			return
+		}
+	}
+
 	traceData, done := lg.dataProvider.GenerateTraces()
 	if done {
 		return
 	}
+	lg.recordBatch(configmodels.TracesDataType, traceData.ToOtlpProtoBytes)
 
-	err := traceSender.ConsumeTraces(context.Background(), traceData)
-	if err == nil {
-		lg.prevErr = nil
-	} else if lg.prevErr == nil || lg.prevErr.Error() != err.Error() {
-		lg.prevErr = err
-		log.Printf("Cannot send traces: %v", err)
+	requests := []pdata.Traces{traceData}
+	if lg.options.DisableClientBatching {
+		requests = splitTraces(traceData)
+	}
+	if lg.totalItems > 0 {
+		itemCount := traceData.SpanCount()
+		if keep := lg.limitToCount(itemCount); keep < itemCount {
+			requests = splitTraces(traceData)[:keep]
+		}
+	}
+
+	for _, req := range requests {
+		req := req
+		lg.send("traces", func(ctx context.Context) error {
+			return traceSender.ConsumeTraces(ctx, req)
+		})
+		lg.sendEmptyRequests("traces", func(ctx context.Context) error {
+			return traceSender.ConsumeTraces(ctx, pdata.NewTraces())
+		})
 	}
 }
 
 func (lg *LoadGenerator) generateMetrics() {
 	metricSender := lg.sender.(MetricDataSender)
 
+	if lg.totalItems > 0 {
+		lg.countMutex.Lock()
+		defer lg.countMutex.Unlock()
+		if lg.CountReached() {
+			return
+		}
+	}
+
 	metricData, done := lg.dataProvider.GenerateMetrics()
 	if done {
 		return
 	}
+	lg.recordBatch(configmodels.MetricsDataType, metricData.ToOtlpProtoBytes)
 
-	err := metricSender.ConsumeMetrics(context.Background(), metricData)
-	if err == nil {
-		lg.prevErr = nil
-	} else if lg.prevErr == nil || lg.prevErr.Error() != err.Error() {
-		lg.prevErr = err
-		log.Printf("Cannot send metrics: %v", err)
+	requests := []pdata.Metrics{metricData}
+	if lg.options.DisableClientBatching {
+		requests = splitMetrics(metricData)
+	}
+	if lg.totalItems > 0 {
+		_, itemCount := metricData.MetricAndDataPointCount()
+		if keep := lg.limitToCount(itemCount); keep < itemCount {
+			requests = splitMetrics(metricData)[:keep]
+		}
+	}
+
+	for _, req := range requests {
+		req := req
+		lg.send("metrics", func(ctx context.Context) error {
+			return metricSender.ConsumeMetrics(ctx, req)
+		})
+		lg.sendEmptyRequests("metrics", func(ctx context.Context) error {
+			return metricSender.ConsumeMetrics(ctx, pdata.NewMetrics())
+		})
 	}
 }
 
 func (lg *LoadGenerator) generateLog() {
 	logSender := lg.sender.(LogDataSender)
 
+	if lg.totalItems > 0 {
+		lg.countMutex.Lock()
+		defer lg.countMutex.Unlock()
+		if lg.CountReached() {
+			return
+		}
+	}
+
 	logData, done := lg.dataProvider.GenerateLogs()
 	if done {
 		return
 	}
+	lg.recordBatch(configmodels.LogsDataType, logData.ToOtlpProtoBytes)
 
-	err := logSender.ConsumeLogs(context.Background(), logData)
+	requests := []pdata.Logs{logData}
+	if lg.options.DisableClientBatching {
+		requests = splitLogs(logData)
+	}
+	if lg.totalItems > 0 {
+		itemCount := logData.LogRecordCount()
+		if keep := lg.limitToCount(itemCount); keep < itemCount {
+			requests = splitLogs(logData)[:keep]
+		}
+	}
+
+	for _, req := range requests {
+		req := req
+		lg.send("logs", func(ctx context.Context) error {
+			return logSender.ConsumeLogs(ctx, req)
+		})
+		lg.sendEmptyRequests("logs", func(ctx context.Context) error {
+			return logSender.ConsumeLogs(ctx, pdata.NewLogs())
+		})
+	}
+}
+
+// send calls consume with the context of one request and records whether it failed,
+// logging errors only when they change to avoid a flood of messages.
+func (lg *LoadGenerator) send(kind string, consume func(ctx context.Context) error) {
+	ctx := context.Background()
+	if lg.options.ExportTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lg.options.ExportTimeout)
+		defer cancel()
+	}
+
+	err := consume(ctx)
 	if err == nil {
 		lg.prevErr = nil
-	} else if lg.prevErr == nil || lg.prevErr.Error() != err.Error() {
+		return
+	}
+	lg.sendErrors.Inc()
+	if ctx.Err() == context.DeadlineExceeded {
+		lg.cancelledExports.Inc()
+	}
+	if lg.prevErr == nil || lg.prevErr.Error() != err.Error() {
 		lg.prevErr = err
-		log.Printf("Cannot send logs: %v", err)
+		log.Printf("Cannot send %s: %v", kind, err)
+	}
+}
+
+// sendEmptyRequests sends the requests without data items which are due after a request
+// with data items to keep the fraction of empty requests at LoadOptions.EmptyRequestRate.
+func (lg *LoadGenerator) sendEmptyRequests(kind string, consume func(ctx context.Context) error) {
+	rate := lg.options.EmptyRequestRate
+	if rate <= 0 || rate >= 1 {
+		return
+	}
+	// Round up tiny floating point errors so that e.g. a rate of 0.2 sends exactly one
+	// empty request after every fourth request with data items.
+	due := uint64(float64(lg.dataRequestsSent.Inc())*rate/(1-rate) + 1e-9)
+	for {
+		sent := lg.emptyRequestsSent.Load()
+		if sent >= due {
+			return
+		}
+		if lg.emptyRequestsSent.CAS(sent, sent+1) {
+			lg.send(kind, consume)
+		}
+	}
+}
+
+// limitToCount returns how many of the itemCount data items of the batch which was
+// just generated can be sent without exceeding the number of items requested by
+// StartCount. The items which are not sent are subtracted from the sent count.
+func (lg *LoadGenerator) limitToCount(itemCount int) int {
+	sent := lg.dataItemsSent.Load()
+	if sent < lg.totalItems {
+		return itemCount
+	}
+	excess := sent - lg.totalItems
+	lg.dataItemsSent.Sub(excess)
+	lg.countReached.Store(true)
+	log.Printf("Sent %d items, stopping generator.", lg.totalItems)
+	return itemCount - int(excess)
+}
+
+// splitTraces splits td into separate Traces containing a single span each.
+func splitTraces(td pdata.Traces) []pdata.Traces {
+	var result []pdata.Traces
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		ilss := rs.InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			ils := ilss.At(j)
+			spans := ils.Spans()
+			for k := 0; k < spans.Len(); k++ {
+				single := pdata.NewTraces()
+				single.ResourceSpans().Resize(1)
+				singleRS := single.ResourceSpans().At(0)
+				rs.Resource().CopyTo(singleRS.Resource())
+				singleRS.InstrumentationLibrarySpans().Resize(1)
+				singleILS := singleRS.InstrumentationLibrarySpans().At(0)
+				ils.InstrumentationLibrary().CopyTo(singleILS.InstrumentationLibrary())
+				singleILS.Spans().Resize(1)
+				spans.At(k).CopyTo(singleILS.Spans().At(0))
+				result = append(result, single)
+			}
+		}
 	}
+	return result
+}
+
+// splitMetrics splits md into separate Metrics containing a single data point each.
+func splitMetrics(md pdata.Metrics) []pdata.Metrics {
+	var result []pdata.Metrics
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		ilms := rm.InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			ilm := ilms.At(j)
+			metrics := ilm.Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				for l := 0; l < metricDataPointCount(metric); l++ {
+					single := pdata.NewMetrics()
+					single.ResourceMetrics().Resize(1)
+					singleRM := single.ResourceMetrics().At(0)
+					rm.Resource().CopyTo(singleRM.Resource())
+					singleRM.InstrumentationLibraryMetrics().Resize(1)
+					singleILM := singleRM.InstrumentationLibraryMetrics().At(0)
+					ilm.InstrumentationLibrary().CopyTo(singleILM.InstrumentationLibrary())
+					singleILM.Metrics().Resize(1)
+					singleMetric := singleILM.Metrics().At(0)
+					metric.CopyTo(singleMetric)
+					keepOnlyDataPoint(singleMetric, l)
+					result = append(result, single)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// splitLogs splits ld into separate Logs containing a single log record each.
+func splitLogs(ld pdata.Logs) []pdata.Logs {
+	var result []pdata.Logs
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		ills := rl.InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			ill := ills.At(j)
+			logs := ill.Logs()
+			for k := 0; k < logs.Len(); k++ {
+				single := pdata.NewLogs()
+				single.ResourceLogs().Resize(1)
+				singleRL := single.ResourceLogs().At(0)
+				rl.Resource().CopyTo(singleRL.Resource())
+				singleRL.InstrumentationLibraryLogs().Resize(1)
+				singleILL := singleRL.InstrumentationLibraryLogs().At(0)
+				ill.InstrumentationLibrary().CopyTo(singleILL.InstrumentationLibrary())
+				singleILL.Logs().Resize(1)
+				logs.At(k).CopyTo(singleILL.Logs().At(0))
+				result = append(result, single)
+			}
+		}
+	}
+	return result
+}
+
+func metricDataPointCount(metric pdata.Metric) int {
+	count := 0
+	forEachDataPoint(metric, func(dataPoint) {
+		count++
+	})
+	return count
+}
+
+// keepOnlyDataPoint removes all data points of metric except the one at index.
+func keepOnlyDataPoint(metric pdata.Metric, index int) {
+	retainDataPoints(metric, func(i int, _ dataPoint) bool {
+		return i == index
+	})
 }
@@ -0,0 +1,328 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/process"
+)
+
+// loadGeneratorProcessEnvVarName is the environment variable which makes DoTestMain run
+// the load generator described by its JSON value instead of the tests, see
+// LoadGenerator.StartProcess.
+const loadGeneratorProcessEnvVarName = "TESTBED_LOAD_GENERATOR_PROCESS"
+
+// loadGeneratorProcessStatsPeriod is how often the load generator process reports its
+// counters, and loadGeneratorProcessSamplePeriod how often its resource consumption is
+// measured.
+const (
+	loadGeneratorProcessStatsPeriod  = 100 * time.Millisecond
+	loadGeneratorProcessSamplePeriod = time.Second
+)
+
+// loadGeneratorProcessSpec describes the load to generate in the load generator process.
+type loadGeneratorProcessSpec struct {
+	// Sender is the type name of the DataSender, see newProcessDataSender.
+	Sender  string
+	Host    string
+	Port    int
+	Options LoadOptions
+}
+
+// loadGeneratorProcessStats are the counters of the load generator process. They are
+// written as one JSON object per line to its standard output.
+type loadGeneratorProcessStats struct {
+	DataItemsSent     uint64
+	BatchesSent       uint64
+	SendErrors        uint64
+	CancelledExports  uint64
+	EmptyRequestsSent uint64
+}
+
+// newProcessDataSender creates the DataSender with type name senderType, as returned by
+// fmt.Sprintf("%T", sender), sending to host and port. Only senders which are fully
+// configured by their host and port are supported.
+func newProcessDataSender(senderType string, host string, port int) (DataSender, error) {
+	switch senderType {
+	case "*testbed.OTLPTraceDataSender":
+		return NewOTLPTraceDataSender(host, port), nil
+	case "*testbed.OTLPMetricsDataSender":
+		return NewOTLPMetricDataSender(host, port), nil
+	case "*testbed.OTLPLogsDataSender":
+		return NewOTLPLogsDataSender(host, port), nil
+	case "*testbed.OTLPHTTPTraceDataSender":
+		return NewOTLPHTTPTraceDataSender(host, port), nil
+	case "*testbed.OTLPHTTPMetricsDataSender":
+		return NewOTLPHTTPMetricDataSender(host, port), nil
+	case "*testbed.OTLPHTTPLogsDataSender":
+		return NewOTLPHTTPLogsDataSender(host, port), nil
+	case "*testbed.JaegerGRPCDataSender":
+		return NewJaegerGRPCDataSender(host, port), nil
+	case "*testbed.OCTraceDataSender":
+		return NewOCTraceDataSender(host, port), nil
+	case "*testbed.OCMetricsDataSender":
+		return NewOCMetricDataSender(host, port), nil
+	case "*testbed.ZipkinDataSender":
+		return NewZipkinDataSender(host, port), nil
+	}
+	return nil, fmt.Errorf("sender %s cannot be used in a load generator process", senderType)
+}
+
+// runLoadGeneratorProcess generates the load described by specJSON until the standard
+// input is closed, reporting the counters on the standard output. Returns the exit code
+// of the process.
+func runLoadGeneratorProcess(specJSON string) int {
+	var spec loadGeneratorProcessSpec
+	if err := json.Unmarshal([]byte(specJSON), &spec); err != nil {
+		log.Printf("Invalid load generator process spec: %s", err.Error())
+		return 1
+	}
+	sender, err := newProcessDataSender(spec.Sender, spec.Host, spec.Port)
+	if err != nil {
+		log.Print(err.Error())
+		return 1
+	}
+	lg, err := NewLoadGenerator(NewPerfTestDataProvider(spec.Options), sender)
+	if err != nil {
+		log.Print(err.Error())
+		return 1
+	}
+
+	// The parent closes the standard input to stop the load. It is also closed if the
+	// parent exits without stopping the load.
+	stdinClosed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, os.Stdin)
+		close(stdinClosed)
+	}()
+
+	lg.Start(spec.Options)
+
+	encoder := json.NewEncoder(os.Stdout)
+	ticker := time.NewTicker(loadGeneratorProcessStatsPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := encoder.Encode(lg.processStats()); err != nil {
+				log.Printf("Cannot report load generator stats: %s", err.Error())
+			}
+		case <-stdinClosed:
+			lg.Stop()
+			if err := encoder.Encode(lg.processStats()); err != nil {
+				log.Printf("Cannot report load generator stats: %s", err.Error())
+				return 1
+			}
+			return 0
+		}
+	}
+}
+
+// loadGeneratorProcess is the separate process a LoadGenerator started with StartProcess
+// generates the load in.
+type loadGeneratorProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	// Closed once the process exited and all its reported stats were applied.
+	exitSignal chan struct{}
+	exitErr    error
+
+	mutex            sync.Mutex
+	processMon       *process.Process
+	startTime        time.Time
+	lastElapsedTime  time.Time
+	lastProcessTimes *cpu.TimesStat
+	cpuPercentMax    float64
+	ramMiBTotal      uint64
+	ramMiBMax        uint32
+	memProbeCount    int
+}
+
+// startLoadGeneratorProcess starts the current executable as a load generator process
+// with spec, writing its standard error to logFilePath. applyStats is called with every
+// stats update reported by the process.
+func startLoadGeneratorProcess(spec loadGeneratorProcessSpec, logFilePath string, applyStats func(loadGeneratorProcessStats)) (*loadGeneratorProcess, error) {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode load generator process spec: %s", err.Error())
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	logFile, err := os.Create(logFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s: %s", logFilePath, err.Error())
+	}
+
+	// #nosec
+	cmd := exec.Command(exePath)
+	cmd.Env = append(os.Environ(), loadGeneratorProcessEnvVarName+"="+string(specJSON))
+	cmd.Stderr = logFile
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("cannot open stdin of load generator process: %s", err.Error())
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("cannot capture stdout of load generator process: %s", err.Error())
+	}
+	if err = cmd.Start(); err != nil {
+		logFile.Close()
+		return nil, fmt.Errorf("cannot start load generator process: %s", err.Error())
+	}
+	log.Printf("Load generator process running, pid=%d, writing log to %s", cmd.Process.Pid, logFilePath)
+
+	p := &loadGeneratorProcess{
+		cmd:        cmd,
+		stdin:      stdin,
+		exitSignal: make(chan struct{}),
+		startTime:  time.Now(),
+	}
+	p.lastElapsedTime = p.startTime
+	if p.processMon, err = process.NewProcess(int32(cmd.Process.Pid)); err != nil {
+		log.Printf("Cannot monitor load generator process %d: %s", cmd.Process.Pid, err.Error())
+	} else if p.lastProcessTimes, err = p.processMon.Times(); err != nil {
+		log.Printf("Cannot get process times for %d: %s", cmd.Process.Pid, err.Error())
+		p.processMon = nil
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			var stats loadGeneratorProcessStats
+			if err := json.Unmarshal(scanner.Bytes(), &stats); err != nil {
+				log.Printf("Invalid load generator process stats %q: %s", scanner.Text(), err.Error())
+				continue
+			}
+			applyStats(stats)
+		}
+		p.exitErr = cmd.Wait()
+		logFile.Close()
+		close(p.exitSignal)
+	}()
+	if p.processMon != nil {
+		go p.monitor()
+	}
+	return p, nil
+}
+
+// monitor measures the resource consumption of the process until it exits.
+func (p *loadGeneratorProcess) monitor() {
+	ticker := time.NewTicker(loadGeneratorProcessSamplePeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.sample()
+		case <-p.exitSignal:
+			return
+		}
+	}
+}
+
+// sample measures the CPU usage since the previous sample and the RAM usage of the process.
+func (p *loadGeneratorProcess) sample() {
+	if p.processMon == nil {
+		return
+	}
+	times, err := p.processMon.Times()
+	if err != nil {
+		// The process exited.
+		return
+	}
+	mi, err := p.processMon.MemoryInfo()
+	if err != nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := time.Now()
+	if elapsed := now.Sub(p.lastElapsedTime).Seconds(); elapsed > 0 {
+		cpuPercent := (times.Total() - p.lastProcessTimes.Total()) * 100 / elapsed
+		if cpuPercent > p.cpuPercentMax {
+			p.cpuPercentMax = cpuPercent
+		}
+	}
+	p.lastProcessTimes = times
+	p.lastElapsedTime = now
+
+	ramMiB := uint32(mi.RSS / mibibyte)
+	p.memProbeCount++
+	p.ramMiBTotal += uint64(ramMiB)
+	if ramMiB > p.ramMiBMax {
+		p.ramMiBMax = ramMiB
+	}
+}
+
+// stop makes the process stop generating and waits for it to exit, killing it if it
+// does not exit within 10 seconds.
+func (p *loadGeneratorProcess) stop() error {
+	// Measure once more so that the consumption includes the end of the load.
+	p.sample()
+	_ = p.stdin.Close()
+	select {
+	case <-p.exitSignal:
+	case <-time.After(10 * time.Second):
+		log.Printf("Load generator process pid=%d is not responding. Sending SIGKILL to kill forcedly.", p.cmd.Process.Pid)
+		_ = p.cmd.Process.Kill()
+		<-p.exitSignal
+	}
+	return p.exitErr
+}
+
+// totalConsumption returns the resource consumption of the process measured so far.
+func (p *loadGeneratorProcess) totalConsumption() *ResourceConsumption {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	rc := &ResourceConsumption{CPUPercentMax: p.cpuPercentMax, RAMMiBMax: p.ramMiBMax}
+	if elapsed := p.lastElapsedTime.Sub(p.startTime).Seconds(); elapsed > 0 && p.lastProcessTimes != nil {
+		rc.CPUPercentAvg = p.lastProcessTimes.Total() / elapsed * 100.0
+	}
+	if p.memProbeCount > 0 {
+		rc.RAMMiBAvg = uint32(p.ramMiBTotal / uint64(p.memProbeCount))
+	}
+	return rc
+}
+
+// splitEndpoint splits an endpoint in host:port form.
+func splitEndpoint(endpoint string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in endpoint %s: %s", endpoint, err.Error())
+	}
+	return host, port, nil
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func TestNewProcessDataSender(t *testing.T) {
+	for _, sender := range []DataSender{
+		NewOTLPTraceDataSender(DefaultHost, 4317),
+		NewOTLPHTTPMetricDataSender(DefaultHost, 4318),
+		NewJaegerGRPCDataSender(DefaultHost, 14250),
+		NewZipkinDataSender(DefaultHost, 9411),
+	} {
+		host, port, err := splitEndpoint(sender.GetEndpoint())
+		require.NoError(t, err)
+		recreated, err := newProcessDataSender(fmt.Sprintf("%T", sender), host, port)
+		require.NoError(t, err)
+		assert.IsType(t, sender, recreated)
+		assert.Equal(t, sender.GetEndpoint(), recreated.GetEndpoint())
+	}
+
+	_, err := newProcessDataSender(fmt.Sprintf("%T", NewPrometheusDataSender(DefaultHost, 8888)), DefaultHost, 8888)
+	assert.EqualError(t, err, "sender *testbed.PrometheusDataSender cannot be used in a load generator process")
+}
+
+func TestLoadGeneratorProcessSpecJSON(t *testing.T) {
+	spec := loadGeneratorProcessSpec{
+		Sender: "*testbed.OTLPLogsDataSender",
+		Host:   DefaultHost,
+		Port:   4317,
+		Options: LoadOptions{
+			DataItemsPerSecond:   1_000,
+			ItemsPerBatch:        10,
+			Attributes:           map[string]string{"service.name": "testbed"},
+			AttributeValueTypes:  map[pdata.AttributeValueType]int{pdata.AttributeValueINT: 2},
+			SeverityDistribution: map[pdata.SeverityNumber]int{pdata.SeverityNumberERROR: 1},
+			IdlePattern:          IdlePattern{Active: time.Second, Idle: 2 * time.Second},
+			MetricStartTime:      time.Unix(1600000000, 0).UTC(),
+		},
+	}
+	data, err := json.Marshal(spec)
+	require.NoError(t, err)
+	var decoded loadGeneratorProcessSpec
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, spec, decoded)
+}
+
+func TestLoadGeneratorApplyProcessStats(t *testing.T) {
+	lg, err := NewLoadGenerator(NewPerfTestDataProvider(LoadOptions{}), NewOTLPTraceDataSender(DefaultHost, 4317))
+	require.NoError(t, err)
+	assert.Nil(t, lg.ProcessResourceConsumption())
+
+	stats := loadGeneratorProcessStats{
+		DataItemsSent:     100,
+		BatchesSent:       10,
+		SendErrors:        2,
+		CancelledExports:  1,
+		EmptyRequestsSent: 3,
+	}
+	lg.applyProcessStats(stats)
+	assert.Equal(t, stats, lg.processStats())
+	assert.EqualValues(t, 100, lg.DataItemsSent())
+}
@@ -16,17 +16,35 @@ package testbed
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"go.uber.org/atomic"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/pdata"
 )
 
+// ErrorMode specifies how MockBackend responds to the data it receives.
+type ErrorMode int
+
+const (
+	// ErrorNone accepts all received data.
+	ErrorNone ErrorMode = iota
+	// ErrorThrottle rejects all received data with gRPC RESOURCE_EXHAUSTED status
+	// carrying a RetryInfo with the configured retry delay. Rejected data is not
+	// counted as received.
+	ErrorThrottle
+)
+
 // MockBackend is a backend that allows receiving the data locally.
 type MockBackend struct {
 	// Metric and trace consumers
@@ -51,6 +69,38 @@ type MockBackend struct {
 	ReceivedTraces  []pdata.Traces
 	ReceivedMetrics []pdata.Metrics
 	ReceivedLogs    []pdata.Logs
+
+	// Timestamp recording fields. ReceivedTimestamps contains the timestamp of the first
+	// data item of each received batch, in the order the batches arrived: the data point
+	// timestamp for metrics, the span start time for traces and the log record timestamp
+	// for logs.
+	isRecordingTimestamps bool
+	ReceivedTimestamps    []pdata.Timestamp
+
+	// Latency recording fields. spanLatencies contains the time from the end of each
+	// received span until it was received.
+	isRecordingLatencies bool
+	spanLatencies        []time.Duration
+
+	// Times when the first and the last data item were received.
+	firstItemReceivedAt time.Time
+	lastItemReceivedAt  time.Time
+
+	// Error mode fields. throttledAt contains the time of every rejected request.
+	errorMode          ErrorMode
+	throttleRetryDelay time.Duration
+	throttledAt        []time.Time
+
+	// Consume delay fields, see SetConsumeDelayDistribution.
+	consumeDelayMean   time.Duration
+	consumeDelayStdDev time.Duration
+
+	// Panic injection fields, see SetPanicRate.
+	panicRate       float64
+	recoveredPanics atomic.Uint64
+
+	// Number of consumed batches without data items, see EmptyRequestsReceived.
+	emptyRequests atomic.Uint64
 }
 
 // NewMockBackend creates a new mock backend that receives data using specified receiver.
@@ -108,6 +158,9 @@ func (mb *MockBackend) Stop() {
 
 		// Print stats.
 		log.Printf("Stopped backend. %s", mb.GetStats())
+		if intervals := mb.ThrottleRetryIntervals(); len(intervals) > 0 {
+			log.Printf("Throttled %d requests, observed retry intervals: %v", len(intervals)+1, intervals)
+		}
 	})
 }
 
@@ -118,16 +171,231 @@ func (mb *MockBackend) EnableRecording() {
 	mb.isRecording = true
 }
 
+// EnableTimestampRecording enables recording of the timestamp of the first data item of
+// every metrics, traces and logs batch received by MockBackend into ReceivedTimestamps.
+func (mb *MockBackend) EnableTimestampRecording() {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	mb.isRecordingTimestamps = true
+}
+
+// EnableSpanLatencyRecording enables recording of the end-to-end latency of every span
+// received by MockBackend, measured from the end time of the span. The latencies are
+// returned by SpanLatencies.
+func (mb *MockBackend) EnableSpanLatencyRecording() {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	mb.isRecordingLatencies = true
+}
+
+// SpanLatencies returns the recorded latencies of the received spans in the order the
+// spans were received.
+func (mb *MockBackend) SpanLatencies() []time.Duration {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	return append([]time.Duration(nil), mb.spanLatencies...)
+}
+
+// SetErrorMode sets how the backend responds to received data. retryDelay is the delay
+// returned to the client in ErrorThrottle mode and is ignored otherwise.
+func (mb *MockBackend) SetErrorMode(mode ErrorMode, retryDelay time.Duration) {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	mb.errorMode = mode
+	mb.throttleRetryDelay = retryDelay
+}
+
+// SetConsumeDelayDistribution makes every consume call of the backend sleep for a
+// normally distributed duration with the given mean and standard deviation before
+// the data is accepted or rejected. Negative samples are clamped to zero. A zero
+// mean and stddev disable the delay.
+func (mb *MockBackend) SetConsumeDelayDistribution(mean, stddev time.Duration) {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	mb.consumeDelayMean = mean
+	mb.consumeDelayStdDev = stddev
+}
+
+// delayConsume sleeps for a duration sampled from the consume delay distribution.
+func (mb *MockBackend) delayConsume() {
+	mb.recordMutex.Lock()
+	mean, stddev := mb.consumeDelayMean, mb.consumeDelayStdDev
+	mb.recordMutex.Unlock()
+	if mean == 0 && stddev == 0 {
+		return
+	}
+	// The top-level functions of math/rand are safe for concurrent use.
+	delay := mean + time.Duration(rand.NormFloat64()*float64(stddev))
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// SetPanicRate makes the given fraction, between 0 and 1, of the consume calls of the
+// backend panic. The panics are recovered by the consumer which then rejects the data
+// with gRPC UNAVAILABLE status, so that the client can retry it. Rejected data is not
+// counted as received. A zero fraction disables the panics.
+func (mb *MockBackend) SetPanicRate(fraction float64) {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	mb.panicRate = fraction
+}
+
+// RecoveredPanics returns the number of consume calls which panicked because of
+// SetPanicRate.
+func (mb *MockBackend) RecoveredPanics() uint64 {
+	return mb.recoveredPanics.Load()
+}
+
+// injectPanic panics with the probability set by SetPanicRate.
+func (mb *MockBackend) injectPanic() {
+	mb.recordMutex.Lock()
+	panicRate := mb.panicRate
+	mb.recordMutex.Unlock()
+	if panicRate > 0 && rand.Float64() < panicRate {
+		panic("panic injected by mock backend")
+	}
+}
+
+// recoverPanic must be deferred by the consumers to turn a panic into the error
+// returned via err.
+func (mb *MockBackend) recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		mb.recoveredPanics.Inc()
+		*err = status.Error(codes.Unavailable, fmt.Sprintf("mock backend recovered from panic: %v", r))
+	}
+}
+
+// ThrottleRetryIntervals returns the intervals between consecutive requests rejected
+// in ErrorThrottle mode. When a single request is retried by the client these are the
+// retry intervals used by the client.
+func (mb *MockBackend) ThrottleRetryIntervals() []time.Duration {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	if len(mb.throttledAt) < 2 {
+		return nil
+	}
+	intervals := make([]time.Duration, 0, len(mb.throttledAt)-1)
+	for i := 1; i < len(mb.throttledAt); i++ {
+		intervals = append(intervals, mb.throttledAt[i].Sub(mb.throttledAt[i-1]))
+	}
+	return intervals
+}
+
+// rejectError returns the error to respond with according to the error mode, or nil
+// if the data must be accepted.
+func (mb *MockBackend) rejectError() error {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	if mb.errorMode != ErrorThrottle {
+		return nil
+	}
+	mb.throttledAt = append(mb.throttledAt, time.Now())
+	st, err := status.New(codes.ResourceExhausted, "throttled by mock backend").WithDetails(
+		&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(mb.throttleRetryDelay)})
+	if err != nil {
+		return err
+	}
+	return st.Err()
+}
+
+// FirstItemReceivedAt returns the time when the first data item was received, or
+// zero time if nothing was received yet.
+func (mb *MockBackend) FirstItemReceivedAt() time.Time {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	return mb.firstItemReceivedAt
+}
+
+// EmptyRequestsReceived returns the number of accepted requests without data items, e.g.
+// sent because of LoadOptions.EmptyRequestRate. They are not errors and do not change
+// the received data item counts. Not every receiver passes them on to the MockBackend,
+// e.g. the OTLP receiver drops them.
+func (mb *MockBackend) EmptyRequestsReceived() uint64 {
+	return mb.emptyRequests.Load()
+}
+
+// LastItemReceivedAt returns the time when the last data item was received, or zero
+// time if nothing was received yet.
+func (mb *MockBackend) LastItemReceivedAt() time.Time {
+	mb.recordMutex.Lock()
+	defer mb.recordMutex.Unlock()
+	return mb.lastItemReceivedAt
+}
+
+// markReceived records the time of the first and the last received data item, or counts
+// an empty request if itemCount is 0. Must be called with recordMutex held.
+func (mb *MockBackend) markReceived(itemCount int) {
+	if itemCount == 0 {
+		mb.emptyRequests.Inc()
+		return
+	}
+	mb.lastItemReceivedAt = time.Now()
+	if mb.firstItemReceivedAt.IsZero() {
+		mb.firstItemReceivedAt = mb.lastItemReceivedAt
+	}
+}
+
 func (mb *MockBackend) GetStats() string {
 	received := mb.DataItemsReceived()
 	return printer.Sprintf("Received:%10d items (%d/sec)", received, int(float64(received)/time.Since(mb.startedAt).Seconds()))
 }
 
+// ConnectionStats returns the connection statistics of the receiver. ok is false if the
+// receiver does not count connections, see BaseOTLPDataReceiver.ConnectionStats.
+func (mb *MockBackend) ConnectionStats() (stats ConnectionStats, ok bool) {
+	if r, isOTLP := mb.receiver.(*BaseOTLPDataReceiver); isOTLP {
+		return r.ConnectionStats()
+	}
+	return ConnectionStats{}, false
+}
+
 // DataItemsReceived returns total number of received spans and metrics.
 func (mb *MockBackend) DataItemsReceived() uint64 {
 	return mb.tc.numSpansReceived.Load() + mb.mc.numMetricsReceived.Load() + mb.lc.numLogRecordsReceived.Load()
 }
 
+// SpansReceived returns the number of received spans.
+func (mb *MockBackend) SpansReceived() uint64 {
+	return mb.tc.numSpansReceived.Load()
+}
+
+// MetricsReceived returns the number of received metric data points.
+func (mb *MockBackend) MetricsReceived() uint64 {
+	return mb.mc.numMetricsReceived.Load()
+}
+
+// LogRecordsReceived returns the number of received log records.
+func (mb *MockBackend) LogRecordsReceived() uint64 {
+	return mb.lc.numLogRecordsReceived.Load()
+}
+
+// RequestsReceived returns the total number of accepted export requests of all signals,
+// including empty ones. Requests rejected by the MockBackend, e.g. because of
+// SetErrorMode, are not counted. Together with DataItemsReceived it gives the average
+// number of items per request, i.e. how the data was batched by the agent.
+func (mb *MockBackend) RequestsReceived() uint64 {
+	return mb.TraceRequestsReceived() + mb.MetricRequestsReceived() + mb.LogRequestsReceived()
+}
+
+// TraceRequestsReceived returns the number of accepted trace export requests, see
+// RequestsReceived.
+func (mb *MockBackend) TraceRequestsReceived() uint64 {
+	return mb.tc.numRequestsReceived.Load()
+}
+
+// MetricRequestsReceived returns the number of accepted metric export requests, see
+// RequestsReceived.
+func (mb *MockBackend) MetricRequestsReceived() uint64 {
+	return mb.mc.numRequestsReceived.Load()
+}
+
+// LogRequestsReceived returns the number of accepted log export requests, see
+// RequestsReceived.
+func (mb *MockBackend) LogRequestsReceived() uint64 {
+	return mb.lc.numRequestsReceived.Load()
+}
+
 // ClearReceivedItems clears the list of received traces and metrics. Note: counters
 // return by DataItemsReceived() are not cleared, they are cumulative.
 func (mb *MockBackend) ClearReceivedItems() {
@@ -136,22 +404,53 @@ func (mb *MockBackend) ClearReceivedItems() {
 	mb.ReceivedTraces = nil
 	mb.ReceivedMetrics = nil
 	mb.ReceivedLogs = nil
+	mb.ReceivedTimestamps = nil
+	mb.spanLatencies = nil
 }
 
 func (mb *MockBackend) ConsumeTrace(td pdata.Traces) {
 	mb.recordMutex.Lock()
 	defer mb.recordMutex.Unlock()
+	mb.markReceived(td.SpanCount())
 	if mb.isRecording {
 		mb.ReceivedTraces = append(mb.ReceivedTraces, td)
 	}
+	if mb.isRecordingTimestamps {
+		mb.ReceivedTimestamps = append(mb.ReceivedTimestamps, getFirstSpanTimestamp(td))
+	}
+	if mb.isRecordingLatencies {
+		mb.spanLatencies = appendSpanLatencies(mb.spanLatencies, td, time.Now())
+	}
+}
+
+// appendSpanLatencies appends the time from the end of every span in td until
+// receivedAt to latencies.
+func appendSpanLatencies(latencies []time.Duration, td pdata.Traces, receivedAt time.Time) []time.Duration {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				endTime := time.Unix(0, int64(spans.At(k).EndTime()))
+				latencies = append(latencies, receivedAt.Sub(endTime))
+			}
+		}
+	}
+	return latencies
 }
 
 func (mb *MockBackend) ConsumeMetric(md pdata.Metrics) {
 	mb.recordMutex.Lock()
 	defer mb.recordMutex.Unlock()
+	_, dataPoints := md.MetricAndDataPointCount()
+	mb.markReceived(dataPoints)
 	if mb.isRecording {
 		mb.ReceivedMetrics = append(mb.ReceivedMetrics, md)
 	}
+	if mb.isRecordingTimestamps {
+		mb.ReceivedTimestamps = append(mb.ReceivedTimestamps, getFirstMetricTimestamp(md))
+	}
 }
 
 var _ consumer.TracesConsumer = (*MockTraceConsumer)(nil)
@@ -159,17 +458,30 @@ var _ consumer.TracesConsumer = (*MockTraceConsumer)(nil)
 func (mb *MockBackend) ConsumeLogs(ld pdata.Logs) {
 	mb.recordMutex.Lock()
 	defer mb.recordMutex.Unlock()
+	mb.markReceived(ld.LogRecordCount())
 	if mb.isRecording {
 		mb.ReceivedLogs = append(mb.ReceivedLogs, ld)
 	}
+	if mb.isRecordingTimestamps {
+		mb.ReceivedTimestamps = append(mb.ReceivedTimestamps, getFirstLogTimestamp(ld))
+	}
 }
 
 type MockTraceConsumer struct {
-	numSpansReceived atomic.Uint64
-	backend          *MockBackend
+	numSpansReceived    atomic.Uint64
+	numRequestsReceived atomic.Uint64
+	backend             *MockBackend
 }
 
-func (tc *MockTraceConsumer) ConsumeTraces(_ context.Context, td pdata.Traces) error {
+func (tc *MockTraceConsumer) ConsumeTraces(_ context.Context, td pdata.Traces) (err error) {
+	defer tc.backend.recoverPanic(&err)
+	tc.backend.delayConsume()
+	tc.backend.injectPanic()
+	if err := tc.backend.rejectError(); err != nil {
+		return err
+	}
+
+	tc.numRequestsReceived.Inc()
 	tc.numSpansReceived.Add(uint64(td.SpanCount()))
 
 	rs := td.ResourceSpans()
@@ -182,7 +494,7 @@ func (tc *MockTraceConsumer) ConsumeTraces(_ context.Context, td pdata.Traces) e
 				var spanSeqnum int64
 				var traceSeqnum int64
 
-				seqnumAttr, ok := span.Attributes().Get("load_generator.span_seq_num")
+				seqnumAttr, ok := span.Attributes().Get(SpanSeqNumAttribute)
 				if ok {
 					spanSeqnum = seqnumAttr.IntVal()
 				}
@@ -208,12 +520,20 @@ func (tc *MockTraceConsumer) ConsumeTraces(_ context.Context, td pdata.Traces) e
 var _ consumer.MetricsConsumer = (*MockMetricConsumer)(nil)
 
 type MockMetricConsumer struct {
-	numMetricsReceived atomic.Uint64
-	backend            *MockBackend
+	numMetricsReceived  atomic.Uint64
+	numRequestsReceived atomic.Uint64
+	backend             *MockBackend
 }
 
-func (mc *MockMetricConsumer) ConsumeMetrics(_ context.Context, md pdata.Metrics) error {
+func (mc *MockMetricConsumer) ConsumeMetrics(_ context.Context, md pdata.Metrics) (err error) {
+	defer mc.backend.recoverPanic(&err)
+	mc.backend.delayConsume()
+	mc.backend.injectPanic()
+	if err := mc.backend.rejectError(); err != nil {
+		return err
+	}
 	_, dataPoints := md.MetricAndDataPointCount()
+	mc.numRequestsReceived.Inc()
 	mc.numMetricsReceived.Add(uint64(dataPoints))
 	mc.backend.ConsumeMetric(md)
 	return nil
@@ -231,11 +551,19 @@ func (mc *MockMetricConsumer) MockConsumeMetricData(metricsCount int) error {
 
 type MockLogConsumer struct {
 	numLogRecordsReceived atomic.Uint64
+	numRequestsReceived   atomic.Uint64
 	backend               *MockBackend
 }
 
-func (mc *MockLogConsumer) ConsumeLogs(_ context.Context, ld pdata.Logs) error {
+func (mc *MockLogConsumer) ConsumeLogs(_ context.Context, ld pdata.Logs) (err error) {
+	defer mc.backend.recoverPanic(&err)
+	mc.backend.delayConsume()
+	mc.backend.injectPanic()
+	if err := mc.backend.rejectError(); err != nil {
+		return err
+	}
 	recordCount := ld.LogRecordCount()
+	mc.numRequestsReceived.Inc()
 	mc.numLogRecordsReceived.Add(uint64(recordCount))
 	mc.backend.ConsumeLogs(ld)
 	return nil
@@ -15,11 +15,19 @@
 package testbed
 
 import (
+	"context"
+	"math"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.opentelemetry.io/collector/consumer/pdata"
 )
 
 func TestGeneratorAndBackend(t *testing.T) {
@@ -40,6 +48,16 @@ func TestGeneratorAndBackend(t *testing.T) {
 			receiver: NewZipkinDataReceiver(port),
 			sender:   NewZipkinDataSender(DefaultHost, port),
 		},
+		{
+			name:     "ZipkinV1Thrift-Zipkin",
+			receiver: NewZipkinDataReceiver(port),
+			sender:   NewZipkinV1DataSender(DefaultHost, port),
+		},
+		{
+			name:     "ZipkinV1JSON-Zipkin",
+			receiver: NewZipkinDataReceiver(port),
+			sender:   NewZipkinV1DataSender(DefaultHost, port).WithEncoding(ZipkinV1JSON),
+		},
 	}
 
 	for _, test := range tests {
@@ -72,6 +90,319 @@ func TestGeneratorAndBackend(t *testing.T) {
 	}
 }
 
+func TestGeneratorStartCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		receiver func(port int) DataReceiver
+		sender   func(port int) DataSender
+	}{
+		{
+			name:     "Traces",
+			receiver: func(port int) DataReceiver { return NewOTLPDataReceiver(port) },
+			sender:   func(port int) DataSender { return NewOTLPTraceDataSender(DefaultHost, port) },
+		},
+		{
+			name:     "Metrics",
+			receiver: func(port int) DataReceiver { return NewOTLPDataReceiver(port) },
+			sender:   func(port int) DataSender { return NewOTLPMetricDataSender(DefaultHost, port) },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port := GetAvailablePort(t)
+			mb := NewMockBackend("mockbackend.log", test.receiver(port))
+			require.NoError(t, mb.Start(), "Cannot start backend")
+			defer mb.Stop()
+
+			// 10,000 is not a multiple of the batch size so the last batch is truncated.
+			options := LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 30}
+			lg, err := NewLoadGenerator(NewPerfTestDataProvider(options), test.sender(port))
+			require.NoError(t, err, "Cannot start load generator")
+
+			lg.StartCount(options, 10_000)
+			WaitFor(t, lg.CountReached, "count reached")
+			WaitFor(t, func() bool { return mb.DataItemsReceived() == 10_000 }, "all items received")
+			lg.Stop()
+
+			assert.EqualValues(t, 10_000, lg.DataItemsSent())
+			assert.EqualValues(t, 10_000, mb.DataItemsReceived())
+		})
+	}
+}
+
+func TestGeneratorEmptyRequestRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		receiver func(port int) DataReceiver
+		sender   func(port int) DataSender
+		// Whether the receiver passes empty requests on to the MockBackend.
+		forwardsEmpty bool
+	}{
+		{
+			name:          "Zipkin",
+			receiver:      func(port int) DataReceiver { return NewZipkinDataReceiver(port) },
+			sender:        func(port int) DataSender { return NewZipkinDataSender(DefaultHost, port) },
+			forwardsEmpty: true,
+		},
+		{
+			name:     "OTLP",
+			receiver: func(port int) DataReceiver { return NewOTLPDataReceiver(port) },
+			sender:   func(port int) DataSender { return NewOTLPTraceDataSender(DefaultHost, port) },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port := GetAvailablePort(t)
+			mb := NewMockBackend("mockbackend.log", test.receiver(port))
+			require.NoError(t, mb.Start(), "Cannot start backend")
+			defer mb.Stop()
+
+			// One in five requests is empty, i.e. one after every fourth batch.
+			options := LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10, EmptyRequestRate: 0.2}
+			lg, err := NewLoadGenerator(NewPerfTestDataProvider(options), test.sender(port))
+			require.NoError(t, err, "Cannot start load generator")
+
+			lg.StartCount(options, 400)
+			WaitFor(t, lg.CountReached, "count reached")
+			WaitFor(t, func() bool { return mb.DataItemsReceived() == 400 }, "all items received")
+			lg.Stop()
+
+			assert.EqualValues(t, 40, lg.BatchesSent())
+			assert.EqualValues(t, 10, lg.EmptyRequestsSent())
+			assert.EqualValues(t, 0, lg.SendErrors())
+			assert.EqualValues(t, 400, lg.DataItemsSent())
+			assert.EqualValues(t, 400, mb.DataItemsReceived())
+			if test.forwardsEmpty {
+				WaitFor(t, func() bool { return mb.EmptyRequestsReceived() == 10 }, "all empty requests received")
+			} else {
+				assert.EqualValues(t, 0, mb.EmptyRequestsReceived())
+			}
+		})
+	}
+}
+
+func TestGeneratorIdlePattern(t *testing.T) {
+	port := GetAvailablePort(t)
+	mb := NewMockBackend("mockbackend.log", NewOTLPDataReceiver(port))
+	require.NoError(t, mb.Start(), "Cannot start backend")
+	defer mb.Stop()
+
+	options := LoadOptions{
+		DataItemsPerSecond: 1000,
+		ItemsPerBatch:      10,
+		IdlePattern:        IdlePattern{Active: 500 * time.Millisecond, Idle: 500 * time.Millisecond},
+	}
+	lg, err := NewLoadGenerator(NewPerfTestDataProvider(options), NewOTLPTraceDataSender(DefaultHost, port))
+	require.NoError(t, err, "Cannot start load generator")
+
+	lg.Start(options)
+	sleepUntil := func(sinceStart time.Duration) {
+		time.Sleep(time.Until(lg.startTime.Add(sinceStart)))
+	}
+
+	// Leave some time for in-flight batches to arrive after the first active window.
+	sleepUntil(650 * time.Millisecond)
+	idleStart := mb.DataItemsReceived()
+	sleepUntil(950 * time.Millisecond)
+	idleEnd := mb.DataItemsReceived()
+	sleepUntil(1400 * time.Millisecond)
+	resumed := mb.DataItemsReceived()
+	lg.Stop()
+
+	assert.NotZero(t, idleStart, "no data sent in the first active window")
+	assert.Equal(t, idleStart, idleEnd, "data sent during the idle window")
+	assert.Greater(t, resumed, idleEnd, "data flow did not resume after the idle window")
+	assert.InDelta(t, 900*time.Millisecond, lg.ActiveDuration(), float64(100*time.Millisecond))
+}
+
+func TestGeneratorExportTimeout(t *testing.T) {
+	port := GetAvailablePort(t)
+	mb := NewMockBackend("mockbackend.log", NewOTLPDataReceiver(port))
+	require.NoError(t, mb.Start(), "Cannot start backend")
+	defer mb.Stop()
+
+	// Every request takes longer than the timeout so all of them are cancelled.
+	mb.SetConsumeDelayDistribution(300*time.Millisecond, 0)
+	options := LoadOptions{DataItemsPerSecond: 100, ItemsPerBatch: 10, ExportTimeout: 50 * time.Millisecond}
+	lg, err := NewLoadGenerator(NewPerfTestDataProvider(options), NewOTLPTraceDataSender(DefaultHost, port))
+	require.NoError(t, err, "Cannot start load generator")
+
+	lg.Start(options)
+	WaitFor(t, func() bool { return lg.CancelledExports() >= 5 }, "CancelledExports >= 5")
+	lg.Stop()
+
+	assert.Equal(t, lg.SendErrors(), lg.CancelledExports())
+	assert.Contains(t, lg.GetStats(), "cancelled exports")
+}
+
+func TestIdlePatternActiveDuration(t *testing.T) {
+	p := IdlePattern{Active: 2 * time.Second, Idle: time.Second}
+	assert.False(t, p.isIdle(1500*time.Millisecond))
+	assert.True(t, p.isIdle(2500*time.Millisecond))
+	assert.False(t, p.isIdle(3500*time.Millisecond))
+	assert.Equal(t, 1500*time.Millisecond, p.activeDuration(1500*time.Millisecond))
+	assert.Equal(t, 2*time.Second, p.activeDuration(2500*time.Millisecond))
+	assert.Equal(t, 6*time.Second, p.activeDuration(8*time.Second))
+
+	// Without both durations the load is generated continuously.
+	p = IdlePattern{Active: time.Second}
+	assert.False(t, p.isIdle(1500*time.Millisecond))
+	assert.Equal(t, 8*time.Second, p.activeDuration(8*time.Second))
+}
+
+func TestMockBackendTimestampRecording(t *testing.T) {
+	genTraces := func(startTime pdata.Timestamp) pdata.Traces {
+		td := pdata.NewTraces()
+		td.ResourceSpans().Resize(1)
+		td.ResourceSpans().At(0).InstrumentationLibrarySpans().Resize(1)
+		spans := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+		spans.Resize(2)
+		spans.At(0).SetStartTime(startTime)
+		spans.At(1).SetStartTime(startTime + 1)
+		return td
+	}
+	genLogs := func(timestamp pdata.Timestamp) pdata.Logs {
+		ld := pdata.NewLogs()
+		ld.ResourceLogs().Resize(1)
+		ld.ResourceLogs().At(0).InstrumentationLibraryLogs().Resize(1)
+		ld.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().Resize(1)
+		ld.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(0).SetTimestamp(timestamp)
+		return ld
+	}
+	genMetrics := func(timestamp pdata.Timestamp) pdata.Metrics {
+		md := pdata.NewMetrics()
+		md.ResourceMetrics().Resize(1)
+		md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().Resize(1)
+		metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+		metrics.Resize(1)
+		metrics.At(0).SetDataType(pdata.MetricDataTypeDoubleGauge)
+		metrics.At(0).DoubleGauge().DataPoints().Resize(1)
+		metrics.At(0).DoubleGauge().DataPoints().At(0).SetTimestamp(timestamp)
+		return md
+	}
+
+	mb := NewMockBackend("mockbackend.log", nil)
+	mb.ConsumeMetric(genMetrics(50))
+	assert.Empty(t, mb.ReceivedTimestamps)
+
+	mb.EnableTimestampRecording()
+	for _, ts := range []pdata.Timestamp{100, 200, 300} {
+		mb.ConsumeTrace(genTraces(ts))
+	}
+	mb.ConsumeLogs(genLogs(400))
+	mb.ConsumeMetric(genMetrics(500))
+	assert.Equal(t, []pdata.Timestamp{100, 200, 300, 400, 500}, mb.ReceivedTimestamps)
+}
+
+// barrierTraceConsumer blocks every call until the expected number of calls arrived.
+type barrierTraceConsumer struct {
+	arrived sync.WaitGroup
+}
+
+func (c *barrierTraceConsumer) ConsumeTraces(context.Context, pdata.Traces) error {
+	c.arrived.Done()
+	c.arrived.Wait()
+	return nil
+}
+
+func TestMockBackendConnectionStats(t *testing.T) {
+	const numSenders = 4
+
+	port := GetAvailablePort(t)
+	receiver := NewOTLPDataReceiver(port).WithConnectionStats()
+	tc := &barrierTraceConsumer{}
+	tc.arrived.Add(numSenders)
+	require.NoError(t, receiver.Start(tc, nil, nil))
+	defer receiver.Stop()
+	mb := NewMockBackend("mockbackend.log", receiver)
+
+	// Every sender uses its own connection. The consumer holds the streams open until
+	// all senders sent, so they are active concurrently.
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var senders sync.WaitGroup
+	sentBytes := 0
+	for i := 0; i < numSenders; i++ {
+		sender := NewOTLPTraceDataSender(DefaultHost, port)
+		require.NoError(t, sender.Start())
+		td, _ := dp.GenerateTraces()
+		request, err := td.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		// Every gRPC message is prefixed by a 5 byte header.
+		sentBytes += len(request) + 5
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			assert.NoError(t, sender.ConsumeTraces(context.Background(), td))
+		}()
+	}
+	senders.Wait()
+
+	WaitFor(t, func() bool {
+		stats, _ := mb.ConnectionStats()
+		return stats.PeakActiveStreams == numSenders && stats.ActiveStreams == 0
+	}, "all streams completed")
+	stats, ok := mb.ConnectionStats()
+	require.True(t, ok)
+	assert.EqualValues(t, numSenders, stats.AcceptedConnections)
+	assert.EqualValues(t, numSenders, stats.PeakActiveStreams)
+	assert.EqualValues(t, 0, stats.ActiveStreams)
+	// The requests are not compressed.
+	assert.EqualValues(t, sentBytes, stats.ReceivedWireBytes)
+
+	_, ok = NewMockBackend("mockbackend.log", NewOTLPDataReceiver(port)).ConnectionStats()
+	assert.False(t, ok, "connections are only counted if enabled")
+	_, ok = NewMockBackend("mockbackend.log", NewOTLPHTTPDataReceiver(port).WithConnectionStats()).ConnectionStats()
+	assert.False(t, ok, "only OTLP over gRPC counts connections")
+}
+
+func TestMockBackendRequestsReceived(t *testing.T) {
+	const numSenders = 4
+	const batchesPerSender = 25
+
+	port := GetAvailablePort(t)
+	mb := NewMockBackend("mockbackend.log", NewOTLPDataReceiver(port))
+	require.NoError(t, mb.Start())
+	defer mb.Stop()
+
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var senders sync.WaitGroup
+	for i := 0; i < numSenders; i++ {
+		traceSender := NewOTLPTraceDataSender(DefaultHost, port)
+		require.NoError(t, traceSender.Start())
+		metricSender := NewOTLPMetricDataSender(DefaultHost, port)
+		require.NoError(t, metricSender.Start())
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			for j := 0; j < batchesPerSender; j++ {
+				td, _ := dp.GenerateTraces()
+				assert.NoError(t, traceSender.ConsumeTraces(context.Background(), td))
+			}
+			md, _ := dp.GenerateMetrics()
+			assert.NoError(t, metricSender.ConsumeMetrics(context.Background(), md))
+		}()
+	}
+	senders.Wait()
+
+	assert.EqualValues(t, numSenders*batchesPerSender, mb.TraceRequestsReceived())
+	assert.EqualValues(t, numSenders*batchesPerSender*10, mb.SpansReceived())
+	assert.EqualValues(t, numSenders, mb.MetricRequestsReceived())
+	assert.EqualValues(t, 0, mb.LogRequestsReceived())
+	assert.EqualValues(t, numSenders*(batchesPerSender+1), mb.RequestsReceived())
+
+	// Rejected requests are not counted.
+	mb.SetErrorMode(ErrorThrottle, time.Second)
+	sender := NewOTLPTraceDataSender(DefaultHost, port)
+	require.NoError(t, sender.Start())
+	td, _ := dp.GenerateTraces()
+	assert.Error(t, sender.ConsumeTraces(context.Background(), td))
+	assert.EqualValues(t, numSenders*batchesPerSender, mb.TraceRequestsReceived())
+}
+
 // WaitFor the specific condition for up to 10 seconds. Records a test error
 // if condition does not become true.
 func WaitFor(t *testing.T, cond func() bool, errMsg ...interface{}) bool {
@@ -99,3 +430,91 @@ func WaitFor(t *testing.T, cond func() bool, errMsg ...interface{}) bool {
 		}
 	}
 }
+
+func TestMockBackendItemReceivedAt(t *testing.T) {
+	mb := NewMockBackend("mockbackend.log", nil)
+	assert.True(t, mb.FirstItemReceivedAt().IsZero())
+	assert.True(t, mb.LastItemReceivedAt().IsZero())
+
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 2})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	td, _ := dp.GenerateTraces()
+	mb.ConsumeTrace(td)
+	first := mb.FirstItemReceivedAt()
+	assert.False(t, first.IsZero())
+	assert.Equal(t, first, mb.LastItemReceivedAt())
+
+	time.Sleep(10 * time.Millisecond)
+	td, _ = dp.GenerateTraces()
+	mb.ConsumeTrace(td)
+	assert.Equal(t, first, mb.FirstItemReceivedAt())
+	assert.True(t, mb.LastItemReceivedAt().After(first))
+
+	// Empty batches are not data items.
+	last := mb.LastItemReceivedAt()
+	mb.ConsumeTrace(pdata.NewTraces())
+	assert.Equal(t, last, mb.LastItemReceivedAt())
+}
+
+func TestMockBackendConsumeDelayDistribution(t *testing.T) {
+	const (
+		mean   = 20 * time.Millisecond
+		stddev = 5 * time.Millisecond
+		calls  = 400
+	)
+	mb := NewMockBackend("mockbackend.log", nil)
+	mb.SetConsumeDelayDistribution(mean, stddev)
+
+	// Consume concurrently so the test takes about one delay per 20 calls.
+	delays := make([]time.Duration, calls)
+	var wg sync.WaitGroup
+	for w := 0; w < 20; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < calls; i += 20 {
+				start := time.Now()
+				require.NoError(t, mb.tc.ConsumeTraces(context.Background(), pdata.NewTraces()))
+				delays[i] = time.Since(start)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var sum float64
+	for _, d := range delays {
+		sum += float64(d)
+	}
+	observedMean := sum / calls
+	var squares float64
+	for _, d := range delays {
+		squares += (float64(d) - observedMean) * (float64(d) - observedMean)
+	}
+	observedStdDev := math.Sqrt(squares / (calls - 1))
+
+	// Sleeping overshoots slightly, which shifts the mean but barely affects the stddev.
+	assert.InDelta(t, float64(mean), observedMean, float64(3*time.Millisecond))
+	assert.InDelta(t, float64(stddev), observedStdDev, float64(1500*time.Microsecond))
+}
+
+func TestMockBackendPanicRate(t *testing.T) {
+	mb := NewMockBackend("mockbackend.log", nil)
+	td := pdata.NewTraces()
+	td.ResourceSpans().Resize(1)
+	td.ResourceSpans().At(0).InstrumentationLibrarySpans().Resize(1)
+	td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().Resize(1)
+
+	mb.SetPanicRate(1)
+	err := mb.tc.ConsumeTraces(context.Background(), td)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Error(t, mb.mc.ConsumeMetrics(context.Background(), pdata.NewMetrics()))
+	assert.Error(t, mb.lc.ConsumeLogs(context.Background(), pdata.NewLogs()))
+	assert.EqualValues(t, 3, mb.RecoveredPanics())
+	assert.EqualValues(t, 0, mb.DataItemsReceived())
+
+	mb.SetPanicRate(0)
+	require.NoError(t, mb.tc.ConsumeTraces(context.Background(), td))
+	assert.EqualValues(t, 3, mb.RecoveredPanics())
+	assert.EqualValues(t, 1, mb.DataItemsReceived())
+}
@@ -17,6 +17,8 @@
 
 package testbed
 
+import "time"
+
 // TestCaseOption defines a TestCase option.
 type TestCaseOption struct {
 	option func(t *TestCase)
@@ -34,9 +36,33 @@ func WithSkipResults() TestCaseOption {
 	}}
 }
 
+// WithAgentStartTimeout sets how long StartAgent waits for the agent to accept
+// connections on the endpoint of the sender. Defaults to 10 seconds.
+func WithAgentStartTimeout(timeout time.Duration) TestCaseOption {
+	return TestCaseOption{func(t *TestCase) {
+		t.agentStartTimeout = timeout
+	}}
+}
+
+// WithDuration sets the duration of the test case, see TestCase.Duration, overriding
+// the duration set by the scenario parameters.
+func WithDuration(duration time.Duration) TestCaseOption {
+	return TestCaseOption{func(t *TestCase) {
+		t.Duration = duration
+	}}
+}
+
 // WithConfigFile allows a custom configuration file for TestCase.
 func WithConfigFile(file string) TestCaseOption {
 	return TestCaseOption{func(t *TestCase) {
 		t.agentConfigFile = file
 	}}
 }
+
+// WithResultReporters adds reporters which the result of the TestCase is reported to
+// when it is stopped, in addition to the TestResultsSummary.
+func WithResultReporters(reporters ...ResultReporter) TestCaseOption {
+	return TestCaseOption{func(t *TestCase) {
+		t.resultReporters = append(t.resultReporters, reporters...)
+	}}
+}
@@ -0,0 +1,262 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// TCPProxy is a passthrough proxy which forwards all TCP connections accepted on its
+// listening address to a target address. It can be placed between the load generator
+// and the collector by setting DataSenderBase.ProxyEndpoint, or between the collector
+// and the MockBackend by setting DataReceiverBase.ProxyEndpoint, to measure the
+// overhead of sending through a proxy. Since it forwards raw bytes it works with any
+// TCP based protocol, e.g. gRPC or HTTP.
+type TCPProxy struct {
+	listenAddr string
+	targetAddr string
+
+	listener  net.Listener
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	conns     map[net.Conn]struct{}
+	isStopped bool
+
+	connections    atomic.Uint64
+	bytesForwarded atomic.Uint64
+
+	// Throttles the data sent by the clients, nil if not throttled.
+	readThrottle *readThrottle
+}
+
+// NewTCPProxy creates a proxy which listens on listenAddr and forwards to targetAddr,
+// both in host:port form.
+func NewTCPProxy(listenAddr string, targetAddr string) *TCPProxy {
+	return &TCPProxy{
+		listenAddr: listenAddr,
+		targetAddr: targetAddr,
+		conns:      make(map[net.Conn]struct{}),
+	}
+}
+
+// WithReadRate throttles forwarding the data sent by the clients of the proxy to at most
+// bytesPerSecond over all connections, so that the proxy behaves like a server which
+// reads request bodies slowly. The clients are slowed down by the flow control of TCP,
+// and of HTTP/2 for gRPC, instead of by delayed responses. The data sent back to the
+// clients is not throttled. Must be called before Start. Panics if bytesPerSecond is
+// not positive.
+func (p *TCPProxy) WithReadRate(bytesPerSecond int) *TCPProxy {
+	if bytesPerSecond <= 0 {
+		panic(fmt.Sprintf("read rate must be positive, got %d bytes per second", bytesPerSecond))
+	}
+	p.readThrottle = &readThrottle{bytesPerSecond: bytesPerSecond}
+	return p
+}
+
+// BytesRead returns the number of bytes sent by the clients which were read so far if
+// the proxy was created WithReadRate, 0 otherwise.
+func (p *TCPProxy) BytesRead() uint64 {
+	if p.readThrottle == nil {
+		return 0
+	}
+	return p.readThrottle.bytesRead.Load()
+}
+
+// Start starts listening and forwarding connections.
+func (p *TCPProxy) Start() error {
+	listener, err := net.Listen("tcp", p.listenAddr)
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	log.Printf("Proxy forwarding %s to %s", listener.Addr(), p.targetAddr)
+
+	p.wg.Add(1)
+	go p.accept()
+	return nil
+}
+
+// Endpoint returns the address on which the proxy listens in host:port form.
+func (p *TCPProxy) Endpoint() string {
+	if p.listener != nil {
+		return p.listener.Addr().String()
+	}
+	return p.listenAddr
+}
+
+// Stop closes the listener and all forwarded connections.
+func (p *TCPProxy) Stop() {
+	p.mutex.Lock()
+	if p.isStopped || p.listener == nil {
+		p.mutex.Unlock()
+		return
+	}
+	p.isStopped = true
+	p.listener.Close()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mutex.Unlock()
+
+	p.wg.Wait()
+	log.Printf("Stopped proxy. %s", p.GetStats())
+}
+
+// Connections returns the number of connections accepted by the proxy.
+func (p *TCPProxy) Connections() uint64 {
+	return p.connections.Load()
+}
+
+// BytesForwarded returns the number of bytes forwarded in both directions.
+func (p *TCPProxy) BytesForwarded() uint64 {
+	return p.bytesForwarded.Load()
+}
+
+// GetStats returns the stats as a printable string.
+func (p *TCPProxy) GetStats() string {
+	return fmt.Sprintf("Connections: %d, forwarded: %d bytes", p.Connections(), p.BytesForwarded())
+}
+
+func (p *TCPProxy) accept() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		p.connections.Inc()
+		p.wg.Add(1)
+		go p.forward(conn)
+	}
+}
+
+// forward copies data between the accepted connection and a new connection to the
+// target address until either side closes.
+func (p *TCPProxy) forward(conn net.Conn) {
+	defer p.wg.Done()
+
+	target, err := net.Dial("tcp", p.targetAddr)
+	if err != nil {
+		log.Printf("Proxy cannot connect to %s: %v", p.targetAddr, err)
+		conn.Close()
+		return
+	}
+	if !p.track(conn, target) {
+		return
+	}
+	defer p.untrack(conn, target)
+
+	done := make(chan struct{}, 2)
+	copyConn := func(dst io.Writer, src io.Reader) {
+		n, _ := io.Copy(dst, src)
+		p.bytesForwarded.Add(uint64(n))
+		done <- struct{}{}
+	}
+	var src io.Reader = conn
+	if p.readThrottle != nil {
+		src = &throttledReader{reader: conn, throttle: p.readThrottle}
+	}
+	go copyConn(target, src)
+	go copyConn(conn, target)
+
+	// When one direction is done close both connections which also ends the other one.
+	<-done
+	conn.Close()
+	target.Close()
+	<-done
+}
+
+// track registers the connections so that Stop can close them. Returns false and
+// closes the connections if the proxy is already stopped.
+func (p *TCPProxy) track(conns ...net.Conn) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.isStopped {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *TCPProxy) untrack(conns ...net.Conn) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+// readThrottle paces the reads of all connections of a proxy to a number of bytes per
+// second.
+type readThrottle struct {
+	bytesPerSecond int
+	bytesRead      atomic.Uint64
+
+	mutex sync.Mutex
+	// The time at which the bytes read so far are due at the configured rate.
+	due time.Time
+}
+
+// chunkSize returns the maximum number of bytes to read at once, so that a read is
+// followed by at most 100ms of waiting.
+func (rt *readThrottle) chunkSize() int {
+	if size := rt.bytesPerSecond / 10; size > 0 {
+		return size
+	}
+	return 1
+}
+
+// wait blocks until reading n more bytes does not exceed the configured rate.
+func (rt *readThrottle) wait(n int) {
+	rt.bytesRead.Add(uint64(n))
+	rt.mutex.Lock()
+	now := time.Now()
+	if rt.due.Before(now) {
+		rt.due = now
+	}
+	rt.due = rt.due.Add(time.Duration(n) * time.Second / time.Duration(rt.bytesPerSecond))
+	delay := rt.due.Sub(now)
+	rt.mutex.Unlock()
+	time.Sleep(delay)
+}
+
+// throttledReader reads from reader at the rate of throttle.
+type throttledReader struct {
+	reader   io.Reader
+	throttle *readThrottle
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if size := tr.throttle.chunkSize(); len(p) > size {
+		p = p[:size]
+	}
+	n, err := tr.reader.Read(p)
+	if n > 0 {
+		tr.throttle.wait(n)
+	}
+	return n, err
+}
@@ -15,24 +15,42 @@
 package testbed
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/config/configmodels"
 	"go.opentelemetry.io/collector/config/confignet"
 	"go.opentelemetry.io/collector/consumer"
+	collectorlog "go.opentelemetry.io/collector/internal/data/protogen/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/collector/internal/data/protogen/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/collector/internal/data/protogen/collector/trace/v1"
 	"go.opentelemetry.io/collector/receiver/jaegerreceiver"
 	"go.opentelemetry.io/collector/receiver/opencensusreceiver"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+	otlplogs "go.opentelemetry.io/collector/receiver/otlpreceiver/logs"
+	otlpmetrics "go.opentelemetry.io/collector/receiver/otlpreceiver/metrics"
+	otlptrace "go.opentelemetry.io/collector/receiver/otlpreceiver/trace"
 	"go.opentelemetry.io/collector/receiver/prometheusreceiver"
 	"go.opentelemetry.io/collector/receiver/zipkinreceiver"
 )
@@ -58,10 +76,39 @@ type DataReceiver interface {
 type DataReceiverBase struct {
 	// Port on which to listen.
 	Port int
+	// Host is the address on which to listen, for example "::1" or "0.0.0.0".
+	// If empty "localhost" is used.
+	Host string
+	// ProxyEndpoint is the address in host:port form of a proxy which forwards to the
+	// receiver endpoint, for example of a TCPProxy. If set the collector exporter is
+	// configured to send to the proxy instead of directly to the receiver.
+	ProxyEndpoint string
 }
 
 const DefaultHost = "127.0.0.1"
 
+// GetEndpoint returns the address on which the receiver listens in host:port form.
+func (mb *DataReceiverBase) GetEndpoint() string {
+	host := mb.Host
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(mb.Port))
+}
+
+// GetExporterEndpoint returns the address to which the collector exporter sends, which
+// is ProxyEndpoint if set or the receiver endpoint otherwise.
+func (mb *DataReceiverBase) GetExporterEndpoint() string {
+	if mb.ProxyEndpoint != "" {
+		return mb.ProxyEndpoint
+	}
+	return mb.GetEndpoint()
+}
+
+func (mb *DataReceiverBase) setProxyEndpoint(endpoint string) {
+	mb.ProxyEndpoint = endpoint
+}
+
 func (mb *DataReceiverBase) ReportFatalError(err error) {
 	log.Printf("Fatal error reported: %v", err)
 }
@@ -102,7 +149,7 @@ func (or *OCDataReceiver) Start(tc consumer.TracesConsumer, mc consumer.MetricsC
 	factory := opencensusreceiver.NewFactory()
 	cfg := factory.CreateDefaultConfig().(*opencensusreceiver.Config)
 	cfg.SetName(or.ProtocolName())
-	cfg.NetAddr = confignet.NetAddr{Endpoint: fmt.Sprintf("localhost:%d", or.Port), Transport: "tcp"}
+	cfg.NetAddr = confignet.NetAddr{Endpoint: or.GetEndpoint(), Transport: "tcp"}
 	var err error
 	params := component.ReceiverCreateParams{Logger: zap.NewNop()}
 	if or.traceReceiver, err = factory.CreateTracesReceiver(context.Background(), params, cfg, tc); err != nil {
@@ -131,8 +178,8 @@ func (or *OCDataReceiver) GenConfigYAMLStr() string {
 	// Note that this generates an exporter config for agent.
 	return fmt.Sprintf(`
   opencensus:
-    endpoint: "localhost:%d"
-    insecure: true`, or.Port)
+    endpoint: "%s"
+    insecure: true`, or.GetExporterEndpoint())
 }
 
 func (or *OCDataReceiver) ProtocolName() string {
@@ -158,7 +205,7 @@ func (jr *JaegerDataReceiver) Start(tc consumer.TracesConsumer, _ consumer.Metri
 	cfg := factory.CreateDefaultConfig().(*jaegerreceiver.Config)
 	cfg.SetName(jr.ProtocolName())
 	cfg.Protocols.GRPC = &configgrpc.GRPCServerSettings{
-		NetAddr: confignet.NetAddr{Endpoint: fmt.Sprintf("localhost:%d", jr.Port), Transport: "tcp"},
+		NetAddr: confignet.NetAddr{Endpoint: jr.GetEndpoint(), Transport: "tcp"},
 	}
 	var err error
 	params := component.ReceiverCreateParams{Logger: zap.NewNop()}
@@ -178,8 +225,8 @@ func (jr *JaegerDataReceiver) GenConfigYAMLStr() string {
 	// Note that this generates an exporter config for agent.
 	return fmt.Sprintf(`
   jaeger:
-    endpoint: "localhost:%d"
-    insecure: true`, jr.Port)
+    endpoint: "%s"
+    insecure: true`, jr.GetExporterEndpoint())
 }
 
 func (jr *JaegerDataReceiver) ProtocolName() string {
@@ -195,17 +242,32 @@ type BaseOTLPDataReceiver struct {
 	metricsReceiver component.MetricsReceiver
 	logReceiver     component.LogsReceiver
 	compression     string
+	encoding        string
+	numConsumers    int
+
+	retryInitialInterval time.Duration
+	retryMaxInterval     time.Duration
+
+	// Server used instead of the OTLP receiver for OTLP over gRPC if connections and
+	// streams are counted, see WithConnectionStats.
+	countConnections bool
+	grpcServer       *grpc.Server
+	connStats        grpcConnStats
 }
 
 func (bor *BaseOTLPDataReceiver) Start(tc consumer.TracesConsumer, mc consumer.MetricsConsumer, lc consumer.LogsConsumer) error {
+	if bor.exporterType == "otlp" && bor.countConnections {
+		return bor.startGRPC(tc, mc, lc)
+	}
+
 	factory := otlpreceiver.NewFactory()
 	cfg := factory.CreateDefaultConfig().(*otlpreceiver.Config)
 	cfg.SetName(bor.exporterType)
 	if bor.exporterType == "otlp" {
-		cfg.GRPC.NetAddr = confignet.NetAddr{Endpoint: fmt.Sprintf("localhost:%d", bor.Port), Transport: "tcp"}
+		cfg.GRPC.NetAddr = confignet.NetAddr{Endpoint: bor.GetEndpoint(), Transport: "tcp"}
 		cfg.HTTP = nil
 	} else {
-		cfg.HTTP.Endpoint = fmt.Sprintf("localhost:%d", bor.Port)
+		cfg.HTTP.Endpoint = bor.GetEndpoint()
 		cfg.GRPC = nil
 	}
 	var err error
@@ -229,12 +291,96 @@ func (bor *BaseOTLPDataReceiver) Start(tc consumer.TracesConsumer, mc consumer.M
 	return bor.logReceiver.Start(context.Background(), bor)
 }
 
+// startGRPC serves the OTLP services of the OTLP receiver on a gRPC server which counts
+// the connections, streams and received bytes.
+func (bor *BaseOTLPDataReceiver) startGRPC(tc consumer.TracesConsumer, mc consumer.MetricsConsumer, lc consumer.LogsConsumer) error {
+	cfg := otlpreceiver.NewFactory().CreateDefaultConfig().(*otlpreceiver.Config)
+	cfg.SetName(bor.exporterType)
+	cfg.GRPC.NetAddr = confignet.NetAddr{Endpoint: bor.GetEndpoint(), Transport: "tcp"}
+	opts, err := cfg.GRPC.ToServerOption()
+	if err != nil {
+		return err
+	}
+	listener, err := cfg.GRPC.ToListener()
+	if err != nil {
+		return err
+	}
+
+	bor.connStats.reset()
+	bor.grpcServer = grpc.NewServer(append(opts, grpc.StatsHandler(&bor.connStats))...)
+	collectortrace.RegisterTraceServiceServer(bor.grpcServer, otlptrace.New(cfg.Name(), tc))
+	collectormetrics.RegisterMetricsServiceServer(bor.grpcServer, otlpmetrics.New(cfg.Name(), mc))
+	collectorlog.RegisterLogsServiceServer(bor.grpcServer, otlplogs.New(cfg.Name(), lc))
+	go func() {
+		if err := bor.grpcServer.Serve(listener); err != nil {
+			log.Printf("OTLP gRPC receiver stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// WithConnectionStats makes the receiver count connections, streams and received bytes,
+// see ConnectionStats. It is only supported for OTLP over gRPC. The OTLP services of the
+// OTLP receiver are then served by a gRPC server of the testbed instead of the OTLP
+// receiver itself.
+func (bor *BaseOTLPDataReceiver) WithConnectionStats() *BaseOTLPDataReceiver {
+	bor.countConnections = true
+	return bor
+}
+
+// ConnectionStats returns the connection statistics of the receiver. ok is false if the
+// receiver does not count connections, see WithConnectionStats.
+func (bor *BaseOTLPDataReceiver) ConnectionStats() (stats ConnectionStats, ok bool) {
+	if bor.exporterType != "otlp" || !bor.countConnections {
+		return ConnectionStats{}, false
+	}
+	return ConnectionStats{
+		AcceptedConnections: bor.connStats.accepted.Load(),
+		ActiveStreams:       bor.connStats.active.Load(),
+		PeakActiveStreams:   bor.connStats.peak.Load(),
+		ReceivedWireBytes:   bor.connStats.wireBytes.Load(),
+	}, true
+}
+
 func (bor *BaseOTLPDataReceiver) WithCompression(compression string) *BaseOTLPDataReceiver {
 	bor.compression = compression
 	return bor
 }
 
+// WithHTTPEncoding sets the encoding of the requests sent by the OTLP/HTTP exporter in
+// the collector to this receiver, one of otlphttpexporter.EncodingProto (the default)
+// or otlphttpexporter.EncodingJSON. The receiver decodes either encoding.
+func (bor *BaseOTLPDataReceiver) WithHTTPEncoding(encoding string) *BaseOTLPDataReceiver {
+	bor.encoding = encoding
+	return bor
+}
+
+// WithNumConsumers sets the number of consumers of the sending queue of the exporter
+// in the collector which sends data to this receiver.
+func (bor *BaseOTLPDataReceiver) WithNumConsumers(numConsumers int) *BaseOTLPDataReceiver {
+	bor.numConsumers = numConsumers
+	return bor
+}
+
+// NumConsumers returns the number of consumers set by WithNumConsumers, 0 if the exporter
+// uses the default.
+func (bor *BaseOTLPDataReceiver) NumConsumers() int {
+	return bor.numConsumers
+}
+
+// WithRetry sets the initial and maximum retry intervals of the exporter in the
+// collector which sends data to this receiver.
+func (bor *BaseOTLPDataReceiver) WithRetry(initialInterval, maxInterval time.Duration) *BaseOTLPDataReceiver {
+	bor.retryInitialInterval = initialInterval
+	bor.retryMaxInterval = maxInterval
+	return bor
+}
+
 func (bor *BaseOTLPDataReceiver) Stop() error {
+	if bor.grpcServer != nil {
+		bor.grpcServer.GracefulStop()
+		return nil
+	}
 	if err := bor.traceReceiver.Shutdown(context.Background()); err != nil {
 		return err
 	}
@@ -249,7 +395,7 @@ func (bor *BaseOTLPDataReceiver) ProtocolName() string {
 }
 
 func (bor *BaseOTLPDataReceiver) GenConfigYAMLStr() string {
-	addr := fmt.Sprintf("localhost:%d", bor.Port)
+	addr := bor.GetExporterEndpoint()
 	if bor.exporterType == "otlphttp" {
 		addr = "http://" + addr
 	}
@@ -264,9 +410,90 @@ func (bor *BaseOTLPDataReceiver) GenConfigYAMLStr() string {
     compression: "%s"`, bor.compression)
 	}
 
+	if bor.encoding != "" {
+		str += fmt.Sprintf(`
+    encoding: "%s"`, bor.encoding)
+	}
+
+	if bor.numConsumers != 0 {
+		str += fmt.Sprintf(`
+    sending_queue:
+      num_consumers: %d`, bor.numConsumers)
+	}
+
+	if bor.retryInitialInterval != 0 {
+		str += fmt.Sprintf(`
+    retry_on_failure:
+      enabled: true
+      initial_interval: %s
+      max_interval: %s`, bor.retryInitialInterval, bor.retryMaxInterval)
+	}
+
 	return str
 }
 
+// ConnectionStats holds the number of connections and streams handled by a receiver.
+type ConnectionStats struct {
+	// Number of connections accepted since the receiver was started.
+	AcceptedConnections uint64
+	// Number of streams currently in progress. Every gRPC call is a stream.
+	ActiveStreams int64
+	// Highest number of concurrently active streams.
+	PeakActiveStreams int64
+	// Number of bytes of the request messages received, as sent over the network, i.e.
+	// after compression.
+	ReceivedWireBytes uint64
+}
+
+// grpcConnStats is a gRPC stats.Handler counting connections, active streams and
+// received bytes.
+type grpcConnStats struct {
+	accepted  atomic.Uint64
+	active    atomic.Int64
+	peak      atomic.Int64
+	wireBytes atomic.Uint64
+}
+
+// reset sets all counters to zero.
+func (s *grpcConnStats) reset() {
+	s.accepted.Store(0)
+	s.active.Store(0)
+	s.peak.Store(0)
+	s.wireBytes.Store(0)
+}
+
+var _ stats.Handler = (*grpcConnStats)(nil)
+
+func (s *grpcConnStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s *grpcConnStats) HandleRPC(_ context.Context, rs stats.RPCStats) {
+	switch rs := rs.(type) {
+	case *stats.InPayload:
+		s.wireBytes.Add(uint64(rs.WireLength))
+	case *stats.Begin:
+		active := s.active.Inc()
+		for peak := s.peak.Load(); active > peak; peak = s.peak.Load() {
+			if s.peak.CAS(peak, active) {
+				break
+			}
+		}
+	case *stats.End:
+		s.active.Dec()
+	}
+}
+
+func (s *grpcConnStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s *grpcConnStats) HandleConn(_ context.Context, cs stats.ConnStats) {
+	if _, ok := cs.(*stats.ConnBegin); ok {
+		s.accepted.Inc()
+	}
+}
+
 const DefaultOTLPPort = 55680
 
 // NewOTLPDataReceiver creates a new OTLP DataReceiver that will listen on the specified port after Start
@@ -305,7 +532,7 @@ func (zr *ZipkinDataReceiver) Start(tc consumer.TracesConsumer, _ consumer.Metri
 	factory := zipkinreceiver.NewFactory()
 	cfg := factory.CreateDefaultConfig().(*zipkinreceiver.Config)
 	cfg.SetName(zr.ProtocolName())
-	cfg.Endpoint = fmt.Sprintf("localhost:%d", zr.Port)
+	cfg.Endpoint = zr.GetEndpoint()
 
 	params := component.ReceiverCreateParams{Logger: zap.NewNop()}
 	var err error
@@ -326,8 +553,8 @@ func (zr *ZipkinDataReceiver) GenConfigYAMLStr() string {
 	// Note that this generates an exporter config for agent.
 	return fmt.Sprintf(`
   zipkin:
-    endpoint: http://localhost:%d/api/v2/spans
-    format: json`, zr.Port)
+    endpoint: http://%s/api/v2/spans
+    format: json`, zr.GetExporterEndpoint())
 }
 
 func (zr *ZipkinDataReceiver) ProtocolName() string {
@@ -350,7 +577,7 @@ func NewPrometheusDataReceiver(port int) *PrometheusDataReceiver {
 func (dr *PrometheusDataReceiver) Start(_ consumer.TracesConsumer, mc consumer.MetricsConsumer, _ consumer.LogsConsumer) error {
 	factory := prometheusreceiver.NewFactory()
 	cfg := factory.CreateDefaultConfig().(*prometheusreceiver.Config)
-	addr := fmt.Sprintf("0.0.0.0:%d", dr.Port)
+	addr := dr.GetEndpoint()
 	cfg.PrometheusConfig = &config.Config{
 		ScrapeConfigs: []*config.ScrapeConfig{{
 			JobName:        "testbed-job",
@@ -386,11 +613,351 @@ func (dr *PrometheusDataReceiver) Stop() error {
 func (dr *PrometheusDataReceiver) GenConfigYAMLStr() string {
 	format := `
   prometheus:
-    endpoint: "localhost:%d"
+    endpoint: "%s"
 `
-	return fmt.Sprintf(format, dr.Port)
+	return fmt.Sprintf(format, dr.GetEndpoint())
 }
 
 func (dr *PrometheusDataReceiver) ProtocolName() string {
 	return "prometheus"
 }
+
+// CorruptingDataReceiver wraps an OTLP/HTTP DataReceiver and corrupts a fraction of
+// the payloads sent by the collector exporter before they are decoded by the wrapped
+// receiver. It is used to exercise the error handling of the receiver and the exporter
+// under load. Payloads which are corrupted but still decode successfully are counted
+// as received by MockBackend.
+type CorruptingDataReceiver struct {
+	DataReceiverBase
+	receiver *BaseOTLPDataReceiver
+	fraction float64
+
+	interposer httpInterposer
+	mutex      sync.Mutex
+	random     *rand.Rand
+
+	payloads  atomic.Uint64
+	corrupted atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+var _ DataReceiver = (*CorruptingDataReceiver)(nil)
+
+// NewCorruptingDataReceiver creates a CorruptingDataReceiver which listens on the
+// specified port and forwards to receiver, which must be created with
+// NewOTLPHTTPDataReceiver. fraction is the fraction of payloads to corrupt in the
+// [0, 1] range.
+func NewCorruptingDataReceiver(port int, receiver *BaseOTLPDataReceiver, fraction float64) *CorruptingDataReceiver {
+	cr := &CorruptingDataReceiver{
+		DataReceiverBase: DataReceiverBase{Port: port},
+		receiver:         receiver,
+		fraction:         fraction,
+		random:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	// The collector exporter must send to this receiver instead of the wrapped one.
+	receiver.setProxyEndpoint(cr.GetEndpoint())
+	return cr
+}
+
+func (cr *CorruptingDataReceiver) Start(tc consumer.TracesConsumer, mc consumer.MetricsConsumer, lc consumer.LogsConsumer) error {
+	if err := cr.receiver.Start(tc, mc, lc); err != nil {
+		return err
+	}
+
+	cr.interposer.modifyResponse = func(resp *http.Response) error {
+		if resp.StatusCode >= http.StatusBadRequest {
+			cr.rejected.Inc()
+		}
+		return nil
+	}
+	return cr.interposer.start(cr.GetEndpoint(), cr.receiver.GetEndpoint(), cr.corruptBody)
+}
+
+// corruptBody flips a few random bytes of the request body with the configured
+// probability.
+func (cr *CorruptingDataReceiver) corruptBody(_ *http.Request, body []byte) []byte {
+	cr.payloads.Inc()
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	if len(body) > 0 && cr.random.Float64() < cr.fraction {
+		cr.corrupted.Inc()
+		for i := 0; i < 1+len(body)/100; i++ {
+			body[cr.random.Intn(len(body))] ^= 0xFF
+		}
+	}
+	return body
+}
+
+func (cr *CorruptingDataReceiver) Stop() error {
+	if err := cr.interposer.stop(); err != nil {
+		return err
+	}
+	log.Printf("Stopped corrupting receiver. %s", cr.GetStats())
+	return cr.receiver.Stop()
+}
+
+func (cr *CorruptingDataReceiver) GenConfigYAMLStr() string {
+	return cr.receiver.GenConfigYAMLStr()
+}
+
+func (cr *CorruptingDataReceiver) ProtocolName() string {
+	return cr.receiver.ProtocolName()
+}
+
+// Payloads returns the number of payloads received from the collector exporter.
+func (cr *CorruptingDataReceiver) Payloads() uint64 {
+	return cr.payloads.Load()
+}
+
+// CorruptedPayloads returns the number of payloads which were corrupted.
+func (cr *CorruptingDataReceiver) CorruptedPayloads() uint64 {
+	return cr.corrupted.Load()
+}
+
+// RejectedPayloads returns the number of payloads which the wrapped receiver failed to
+// decode or otherwise rejected.
+func (cr *CorruptingDataReceiver) RejectedPayloads() uint64 {
+	return cr.rejected.Load()
+}
+
+// GetStats returns the stats as a printable string.
+func (cr *CorruptingDataReceiver) GetStats() string {
+	return fmt.Sprintf("Payloads: %d, corrupted: %d, rejected: %d",
+		cr.Payloads(), cr.CorruptedPayloads(), cr.RejectedPayloads())
+}
+
+// httpInterposer is an HTTP reverse proxy placed between the collector exporter and a
+// wrapped receiver, which passes the body of each request to a hook before forwarding it.
+type httpInterposer struct {
+	// modifyResponse is called with the responses of the wrapped receiver if set.
+	modifyResponse func(resp *http.Response) error
+
+	server *http.Server
+}
+
+// start listens on endpoint and forwards the requests to target. onBody is called with
+// each request and its complete body and returns the body to forward.
+func (hi *httpInterposer) start(endpoint, target string, onBody func(req *http.Request, body []byte) []byte) error {
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: target})
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			log.Printf("Cannot read payload: %v", err)
+		}
+		body = onBody(req, body)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		director(req)
+	}
+	proxy.ModifyResponse = hi.modifyResponse
+
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	hi.server = &http.Server{Handler: proxy}
+	go func() {
+		_ = hi.server.Serve(listener)
+	}()
+	return nil
+}
+
+func (hi *httpInterposer) stop() error {
+	if hi.server == nil {
+		return nil
+	}
+	return hi.server.Close()
+}
+
+// proxiedDataReceiver is a DataReceiver which can be placed behind a proxy.
+type proxiedDataReceiver interface {
+	DataReceiver
+	GetEndpoint() string
+	setProxyEndpoint(endpoint string)
+}
+
+// CapturedRequest is a request received by a CapturingDataReceiver, as it was sent on
+// the wire by the collector exporter.
+type CapturedRequest struct {
+	Time   time.Time
+	Method string
+	Path   string
+	// Header holds the request headers, including Content-Type and Content-Encoding.
+	Header http.Header
+	// Body is the raw body, still compressed if the exporter compressed it, truncated to
+	// the body size cap of the receiver.
+	Body []byte
+	// BodySize is the size of the complete body, larger than len(Body) if truncated.
+	BodySize int
+}
+
+// ContentType returns the Content-Type header of the request.
+func (r CapturedRequest) ContentType() string {
+	return r.Header.Get("Content-Type")
+}
+
+// Truncated returns whether Body holds only a prefix of the body.
+func (r CapturedRequest) Truncated() bool {
+	return len(r.Body) < r.BodySize
+}
+
+// CapturingDataReceiver wraps an HTTP based DataReceiver, like OTLP/HTTP or Zipkin, and
+// keeps the raw bodies and metadata of the latest requests sent by the collector
+// exporter before they are decoded by the wrapped receiver. It helps to diagnose
+// compression and encoding mismatches, which are hidden once the data is decoded.
+type CapturingDataReceiver struct {
+	DataReceiverBase
+	receiver     proxiedDataReceiver
+	maxRequests  int
+	maxBodyBytes int
+
+	interposer httpInterposer
+
+	mutex    sync.Mutex
+	requests []CapturedRequest
+	// Index in requests at which the next request is stored once it is full.
+	next  int
+	total uint64
+}
+
+var _ DataReceiver = (*CapturingDataReceiver)(nil)
+
+// NewCapturingDataReceiver creates a CapturingDataReceiver which listens on the specified
+// port and forwards to receiver, which must receive over HTTP. It keeps the latest
+// maxRequests requests and at most maxBodyBytes of each body.
+func NewCapturingDataReceiver(port int, receiver proxiedDataReceiver, maxRequests, maxBodyBytes int) *CapturingDataReceiver {
+	cr := &CapturingDataReceiver{
+		DataReceiverBase: DataReceiverBase{Port: port},
+		receiver:         receiver,
+		maxRequests:      maxRequests,
+		maxBodyBytes:     maxBodyBytes,
+	}
+	// The collector exporter must send to this receiver instead of the wrapped one.
+	receiver.setProxyEndpoint(cr.GetEndpoint())
+	return cr
+}
+
+func (cr *CapturingDataReceiver) Start(tc consumer.TracesConsumer, mc consumer.MetricsConsumer, lc consumer.LogsConsumer) error {
+	if err := cr.receiver.Start(tc, mc, lc); err != nil {
+		return err
+	}
+
+	return cr.interposer.start(cr.GetEndpoint(), cr.receiver.GetEndpoint(), cr.capture)
+}
+
+// capture stores the request and returns its body unchanged for forwarding.
+func (cr *CapturingDataReceiver) capture(req *http.Request, body []byte) []byte {
+	captured := CapturedRequest{
+		Time:     time.Now(),
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Header:   req.Header.Clone(),
+		BodySize: len(body),
+	}
+	kept := body
+	if len(kept) > cr.maxBodyBytes {
+		kept = kept[:cr.maxBodyBytes]
+	}
+	captured.Body = append([]byte(nil), kept...)
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	cr.total++
+	switch {
+	case cr.maxRequests <= 0:
+	case len(cr.requests) < cr.maxRequests:
+		cr.requests = append(cr.requests, captured)
+	default:
+		cr.requests[cr.next] = captured
+		cr.next = (cr.next + 1) % cr.maxRequests
+	}
+	return body
+}
+
+// CapturedRequests returns the latest captured requests, oldest first.
+func (cr *CapturingDataReceiver) CapturedRequests() []CapturedRequest {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	requests := make([]CapturedRequest, 0, len(cr.requests))
+	requests = append(requests, cr.requests[cr.next:]...)
+	return append(requests, cr.requests[:cr.next]...)
+}
+
+// Requests returns the number of requests received, including the ones which are no
+// longer kept.
+func (cr *CapturingDataReceiver) Requests() uint64 {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	return cr.total
+}
+
+func (cr *CapturingDataReceiver) Stop() error {
+	if err := cr.interposer.stop(); err != nil {
+		return err
+	}
+	return cr.receiver.Stop()
+}
+
+func (cr *CapturingDataReceiver) GenConfigYAMLStr() string {
+	return cr.receiver.GenConfigYAMLStr()
+}
+
+func (cr *CapturingDataReceiver) ProtocolName() string {
+	return cr.receiver.ProtocolName()
+}
+
+// SlowReadingDataReceiver wraps a DataReceiver and reads the data sent by the collector
+// exporter at a limited number of bytes per second. Unlike a receiver which delays its
+// responses it throttles at the transport layer, so it exercises the flow control and
+// buffering of the exporter, e.g. of HTTP/2 for gRPC. The data reaches the wrapped
+// receiver unchanged and is counted by MockBackend once it is fully read.
+type SlowReadingDataReceiver struct {
+	DataReceiverBase
+	receiver proxiedDataReceiver
+	proxy    *TCPProxy
+}
+
+var _ DataReceiver = (*SlowReadingDataReceiver)(nil)
+
+// NewSlowReadingDataReceiver creates a SlowReadingDataReceiver which listens on the
+// specified port and forwards to receiver, reading at most bytesPerSecond over all
+// connections. Panics if bytesPerSecond is not positive.
+func NewSlowReadingDataReceiver(port int, receiver proxiedDataReceiver, bytesPerSecond int) *SlowReadingDataReceiver {
+	sr := &SlowReadingDataReceiver{
+		DataReceiverBase: DataReceiverBase{Port: port},
+		receiver:         receiver,
+	}
+	sr.proxy = NewTCPProxy(sr.GetEndpoint(), receiver.GetEndpoint()).WithReadRate(bytesPerSecond)
+	// The collector exporter must send to this receiver instead of the wrapped one.
+	receiver.setProxyEndpoint(sr.GetEndpoint())
+	return sr
+}
+
+func (sr *SlowReadingDataReceiver) Start(tc consumer.TracesConsumer, mc consumer.MetricsConsumer, lc consumer.LogsConsumer) error {
+	if err := sr.receiver.Start(tc, mc, lc); err != nil {
+		return err
+	}
+	return sr.proxy.Start()
+}
+
+func (sr *SlowReadingDataReceiver) Stop() error {
+	sr.proxy.Stop()
+	return sr.receiver.Stop()
+}
+
+func (sr *SlowReadingDataReceiver) GenConfigYAMLStr() string {
+	return sr.receiver.GenConfigYAMLStr()
+}
+
+func (sr *SlowReadingDataReceiver) ProtocolName() string {
+	return sr.receiver.ProtocolName()
+}
+
+// BytesRead returns the number of bytes sent by the collector exporter which were read
+// so far.
+func (sr *SlowReadingDataReceiver) BytesRead() uint64 {
+	return sr.proxy.BytesRead()
+}
@@ -0,0 +1,130 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestCapturingDataReceiver(t *testing.T) {
+	otlpReceiver := NewOTLPHTTPDataReceiver(GetAvailablePort(t))
+	receiver := NewCapturingDataReceiver(GetAvailablePort(t), otlpReceiver, 2, 1<<20)
+	assert.Equal(t, receiver.GetEndpoint(), otlpReceiver.GetExporterEndpoint())
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, receiver.Start(sink, consumertest.NewMetricsNop(), consumertest.NewLogsNop()))
+	defer receiver.Stop()
+
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var sent [][]byte
+	for i := 0; i < 3; i++ {
+		traces, _ := dp.GenerateTraces()
+		body, err := traces.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		sent = append(sent, body)
+
+		req, err := http.NewRequest(http.MethodPost, "http://"+receiver.GetEndpoint()+"/v1/traces", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("X-Testbed-Request", "capture")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// The requests reached the wrapped receiver unchanged.
+	assert.Equal(t, 30, sink.SpansCount())
+
+	// Only the latest two requests are kept.
+	assert.EqualValues(t, 3, receiver.Requests())
+	captured := receiver.CapturedRequests()
+	require.Len(t, captured, 2)
+	for i, req := range captured {
+		assert.Equal(t, sent[i+1], req.Body)
+		assert.Equal(t, len(sent[i+1]), req.BodySize)
+		assert.False(t, req.Truncated())
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/v1/traces", req.Path)
+		assert.Equal(t, "application/x-protobuf", req.ContentType())
+		assert.Equal(t, "capture", req.Header.Get("X-Testbed-Request"))
+	}
+}
+
+func TestCapturingDataReceiverTruncatesBodies(t *testing.T) {
+	otlpReceiver := NewOTLPHTTPDataReceiver(GetAvailablePort(t))
+	receiver := NewCapturingDataReceiver(GetAvailablePort(t), otlpReceiver, 10, 16)
+	require.NoError(t, receiver.Start(consumertest.NewTracesNop(), consumertest.NewMetricsNop(), consumertest.NewLogsNop()))
+	defer receiver.Stop()
+
+	body := []byte("this body is longer than sixteen bytes")
+	resp, err := http.Post("http://"+receiver.GetEndpoint()+"/v1/traces", "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	captured := receiver.CapturedRequests()
+	require.Len(t, captured, 1)
+	assert.Equal(t, body[:16], captured[0].Body)
+	assert.Equal(t, len(body), captured[0].BodySize)
+	assert.True(t, captured[0].Truncated())
+}
+
+func TestSlowReadingDataReceiver(t *testing.T) {
+	const bytesPerSecond = 100_000
+	receiver := NewSlowReadingDataReceiver(GetAvailablePort(t), NewOTLPDataReceiver(GetAvailablePort(t)), bytesPerSecond)
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, receiver.Start(sink, consumertest.NewMetricsNop(), consumertest.NewLogsNop()))
+	defer receiver.Stop()
+
+	sender := NewOTLPTraceDataSender(DefaultHost, receiver.Port)
+	require.NoError(t, sender.Start())
+
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 100})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var bodySize int
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		traces, _ := dp.GenerateTraces()
+		body, err := traces.ToOtlpProtoBytes()
+		require.NoError(t, err)
+		bodySize += len(body)
+		require.NoError(t, sender.ConsumeTraces(context.Background(), traces))
+	}
+	elapsed := time.Since(start)
+
+	// All data arrives, but no faster than the receiver reads it.
+	assert.Equal(t, 300, sink.SpansCount())
+	assert.GreaterOrEqual(t, int64(elapsed), int64(time.Duration(bodySize)*time.Second/bytesPerSecond*9/10))
+	assert.Greater(t, receiver.BytesRead(), uint64(bodySize))
+}
+
+func TestSlowReadingDataReceiverRejectsNonPositiveRates(t *testing.T) {
+	for _, bytesPerSecond := range []int{0, -1} {
+		assert.Panics(t, func() {
+			NewSlowReadingDataReceiver(GetAvailablePort(t), NewOTLPDataReceiver(GetAvailablePort(t)), bytesPerSecond)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// TestResult is the outcome of a single TestCase passed to its ResultReporters. Unlike
+// the results recorded in a TestResultsSummary it is the same for all kinds of tests.
+type TestResult struct {
+	TestName         string
+	Result           string
+	Duration         time.Duration
+	SentItems        uint64
+	ReceivedItems    uint64
+	CPUPercentageAvg float64
+	CPUPercentageMax float64
+	RAMMiBAvg        uint32
+	RAMMiBMax        uint32
+	ErrorCause       string
+	Metadata         map[string]string
+}
+
+// ResultReporter publishes the results of test cases, e.g. to a file or an HTTP
+// endpoint. Reporters are added to a TestCase with WithResultReporters and called
+// when the TestCase is stopped.
+type ResultReporter interface {
+	// Report publishes the result of one test case. Errors must be handled, e.g.
+	// logged, by the reporter.
+	Report(result TestResult)
+}
+
+// ConsoleResultReporter implements ResultReporter writing one line per test to a writer.
+type ConsoleResultReporter struct {
+	w io.Writer
+}
+
+// NewConsoleResultReporter creates a ConsoleResultReporter writing to w, or to the
+// standard output if w is nil.
+func NewConsoleResultReporter(w io.Writer) *ConsoleResultReporter {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &ConsoleResultReporter{w: w}
+}
+
+func (r *ConsoleResultReporter) Report(result TestResult) {
+	line := fmt.Sprintf("%s %s in %.1fs: sent %d, received %d items, CPU avg %.1f%% max %.1f%%, RAM avg %d MiB max %d MiB",
+		result.TestName, result.Result, result.Duration.Seconds(), result.SentItems, result.ReceivedItems,
+		result.CPUPercentageAvg, result.CPUPercentageMax, result.RAMMiBAvg, result.RAMMiBMax)
+	if result.ErrorCause != "" {
+		line += ", error: " + result.ErrorCause
+	}
+	if len(result.Metadata) > 0 {
+		line += ", " + formatRunMetadata(result.Metadata)
+	}
+	if _, err := io.WriteString(r.w, line+"\n"); err != nil {
+		log.Printf("Cannot report result of %s: %v", result.TestName, err)
+	}
+}
+
+// JSONFileResultReporter implements ResultReporter appending every result to a file as
+// one JSON object per line.
+type JSONFileResultReporter struct {
+	filePath string
+	mutex    sync.Mutex
+}
+
+// NewJSONFileResultReporter creates a JSONFileResultReporter appending to the file at
+// filePath, which is created if it does not exist.
+func NewJSONFileResultReporter(filePath string) *JSONFileResultReporter {
+	return &JSONFileResultReporter{filePath: filePath}
+}
+
+// testResultJSON is the serialized form of TestResult.
+type testResultJSON struct {
+	TestName         string            `json:"test_name"`
+	Result           string            `json:"result"`
+	DurationSeconds  float64           `json:"duration_seconds"`
+	SentItems        uint64            `json:"sent_items"`
+	ReceivedItems    uint64            `json:"received_items"`
+	CPUPercentageAvg float64           `json:"cpu_percentage_avg"`
+	CPUPercentageMax float64           `json:"cpu_percentage_max"`
+	RAMMiBAvg        uint32            `json:"ram_mib_avg"`
+	RAMMiBMax        uint32            `json:"ram_mib_max"`
+	ErrorCause       string            `json:"error_cause,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+func (r *JSONFileResultReporter) Report(result TestResult) {
+	data, err := json.Marshal(testResultJSON{
+		TestName:         result.TestName,
+		Result:           result.Result,
+		DurationSeconds:  result.Duration.Seconds(),
+		SentItems:        result.SentItems,
+		ReceivedItems:    result.ReceivedItems,
+		CPUPercentageAvg: result.CPUPercentageAvg,
+		CPUPercentageMax: result.CPUPercentageMax,
+		RAMMiBAvg:        result.RAMMiBAvg,
+		RAMMiBMax:        result.RAMMiBMax,
+		ErrorCause:       result.ErrorCause,
+		Metadata:         result.Metadata,
+	})
+	if err != nil {
+		log.Printf("Cannot serialize result of %s: %v", result.TestName, err)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	f, err := os.OpenFile(r.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Cannot open %s: %v", r.filePath, err)
+		return
+	}
+	defer f.Close()
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		log.Printf("Cannot write result of %s to %s: %v", result.TestName, r.filePath, err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultReporters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reporters")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	results := []TestResult{
+		{
+			TestName:      "Trace10kSPS",
+			Result:        "PASS",
+			Duration:      15 * time.Second,
+			SentItems:     150000,
+			ReceivedItems: 150000,
+			RAMMiBMax:     80,
+			Metadata:      map[string]string{"commit": "abc123"},
+		},
+		{
+			TestName:   "Metric10kDPS",
+			Result:     "FAIL",
+			ErrorCause: "RAM consumption is 200 MiB",
+		},
+	}
+
+	var console bytes.Buffer
+	consoleReporter := NewConsoleResultReporter(&console)
+	jsonReporter := NewJSONFileResultReporter(path.Join(dir, "results.jsonl"))
+	for _, result := range results {
+		consoleReporter.Report(result)
+		jsonReporter.Report(result)
+	}
+
+	assert.Equal(t,
+		"Trace10kSPS PASS in 15.0s: sent 150000, received 150000 items, CPU avg 0.0% max 0.0%, RAM avg 0 MiB max 80 MiB, commit=abc123\n"+
+			"Metric10kDPS FAIL in 0.0s: sent 0, received 0 items, CPU avg 0.0% max 0.0%, RAM avg 0 MiB max 0 MiB, error: RAM consumption is 200 MiB\n",
+		console.String())
+
+	f, err := os.Open(path.Join(dir, "results.jsonl"))
+	require.NoError(t, err)
+	defer f.Close()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "Trace10kSPS", records[0]["test_name"])
+	assert.EqualValues(t, 15, records[0]["duration_seconds"])
+	assert.EqualValues(t, 150000, records[0]["received_items"])
+	assert.Equal(t, map[string]interface{}{"commit": "abc123"}, records[0]["metadata"])
+	assert.Equal(t, "FAIL", records[1]["result"])
+	assert.Equal(t, "RAM consumption is 200 MiB", records[1]["error_cause"])
+	assert.NotContains(t, records[1], "metadata")
+}
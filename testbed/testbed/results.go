@@ -15,11 +15,15 @@
 package testbed
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -53,7 +57,222 @@ type PerformanceTestResult struct {
 	ramMibMax         uint32
 	sentSpanCount     uint64
 	receivedSpanCount uint64
-	errorCause        string
+	// Number of export requests received by the MockBackend.
+	receivedRequests uint64
+	errorCause       string
+	// Additional environment variables the agent was run with, if any.
+	agentEnv []string
+	// Path and version of the agent executable if it is not the default one.
+	agentExe     string
+	agentVersion string
+	// CPU cores the agent was pinned to, if any.
+	agentCPUs []int
+	// Feature gates the agent was run with, if any.
+	agentFeatureGates []string
+	// Time from the start of the agent until the first data item was received.
+	timeToFirstItem time.Duration
+	// Time from stopping the load until the last data item was received, if measured
+	// with TestCase.WaitForDrain, and whether the agent failed to drain in time.
+	timeToDrain   time.Duration
+	drainTimedOut bool
+	// RSS of the idle agent before the load was started.
+	baselineRAMMiB uint32
+	// Peak number of OS threads of the agent if it was sampled.
+	threadsMax uint32
+	// Metadata attached to the run via TestCase.SetRunMetadata, if any.
+	runMetadata map[string]string
+	// Duration of the active windows if the load had an idle pattern, 0 otherwise.
+	activeDuration time.Duration
+	// Connections and streams handled by the MockBackend receiver, if it counts them.
+	connStats ConnectionStats
+	// Consumers of the sending queue of the agent exporter if set, see
+	// BaseOTLPDataReceiver.WithNumConsumers.
+	exporterNumConsumers int
+	// Exporter queue sizes of the agent if they were scraped, see ChildProcess.MetricsPort.
+	queueSizes []QueueSizeSample
+	// Requests cancelled by the load generator because LoadOptions.ExportTimeout expired.
+	cancelledExports uint64
+	// Share of the agent CPU time spent in each component, if a CPU profile was written.
+	componentCPU []ComponentCPUShare
+	// Allocation rate and garbage collections of the agent if they were scraped, see
+	// ChildProcess.GCStats.
+	gcStats GCStats
+	// Resource consumption of the load generator if it ran in a separate process, see
+	// LoadGenerator.StartProcess.
+	loadGeneratorResources *ResourceConsumption
+}
+
+// performanceTestResultJSON is the serialized form of PerformanceTestResult in
+// TESTRESULTS.json.
+type performanceTestResultJSON struct {
+	TestName                  string             `json:"test_name"`
+	Result                    string             `json:"result"`
+	DurationSeconds           float64            `json:"duration_seconds"`
+	ActiveDurationSeconds     float64            `json:"active_duration_seconds,omitempty"`
+	CPUPercentageAvg          float64            `json:"cpu_percentage_avg"`
+	CPUPercentageMax          float64            `json:"cpu_percentage_max"`
+	RAMMiBAvg                 uint32             `json:"ram_mib_avg"`
+	RAMMiBMax                 uint32             `json:"ram_mib_max"`
+	ThreadsMax                uint32             `json:"threads_max,omitempty"`
+	SentItemCount             uint64             `json:"sent_items"`
+	ReceivedItemCount         uint64             `json:"received_items"`
+	ReceivedRequestCount      uint64             `json:"received_requests,omitempty"`
+	ItemsPerRequest           float64            `json:"items_per_request,omitempty"`
+	CPUSecondsPerMillionItems float64            `json:"cpu_seconds_per_million_items"`
+	RAMBytesPer1kItemsPerSec  float64            `json:"ram_bytes_per_1k_items_per_sec"`
+	AcceptedConnections       uint64             `json:"accepted_connections,omitempty"`
+	PeakActiveStreams         int64              `json:"peak_active_streams,omitempty"`
+	ExporterNumConsumers      int                `json:"exporter_num_consumers,omitempty"`
+	ExporterQueueSizes        []queueSizeJSON    `json:"exporter_queue_sizes,omitempty"`
+	PeakExporterQueueSize     int64              `json:"peak_exporter_queue_size,omitempty"`
+	CancelledExports          uint64             `json:"cancelled_exports,omitempty"`
+	TimeToDrainSeconds        float64            `json:"time_to_drain_seconds,omitempty"`
+	DrainTimedOut             bool               `json:"drain_timed_out,omitempty"`
+	ComponentCPUShares        []componentCPUJSON `json:"component_cpu_shares,omitempty"`
+	AllocBytesPerSec          float64            `json:"alloc_bytes_per_sec,omitempty"`
+	GCCount                   uint64             `json:"gc_count,omitempty"`
+	GCPauseSeconds            float64            `json:"gc_pause_seconds,omitempty"`
+	LoadGenerator             *resourcesJSON     `json:"load_generator,omitempty"`
+	AgentExecutable           string             `json:"agent_executable,omitempty"`
+	AgentVersion              string             `json:"agent_version,omitempty"`
+	AgentCPUAffinity          []int              `json:"agent_cpu_affinity,omitempty"`
+	AgentFeatureGates         []string           `json:"agent_feature_gates,omitempty"`
+	ErrorCause                string             `json:"error_cause,omitempty"`
+	Metadata                  map[string]string  `json:"metadata,omitempty"`
+}
+
+// queueSizeJSON is the serialized form of QueueSizeSample in TESTRESULTS.json.
+type queueSizeJSON struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Size           int64   `json:"size"`
+}
+
+// resourcesJSON is the serialized form of the ResourceConsumption of a process other
+// than the agent in TESTRESULTS.json.
+type resourcesJSON struct {
+	CPUPercentageAvg float64 `json:"cpu_percentage_avg"`
+	CPUPercentageMax float64 `json:"cpu_percentage_max"`
+	RAMMiBAvg        uint32  `json:"ram_mib_avg"`
+	RAMMiBMax        uint32  `json:"ram_mib_max"`
+}
+
+// componentCPUJSON is the serialized form of ComponentCPUShare in TESTRESULTS.json.
+type componentCPUJSON struct {
+	Component string  `json:"component"`
+	Percent   float64 `json:"percent"`
+}
+
+// MarshalJSON serializes the result as one record of TESTRESULTS.json.
+func (r *PerformanceTestResult) MarshalJSON() ([]byte, error) {
+	var queueSizes []queueSizeJSON
+	for _, sample := range r.queueSizes {
+		queueSizes = append(queueSizes, queueSizeJSON{ElapsedSeconds: sample.Elapsed.Seconds(), Size: sample.Size})
+	}
+	var componentCPU []componentCPUJSON
+	for _, share := range r.componentCPU {
+		componentCPU = append(componentCPU, componentCPUJSON{Component: share.Component, Percent: share.Percent})
+	}
+	var loadGenerator *resourcesJSON
+	if rc := r.loadGeneratorResources; rc != nil {
+		loadGenerator = &resourcesJSON{
+			CPUPercentageAvg: rc.CPUPercentAvg,
+			CPUPercentageMax: rc.CPUPercentMax,
+			RAMMiBAvg:        rc.RAMMiBAvg,
+			RAMMiBMax:        rc.RAMMiBMax,
+		}
+	}
+	return json.Marshal(performanceTestResultJSON{
+		TestName:                  r.testName,
+		Result:                    r.result,
+		DurationSeconds:           r.duration.Seconds(),
+		ActiveDurationSeconds:     r.activeDuration.Seconds(),
+		CPUPercentageAvg:          r.cpuPercentageAvg,
+		CPUPercentageMax:          r.cpuPercentageMax,
+		RAMMiBAvg:                 r.ramMibAvg,
+		RAMMiBMax:                 r.ramMibMax,
+		ThreadsMax:                r.threadsMax,
+		SentItemCount:             r.sentSpanCount,
+		ReceivedItemCount:         r.receivedSpanCount,
+		ReceivedRequestCount:      r.receivedRequests,
+		ItemsPerRequest:           r.itemsPerRequest(),
+		CPUSecondsPerMillionItems: r.cpuSecondsPerMillionItems(),
+		RAMBytesPer1kItemsPerSec:  r.ramBytesPer1kItemsPerSec(),
+		AcceptedConnections:       r.connStats.AcceptedConnections,
+		PeakActiveStreams:         r.connStats.PeakActiveStreams,
+		ExporterNumConsumers:      r.exporterNumConsumers,
+		ExporterQueueSizes:        queueSizes,
+		PeakExporterQueueSize:     r.peakQueueSize(),
+		CancelledExports:          r.cancelledExports,
+		TimeToDrainSeconds:        r.timeToDrain.Seconds(),
+		DrainTimedOut:             r.drainTimedOut,
+		ComponentCPUShares:        componentCPU,
+		AllocBytesPerSec:          r.gcStats.AllocBytesPerSec,
+		GCCount:                   r.gcStats.NumGC,
+		GCPauseSeconds:            r.gcStats.GCPause.Seconds(),
+		LoadGenerator:             loadGenerator,
+		AgentExecutable:           r.agentExe,
+		AgentVersion:              r.agentVersion,
+		AgentCPUAffinity:          r.agentCPUs,
+		AgentFeatureGates:         r.agentFeatureGates,
+		ErrorCause:                r.errorCause,
+		Metadata:                  r.runMetadata,
+	})
+}
+
+// peakQueueSize returns the largest scraped exporter queue size, 0 if none was scraped.
+func (r *PerformanceTestResult) peakQueueSize() int64 {
+	var peak int64
+	for _, sample := range r.queueSizes {
+		if sample.Size > peak {
+			peak = sample.Size
+		}
+	}
+	return peak
+}
+
+// itemsPerRequest returns the average number of data items per received export request,
+// 0 if no request was received.
+func (r *PerformanceTestResult) itemsPerRequest() float64 {
+	if r.receivedRequests == 0 {
+		return 0
+	}
+	return float64(r.receivedSpanCount) / float64(r.receivedRequests)
+}
+
+// receivedItemsPerSecond returns the data items received per second over the test
+// duration, 0 if the duration is unknown.
+func (r *PerformanceTestResult) receivedItemsPerSecond() float64 {
+	if r.duration <= 0 {
+		return 0
+	}
+	return float64(r.receivedSpanCount) / r.duration.Seconds()
+}
+
+// cpuSecondsPerMillionItems returns the CPU time the agent spent per million sent data
+// items, derived from the average CPU usage over the test duration. Returns 0 if no
+// items were sent.
+func (r *PerformanceTestResult) cpuSecondsPerMillionItems() float64 {
+	if r.sentSpanCount == 0 {
+		return 0
+	}
+	cpuSeconds := r.cpuPercentageAvg / 100 * r.duration.Seconds()
+	return cpuSeconds / (float64(r.sentSpanCount) / 1e6)
+}
+
+// ramBytesPer1kItemsPerSec returns the peak RAM of the agent per 1k data items sent per
+// second, i.e. normalized by the throughput. The throughput only counts the active
+// windows if the load had an idle pattern. Returns 0 if no items were sent or the
+// duration is unknown.
+func (r *PerformanceTestResult) ramBytesPer1kItemsPerSec() float64 {
+	duration := r.duration
+	if r.activeDuration > 0 {
+		duration = r.activeDuration
+	}
+	if r.sentSpanCount == 0 || duration <= 0 {
+		return 0
+	}
+	itemsPerSecond := float64(r.sentSpanCount) / duration.Seconds()
+	return float64(r.ramMibMax) * mibibyte / (itemsPerSecond / 1000)
 }
 
 func (r *PerformanceResults) Init(resultsDir string) {
@@ -80,7 +299,151 @@ func (r *PerformanceResults) Init(resultsDir string) {
 func (r *PerformanceResults) Save() {
 	_, _ = io.WriteString(r.resultsFile,
 		fmt.Sprintf("\nTotal duration: %.0fs\n", r.totalDuration.Seconds()))
+
+	// Record non-default agent environments for reproducibility.
+	r.writeSection("Agent environment", func(testResult *PerformanceTestResult) string {
+		return strings.Join(testResult.agentEnv, " ")
+	})
+	r.writeSection("Agent executable", func(testResult *PerformanceTestResult) string {
+		if testResult.agentExe == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s (version %s)", testResult.agentExe, testResult.agentVersion)
+	})
+	r.writeSection("Agent CPU affinity", func(testResult *PerformanceTestResult) string {
+		if len(testResult.agentCPUs) == 0 {
+			return ""
+		}
+		return "CPUs " + formatCPUList(testResult.agentCPUs)
+	})
+	r.writeSection("Agent feature gates", func(testResult *PerformanceTestResult) string {
+		return strings.Join(testResult.agentFeatureGates, ",")
+	})
+	r.writeSection("Cancelled exports", func(testResult *PerformanceTestResult) string {
+		if testResult.cancelledExports == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", testResult.cancelledExports)
+	})
+	r.writeSection("Component CPU shares", func(testResult *PerformanceTestResult) string {
+		if len(testResult.componentCPU) == 0 {
+			return ""
+		}
+		return formatComponentCPUShares(testResult.componentCPU)
+	})
+	r.writeSection("Time to first item", func(testResult *PerformanceTestResult) string {
+		if testResult.timeToFirstItem == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3fs", testResult.timeToFirstItem.Seconds())
+	})
+	r.writeSection("Time to drain", func(testResult *PerformanceTestResult) string {
+		if testResult.drainTimedOut {
+			return fmt.Sprintf("not drained after %.3fs", testResult.timeToDrain.Seconds())
+		}
+		if testResult.timeToDrain == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3fs", testResult.timeToDrain.Seconds())
+	})
+	r.writeSection("Baseline RAM", func(testResult *PerformanceTestResult) string {
+		if testResult.baselineRAMMiB == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d MiB (peak %d MiB)", testResult.baselineRAMMiB, testResult.ramMibMax)
+	})
+	r.writeSection("OS threads", func(testResult *PerformanceTestResult) string {
+		if testResult.threadsMax == 0 {
+			return ""
+		}
+		return fmt.Sprintf("peak %d", testResult.threadsMax)
+	})
+	r.writeSection("Load generator process", func(testResult *PerformanceTestResult) string {
+		rc := testResult.loadGeneratorResources
+		if rc == nil {
+			return ""
+		}
+		return fmt.Sprintf("CPU %.1f%% avg, %.1f%% max, RAM %d MiB avg, %d MiB max",
+			rc.CPUPercentAvg, rc.CPUPercentMax, rc.RAMMiBAvg, rc.RAMMiBMax)
+	})
+	r.writeSection("Efficiency", func(testResult *PerformanceTestResult) string {
+		if testResult.sentSpanCount == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f CPU seconds per million items, %.0f bytes RAM per 1k items/sec",
+			testResult.cpuSecondsPerMillionItems(), testResult.ramBytesPer1kItemsPerSec())
+	})
+	r.writeSection("Allocation", func(testResult *PerformanceTestResult) string {
+		if testResult.gcStats.AllocBytesPerSec == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.1f MiB/s allocated, %d GCs, %.3fms GC pause",
+			testResult.gcStats.AllocBytesPerSec/mibibyte, testResult.gcStats.NumGC,
+			float64(testResult.gcStats.GCPause)/float64(time.Millisecond))
+	})
+	r.writeSection("Connections", func(testResult *PerformanceTestResult) string {
+		if testResult.connStats.AcceptedConnections == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d accepted connections, peak %d active streams",
+			testResult.connStats.AcceptedConnections, testResult.connStats.PeakActiveStreams)
+	})
+	// Compare the throughput of runs with a different number of exporter queue consumers.
+	r.writeSection("Throughput by exporter queue consumers", func(testResult *PerformanceTestResult) string {
+		if testResult.exporterNumConsumers == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d consumers, %.1f items/sec", testResult.exporterNumConsumers, testResult.receivedItemsPerSecond())
+	})
+	r.writeSection("Exporter queue", func(testResult *PerformanceTestResult) string {
+		if len(testResult.queueSizes) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("peak %d batches (%d samples)", testResult.peakQueueSize(), len(testResult.queueSizes))
+	})
+	r.writeSection("Run metadata", func(testResult *PerformanceTestResult) string {
+		return formatRunMetadata(testResult.runMetadata)
+	})
 	r.resultsFile.Close()
+
+	// Also save the results in machine readable form for dashboards.
+	jsonResults, err := json.MarshalIndent(r.perTestResults, "", "  ")
+	if err != nil {
+		log.Printf("Cannot serialize test results: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(path.Join(r.resultsDir, "TESTRESULTS.json"), jsonResults, 0644); err != nil {
+		log.Printf("Cannot save test results: %v", err)
+	}
+}
+
+// writeSection writes a list of the tests with the row returned for them under header,
+// skipping the tests for which row returns an empty string. Nothing is written if row
+// returns an empty string for all tests.
+func (r *PerformanceResults) writeSection(header string, row func(testResult *PerformanceTestResult) string) {
+	prefix := "\n" + header + ":\n"
+	for _, testResult := range r.perTestResults {
+		text := row(testResult)
+		if text == "" {
+			continue
+		}
+		_, _ = io.WriteString(r.resultsFile, fmt.Sprintf("%s- %s: %s\n", prefix, testResult.testName, text))
+		prefix = ""
+	}
+}
+
+// formatRunMetadata formats metadata as space separated key=value pairs sorted by key.
+func formatRunMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+metadata[k])
+	}
+	return strings.Join(pairs, " ")
 }
 
 // Add results for one test.
@@ -103,6 +466,7 @@ func (r *PerformanceResults) Add(_ string, result interface{}) {
 			testResult.errorCause,
 		),
 	)
+	r.perTestResults = append(r.perTestResults, testResult)
 	r.totalDuration += testResult.duration
 }
 
@@ -0,0 +1,246 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformanceResultsRunMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	metadata := map[string]string{"commit": "abc123", "branch": "main", "host": "ci-runner-1"}
+	tc := &TestCase{}
+	tc.SetRunMetadata(metadata)
+	// Changes after setting must not affect the recorded metadata.
+	metadata["commit"] = "def456"
+
+	results := &PerformanceResults{}
+	results.Init(dir)
+	results.Add("TestTrace10kSPS", &PerformanceTestResult{
+		testName:          "Trace10kSPS",
+		result:            "PASS",
+		duration:          15 * time.Second,
+		sentSpanCount:     150000,
+		receivedSpanCount: 150000,
+		runMetadata:       tc.RunMetadata(),
+	})
+	results.Add("TestMetric10kDPS", &PerformanceTestResult{
+		testName: "Metric10kDPS",
+		result:   "PASS",
+	})
+	results.Save()
+
+	data, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.json"))
+	require.NoError(t, err)
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &records))
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "Trace10kSPS", records[0]["test_name"])
+	assert.EqualValues(t, 150000, records[0]["sent_items"])
+	assert.Equal(t, map[string]interface{}{"commit": "abc123", "branch": "main", "host": "ci-runner-1"}, records[0]["metadata"])
+	assert.NotContains(t, records[1], "metadata")
+
+	md, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "\nRun metadata:\n- Trace10kSPS: branch=main commit=abc123 host=ci-runner-1\n")
+}
+
+func TestPerformanceTestResultEfficiency(t *testing.T) {
+	result := &PerformanceTestResult{
+		duration:         10 * time.Second,
+		cpuPercentageAvg: 50,
+		ramMibMax:        100,
+		sentSpanCount:    100_000,
+	}
+	// 5 CPU seconds for 0.1 million items.
+	assert.InDelta(t, 50, result.cpuSecondsPerMillionItems(), 1e-9)
+	// 100 MiB at 10k items/sec.
+	assert.InDelta(t, 100*1024*1024/10, result.ramBytesPer1kItemsPerSec(), 1e-9)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.InDelta(t, 50, record["cpu_seconds_per_million_items"], 1e-9)
+	assert.InDelta(t, 100*1024*1024/10, record["ram_bytes_per_1k_items_per_sec"], 1e-9)
+
+	// Nothing sent, e.g. the agent failed at startup.
+	empty := &PerformanceTestResult{cpuPercentageAvg: 50, ramMibMax: 100}
+	assert.Zero(t, empty.cpuSecondsPerMillionItems())
+	assert.Zero(t, empty.ramBytesPer1kItemsPerSec())
+}
+
+func TestPerformanceTestResultItemsPerRequest(t *testing.T) {
+	result := &PerformanceTestResult{receivedSpanCount: 1000, receivedRequests: 8}
+	assert.InDelta(t, 125, result.itemsPerRequest(), 1e-9)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.EqualValues(t, 8, record["received_requests"])
+	assert.InDelta(t, 125, record["items_per_request"], 1e-9)
+
+	// Nothing received.
+	data, err = json.Marshal(&PerformanceTestResult{receivedSpanCount: 1000})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "items_per_request")
+}
+
+func TestPerformanceResultsFeatureGates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	results := &PerformanceResults{}
+	results.Init(dir)
+	results.Add("TestTrace10kSPSGated", &PerformanceTestResult{
+		testName:          "Trace10kSPSGated",
+		result:            "PASS",
+		agentFeatureGates: []string{"example.gate", "-other.gate"},
+	})
+	results.Add("TestTrace10kSPS", &PerformanceTestResult{
+		testName: "Trace10kSPS",
+		result:   "PASS",
+	})
+	results.Save()
+
+	data, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.json"))
+	require.NoError(t, err)
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &records))
+	require.Len(t, records, 2)
+	assert.Equal(t, []interface{}{"example.gate", "-other.gate"}, records[0]["agent_feature_gates"])
+	assert.NotContains(t, records[1], "agent_feature_gates")
+
+	md, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "\nAgent feature gates:\n- Trace10kSPSGated: example.gate,-other.gate\n")
+}
+
+func TestPerformanceResultsThreadsMax(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	results := &PerformanceResults{}
+	results.Init(dir)
+	results.Add("TestTrace10kSPS", &PerformanceTestResult{testName: "Trace10kSPS", result: "PASS", threadsMax: 17})
+	results.Add("TestMetric10kDPS", &PerformanceTestResult{testName: "Metric10kDPS", result: "PASS"})
+	results.Save()
+
+	data, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.json"))
+	require.NoError(t, err)
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &records))
+	require.Len(t, records, 2)
+	assert.EqualValues(t, 17, records[0]["threads_max"])
+	assert.NotContains(t, records[1], "threads_max")
+
+	md, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "\nOS threads:\n- Trace10kSPS: peak 17\n")
+}
+
+func TestPerformanceResultsLoadGeneratorResources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	results := &PerformanceResults{}
+	results.Init(dir)
+	results.Add("TestTrace10kSPS", &PerformanceTestResult{
+		testName: "Trace10kSPS",
+		result:   "PASS",
+		loadGeneratorResources: &ResourceConsumption{
+			CPUPercentAvg: 12.5,
+			CPUPercentMax: 20,
+			RAMMiBAvg:     30,
+			RAMMiBMax:     42,
+		},
+	})
+	results.Add("TestMetric10kDPS", &PerformanceTestResult{testName: "Metric10kDPS", result: "PASS"})
+	results.Save()
+
+	data, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.json"))
+	require.NoError(t, err)
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &records))
+	require.Len(t, records, 2)
+	assert.Equal(t, map[string]interface{}{
+		"cpu_percentage_avg": 12.5,
+		"cpu_percentage_max": 20.0,
+		"ram_mib_avg":        30.0,
+		"ram_mib_max":        42.0,
+	}, records[0]["load_generator"])
+	assert.NotContains(t, records[1], "load_generator")
+
+	md, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(md),
+		"\nLoad generator process:\n- Trace10kSPS: CPU 12.5% avg, 20.0% max, RAM 30 MiB avg, 42 MiB max\n")
+}
+
+func TestPerformanceResultsExporterNumConsumers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	results := &PerformanceResults{}
+	results.Init(dir)
+	results.Add("TestTraceSendingQueueConsumers/1Consumer", &PerformanceTestResult{
+		testName:             "TraceSendingQueueConsumers/1Consumer",
+		result:               "PASS",
+		duration:             10 * time.Second,
+		receivedSpanCount:    50_000,
+		exporterNumConsumers: 1,
+	})
+	results.Add("TestTraceSendingQueueConsumers/10Consumers", &PerformanceTestResult{
+		testName:             "TraceSendingQueueConsumers/10Consumers",
+		result:               "PASS",
+		duration:             10 * time.Second,
+		receivedSpanCount:    100_000,
+		exporterNumConsumers: 10,
+	})
+	results.Add("TestTrace10kSPS", &PerformanceTestResult{testName: "Trace10kSPS", result: "PASS"})
+	results.Save()
+
+	data, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.json"))
+	require.NoError(t, err)
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &records))
+	require.Len(t, records, 3)
+	assert.EqualValues(t, 1, records[0]["exporter_num_consumers"])
+	assert.EqualValues(t, 10, records[1]["exporter_num_consumers"])
+	assert.NotContains(t, records[2], "exporter_num_consumers")
+
+	md, err := ioutil.ReadFile(path.Join(dir, "TESTRESULTS.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "\nThroughput by exporter queue consumers:\n"+
+		"- TraceSendingQueueConsumers/1Consumer: 1 consumers, 5000.0 items/sec\n"+
+		"- TraceSendingQueueConsumers/10Consumers: 10 consumers, 10000.0 items/sec\n")
+}
@@ -0,0 +1,180 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ScenarioParams are the parameters of a scenario which can be changed without editing
+// code, so that the same test binary can run many configurations. Only the scenarios
+// which call ReadScenarioParams use them, other scenarios keep their fixed parameters
+// so that their results stay comparable. ReadScenarioParams reads them from the
+// following command line flags, which are passed to a test binary after -args and are
+// registered by DoTestMain, or environment variables:
+//
+//	-testbed.items_per_second  TESTBED_ITEMS_PER_SECOND  DataItemsPerSecond
+//	-testbed.items_per_batch   TESTBED_ITEMS_PER_BATCH   ItemsPerBatch
+//	-testbed.duration          TESTCASE_DURATION         Duration
+//	-testbed.sender            TESTBED_SENDER            Sender
+//	-testbed.receiver          TESTBED_RECEIVER          Receiver
+//
+// A flag takes precedence over the environment variable, parameters set by neither keep
+// the default of the scenario.
+type ScenarioParams struct {
+	// DataItemsPerSecond and ItemsPerBatch are used for the LoadOptions of the scenario.
+	DataItemsPerSecond int
+	ItemsPerBatch      int
+
+	// Duration of the load phase, see TestCase.Duration.
+	Duration time.Duration
+
+	// Protocols of the sender and of the receiver of trace data, see NewTraceDataSender
+	// and NewTraceDataReceiver.
+	Sender   string
+	Receiver string
+}
+
+// scenarioParam describes a parameter of ScenarioParams and how it is set.
+type scenarioParam struct {
+	flag   string
+	envVar string
+	set    func(params *ScenarioParams, value string) error
+}
+
+var scenarioParamList = []scenarioParam{
+	{
+		flag:   "testbed.items_per_second",
+		envVar: "TESTBED_ITEMS_PER_SECOND",
+		set: func(params *ScenarioParams, value string) (err error) {
+			params.DataItemsPerSecond, err = strconv.Atoi(value)
+			return err
+		},
+	},
+	{
+		flag:   "testbed.items_per_batch",
+		envVar: "TESTBED_ITEMS_PER_BATCH",
+		set: func(params *ScenarioParams, value string) (err error) {
+			params.ItemsPerBatch, err = strconv.Atoi(value)
+			return err
+		},
+	},
+	{
+		flag:   "testbed.duration",
+		envVar: testcaseDurationVar,
+		set: func(params *ScenarioParams, value string) (err error) {
+			params.Duration, err = time.ParseDuration(value)
+			return err
+		},
+	},
+	{
+		flag:   "testbed.sender",
+		envVar: "TESTBED_SENDER",
+		set: func(params *ScenarioParams, value string) error {
+			params.Sender = value
+			return nil
+		},
+	},
+	{
+		flag:   "testbed.receiver",
+		envVar: "TESTBED_RECEIVER",
+		set: func(params *ScenarioParams, value string) error {
+			params.Receiver = value
+			return nil
+		},
+	},
+}
+
+// registerScenarioFlags defines the flags of all scenario parameters in fs.
+func registerScenarioFlags(fs *flag.FlagSet) {
+	for _, param := range scenarioParamList {
+		fs.String(param.flag, "", fmt.Sprintf("Overrides the scenario parameter set by %s.", param.envVar))
+	}
+}
+
+// ReadScenarioParams returns defaults with the parameters set by the command line flags
+// or the environment variables overridden, see ScenarioParams.
+func ReadScenarioParams(defaults ScenarioParams) (ScenarioParams, error) {
+	return readScenarioParams(defaults, flag.CommandLine, os.LookupEnv)
+}
+
+func readScenarioParams(defaults ScenarioParams, fs *flag.FlagSet, lookupEnv func(string) (string, bool)) (ScenarioParams, error) {
+	flagValues := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flagValues[f.Name] = f.Value.String()
+	})
+
+	params := defaults
+	for _, param := range scenarioParamList {
+		source := "-" + param.flag
+		value, ok := flagValues[param.flag]
+		if !ok {
+			source = param.envVar
+			value, ok = lookupEnv(param.envVar)
+		}
+		if !ok || value == "" {
+			continue
+		}
+		if err := param.set(&params, value); err != nil {
+			return defaults, fmt.Errorf("invalid %s %q: %s", source, value, err.Error())
+		}
+	}
+	return params, nil
+}
+
+// LoadOptions returns LoadOptions with the rate and batch size of the parameters.
+func (params ScenarioParams) LoadOptions() LoadOptions {
+	return LoadOptions{DataItemsPerSecond: params.DataItemsPerSecond, ItemsPerBatch: params.ItemsPerBatch}
+}
+
+// NewTraceDataSender creates a sender of trace data using protocol, one of "otlp",
+// "otlphttp", "jaeger", "opencensus" or "zipkin", to the agent on the given port.
+func NewTraceDataSender(protocol string, port int) (DataSender, error) {
+	switch protocol {
+	case "otlp":
+		return NewOTLPTraceDataSender(DefaultHost, port), nil
+	case "otlphttp":
+		return NewOTLPHTTPTraceDataSender(DefaultHost, port), nil
+	case "jaeger":
+		return NewJaegerGRPCDataSender(DefaultHost, port), nil
+	case "opencensus":
+		return NewOCTraceDataSender(DefaultHost, port), nil
+	case "zipkin":
+		return NewZipkinDataSender(DefaultHost, port), nil
+	}
+	return nil, fmt.Errorf("unknown trace sender %q", protocol)
+}
+
+// NewTraceDataReceiver creates a MockBackend receiver of trace data using protocol, one
+// of "otlp", "otlphttp", "jaeger", "opencensus" or "zipkin", on the given port.
+func NewTraceDataReceiver(protocol string, port int) (DataReceiver, error) {
+	switch protocol {
+	case "otlp":
+		return NewOTLPDataReceiver(port), nil
+	case "otlphttp":
+		return NewOTLPHTTPDataReceiver(port), nil
+	case "jaeger":
+		return NewJaegerDataReceiver(port), nil
+	case "opencensus":
+		return NewOCDataReceiver(port), nil
+	case "zipkin":
+		return NewZipkinDataReceiver(port), nil
+	}
+	return nil, fmt.Errorf("unknown trace receiver %q", protocol)
+}
@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadScenarioParams(t *testing.T) {
+	defaults := ScenarioParams{
+		DataItemsPerSecond: 10_000,
+		ItemsPerBatch:      100,
+		Duration:           15 * time.Second,
+		Sender:             "otlp",
+		Receiver:           "otlp",
+	}
+	env := map[string]string{
+		"TESTBED_ITEMS_PER_SECOND": "20000",
+		"TESTBED_ITEMS_PER_BATCH":  "50",
+		"TESTCASE_DURATION":        "1m",
+		"TESTBED_SENDER":           "jaeger",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerScenarioFlags(fs)
+	params, err := readScenarioParams(defaults, fs, lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioParams{
+		DataItemsPerSecond: 20_000,
+		ItemsPerBatch:      50,
+		Duration:           time.Minute,
+		Sender:             "jaeger",
+		Receiver:           "otlp",
+	}, params)
+	assert.Equal(t, LoadOptions{DataItemsPerSecond: 20_000, ItemsPerBatch: 50}, params.LoadOptions())
+
+	// Flags take precedence over the environment.
+	require.NoError(t, fs.Parse([]string{"-testbed.items_per_second=5000", "-testbed.receiver=zipkin"}))
+	params, err = readScenarioParams(defaults, fs, lookupEnv)
+	require.NoError(t, err)
+	assert.Equal(t, 5_000, params.DataItemsPerSecond)
+	assert.Equal(t, 50, params.ItemsPerBatch)
+	assert.Equal(t, "zipkin", params.Receiver)
+
+	env["TESTBED_ITEMS_PER_BATCH"] = "many"
+	_, err = readScenarioParams(defaults, fs, lookupEnv)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid TESTBED_ITEMS_PER_BATCH "many"`)
+
+	// ReadScenarioParams reads the real environment.
+	require.NoError(t, os.Setenv("TESTBED_ITEMS_PER_BATCH", "25"))
+	defer os.Unsetenv("TESTBED_ITEMS_PER_BATCH")
+	params, err = ReadScenarioParams(defaults)
+	require.NoError(t, err)
+	assert.Equal(t, 25, params.ItemsPerBatch)
+}
+
+func TestNewTraceDataSenderAndReceiver(t *testing.T) {
+	for _, protocol := range []string{"otlp", "otlphttp", "jaeger", "opencensus", "zipkin"} {
+		sender, err := NewTraceDataSender(protocol, 4317)
+		require.NoError(t, err, protocol)
+		assert.Implements(t, (*TraceDataSender)(nil), sender, protocol)
+		_, err = NewTraceDataReceiver(protocol, 4318)
+		require.NoError(t, err, protocol)
+	}
+
+	_, err := NewTraceDataSender("carrier-pigeon", 4317)
+	assert.EqualError(t, err, `unknown trace sender "carrier-pigeon"`)
+	_, err = NewTraceDataReceiver("carrier-pigeon", 4318)
+	assert.EqualError(t, err, `unknown trace receiver "carrier-pigeon"`)
+}
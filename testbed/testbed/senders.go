@@ -15,18 +15,30 @@
 package testbed
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
 
+	jaegerzipkin "github.com/jaegertracing/jaeger/model/converter/thrift/zipkin"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configauth"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/config/configmodels"
 	"go.opentelemetry.io/collector/config/configtls"
 	"go.opentelemetry.io/collector/consumer"
@@ -37,6 +49,12 @@ import (
 	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
 	"go.opentelemetry.io/collector/exporter/prometheusexporter"
 	"go.opentelemetry.io/collector/exporter/zipkinexporter"
+	"go.opentelemetry.io/collector/internal"
+	collectorlog "go.opentelemetry.io/collector/internal/data/protogen/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/collector/internal/data/protogen/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/collector/internal/data/protogen/collector/trace/v1"
+	"go.opentelemetry.io/collector/translator/conventions"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
 )
 
 // DataSender defines the interface that allows sending data. This is an interface
@@ -86,10 +104,23 @@ type LogDataSender interface {
 type DataSenderBase struct {
 	Port int
 	Host string
+	// ProxyEndpoint is the address in host:port form of a proxy which forwards to the
+	// endpoint of the collector receiver, for example of a TCPProxy. If set the sender
+	// sends to the proxy instead of directly to the collector.
+	ProxyEndpoint string
 }
 
 func (dsb *DataSenderBase) GetEndpoint() string {
-	return fmt.Sprintf("%s:%d", dsb.Host, dsb.Port)
+	return net.JoinHostPort(dsb.Host, strconv.Itoa(dsb.Port))
+}
+
+// GetClientEndpoint returns the address to which the sender sends, which is
+// ProxyEndpoint if set or the collector receiver endpoint otherwise.
+func (dsb *DataSenderBase) GetClientEndpoint() string {
+	if dsb.ProxyEndpoint != "" {
+		return dsb.ProxyEndpoint
+	}
+	return dsb.GetEndpoint()
 }
 
 func (dsb *DataSenderBase) ReportFatalError(err error) {
@@ -138,7 +169,7 @@ func (je *JaegerGRPCDataSender) Start() error {
 	cfg.RetrySettings.Enabled = false
 	// Disable sending queue, we should push data from the caller goroutine.
 	cfg.QueueSettings.Enabled = false
-	cfg.Endpoint = je.GetEndpoint()
+	cfg.Endpoint = je.GetClientEndpoint()
 	cfg.TLSSetting = configtls.TLSClientSetting{
 		Insecure: true,
 	}
@@ -169,7 +200,7 @@ type ocDataSender struct {
 }
 
 func (ods *ocDataSender) fillConfig(cfg *opencensusexporter.Config) *opencensusexporter.Config {
-	cfg.Endpoint = ods.GetEndpoint()
+	cfg.Endpoint = ods.GetClientEndpoint()
 	cfg.TLSSetting = configtls.TLSClientSetting{
 		Insecure: true,
 	}
@@ -257,10 +288,16 @@ func (ome *OCMetricsDataSender) Start() error {
 
 type otlpHTTPDataSender struct {
 	DataSenderBase
+	encoding string
+	// Headers sent with every request in addition to the ones set by the exporter.
+	headers map[string]string
+	// Read and idle timeouts of the OTLP/HTTP receiver of the agent, 0 if not set.
+	receiverReadTimeout time.Duration
+	receiverIdleTimeout time.Duration
 }
 
 func (ods *otlpHTTPDataSender) fillConfig(cfg *otlphttpexporter.Config) *otlphttpexporter.Config {
-	cfg.Endpoint = fmt.Sprintf("http://%s", ods.GetEndpoint())
+	cfg.Endpoint = fmt.Sprintf("http://%s", ods.GetClientEndpoint())
 	// Disable retries, we should push data and if error just log it.
 	cfg.RetrySettings.Enabled = false
 	// Disable sending queue, we should push data from the caller goroutine.
@@ -268,16 +305,36 @@ func (ods *otlpHTTPDataSender) fillConfig(cfg *otlphttpexporter.Config) *otlphtt
 	cfg.TLSSetting = configtls.TLSClientSetting{
 		Insecure: true,
 	}
+	cfg.Encoding = ods.encoding
+	for k, v := range ods.headers {
+		cfg.Headers[k] = v
+	}
 	return cfg
 }
 
 func (ods *otlpHTTPDataSender) GenConfigYAMLStr() string {
 	// Note that this generates a receiver config for agent.
-	return fmt.Sprintf(`
+	str := fmt.Sprintf(`
   otlp:
     protocols:
       http:
         endpoint: "%s"`, ods.GetEndpoint())
+	if ods.receiverReadTimeout != 0 {
+		str += fmt.Sprintf(`
+        read_timeout: %s`, ods.receiverReadTimeout)
+	}
+	if ods.receiverIdleTimeout != 0 {
+		str += fmt.Sprintf(`
+        idle_timeout: %s`, ods.receiverIdleTimeout)
+	}
+	return str
+}
+
+// setReceiverTimeouts sets the read and idle timeouts of the OTLP/HTTP receiver of the
+// agent, see OTLPHTTPTraceDataSender.WithReceiverTimeouts.
+func (ods *otlpHTTPDataSender) setReceiverTimeouts(readTimeout, idleTimeout time.Duration) {
+	ods.receiverReadTimeout = readTimeout
+	ods.receiverIdleTimeout = idleTimeout
 }
 
 func (ods *otlpHTTPDataSender) ProtocolName() string {
@@ -305,6 +362,28 @@ func NewOTLPHTTPTraceDataSender(host string, port int) *OTLPHTTPTraceDataSender
 	}
 }
 
+// WithHTTPEncoding sets the encoding of the requests, one of otlphttpexporter.EncodingProto
+// (the default) or otlphttpexporter.EncodingJSON.
+func (ote *OTLPHTTPTraceDataSender) WithHTTPEncoding(encoding string) *OTLPHTTPTraceDataSender {
+	ote.encoding = encoding
+	return ote
+}
+
+// WithHTTPHeaders sets headers, for example a tenant ID or a routing key required by a
+// gateway, which are sent with every request.
+func (ote *OTLPHTTPTraceDataSender) WithHTTPHeaders(headers map[string]string) *OTLPHTTPTraceDataSender {
+	ote.headers = headers
+	return ote
+}
+
+// WithReceiverTimeouts sets the read_timeout and idle_timeout of the OTLP/HTTP receiver
+// of the agent, which disconnects clients not completing a request within readTimeout.
+// A zero duration leaves the setting unset.
+func (ote *OTLPHTTPTraceDataSender) WithReceiverTimeouts(readTimeout, idleTimeout time.Duration) *OTLPHTTPTraceDataSender {
+	ote.setReceiverTimeouts(readTimeout, idleTimeout)
+	return ote
+}
+
 func (ote *OTLPHTTPTraceDataSender) Start() error {
 	factory := otlphttpexporter.NewFactory()
 	cfg := ote.fillConfig(factory.CreateDefaultConfig().(*otlphttpexporter.Config))
@@ -339,6 +418,28 @@ func NewOTLPHTTPMetricDataSender(host string, port int) *OTLPHTTPMetricsDataSend
 	}
 }
 
+// WithHTTPEncoding sets the encoding of the requests, one of otlphttpexporter.EncodingProto
+// (the default) or otlphttpexporter.EncodingJSON.
+func (ome *OTLPHTTPMetricsDataSender) WithHTTPEncoding(encoding string) *OTLPHTTPMetricsDataSender {
+	ome.encoding = encoding
+	return ome
+}
+
+// WithHTTPHeaders sets headers, for example a tenant ID or a routing key required by a
+// gateway, which are sent with every request.
+func (ome *OTLPHTTPMetricsDataSender) WithHTTPHeaders(headers map[string]string) *OTLPHTTPMetricsDataSender {
+	ome.headers = headers
+	return ome
+}
+
+// WithReceiverTimeouts sets the read_timeout and idle_timeout of the OTLP/HTTP receiver
+// of the agent, which disconnects clients not completing a request within readTimeout.
+// A zero duration leaves the setting unset.
+func (ome *OTLPHTTPMetricsDataSender) WithReceiverTimeouts(readTimeout, idleTimeout time.Duration) *OTLPHTTPMetricsDataSender {
+	ome.setReceiverTimeouts(readTimeout, idleTimeout)
+	return ome
+}
+
 func (ome *OTLPHTTPMetricsDataSender) Start() error {
 	factory := otlphttpexporter.NewFactory()
 	cfg := ome.fillConfig(factory.CreateDefaultConfig().(*otlphttpexporter.Config))
@@ -373,6 +474,28 @@ func NewOTLPHTTPLogsDataSender(host string, port int) *OTLPHTTPLogsDataSender {
 	}
 }
 
+// WithHTTPEncoding sets the encoding of the requests, one of otlphttpexporter.EncodingProto
+// (the default) or otlphttpexporter.EncodingJSON.
+func (olds *OTLPHTTPLogsDataSender) WithHTTPEncoding(encoding string) *OTLPHTTPLogsDataSender {
+	olds.encoding = encoding
+	return olds
+}
+
+// WithHTTPHeaders sets headers, for example a tenant ID or a routing key required by a
+// gateway, which are sent with every request.
+func (olds *OTLPHTTPLogsDataSender) WithHTTPHeaders(headers map[string]string) *OTLPHTTPLogsDataSender {
+	olds.headers = headers
+	return olds
+}
+
+// WithReceiverTimeouts sets the read_timeout and idle_timeout of the OTLP/HTTP receiver
+// of the agent, which disconnects clients not completing a request within readTimeout.
+// A zero duration leaves the setting unset.
+func (olds *OTLPHTTPLogsDataSender) WithReceiverTimeouts(readTimeout, idleTimeout time.Duration) *OTLPHTTPLogsDataSender {
+	olds.setReceiverTimeouts(readTimeout, idleTimeout)
+	return olds
+}
+
 func (olds *OTLPHTTPLogsDataSender) Start() error {
 	factory := otlphttpexporter.NewFactory()
 	cfg := olds.fillConfig(factory.CreateDefaultConfig().(*otlphttpexporter.Config))
@@ -387,10 +510,34 @@ func (olds *OTLPHTTPLogsDataSender) Start() error {
 
 type otlpDataSender struct {
 	DataSenderBase
+	// Authentication required by the OTLP receiver of the agent, nil if none.
+	receiverAuth *configauth.Authentication
+	// Value of the authorization header sent with every request, "" if none.
+	authHeader string
+	// Keepalive settings of the OTLP receiver of the agent, nil if not set.
+	receiverKeepalive *configgrpc.KeepaliveServerConfig
+}
+
+// setAuth enables auth on the OTLP receiver of the agent and makes the sender attach
+// authHeader to every request, see OTLPTraceDataSender.WithAuth.
+func (ods *otlpDataSender) setAuth(auth configauth.Authentication, authHeader string) {
+	ods.receiverAuth = &auth
+	ods.authHeader = authHeader
+}
+
+// outgoingContext returns ctx with the authorization header of the sender attached.
+func (ods *otlpDataSender) outgoingContext(ctx context.Context) context.Context {
+	if ods.authHeader == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", ods.authHeader)
 }
 
 func (ods *otlpDataSender) fillConfig(cfg *otlpexporter.Config) *otlpexporter.Config {
-	cfg.Endpoint = ods.GetEndpoint()
+	cfg.Endpoint = ods.GetClientEndpoint()
+	if ods.authHeader != "" {
+		cfg.Headers = map[string]string{"authorization": ods.authHeader}
+	}
 	// Disable retries, we should push data and if error just log it.
 	cfg.RetrySettings.Enabled = false
 	// Disable sending queue, we should push data from the caller goroutine.
@@ -407,7 +554,69 @@ func (ods *otlpDataSender) GenConfigYAMLStr() string {
   otlp:
     protocols:
       grpc:
-        endpoint: "%s"`, ods.GetEndpoint())
+        endpoint: "%s"`, ods.GetEndpoint()) + ods.authConfigYAMLStr() + ods.keepaliveConfigYAMLStr()
+}
+
+// setReceiverKeepalive sets the keepalive settings of the OTLP receiver of the agent,
+// see OTLPTraceDataSender.WithReceiverKeepalive.
+func (ods *otlpDataSender) setReceiverKeepalive(keepalive configgrpc.KeepaliveServerConfig) {
+	ods.receiverKeepalive = &keepalive
+}
+
+// keepaliveConfigYAMLStr generates the keepalive settings of the gRPC protocol of the
+// OTLP receiver, "" if they are not set. Zero durations are omitted.
+func (ods *otlpDataSender) keepaliveConfigYAMLStr() string {
+	keepalive := ods.receiverKeepalive
+	if keepalive == nil {
+		return ""
+	}
+	str := `
+        keepalive:`
+	if params := keepalive.ServerParameters; params != nil {
+		str += `
+          server_parameters:`
+		for _, setting := range []struct {
+			name  string
+			value time.Duration
+		}{
+			{"max_connection_idle", params.MaxConnectionIdle},
+			{"max_connection_age", params.MaxConnectionAge},
+			{"max_connection_age_grace", params.MaxConnectionAgeGrace},
+			{"time", params.Time},
+			{"timeout", params.Timeout},
+		} {
+			if setting.value != 0 {
+				str += fmt.Sprintf(`
+            %s: %s`, setting.name, setting.value)
+			}
+		}
+	}
+	if policy := keepalive.EnforcementPolicy; policy != nil {
+		str += fmt.Sprintf(`
+          enforcement_policy:
+            min_time: %s
+            permit_without_stream: %t`, policy.MinTime, policy.PermitWithoutStream)
+	}
+	return str
+}
+
+// authConfigYAMLStr generates the auth settings of the gRPC protocol of the OTLP
+// receiver, "" if auth is not enabled.
+func (ods *otlpDataSender) authConfigYAMLStr() string {
+	auth := ods.receiverAuth
+	if auth == nil {
+		return ""
+	}
+	if auth.Basic != nil {
+		return fmt.Sprintf(`
+        auth:
+          basic:
+            username: %q
+            password: %q`, auth.Basic.Username, auth.Basic.Password)
+	}
+	return fmt.Sprintf(`
+        auth:
+          bearer_token: %q`, auth.BearerToken)
 }
 
 func (ods *otlpDataSender) ProtocolName() string {
@@ -435,6 +644,22 @@ func NewOTLPTraceDataSender(host string, port int) *OTLPTraceDataSender {
 	}
 }
 
+// WithAuth enables auth on the OTLP receiver of the agent, which then accepts only
+// requests authenticated as configured by auth, and makes the sender attach authHeader,
+// for example configauth.BearerTokenHeader("token"), to every request. Only static
+// credentials, BearerToken or Basic, are supported.
+func (ote *OTLPTraceDataSender) WithAuth(auth configauth.Authentication, authHeader string) *OTLPTraceDataSender {
+	ote.setAuth(auth, authHeader)
+	return ote
+}
+
+// WithReceiverKeepalive sets the keepalive settings of the OTLP receiver of the agent,
+// for example to close idle or long-lived client connections.
+func (ote *OTLPTraceDataSender) WithReceiverKeepalive(keepalive configgrpc.KeepaliveServerConfig) *OTLPTraceDataSender {
+	ote.setReceiverKeepalive(keepalive)
+	return ote
+}
+
 func (ote *OTLPTraceDataSender) Start() error {
 	factory := otlpexporter.NewFactory()
 	cfg := ote.fillConfig(factory.CreateDefaultConfig().(*otlpexporter.Config))
@@ -469,6 +694,22 @@ func NewOTLPMetricDataSender(host string, port int) *OTLPMetricsDataSender {
 	}
 }
 
+// WithAuth enables auth on the OTLP receiver of the agent, which then accepts only
+// requests authenticated as configured by auth, and makes the sender attach authHeader,
+// for example configauth.BearerTokenHeader("token"), to every request. Only static
+// credentials, BearerToken or Basic, are supported.
+func (ome *OTLPMetricsDataSender) WithAuth(auth configauth.Authentication, authHeader string) *OTLPMetricsDataSender {
+	ome.setAuth(auth, authHeader)
+	return ome
+}
+
+// WithReceiverKeepalive sets the keepalive settings of the OTLP receiver of the agent,
+// for example to close idle or long-lived client connections.
+func (ome *OTLPMetricsDataSender) WithReceiverKeepalive(keepalive configgrpc.KeepaliveServerConfig) *OTLPMetricsDataSender {
+	ome.setReceiverKeepalive(keepalive)
+	return ome
+}
+
 func (ome *OTLPMetricsDataSender) Start() error {
 	factory := otlpexporter.NewFactory()
 	cfg := ome.fillConfig(factory.CreateDefaultConfig().(*otlpexporter.Config))
@@ -503,6 +744,22 @@ func NewOTLPLogsDataSender(host string, port int) *OTLPLogsDataSender {
 	}
 }
 
+// WithAuth enables auth on the OTLP receiver of the agent, which then accepts only
+// requests authenticated as configured by auth, and makes the sender attach authHeader,
+// for example configauth.BearerTokenHeader("token"), to every request. Only static
+// credentials, BearerToken or Basic, are supported.
+func (olds *OTLPLogsDataSender) WithAuth(auth configauth.Authentication, authHeader string) *OTLPLogsDataSender {
+	olds.setAuth(auth, authHeader)
+	return olds
+}
+
+// WithReceiverKeepalive sets the keepalive settings of the OTLP receiver of the agent,
+// for example to close idle or long-lived client connections.
+func (olds *OTLPLogsDataSender) WithReceiverKeepalive(keepalive configgrpc.KeepaliveServerConfig) *OTLPLogsDataSender {
+	olds.setReceiverKeepalive(keepalive)
+	return olds
+}
+
 func (olds *OTLPLogsDataSender) Start() error {
 	factory := otlpexporter.NewFactory()
 	cfg := olds.fillConfig(factory.CreateDefaultConfig().(*otlpexporter.Config))
@@ -515,6 +772,90 @@ func (olds *OTLPLogsDataSender) Start() error {
 	return exp.Start(context.Background(), olds)
 }
 
+// OTLPDataSender implements TraceDataSender, MetricDataSender and LogDataSender for OTLP
+// sending all signals over a single gRPC connection, like the SDKs do, unlike the
+// OTLP exporter which uses a connection per signal. A LoadGenerator using it sends
+// traces, metrics and logs.
+type OTLPDataSender struct {
+	otlpDataSender
+	conn          *grpc.ClientConn
+	traceClient   collectortrace.TraceServiceClient
+	metricsClient collectormetrics.MetricsServiceClient
+	logsClient    collectorlog.LogsServiceClient
+}
+
+// Ensure OTLPDataSender implements all data senders.
+var _ TraceDataSender = (*OTLPDataSender)(nil)
+var _ MetricDataSender = (*OTLPDataSender)(nil)
+var _ LogDataSender = (*OTLPDataSender)(nil)
+
+// NewOTLPDataSender creates a new OTLP sender for all signals that will send to the
+// specified port after Start is called.
+func NewOTLPDataSender(host string, port int) *OTLPDataSender {
+	return &OTLPDataSender{
+		otlpDataSender: otlpDataSender{
+			DataSenderBase: DataSenderBase{
+				Port: port,
+				Host: host,
+			},
+		},
+	}
+}
+
+// WithAuth enables auth on the OTLP receiver of the agent, which then accepts only
+// requests authenticated as configured by auth, and makes the sender attach authHeader,
+// for example configauth.BearerTokenHeader("token"), to every request. Only static
+// credentials, BearerToken or Basic, are supported.
+func (ods *OTLPDataSender) WithAuth(auth configauth.Authentication, authHeader string) *OTLPDataSender {
+	ods.setAuth(auth, authHeader)
+	return ods
+}
+
+// WithReceiverKeepalive sets the keepalive settings of the OTLP receiver of the agent,
+// for example to close idle or long-lived client connections.
+func (ods *OTLPDataSender) WithReceiverKeepalive(keepalive configgrpc.KeepaliveServerConfig) *OTLPDataSender {
+	ods.setReceiverKeepalive(keepalive)
+	return ods
+}
+
+func (ods *OTLPDataSender) Start() error {
+	// Dial like the OTLP exporter does.
+	cfg := ods.fillConfig(otlpexporter.NewFactory().CreateDefaultConfig().(*otlpexporter.Config))
+	dialOpts, err := cfg.GRPCClientSettings.ToDialOptions()
+	if err != nil {
+		return err
+	}
+	if ods.conn, err = grpc.Dial(cfg.GRPCClientSettings.Endpoint, dialOpts...); err != nil {
+		return err
+	}
+
+	ods.traceClient = collectortrace.NewTraceServiceClient(ods.conn)
+	ods.metricsClient = collectormetrics.NewMetricsServiceClient(ods.conn)
+	ods.logsClient = collectorlog.NewLogsServiceClient(ods.conn)
+	return nil
+}
+
+func (ods *OTLPDataSender) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	_, err := ods.traceClient.Export(ods.outgoingContext(ctx), &collectortrace.ExportTraceServiceRequest{
+		ResourceSpans: pdata.TracesToOtlp(td),
+	})
+	return err
+}
+
+func (ods *OTLPDataSender) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	_, err := ods.metricsClient.Export(ods.outgoingContext(ctx), &collectormetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: pdata.MetricsToOtlp(md),
+	})
+	return err
+}
+
+func (ods *OTLPDataSender) ConsumeLogs(ctx context.Context, ld pdata.Logs) error {
+	_, err := ods.logsClient.Export(ods.outgoingContext(ctx), &collectorlog.ExportLogsServiceRequest{
+		ResourceLogs: internal.LogsToOtlp(ld.InternalRep()),
+	})
+	return err
+}
+
 // ZipkinDataSender implements TraceDataSender for Zipkin http exporterType.
 type ZipkinDataSender struct {
 	DataSenderBase
@@ -538,7 +879,7 @@ func NewZipkinDataSender(host string, port int) *ZipkinDataSender {
 func (zs *ZipkinDataSender) Start() error {
 	factory := zipkinexporter.NewFactory()
 	cfg := factory.CreateDefaultConfig().(*zipkinexporter.Config)
-	cfg.Endpoint = fmt.Sprintf("http://%s/api/v2/spans", zs.GetEndpoint())
+	cfg.Endpoint = fmt.Sprintf("http://%s/api/v2/spans", zs.GetClientEndpoint())
 	// Disable retries, we should push data and if error just log it.
 	cfg.RetrySettings.Enabled = false
 	// Disable sending queue, we should push data from the caller goroutine.
@@ -563,6 +904,267 @@ func (zs *ZipkinDataSender) ProtocolName() string {
 	return "zipkin"
 }
 
+// ZipkinV1Encoding is the wire encoding used by ZipkinV1DataSender.
+type ZipkinV1Encoding string
+
+const (
+	// ZipkinV1Thrift sends spans as a thrift encoded list of zipkincore.Span.
+	ZipkinV1Thrift ZipkinV1Encoding = "thrift"
+	// ZipkinV1JSON sends spans using the legacy v1 JSON format.
+	ZipkinV1JSON ZipkinV1Encoding = "json"
+)
+
+// ZipkinV1DataSender implements TraceDataSender for the legacy Zipkin v1 HTTP API.
+// There is no v1 exporter in the Collector so spans are encoded and posted directly
+// to the "/api/v1/spans" endpoint of the zipkin receiver.
+type ZipkinV1DataSender struct {
+	DataSenderBase
+	encoding ZipkinV1Encoding
+	client   *http.Client
+}
+
+// Ensure ZipkinV1DataSender implements TraceDataSender.
+var _ TraceDataSender = (*ZipkinV1DataSender)(nil)
+
+// NewZipkinV1DataSender creates a new Zipkin v1 sender that will send thrift
+// encoded spans to the specified port after Start is called.
+func NewZipkinV1DataSender(host string, port int) *ZipkinV1DataSender {
+	return &ZipkinV1DataSender{
+		DataSenderBase: DataSenderBase{
+			Port: port,
+			Host: host,
+		},
+		encoding: ZipkinV1Thrift,
+	}
+}
+
+// WithEncoding sets the wire encoding of the sent spans.
+func (zs *ZipkinV1DataSender) WithEncoding(encoding ZipkinV1Encoding) *ZipkinV1DataSender {
+	zs.encoding = encoding
+	return zs
+}
+
+func (zs *ZipkinV1DataSender) Start() error {
+	zs.client = &http.Client{Timeout: 10 * time.Second}
+	return nil
+}
+
+func (zs *ZipkinV1DataSender) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
+	var body []byte
+	var contentType string
+	switch zs.encoding {
+	case ZipkinV1JSON:
+		var err error
+		if body, err = json.Marshal(tracesToZipkinV1JSON(td)); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	default:
+		body = jaegerzipkin.SerializeThrift(tracesToZipkinV1Thrift(td))
+		contentType = "application/x-thrift"
+	}
+
+	url := fmt.Sprintf("http://%s/api/v1/spans", zs.GetClientEndpoint())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := zs.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("zipkin v1 request failed with status %q", resp.Status)
+	}
+	return nil
+}
+
+func (zs *ZipkinV1DataSender) GenConfigYAMLStr() string {
+	str := fmt.Sprintf(`
+  zipkin:
+    endpoint: %s`, zs.GetEndpoint())
+
+	if zs.encoding == ZipkinV1JSON {
+		// JSON binary annotations are always strings, parse them back to
+		// typed attributes so that the load generator seqnums are preserved.
+		str += `
+    parse_string_tags: true`
+	}
+	return str
+}
+
+func (zs *ZipkinV1DataSender) ProtocolName() string {
+	return "zipkin"
+}
+
+// zipkinV1JSONSpan is the legacy Zipkin v1 JSON span model.
+type zipkinV1JSONSpan struct {
+	TraceID           string                         `json:"traceId"`
+	Name              string                         `json:"name,omitempty"`
+	ParentID          string                         `json:"parentId,omitempty"`
+	ID                string                         `json:"id"`
+	Timestamp         int64                          `json:"timestamp"`
+	Duration          int64                          `json:"duration"`
+	Annotations       []zipkinV1JSONAnnotation       `json:"annotations,omitempty"`
+	BinaryAnnotations []zipkinV1JSONBinaryAnnotation `json:"binaryAnnotations,omitempty"`
+}
+
+type zipkinV1JSONEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinV1JSONAnnotation struct {
+	Timestamp int64                 `json:"timestamp"`
+	Value     string                `json:"value"`
+	Endpoint  *zipkinV1JSONEndpoint `json:"endpoint,omitempty"`
+}
+
+type zipkinV1JSONBinaryAnnotation struct {
+	Key      string                `json:"key"`
+	Value    string                `json:"value"`
+	Endpoint *zipkinV1JSONEndpoint `json:"endpoint,omitempty"`
+}
+
+func tracesToZipkinV1JSON(td pdata.Traces) []zipkinV1JSONSpan {
+	var zSpans []zipkinV1JSONSpan
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		ep := &zipkinV1JSONEndpoint{ServiceName: zipkinV1ServiceName(rs.Resource())}
+		ilss := rs.InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				start, duration := zipkinV1Times(span)
+				zSpan := zipkinV1JSONSpan{
+					TraceID:   span.TraceID().HexString(),
+					ID:        span.SpanID().HexString(),
+					ParentID:  span.ParentSpanID().HexString(),
+					Name:      span.Name(),
+					Timestamp: start,
+					Duration:  duration,
+				}
+				for _, value := range zipkinV1KindAnnotations(span.Kind()) {
+					zSpan.Annotations = append(zSpan.Annotations, zipkinV1JSONAnnotation{
+						Timestamp: start,
+						Value:     value,
+						Endpoint:  ep,
+					})
+				}
+				span.Attributes().ForEach(func(k string, v pdata.AttributeValue) {
+					zSpan.BinaryAnnotations = append(zSpan.BinaryAnnotations, zipkinV1JSONBinaryAnnotation{
+						Key:      k,
+						Value:    tracetranslator.AttributeValueToString(v, false),
+						Endpoint: ep,
+					})
+				})
+				zSpans = append(zSpans, zSpan)
+			}
+		}
+	}
+	return zSpans
+}
+
+func tracesToZipkinV1Thrift(td pdata.Traces) []*zipkincore.Span {
+	var zSpans []*zipkincore.Span
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		ep := &zipkincore.Endpoint{ServiceName: zipkinV1ServiceName(rs.Resource())}
+		ilss := rs.InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				traceIDHigh, traceIDLow := tracetranslator.TraceIDToUInt64Pair(span.TraceID())
+				start, duration := zipkinV1Times(span)
+				zSpan := &zipkincore.Span{
+					TraceID:     int64(traceIDLow),
+					TraceIDHigh: int64Ptr(int64(traceIDHigh)),
+					ID:          int64(tracetranslator.SpanIDToUInt64(span.SpanID())),
+					Name:        span.Name(),
+					Timestamp:   int64Ptr(start),
+					Duration:    int64Ptr(duration),
+				}
+				if !span.ParentSpanID().IsEmpty() {
+					zSpan.ParentID = int64Ptr(int64(tracetranslator.SpanIDToUInt64(span.ParentSpanID())))
+				}
+				for _, value := range zipkinV1KindAnnotations(span.Kind()) {
+					zSpan.Annotations = append(zSpan.Annotations, &zipkincore.Annotation{
+						Timestamp: start,
+						Value:     value,
+						Host:      ep,
+					})
+				}
+				span.Attributes().ForEach(func(k string, v pdata.AttributeValue) {
+					zSpan.BinaryAnnotations = append(zSpan.BinaryAnnotations, zipkinV1ThriftBinaryAnnotation(k, v, ep))
+				})
+				zSpans = append(zSpans, zSpan)
+			}
+		}
+	}
+	return zSpans
+}
+
+func zipkinV1ThriftBinaryAnnotation(key string, v pdata.AttributeValue, ep *zipkincore.Endpoint) *zipkincore.BinaryAnnotation {
+	ba := &zipkincore.BinaryAnnotation{Key: key, Host: ep}
+	switch v.Type() {
+	case pdata.AttributeValueINT:
+		ba.AnnotationType = zipkincore.AnnotationType_I64
+		ba.Value = make([]byte, 8)
+		binary.BigEndian.PutUint64(ba.Value, uint64(v.IntVal()))
+	case pdata.AttributeValueDOUBLE:
+		ba.AnnotationType = zipkincore.AnnotationType_DOUBLE
+		ba.Value = make([]byte, 8)
+		binary.BigEndian.PutUint64(ba.Value, math.Float64bits(v.DoubleVal()))
+	case pdata.AttributeValueBOOL:
+		ba.AnnotationType = zipkincore.AnnotationType_BOOL
+		ba.Value = []byte{0}
+		if v.BoolVal() {
+			ba.Value[0] = 1
+		}
+	default:
+		ba.AnnotationType = zipkincore.AnnotationType_STRING
+		ba.Value = []byte(tracetranslator.AttributeValueToString(v, false))
+	}
+	return ba
+}
+
+func zipkinV1ServiceName(resource pdata.Resource) string {
+	if sn, ok := resource.Attributes().Get(conventions.AttributeServiceName); ok {
+		return sn.StringVal()
+	}
+	return "load-generator"
+}
+
+// zipkinV1Times returns the span start time and duration in microseconds.
+func zipkinV1Times(span pdata.Span) (int64, int64) {
+	start := int64(span.StartTime()) / int64(time.Microsecond)
+	end := int64(span.EndTime()) / int64(time.Microsecond)
+	return start, end - start
+}
+
+// zipkinV1KindAnnotations returns the core annotations that Zipkin v1 uses to encode the span kind.
+func zipkinV1KindAnnotations(kind pdata.SpanKind) []string {
+	switch kind {
+	case pdata.SpanKindCLIENT:
+		return []string{zipkincore.CLIENT_SEND, zipkincore.CLIENT_RECV}
+	case pdata.SpanKindSERVER:
+		return []string{zipkincore.SERVER_RECV, zipkincore.SERVER_SEND}
+	}
+	return nil
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
 // prometheus
 
 type PrometheusDataSender struct {
@@ -0,0 +1,152 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"go.opentelemetry.io/collector/config/configgrpc"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
+)
+
+func TestZipkinV1DataSenderPreservesSeqNums(t *testing.T) {
+	for _, encoding := range []ZipkinV1Encoding{ZipkinV1Thrift, ZipkinV1JSON} {
+		t.Run(string(encoding), func(t *testing.T) {
+			port := GetAvailablePort(t)
+			mb := NewMockBackend("mockbackend.log", NewZipkinDataReceiver(port))
+			mb.EnableRecording()
+			require.NoError(t, mb.Start())
+			defer mb.Stop()
+
+			sender := NewZipkinV1DataSender(DefaultHost, port).WithEncoding(encoding)
+			require.NoError(t, sender.Start())
+
+			dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 5})
+			dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+			td, _ := dp.GenerateTraces()
+			require.NoError(t, sender.ConsumeTraces(context.Background(), td))
+
+			require.EqualValues(t, 5, mb.DataItemsReceived())
+			spans := mb.ReceivedTraces[0].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+			for i := 0; i < spans.Len(); i++ {
+				seqNum, ok := spans.At(i).Attributes().Get(SpanSeqNumAttribute)
+				require.True(t, ok)
+				// JSON binary annotations are strings unless the receiver parses them,
+				// which is what the generated agent config does.
+				assert.Equal(t, strconv.Itoa(i+1), tracetranslator.AttributeValueToString(seqNum, false))
+				if encoding == ZipkinV1Thrift {
+					assert.Equal(t, pdata.AttributeValueINT, seqNum.Type())
+				}
+			}
+		})
+	}
+}
+
+func TestOTLPDataSenderSingleConnection(t *testing.T) {
+	port := GetAvailablePort(t)
+	mb := NewMockBackend("mockbackend.log", NewOTLPDataReceiver(port).WithConnectionStats())
+	require.NoError(t, mb.Start())
+	defer mb.Stop()
+
+	sender := NewOTLPDataSender(DefaultHost, port)
+	require.NoError(t, sender.Start())
+
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10, DataPointsPerMetric: 2})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	td, _ := dp.GenerateTraces()
+	require.NoError(t, sender.ConsumeTraces(context.Background(), td))
+	md, _ := dp.GenerateMetrics()
+	require.NoError(t, sender.ConsumeMetrics(context.Background(), md))
+	ld, _ := dp.GenerateLogs()
+	require.NoError(t, sender.ConsumeLogs(context.Background(), ld))
+
+	assert.EqualValues(t, 10, mb.SpansReceived())
+	assert.EqualValues(t, 20, mb.MetricsReceived())
+	assert.EqualValues(t, 10, mb.LogRecordsReceived())
+	stats, ok := mb.ConnectionStats()
+	require.True(t, ok)
+	assert.EqualValues(t, 1, stats.AcceptedConnections)
+}
+
+func TestOTLPHTTPDataSenderHeaders(t *testing.T) {
+	otlpReceiver := NewOTLPHTTPDataReceiver(GetAvailablePort(t))
+	receiver := NewCapturingDataReceiver(GetAvailablePort(t), otlpReceiver, 10, 0)
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, receiver.Start(sink, consumertest.NewMetricsNop(), consumertest.NewLogsNop()))
+	defer receiver.Stop()
+
+	sender := NewOTLPHTTPTraceDataSender(DefaultHost, receiver.Port).
+		WithHTTPHeaders(map[string]string{"X-Tenant-Id": "tenant-1"})
+	require.NoError(t, sender.Start())
+
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 10})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	for i := 0; i < 2; i++ {
+		td, _ := dp.GenerateTraces()
+		require.NoError(t, sender.ConsumeTraces(context.Background(), td))
+	}
+
+	assert.Equal(t, 20, sink.SpansCount())
+	captured := receiver.CapturedRequests()
+	require.Len(t, captured, 2)
+	for _, req := range captured {
+		assert.Equal(t, "tenant-1", req.Header.Get("X-Tenant-Id"))
+		assert.Equal(t, "application/x-protobuf", req.ContentType())
+	}
+}
+
+func TestOTLPDataSenderReceiverSettings(t *testing.T) {
+	httpSender := NewOTLPHTTPLogsDataSender(DefaultHost, 4318).
+		WithReceiverTimeouts(500*time.Millisecond, time.Minute)
+	assert.Equal(t, `
+  otlp:
+    protocols:
+      http:
+        endpoint: "127.0.0.1:4318"
+        read_timeout: 500ms
+        idle_timeout: 1m0s`, httpSender.GenConfigYAMLStr())
+
+	grpcSender := NewOTLPTraceDataSender(DefaultHost, 4317).
+		WithReceiverKeepalive(configgrpc.KeepaliveServerConfig{
+			ServerParameters: &configgrpc.KeepaliveServerParameters{
+				MaxConnectionIdle: 10 * time.Second,
+				Time:              time.Second,
+				Timeout:           500 * time.Millisecond,
+			},
+			EnforcementPolicy: &configgrpc.KeepaliveEnforcementPolicy{MinTime: time.Second},
+		})
+	assert.Equal(t, `
+  otlp:
+    protocols:
+      grpc:
+        endpoint: "127.0.0.1:4317"
+        keepalive:
+          server_parameters:
+            max_connection_idle: 10s
+            time: 1s
+            timeout: 500ms
+          enforcement_policy:
+            min_time: 1s
+            permit_without_stream: false`, grpcSender.GenConfigYAMLStr())
+}
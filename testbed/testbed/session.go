@@ -0,0 +1,226 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/consumer/pdata"
+	otlptrace "go.opentelemetry.io/collector/internal/data/protogen/trace/v1"
+)
+
+// sessionEntry is one generated batch in a session file written by
+// LoadGenerator.RecordSession. The file holds one JSON encoded entry per line in the
+// order the batches were generated.
+type sessionEntry struct {
+	// Offset is the time from the start of the load until the batch was generated.
+	Offset time.Duration `json:"offset"`
+	// Signal is the data type of the batch: traces, metrics or logs.
+	Signal configmodels.DataType `json:"signal"`
+	// Data is the OTLP protobuf encoded batch.
+	Data []byte `json:"data"`
+}
+
+// sessionRecorder appends the batches generated by a LoadGenerator to a session file.
+type sessionRecorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	err     error
+}
+
+func newSessionRecorder(path string) (*sessionRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionRecorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// record appends a batch to the session. The first error is kept and returned by close,
+// later batches are not recorded since the session would be incomplete anyway.
+func (sr *sessionRecorder) record(offset time.Duration, signal configmodels.DataType, marshal func() ([]byte, error)) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	if sr.err != nil {
+		return
+	}
+	data, err := marshal()
+	if err != nil {
+		sr.err = err
+		return
+	}
+	sr.err = sr.encoder.Encode(sessionEntry{Offset: offset, Signal: signal, Data: data})
+}
+
+func (sr *sessionRecorder) close() error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	if err := sr.file.Close(); sr.err == nil {
+		sr.err = err
+	}
+	return sr.err
+}
+
+// SessionDataProvider is a DataProvider which replays a session recorded by
+// LoadGenerator.RecordSession. It returns the recorded batches of every signal in the
+// order they were generated, each one not earlier than its recorded offset from the
+// first replayed batch, so that a run with a nondeterministic data provider can be
+// reproduced exactly. The load generator rate should be set at least as high as the
+// recorded rate since batches are never generated earlier than the rate allows. Once
+// all batches of a signal are replayed no more data of it is generated.
+type SessionDataProvider struct {
+	batchesGenerated   *atomic.Uint64
+	dataItemsGenerated *atomic.Uint64
+
+	batches map[configmodels.DataType][]sessionBatch
+
+	mutex      sync.Mutex
+	next       map[configmodels.DataType]int
+	replayFrom time.Time
+}
+
+// sessionBatch is a decoded batch of a session, one of pdata.Traces, pdata.Metrics or
+// pdata.Logs.
+type sessionBatch struct {
+	offset time.Duration
+	data   interface{}
+}
+
+var _ DataProvider = (*SessionDataProvider)(nil)
+
+// NewSessionDataProvider creates a SessionDataProvider replaying the session file at
+// path.
+func NewSessionDataProvider(path string) (*SessionDataProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	batches := make(map[configmodels.DataType][]sessionBatch)
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var entry sessionEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("cannot read session %s: %s", path, err.Error())
+		}
+		batch := sessionBatch{offset: entry.Offset}
+		switch entry.Signal {
+		case configmodels.TracesDataType:
+			td := pdata.NewTraces()
+			err = td.FromOtlpProtoBytes(entry.Data)
+			batch.data = td
+		case configmodels.MetricsDataType:
+			md := pdata.NewMetrics()
+			err = md.FromOtlpProtoBytes(entry.Data)
+			batch.data = md
+		case configmodels.LogsDataType:
+			ld := pdata.NewLogs()
+			err = ld.FromOtlpProtoBytes(entry.Data)
+			batch.data = ld
+		default:
+			err = fmt.Errorf("unsupported data type %q", entry.Signal)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode batch of session %s: %s", path, err.Error())
+		}
+		batches[entry.Signal] = append(batches[entry.Signal], batch)
+	}
+	if len(batches) == 0 {
+		return nil, fmt.Errorf("no data found in session %s", path)
+	}
+	return &SessionDataProvider{batches: batches, next: make(map[configmodels.DataType]int)}, nil
+}
+
+// Batches returns the number of recorded batches of the signal.
+func (dp *SessionDataProvider) Batches(signal configmodels.DataType) int {
+	return len(dp.batches[signal])
+}
+
+func (dp *SessionDataProvider) SetLoadGeneratorCounters(batchesGenerated *atomic.Uint64, dataItemsGenerated *atomic.Uint64) {
+	dp.batchesGenerated = batchesGenerated
+	dp.dataItemsGenerated = dataItemsGenerated
+}
+
+// nextBatch returns the next recorded batch of the signal, blocking until its offset
+// has elapsed since the replay started. ok is false if all batches were replayed.
+func (dp *SessionDataProvider) nextBatch(signal configmodels.DataType) (data interface{}, ok bool) {
+	dp.mutex.Lock()
+	index := dp.next[signal]
+	if index >= len(dp.batches[signal]) {
+		dp.mutex.Unlock()
+		return nil, false
+	}
+	dp.next[signal] = index + 1
+	batch := dp.batches[signal][index]
+	if dp.replayFrom.IsZero() {
+		// The first replayed batch defines the start of the session.
+		dp.replayFrom = time.Now().Add(-batch.offset)
+	}
+	sendAt := dp.replayFrom.Add(batch.offset)
+	dp.mutex.Unlock()
+
+	if wait := time.Until(sendAt); wait > 0 {
+		time.Sleep(wait)
+	}
+	return batch.data, true
+}
+
+func (dp *SessionDataProvider) GenerateTraces() (pdata.Traces, bool) {
+	data, ok := dp.nextBatch(configmodels.TracesDataType)
+	if !ok {
+		return pdata.NewTraces(), true
+	}
+	td := data.(pdata.Traces)
+	dp.batchesGenerated.Inc()
+	dp.dataItemsGenerated.Add(uint64(td.SpanCount()))
+	return td, false
+}
+
+func (dp *SessionDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
+	data, ok := dp.nextBatch(configmodels.MetricsDataType)
+	if !ok {
+		return pdata.NewMetrics(), true
+	}
+	md := data.(pdata.Metrics)
+	dp.batchesGenerated.Inc()
+	_, dataPointCount := md.MetricAndDataPointCount()
+	dp.dataItemsGenerated.Add(uint64(dataPointCount))
+	return md, false
+}
+
+func (dp *SessionDataProvider) GenerateLogs() (pdata.Logs, bool) {
+	data, ok := dp.nextBatch(configmodels.LogsDataType)
+	if !ok {
+		return pdata.NewLogs(), true
+	}
+	ld := data.(pdata.Logs)
+	dp.batchesGenerated.Inc()
+	dp.dataItemsGenerated.Add(uint64(ld.LogRecordCount()))
+	return ld, false
+}
+
+func (dp *SessionDataProvider) GetGeneratedSpan(pdata.TraceID, pdata.SpanID) *otlptrace.Span {
+	// Nothing to do. This function is only used by data providers used in correctness tests for traces.
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/config/configmodels"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func TestRecordAndReplaySession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sessionPath := filepath.Join(dir, "session.json")
+
+	// runLoad sends the data of dataProvider to a new MockBackend until it stops and
+	// returns the received traces.
+	runLoad := func(dataProvider DataProvider, options LoadOptions, record bool, stop func(lg *LoadGenerator) bool) []pdata.Traces {
+		port := GetAvailablePort(t)
+		mb := NewMockBackend("mockbackend.log", NewOTLPDataReceiver(port))
+		mb.EnableRecording()
+		require.NoError(t, mb.Start())
+		defer mb.Stop()
+
+		lg, err := NewLoadGenerator(dataProvider, NewOTLPTraceDataSender(DefaultHost, port))
+		require.NoError(t, err)
+		if record {
+			require.NoError(t, lg.RecordSession(sessionPath))
+		}
+		lg.Start(options)
+		WaitFor(t, func() bool { return stop(lg) }, "load generated")
+		lg.Stop()
+		WaitFor(t, func() bool { return lg.DataItemsSent() == mb.DataItemsReceived() }, "all data items received")
+		return mb.ReceivedTraces
+	}
+
+	// The perf test data provider generates different IDs and timestamps on every run.
+	options := LoadOptions{DataItemsPerSecond: 200, ItemsPerBatch: 10}
+	recorded := runLoad(NewPerfTestDataProvider(options), options, true, func(lg *LoadGenerator) bool {
+		return lg.BatchesSent() >= 5
+	})
+	require.NotEmpty(t, recorded)
+
+	replayProvider, err := NewSessionDataProvider(sessionPath)
+	require.NoError(t, err)
+	require.Equal(t, len(recorded), replayProvider.Batches(configmodels.TracesDataType))
+	assert.Zero(t, replayProvider.Batches(configmodels.MetricsDataType))
+
+	start := time.Now()
+	replayed := runLoad(replayProvider, LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}, false, func(lg *LoadGenerator) bool {
+		return int(lg.BatchesSent()) == len(recorded)
+	})
+
+	// The replay sends identical data at the recorded pace, which is slower than the
+	// rate of the replaying load generator.
+	require.Len(t, replayed, len(recorded))
+	for i := range recorded {
+		assert.Equal(t, recorded[i], replayed[i], "batch %d", i)
+	}
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Duration(len(recorded)-1)*40*time.Millisecond))
+
+	_, err = NewSessionDataProvider(filepath.Join(dir, "missing.json"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -24,9 +24,11 @@
 package testbed
 
 import (
+	"flag"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -63,9 +65,27 @@ var GlobalConfig = struct {
 	DefaultAgentExeRelativeFile: "../../bin/otelcol_{{.GOOS}}_{{.GOARCH}}",
 }
 
+var registerFlagsOnce sync.Once
+
+// registerFlags defines the command line flags of the testbed, see ScenarioParams and
+// DoTestMainWithControlServer. They must be registered before the flags are parsed.
+func registerFlags() {
+	registerFlagsOnce.Do(func() {
+		registerScenarioFlags(flag.CommandLine)
+		flag.String(controlServerFlag, "", "Endpoint on which to serve scenario requests instead of running the tests.")
+	})
+}
+
 // DoTestMain is intended to be run from TestMain somewhere in the test suit.
 // This enables the testbed.
 func DoTestMain(m *testing.M, resultsSummary TestResultsSummary) {
+	registerFlags()
+
+	if spec := os.Getenv(loadGeneratorProcessEnvVarName); spec != "" {
+		// The test binary was started as a load generator process.
+		os.Exit(runLoadGeneratorProcess(spec))
+	}
+
 	testBedConfigFile := os.Getenv(testBedEnableEnvVarName)
 	if testBedConfigFile == "" {
 		log.Printf(testBedEnableEnvVarName + " is not defined, skipping E2E tests.")
@@ -87,3 +107,21 @@ func DoTestMain(m *testing.M, resultsSummary TestResultsSummary) {
 	// Now run all tests.
 	os.Exit(res)
 }
+
+// DoTestMainWithControlServer is like DoTestMain, but if the control server is enabled
+// by the -testbed.control_server flag or the TESTBED_CONTROL_SERVER environment variable,
+// it serves the scenarios run by run on the endpoint given by their value, see
+// ControlServer, until the process is interrupted, instead of running the tests.
+func DoTestMainWithControlServer(m *testing.M, resultsSummary TestResultsSummary, run ScenarioRunner) {
+	registerFlags()
+	// Flags, which may enable the control server, are parsed by m.Run otherwise.
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	endpoint := controlServerEndpoint()
+	if endpoint == "" || os.Getenv(loadGeneratorProcessEnvVarName) != "" {
+		DoTestMain(m, resultsSummary)
+		return
+	}
+	os.Exit(serveControl(endpoint, run))
+}
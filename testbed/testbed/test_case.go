@@ -15,20 +15,101 @@
 package testbed
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 )
 
+// TestingT is the subset of testing.TB used by TestCase. It is implemented by *testing.T
+// and by HeadlessT which allows running test cases outside of "go test".
+type TestingT interface {
+	Name() string
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+	FailNow()
+	Failed() bool
+}
+
+var _ TestingT = (*testing.T)(nil)
+var _ TestingT = (*HeadlessT)(nil)
+
+// HeadlessT implements TestingT for running test cases programmatically, e.g. from a
+// benchmark binary. Errors are collected instead of being reported to the testing
+// framework. Like testing.T, FailNow stops the calling goroutine so test cases using
+// HeadlessT should run in a goroutine of their own.
+type HeadlessT struct {
+	name   string
+	mutex  sync.Mutex
+	errors []string
+}
+
+// NewHeadlessT creates a HeadlessT for the test case with the specified name. The name
+// is used for the results directory.
+func NewHeadlessT(name string) *HeadlessT {
+	return &HeadlessT{name: name}
+}
+
+func (ht *HeadlessT) Name() string {
+	return ht.name
+}
+
+func (ht *HeadlessT) Error(args ...interface{}) {
+	ht.addError(fmt.Sprintln(args...))
+}
+
+func (ht *HeadlessT) Errorf(format string, args ...interface{}) {
+	ht.addError(fmt.Sprintf(format, args...))
+}
+
+func (ht *HeadlessT) addError(msg string) {
+	log.Print(msg)
+	ht.mutex.Lock()
+	defer ht.mutex.Unlock()
+	ht.errors = append(ht.errors, strings.TrimSpace(msg))
+}
+
+func (ht *HeadlessT) FailNow() {
+	ht.mutex.Lock()
+	if len(ht.errors) == 0 {
+		ht.errors = append(ht.errors, "FailNow called")
+	}
+	ht.mutex.Unlock()
+	runtime.Goexit()
+}
+
+func (ht *HeadlessT) Failed() bool {
+	ht.mutex.Lock()
+	defer ht.mutex.Unlock()
+	return len(ht.errors) > 0
+}
+
+// Err returns an error combining all errors recorded so far or nil if there were none.
+func (ht *HeadlessT) Err() error {
+	ht.mutex.Lock()
+	defer ht.mutex.Unlock()
+	if len(ht.errors) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(ht.errors, "; "))
+}
+
 // TestCase defines a running test case.
 type TestCase struct {
-	t *testing.T
+	t TestingT
 
 	// Directory where test case results and logs will be written.
 	resultDir string
@@ -39,6 +120,9 @@ type TestCase struct {
 	// Agent config file path.
 	agentConfigFile string
 
+	// How long StartAgent waits for the agent to become ready.
+	agentStartTimeout time.Duration
+
 	// Load generator spec file path.
 	// loadSpecFile string
 
@@ -53,14 +137,32 @@ type TestCase struct {
 
 	LoadGenerator *LoadGenerator
 	MockBackend   *MockBackend
+	// Additional backends added by AddMockBackend.
+	extraBackends []*MockBackend
 	validator     TestCaseValidator
 
 	startTime time.Time
 
+	// Times when the agent and the load were started, used for TimeToFirstItem.
+	agentStartTime time.Time
+	loadStartTime  time.Time
+
+	// Time when the load was stopped and the drain measured by WaitForDrain.
+	loadStopTime  time.Time
+	timeToDrain   time.Duration
+	drainTimedOut bool
+
+	// RSS of the agent in MiB sampled before the load was started.
+	baselineRAMMiB uint32
+
+	// Metadata to attribute the results of the run, e.g. commit SHA or host.
+	runMetadata map[string]string
+
 	// ErrorSignal indicates an error in the test case execution, e.g. process execution
 	// failure or exceeding resource consumption, etc. The actual error message is already
 	// logged, this is only an indicator on which you can wait to be informed.
 	ErrorSignal chan struct{}
+	errorOnce   sync.Once
 
 	// Duration is the requested duration of the tests. Configured via TESTBED_DURATION
 	// env variable and defaults to 15 seconds if env variable is unspecified.
@@ -71,14 +173,27 @@ type TestCase struct {
 	errorCause string
 
 	resultsSummary TestResultsSummary
+
+	// Reporters added with WithResultReporters.
+	resultReporters []ResultReporter
+
+	// Set if the test case exceeded the timeout set with SetTimeout.
+	timedOut atomic.Bool
+}
+
+// goroutineDumper is implemented by OtelcolRunners which can dump the goroutines of the
+// agent, see ChildProcess.DumpGoroutines.
+type goroutineDumper interface {
+	DumpGoroutines() (string, error)
 }
 
 const mibibyte = 1024 * 1024
 const testcaseDurationVar = "TESTCASE_DURATION"
+const defaultAgentStartTimeout = 10 * time.Second
 
 // NewTestCase creates a new TestCase. It expects agent-config.yaml in the specified directory.
 func NewTestCase(
-	t *testing.T,
+	t TestingT,
 	dataProvider DataProvider,
 	sender DataSender,
 	receiver DataReceiver,
@@ -98,17 +213,14 @@ func NewTestCase(
 	tc.agentProc = agentProc
 	tc.validator = validator
 	tc.resultsSummary = resultsSummary
+	tc.agentStartTimeout = defaultAgentStartTimeout
 
-	// Get requested test case duration from env variable.
-	duration := os.Getenv(testcaseDurationVar)
-	if duration == "" {
-		duration = "15s"
-	}
-	var err error
-	tc.Duration, err = time.ParseDuration(duration)
+	// Get requested test case duration from the flag or env variable.
+	params, err := ReadScenarioParams(ScenarioParams{Duration: 15 * time.Second})
 	if err != nil {
-		log.Fatalf("Invalid "+testcaseDurationVar+": %v. Expecting a valid duration string.", duration)
+		log.Fatalf("Invalid scenario parameters: %v", err)
 	}
+	tc.Duration = params.Duration
 
 	// Apply all provided options.
 	for _, opt := range opts {
@@ -168,6 +280,7 @@ func (tc *TestCase) StartAgent(args ...string) {
 	}
 	logFileName := tc.composeTestResultFileName("agent.log")
 
+	tc.agentStartTime = time.Now()
 	err := tc.agentProc.Start(StartParams{
 		Name:         "Agent",
 		LogFilePath:  logFileName,
@@ -194,10 +307,91 @@ func (tc *TestCase) StartAgent(args ...string) {
 		// connect to the port to which we intend to send load. We only do this
 		// if the endpoint is not-empty, i.e. the sender does use network (some senders
 		// like text log writers don't).
-		tc.WaitFor(func() bool {
-			_, err := net.Dial("tcp", tc.LoadGenerator.sender.GetEndpoint())
-			return err == nil
-		})
+		if err := tc.waitForAgentReady(endpoint); err != nil {
+			tc.indicateError(err)
+		}
+	}
+}
+
+// waitForAgentReady probes endpoint until the agent accepts connections, the agent start
+// timeout is reached or an error is signaled. The collector starts receivers after the
+// rest of the pipelines so data sent once the receiver accepts connections is processed.
+func (tc *TestCase) waitForAgentReady(endpoint string) error {
+	deadline := time.Now().Add(tc.agentStartTimeout)
+	waitInterval := 5 * time.Millisecond
+	for {
+		conn, err := net.DialTimeout("tcp", endpoint, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("agent is not ready: cannot connect to %s within %s: %v",
+				endpoint, tc.agentStartTimeout, err)
+		}
+
+		select {
+		case <-time.After(waitInterval):
+		case <-tc.ErrorSignal:
+			// The agent failed, the error is already recorded.
+			return nil
+		}
+
+		// Increase waiting interval exponentially up to 500 ms.
+		if waitInterval < 500*time.Millisecond {
+			waitInterval *= 2
+		}
+	}
+}
+
+// SetTimeout fails the test case if it is not stopped within d. On timeout the
+// goroutines of the test process and of the agent are dumped to the log and to the
+// "goroutines-test.log" and "goroutines-agent.log" files in the test directory, which
+// stops the agent, and an error is signaled so that Sleep and WaitFor return. Use
+// TimedOut to distinguish a timeout from other failures.
+func (tc *TestCase) SetTimeout(d time.Duration) {
+	go func() {
+		select {
+		case <-time.After(d):
+			tc.onTimeout(d)
+		case <-tc.doneSignal:
+		}
+	}()
+}
+
+// TimedOut returns true if the test case exceeded the timeout set with SetTimeout.
+func (tc *TestCase) TimedOut() bool {
+	return tc.timedOut.Load()
+}
+
+func (tc *TestCase) onTimeout(d time.Duration) {
+	tc.timedOut.Store(true)
+	log.Printf("Test case %s timed out after %s, dumping goroutines", tc.t.Name(), d)
+
+	var buf strings.Builder
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		log.Printf("Cannot dump goroutines of the test: %v", err)
+	} else {
+		tc.writeGoroutineDump("test", buf.String())
+	}
+
+	if dumper, ok := tc.agentProc.(goroutineDumper); ok {
+		dump, err := dumper.DumpGoroutines()
+		if err != nil {
+			log.Printf("Cannot dump goroutines of the agent: %v", err)
+		} else {
+			tc.writeGoroutineDump("agent", dump)
+		}
+	}
+
+	tc.indicateError(fmt.Errorf("test case timed out after %s", d))
+}
+
+func (tc *TestCase) writeGoroutineDump(process string, dump string) {
+	log.Printf("Goroutines of the %s:\n%s", process, dump)
+	fileName := tc.composeTestResultFileName("goroutines-" + process + ".log")
+	if err := ioutil.WriteFile(fileName, []byte(dump), 0644); err != nil {
+		log.Printf("Cannot write %s: %v", fileName, err)
 	}
 }
 
@@ -209,27 +403,221 @@ func (tc *TestCase) StopAgent() {
 // StartLoad starts the load generator and redirects its standard output and standard error
 // to "load-generator.log" file located in the test directory.
 func (tc *TestCase) StartLoad(options LoadOptions) {
+	tc.sampleBaselineRAM()
+	tc.loadStartTime = time.Now()
 	tc.LoadGenerator.Start(options)
 }
 
+// StartLoadProcess starts the load generator like StartLoad but in a separate process,
+// see LoadGenerator.StartProcess. The standard error of the process is written to
+// "load-generator.log" file located in the test directory.
+func (tc *TestCase) StartLoadProcess(options LoadOptions) {
+	tc.sampleBaselineRAM()
+	tc.loadStartTime = time.Now()
+	err := tc.LoadGenerator.StartProcess(options, tc.composeTestResultFileName("load-generator.log"))
+	require.NoError(tc.t, err, "Cannot start load generator process")
+}
+
+// StartLoadCount starts the load generator like StartLoad but stops generating once
+// exactly totalItems data items were sent. Wait for tc.LoadGenerator.CountReached()
+// and then for the items to be received to make loss and duplicate checks deterministic.
+func (tc *TestCase) StartLoadCount(options LoadOptions, totalItems uint64) {
+	tc.sampleBaselineRAM()
+	tc.loadStartTime = time.Now()
+	tc.LoadGenerator.StartCount(options, totalItems)
+}
+
+// ScheduleLoad starts the load generator like StartLoad at the wall-clock time start and
+// stops it at stop, blocking until the load is stopped, so that several testbed
+// instances, e.g. on different machines, can generate load in the same window. A start
+// time in the past starts the load immediately. The actual times are logged and
+// reported by LoadStartTime and LoadStopTime. Returns without starting the load if an
+// error is signaled before start.
+func (tc *TestCase) ScheduleLoad(start, stop time.Time, options LoadOptions) {
+	if !stop.After(start) {
+		tc.t.Errorf("Load stop time %v is not after start time %v", stop, start)
+		return
+	}
+	if wait := time.Until(start); wait > 0 {
+		log.Printf("Load scheduled to start at %v, waiting %v.", start, wait)
+		tc.Sleep(wait)
+		if time.Now().Before(start) {
+			log.Printf("Scheduled load not started due to an error.")
+			return
+		}
+	}
+	tc.StartLoad(options)
+	log.Printf("Scheduled load started at %v (scheduled %v).", tc.loadStartTime, start)
+
+	tc.Sleep(time.Until(stop))
+	tc.StopLoad()
+	log.Printf("Scheduled load stopped at %v (scheduled %v).", tc.loadStopTime, stop)
+}
+
+// LoadStartTime returns the time when the load was started, or zero if it was not.
+func (tc *TestCase) LoadStartTime() time.Time {
+	return tc.loadStartTime
+}
+
+// LoadStopTime returns the time when the load was stopped, or zero if it was not.
+func (tc *TestCase) LoadStopTime() time.Time {
+	return tc.loadStopTime
+}
+
+// sampleBaselineRAM records the RSS of the idle agent at the end of the warmup window,
+// right before the load is started. Nothing is recorded if the agent process is not
+// monitored.
+func (tc *TestCase) sampleBaselineRAM() {
+	if tc.agentProc.GetProcessMon() == nil {
+		return
+	}
+	rss, _, err := tc.AgentMemoryInfo()
+	if err != nil {
+		log.Printf("Cannot sample agent baseline RAM: %v", err)
+		return
+	}
+	tc.baselineRAMMiB = rss
+}
+
+// BaselineRAMMiB returns the RSS of the agent in MiB sampled after the agent started but
+// before the load was started, or 0 if it was not sampled. The difference to the peak
+// RAM during the test is the marginal memory cost of the load.
+func (tc *TestCase) BaselineRAMMiB() uint32 {
+	return tc.baselineRAMMiB
+}
+
+// TimeToFirstItem returns the time from StartAgent (or from StartLoad if the agent was
+// not started by this test case) until the MockBackend received the first data item.
+// Returns 0 if no data was received yet. If the load is started before the agent is
+// ready the data sent in the meantime is dropped or retried by the sender, the time
+// until the agent becomes ready is included in the measurement.
+func (tc *TestCase) TimeToFirstItem() time.Duration {
+	firstItemAt := tc.MockBackend.FirstItemReceivedAt()
+	if firstItemAt.IsZero() {
+		return 0
+	}
+	startTime := tc.agentStartTime
+	if startTime.IsZero() {
+		startTime = tc.loadStartTime
+	}
+	if startTime.IsZero() {
+		return 0
+	}
+	return firstItemAt.Sub(startTime)
+}
+
+// SetRunMetadata attaches metadata such as commit SHA, branch or machine info to the
+// results of this test case. The metadata is included in the logged summary and in
+// the result record written to TESTRESULTS.json so that results can be grouped by it.
+func (tc *TestCase) SetRunMetadata(metadata map[string]string) {
+	tc.runMetadata = make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		tc.runMetadata[k] = v
+	}
+}
+
+// RunMetadata returns the metadata set by SetRunMetadata or nil if none was set.
+func (tc *TestCase) RunMetadata() map[string]string {
+	return tc.runMetadata
+}
+
 // StopLoad stops load generator.
 func (tc *TestCase) StopLoad() {
 	tc.LoadGenerator.Stop()
+	// The load generator can only be stopped once, keep the time of the first call.
+	if tc.loadStopTime.IsZero() {
+		tc.loadStopTime = time.Now()
+	}
+}
+
+// WaitForDrain waits after StopLoad until the MockBackends received no data items for
+// stableFor, i.e. until the agent drained its queues, and records the time from StopLoad
+// until the last data item was received, see TimeToDrain. Records a test error and
+// returns false if the received data items do not stabilize within timeout.
+func (tc *TestCase) WaitForDrain(stableFor time.Duration, timeout time.Duration) bool {
+	if tc.loadStopTime.IsZero() {
+		tc.t.Error("WaitForDrain called before StopLoad")
+		return false
+	}
+	backends := append([]*MockBackend{tc.MockBackend}, tc.extraBackends...)
+	lastItemReceivedAt := func() time.Time {
+		var last time.Time
+		for _, mb := range backends {
+			if at := mb.LastItemReceivedAt(); at.After(last) {
+				last = at
+			}
+		}
+		return last
+	}
+
+	drained := tc.WaitForN(func() bool {
+		last := lastItemReceivedAt()
+		if last.Before(tc.loadStopTime) {
+			last = tc.loadStopTime
+		}
+		return time.Since(last) >= stableFor
+	}, timeout, "data items received to stabilize")
+
+	if !drained {
+		tc.drainTimedOut = true
+		tc.timeToDrain = time.Since(tc.loadStopTime)
+		log.Printf("Agent did not drain within %v after the load stopped.", tc.timeToDrain)
+		return false
+	}
+	tc.timeToDrain = 0
+	if last := lastItemReceivedAt(); last.After(tc.loadStopTime) {
+		tc.timeToDrain = last.Sub(tc.loadStopTime)
+	}
+	log.Printf("Agent drained %v after the load stopped.", tc.timeToDrain)
+	return true
+}
+
+// TimeToDrain returns the time from StopLoad until the last data item was received as
+// measured by WaitForDrain, or the time WaitForDrain waited if the agent did not drain,
+// see DrainTimedOut. Returns 0 if WaitForDrain was not called. Long drains indicate deep
+// queues or slow exporters.
+func (tc *TestCase) TimeToDrain() time.Duration {
+	return tc.timeToDrain
+}
+
+// DrainTimedOut returns true if WaitForDrain timed out before the agent drained.
+func (tc *TestCase) DrainTimedOut() bool {
+	return tc.drainTimedOut
+}
+
+// AddMockBackend adds another MockBackend receiving with the given receiver, e.g. for
+// tests where the collector exports to several destinations. It is started, stopped
+// and recorded together with the MockBackend of the test case. Its log file is named
+// after the position of the backend, e.g. "backend-2.log" for the first added one.
+func (tc *TestCase) AddMockBackend(receiver DataReceiver) *MockBackend {
+	fileName := fmt.Sprintf("backend-%d.log", len(tc.extraBackends)+2)
+	mb := NewMockBackend(tc.composeTestResultFileName(fileName), receiver)
+	tc.extraBackends = append(tc.extraBackends, mb)
+	return mb
 }
 
 // StartBackend starts the specified backend type.
 func (tc *TestCase) StartBackend() {
 	require.NoError(tc.t, tc.MockBackend.Start(), "Cannot start backend")
+	for _, mb := range tc.extraBackends {
+		require.NoError(tc.t, mb.Start(), "Cannot start backend")
+	}
 }
 
 // StopBackend stops the backend.
 func (tc *TestCase) StopBackend() {
 	tc.MockBackend.Stop()
+	for _, mb := range tc.extraBackends {
+		mb.Stop()
+	}
 }
 
 // EnableRecording enables recording of all data received by MockBackend.
 func (tc *TestCase) EnableRecording() {
 	tc.MockBackend.EnableRecording()
+	for _, mb := range tc.extraBackends {
+		mb.EnableRecording()
+	}
 }
 
 // AgentMemoryInfo returns raw memory info struct about the agent
@@ -252,12 +640,46 @@ func (tc *TestCase) Stop() {
 	// Stop logging
 	close(tc.doneSignal)
 
+	if len(tc.runMetadata) > 0 {
+		log.Printf("Run metadata: %s", formatRunMetadata(tc.runMetadata))
+	}
+
 	if tc.skipResults {
 		return
 	}
 
 	// Report test results
 	tc.validator.RecordResults(tc)
+	if len(tc.resultReporters) > 0 {
+		result := tc.testResult()
+		for _, reporter := range tc.resultReporters {
+			reporter.Report(result)
+		}
+	}
+}
+
+// testResult returns the result of the test case for the ResultReporters.
+func (tc *TestCase) testResult() TestResult {
+	rc := tc.agentProc.GetTotalConsumption()
+	result := TestResult{
+		TestName:         strings.TrimPrefix(tc.t.Name(), "Test"),
+		Result:           "PASS",
+		Duration:         time.Since(tc.startTime),
+		SentItems:        tc.LoadGenerator.DataItemsSent(),
+		ReceivedItems:    tc.MockBackend.DataItemsReceived(),
+		CPUPercentageAvg: rc.CPUPercentAvg,
+		CPUPercentageMax: rc.CPUPercentMax,
+		RAMMiBAvg:        rc.RAMMiBAvg,
+		RAMMiBMax:        rc.RAMMiBMax,
+		ErrorCause:       tc.errorCause,
+		Metadata:         tc.RunMetadata(),
+	}
+	if tc.TimedOut() {
+		result.Result = "TIMEOUT"
+	} else if tc.t.Failed() {
+		result.Result = "FAIL"
+	}
+	return result
 }
 
 // ValidateData validates data received by mock backend against what was generated and sent to the collector
@@ -327,10 +749,12 @@ func (tc *TestCase) indicateError(err error) {
 	// Indicate error for the test
 	tc.t.Error(err.Error())
 
-	tc.errorCause = err.Error()
+	tc.errorOnce.Do(func() {
+		tc.errorCause = err.Error()
 
-	// Signal the error via channel
-	close(tc.ErrorSignal)
+		// Signal the error via channel
+		close(tc.ErrorSignal)
+	})
 }
 
 func (tc *TestCase) logStats() {
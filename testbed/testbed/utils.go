@@ -15,11 +15,95 @@
 package testbed
 
 import (
+	"fmt"
+	"net"
+	"strconv"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"go.opentelemetry.io/collector/testutil"
 )
 
+// AddressFamily selects the IP address family a port is allocated for, see
+// GetAvailablePortForFamily.
+type AddressFamily int
+
+const (
+	// IPv4 allocates a port on the IPv4 loopback address 127.0.0.1.
+	IPv4 AddressFamily = iota
+	// IPv6 allocates a port on the IPv6 loopback address ::1.
+	IPv6
+	// DualStack allocates a port which is free on both loopback addresses, for
+	// components listening on "localhost" which may resolve to either of them.
+	DualStack
+)
+
+// maxDualStackAttempts limits how often a port free on the IPv4 loopback is tried on
+// the IPv6 loopback before giving up.
+const maxDualStackAttempts = 10
+
+func (f AddressFamily) String() string {
+	switch f {
+	case IPv4:
+		return "IPv4"
+	case IPv6:
+		return "IPv6"
+	case DualStack:
+		return "dual-stack"
+	}
+	return fmt.Sprintf("AddressFamily(%d)", int(f))
+}
+
+// LoopbackHost returns the loopback address of the family, "127.0.0.1" for DualStack.
+func (f AddressFamily) LoopbackHost() string {
+	if f == IPv6 {
+		return "::1"
+	}
+	return "127.0.0.1"
+}
+
+// GetAvailablePort finds a port which is available on the address "localhost" resolves
+// to. Use GetAvailablePortForFamily if the listener binds a specific address family.
 func GetAvailablePort(t *testing.T) int {
 	return int(testutil.GetAvailablePort(t))
 }
+
+// FindAvailablePort is like GetAvailablePort but returns an error instead of failing
+// a test, e.g. for scenarios run outside of "go test".
+func FindAvailablePort() (int, error) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// GetAvailablePortForFamily finds a port which is available on the loopback address of
+// the given family, or on both loopback addresses for DualStack. The test fails if the
+// family is not available, e.g. IPv6 on an IPv4-only runner.
+func GetAvailablePortForFamily(t *testing.T, family AddressFamily) int {
+	if family != IPv4 && family != IPv6 && family != DualStack {
+		require.FailNow(t, "Unknown address family", family.String())
+	}
+	for i := 0; i < maxDualStackAttempts; i++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort(family.LoopbackHost(), "0"))
+		require.NoError(t, err, "Failed to get a free %s port", family)
+		// There is a possible race if something else takes this same port before
+		// the test uses it, however, that is unlikely in practice.
+		port := ln.Addr().(*net.TCPAddr).Port
+		ln.Close()
+		if family != DualStack {
+			return port
+		}
+		// The port may be taken on the IPv6 loopback by an unrelated listener.
+		ln, err = net.Listen("tcp6", net.JoinHostPort(IPv6.LoopbackHost(), strconv.Itoa(port)))
+		if err == nil {
+			ln.Close()
+			return port
+		}
+	}
+	require.FailNow(t, "Failed to get a free dual-stack port")
+	return 0
+}
@@ -0,0 +1,45 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAvailablePortForFamily(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback not available: %s", err.Error())
+	}
+	ln.Close()
+
+	port := GetAvailablePortForFamily(t, IPv6)
+	ln, err = net.Listen("tcp6", net.JoinHostPort(IPv6.LoopbackHost(), strconv.Itoa(port)))
+	require.NoError(t, err)
+	assert.Equal(t, "[::1]:"+strconv.Itoa(port), ln.Addr().String())
+	require.NoError(t, ln.Close())
+
+	port = GetAvailablePortForFamily(t, DualStack)
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		ln, err = net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		require.NoError(t, err, host)
+		defer ln.Close()
+	}
+}
@@ -15,11 +15,16 @@
 package testbed
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -52,6 +57,24 @@ func (v *PerfTestValidator) Validate(tc *TestCase) {
 func (v *PerfTestValidator) RecordResults(tc *TestCase) {
 	rc := tc.agentProc.GetTotalConsumption()
 
+	var agentEnv []string
+	var agentExe, agentVersion string
+	var queueSizes []QueueSizeSample
+	var agentCPUs []int
+	var agentFeatureGates []string
+	var gcStats GCStats
+	if cp, ok := tc.agentProc.(*ChildProcess); ok {
+		agentEnv = cp.envList()
+		agentFeatureGates = cp.FeatureGates
+		queueSizes = cp.ExporterQueueSizes()
+		gcStats, _ = cp.GCStats()
+		agentCPUs = cp.PinnedCPUs()
+		if cp.hasCustomAgentExe() {
+			agentExe = cp.agentExeAbsPath()
+			agentVersion = cp.AgentVersion()
+		}
+	}
+
 	var result string
 	if tc.t.Failed() {
 		result = "FAIL"
@@ -59,21 +82,1734 @@ func (v *PerfTestValidator) RecordResults(tc *TestCase) {
 		result = "PASS"
 	}
 
-	// Remove "Test" prefix from test name.
-	testName := tc.t.Name()[4:]
+	connStats, _ := tc.MockBackend.ConnectionStats()
+	var numConsumers int
+	if r, ok := tc.MockBackend.receiver.(*BaseOTLPDataReceiver); ok {
+		numConsumers = r.NumConsumers()
+	}
+
+	var activeDuration time.Duration
+	if tc.LoadGenerator.options.IdlePattern.enabled() {
+		activeDuration = tc.LoadGenerator.ActiveDuration()
+	}
+
+	// The pprof extension of the agent writes the CPU profile on shutdown, if configured.
+	componentCPU, err := FindComponentCPUShares(filepath.Join(tc.resultDir, "cpu.prof"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Cannot attribute the agent CPU time to components: %s", err.Error())
+	}
+
+	// Remove "Test" prefix from test name.
+	testName := strings.TrimPrefix(tc.t.Name(), "Test")
+
+	tc.resultsSummary.Add(tc.t.Name(), &PerformanceTestResult{
+		testName:               testName,
+		result:                 result,
+		receivedSpanCount:      tc.MockBackend.DataItemsReceived(),
+		receivedRequests:       tc.MockBackend.RequestsReceived(),
+		sentSpanCount:          tc.LoadGenerator.DataItemsSent(),
+		duration:               time.Since(tc.startTime),
+		cpuPercentageAvg:       rc.CPUPercentAvg,
+		cpuPercentageMax:       rc.CPUPercentMax,
+		ramMibAvg:              rc.RAMMiBAvg,
+		ramMibMax:              rc.RAMMiBMax,
+		threadsMax:             rc.ThreadsMax,
+		errorCause:             tc.errorCause,
+		agentEnv:               agentEnv,
+		agentExe:               agentExe,
+		agentVersion:           agentVersion,
+		agentCPUs:              agentCPUs,
+		agentFeatureGates:      agentFeatureGates,
+		timeToFirstItem:        tc.TimeToFirstItem(),
+		timeToDrain:            tc.TimeToDrain(),
+		drainTimedOut:          tc.DrainTimedOut(),
+		baselineRAMMiB:         tc.BaselineRAMMiB(),
+		runMetadata:            tc.RunMetadata(),
+		activeDuration:         activeDuration,
+		connStats:              connStats,
+		exporterNumConsumers:   numConsumers,
+		queueSizes:             queueSizes,
+		cancelledExports:       tc.LoadGenerator.CancelledExports(),
+		componentCPU:           componentCPU,
+		gcStats:                gcStats,
+		loadGeneratorResources: tc.LoadGenerator.ProcessResourceConsumption(),
+	})
+}
+
+// ScrapeCycleValidator implements TestCaseValidator for metric tests where the data is
+// produced in scrape cycles. In addition to the checks done by PerfTestValidator it
+// verifies that no export batch received by MockBackend mixes data points from more
+// than one scrape cycle. Data points of one cycle are identified by sharing the same
+// timestamp. Recording must be enabled on the MockBackend.
+type ScrapeCycleValidator struct {
+	PerfTestValidator
+}
+
+func (v *ScrapeCycleValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, batch := range FindMixedScrapeCycleBatches(tc.MockBackend.ReceivedMetrics) {
+		assert.Fail(tc.t, "Export batch mixes data points from several scrape cycles.", "%s", batch)
+	}
+}
+
+// MixedScrapeCycleBatch describes a received metrics batch which contains data points
+// from more than one scrape cycle.
+type MixedScrapeCycleBatch struct {
+	// Index of the batch in the list of received batches.
+	Index int
+	// Timestamps of the scrape cycles found in the batch, in ascending order.
+	Timestamps []pdata.Timestamp
+}
+
+func (b MixedScrapeCycleBatch) String() string {
+	timestamps := make([]string, len(b.Timestamps))
+	for i, ts := range b.Timestamps {
+		timestamps[i] = strconv.FormatUint(uint64(ts), 10)
+	}
+	return fmt.Sprintf("batch %d contains timestamps [%s]", b.Index, strings.Join(timestamps, ", "))
+}
+
+// FindMixedScrapeCycleBatches groups the data points of each batch by timestamp and
+// returns the batches which contain more than one distinct timestamp.
+func FindMixedScrapeCycleBatches(batches []pdata.Metrics) []MixedScrapeCycleBatch {
+	var mixed []MixedScrapeCycleBatch
+	for i, md := range batches {
+		cycles := make(map[pdata.Timestamp]struct{})
+		rms := md.ResourceMetrics()
+		for j := 0; j < rms.Len(); j++ {
+			ilms := rms.At(j).InstrumentationLibraryMetrics()
+			for k := 0; k < ilms.Len(); k++ {
+				metrics := ilms.At(k).Metrics()
+				for l := 0; l < metrics.Len(); l++ {
+					forEachDataPoint(metrics.At(l), func(dp dataPoint) {
+						cycles[dp.Timestamp()] = struct{}{}
+					})
+				}
+			}
+		}
+		if len(cycles) <= 1 {
+			continue
+		}
+		timestamps := make([]pdata.Timestamp, 0, len(cycles))
+		for ts := range cycles {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(a, b int) bool { return timestamps[a] < timestamps[b] })
+		mixed = append(mixed, MixedScrapeCycleBatch{Index: i, Timestamps: timestamps})
+	}
+	return mixed
+}
+
+// HistogramBoundsValidator implements TestCaseValidator for metric tests where the
+// histogram bucket boundaries must not be changed by the collector. In addition to the
+// checks done by PerfTestValidator it verifies that every received histogram has the
+// same explicit bounds as the sent histogram with the same metric name. The sent bounds
+// are recorded by the DataProvider returned from NewRecordingDataProvider. Recording
+// must be enabled on the MockBackend.
+type HistogramBoundsValidator struct {
+	PerfTestValidator
+	sentBounds map[string][]float64
+}
+
+// NewHistogramBoundsValidator creates a new HistogramBoundsValidator.
+func NewHistogramBoundsValidator() *HistogramBoundsValidator {
+	return &HistogramBoundsValidator{sentBounds: make(map[string][]float64)}
+}
+
+// RecordSentMetrics records the bounds of the histograms in md keyed by metric name.
+// Only the first bounds seen for each metric name are recorded.
+func (v *HistogramBoundsValidator) RecordSentMetrics(md pdata.Metrics) {
+	forEachHistogramBounds(md, func(name string, bounds []float64) {
+		if _, ok := v.sentBounds[name]; !ok {
+			v.sentBounds[name] = bounds
+		}
+	})
+}
+
+func (v *HistogramBoundsValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, mismatch := range FindHistogramBoundsMismatches(v.sentBounds, tc.MockBackend.ReceivedMetrics) {
+		assert.Fail(tc.t, "Histogram bucket bounds were changed.", "%s", mismatch)
+	}
+}
+
+// HistogramBoundsMismatch describes a received histogram whose explicit bounds differ
+// from the bounds of the sent histogram with the same name.
+type HistogramBoundsMismatch struct {
+	MetricName string
+	Sent       []float64
+	Received   []float64
+}
+
+func (m HistogramBoundsMismatch) String() string {
+	return fmt.Sprintf("metric %q: sent bounds %v, received bounds %v", m.MetricName, m.Sent, m.Received)
+}
+
+// FindHistogramBoundsMismatches compares the explicit bounds of all histograms in the
+// received batches with sentBounds and returns the mismatches. Each distinct pair of
+// sent and received bounds is reported once per metric name. Histograms with names not
+// present in sentBounds are ignored.
+func FindHistogramBoundsMismatches(sentBounds map[string][]float64, received []pdata.Metrics) []HistogramBoundsMismatch {
+	var mismatches []HistogramBoundsMismatch
+	reported := make(map[string]struct{})
+	for _, md := range received {
+		forEachHistogramBounds(md, func(name string, bounds []float64) {
+			sent, ok := sentBounds[name]
+			if !ok || reflect.DeepEqual(normalizeBounds(sent), normalizeBounds(bounds)) {
+				return
+			}
+			key := fmt.Sprintf("%s %v", name, bounds)
+			if _, ok := reported[key]; ok {
+				return
+			}
+			reported[key] = struct{}{}
+			mismatches = append(mismatches, HistogramBoundsMismatch{MetricName: name, Sent: sent, Received: bounds})
+		})
+	}
+	return mismatches
+}
+
+// normalizeBounds returns nil for empty bounds so that nil and empty bounds are equal.
+func normalizeBounds(bounds []float64) []float64 {
+	if len(bounds) == 0 {
+		return nil
+	}
+	return bounds
+}
+
+// forEachHistogramBounds calls fn with the metric name and the explicit bounds of every
+// histogram data point in md.
+func forEachHistogramBounds(md pdata.Metrics, fn func(name string, bounds []float64)) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		ilms := rms.At(i).InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			metrics := ilms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				switch metric.DataType() {
+				case pdata.MetricDataTypeIntHistogram:
+					dps := metric.IntHistogram().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						fn(metric.Name(), dps.At(l).ExplicitBounds())
+					}
+				case pdata.MetricDataTypeDoubleHistogram:
+					dps := metric.DoubleHistogram().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						fn(metric.Name(), dps.At(l).ExplicitBounds())
+					}
+				}
+			}
+		}
+	}
+}
+
+// MetricMetadataValidator implements TestCaseValidator for metric tests where the unit
+// and description of metrics must not be changed by the collector, see
+// LoadOptions.MetricUnits and LoadOptions.MetricDescriptions. In addition to the checks
+// done by PerfTestValidator it verifies that every received metric has the same unit
+// and description as the sent metric with the same name, reporting stripped or altered
+// metadata. The sent metadata is recorded by the DataProvider returned from
+// NewRecordingDataProvider. Recording must be enabled on the MockBackend.
+type MetricMetadataValidator struct {
+	PerfTestValidator
+	sentMetadata map[string]MetricMetadata
+}
+
+// MetricMetadata is the unit and description of a metric.
+type MetricMetadata struct {
+	Unit        string
+	Description string
+}
+
+func (m MetricMetadata) String() string {
+	return fmt.Sprintf("unit %q, description %q", m.Unit, m.Description)
+}
+
+// NewMetricMetadataValidator creates a new MetricMetadataValidator.
+func NewMetricMetadataValidator() *MetricMetadataValidator {
+	return &MetricMetadataValidator{sentMetadata: make(map[string]MetricMetadata)}
+}
+
+// RecordSentMetrics records the metadata of the metrics in md keyed by metric name.
+// Only the first metadata seen for each metric name is recorded.
+func (v *MetricMetadataValidator) RecordSentMetrics(md pdata.Metrics) {
+	forEachMetric(md, func(metric pdata.Metric) {
+		if _, ok := v.sentMetadata[metric.Name()]; !ok {
+			v.sentMetadata[metric.Name()] = MetricMetadata{Unit: metric.Unit(), Description: metric.Description()}
+		}
+	})
+}
+
+func (v *MetricMetadataValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, mismatch := range FindMetricMetadataMismatches(v.sentMetadata, tc.MockBackend.ReceivedMetrics) {
+		assert.Fail(tc.t, "Metric metadata was changed.", "%s", mismatch)
+	}
+}
+
+// MetricMetadataMismatch describes a received metric whose unit or description differs
+// from the sent metric with the same name.
+type MetricMetadataMismatch struct {
+	MetricName string
+	Sent       MetricMetadata
+	Received   MetricMetadata
+}
+
+func (m MetricMetadataMismatch) String() string {
+	return fmt.Sprintf("metric %q: sent %s, received %s", m.MetricName, m.Sent, m.Received)
+}
+
+// FindMetricMetadataMismatches compares the metadata of all metrics in the received
+// batches with sentMetadata and returns the mismatches. Each distinct received metadata
+// is reported once per metric name. Metrics with names not present in sentMetadata are
+// ignored.
+func FindMetricMetadataMismatches(sentMetadata map[string]MetricMetadata, received []pdata.Metrics) []MetricMetadataMismatch {
+	var mismatches []MetricMetadataMismatch
+	reported := make(map[MetricMetadataMismatch]struct{})
+	for _, md := range received {
+		forEachMetric(md, func(metric pdata.Metric) {
+			sent, ok := sentMetadata[metric.Name()]
+			if !ok {
+				return
+			}
+			mismatch := MetricMetadataMismatch{
+				MetricName: metric.Name(),
+				Sent:       sent,
+				Received:   MetricMetadata{Unit: metric.Unit(), Description: metric.Description()},
+			}
+			if mismatch.Received == sent {
+				return
+			}
+			if _, ok := reported[mismatch]; ok {
+				return
+			}
+			reported[mismatch] = struct{}{}
+			mismatches = append(mismatches, mismatch)
+		})
+	}
+	return mismatches
+}
+
+// InstrumentationLibraryValidator implements TestCaseValidator for metric tests where the
+// instrumentation library set via LoadOptions.InstrumentationLibraryName and
+// LoadOptions.InstrumentationLibraryVersion must not be changed by the collector. In
+// addition to the checks done by PerfTestValidator it verifies that all received metrics
+// belong to the expected instrumentation library. Recording must be enabled on the
+// MockBackend.
+type InstrumentationLibraryValidator struct {
+	PerfTestValidator
+	expected InstrumentationLibrary
+}
+
+// InstrumentationLibrary is the name and version of an instrumentation library.
+type InstrumentationLibrary struct {
+	Name    string
+	Version string
+}
+
+func (l InstrumentationLibrary) String() string {
+	return fmt.Sprintf("name %q, version %q", l.Name, l.Version)
+}
+
+// NewInstrumentationLibraryValidator creates a new InstrumentationLibraryValidator which
+// expects the instrumentation library name and version given in options.
+func NewInstrumentationLibraryValidator(options LoadOptions) *InstrumentationLibraryValidator {
+	return &InstrumentationLibraryValidator{expected: InstrumentationLibrary{
+		Name:    options.InstrumentationLibraryName,
+		Version: options.InstrumentationLibraryVersion,
+	}}
+}
+
+func (v *InstrumentationLibraryValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, mismatch := range FindInstrumentationLibraryMismatches(v.expected, tc.MockBackend.ReceivedMetrics) {
+		assert.Fail(tc.t, "Instrumentation library was changed.", "%s", mismatch)
+	}
+}
+
+// InstrumentationLibraryMismatch describes received metrics which belong to an
+// instrumentation library other than the expected one.
+type InstrumentationLibraryMismatch struct {
+	Expected    InstrumentationLibrary
+	Received    InstrumentationLibrary
+	MetricCount int
+}
+
+func (m InstrumentationLibraryMismatch) String() string {
+	return fmt.Sprintf("%d metrics: expected %s, received %s", m.MetricCount, m.Expected, m.Received)
+}
+
+// FindInstrumentationLibraryMismatches returns one mismatch for every distinct
+// instrumentation library other than expected found in the received batches, together
+// with the number of metrics belonging to it. Mismatches are returned in the order they
+// are first seen.
+func FindInstrumentationLibraryMismatches(expected InstrumentationLibrary, received []pdata.Metrics) []InstrumentationLibraryMismatch {
+	var mismatches []InstrumentationLibraryMismatch
+	index := make(map[InstrumentationLibrary]int)
+	for _, md := range received {
+		rms := md.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			ilms := rms.At(i).InstrumentationLibraryMetrics()
+			for j := 0; j < ilms.Len(); j++ {
+				il := ilms.At(j).InstrumentationLibrary()
+				lib := InstrumentationLibrary{Name: il.Name(), Version: il.Version()}
+				count := ilms.At(j).Metrics().Len()
+				if lib == expected || count == 0 {
+					continue
+				}
+				k, ok := index[lib]
+				if !ok {
+					k = len(mismatches)
+					index[lib] = k
+					mismatches = append(mismatches, InstrumentationLibraryMismatch{Expected: expected, Received: lib})
+				}
+				mismatches[k].MetricCount += count
+			}
+		}
+	}
+	return mismatches
+}
+
+// forEachMetric calls fn with every metric in md.
+func forEachMetric(md pdata.Metrics, fn func(metric pdata.Metric)) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		ilms := rms.At(i).InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			metrics := ilms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				fn(metrics.At(k))
+			}
+		}
+	}
+}
+
+// LogBodyValidator implements TestCaseValidator for log tests where the bodies of the log
+// records must not be changed by the collector, e.g. binary or multiline bodies generated
+// with LoadOptions.LogBodyFormat. In addition to the checks done by PerfTestValidator it
+// verifies that every received log record has byte-for-byte the same body as the sent
+// record with the same sequence number, which catches encoding bugs in logs processors.
+// The sent bodies are recorded by the DataProvider returned from NewRecordingDataProvider.
+// Recording must be enabled on the MockBackend.
+type LogBodyValidator struct {
+	PerfTestValidator
+	sentBodies map[int64]string
+}
+
+// NewLogBodyValidator creates a new LogBodyValidator.
+func NewLogBodyValidator() *LogBodyValidator {
+	return &LogBodyValidator{sentBodies: make(map[int64]string)}
+}
+
+// RecordSentLogs records the bodies of the log records in ld keyed by the record
+// sequence number. Records without a sequence number are ignored.
+func (v *LogBodyValidator) RecordSentLogs(ld pdata.Logs) {
+	forEachLogBody(ld, func(seqNum int64, body string) {
+		v.sentBodies[seqNum] = body
+	})
+}
+
+func (v *LogBodyValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, mismatch := range FindLogBodyMismatches(v.sentBodies, tc.MockBackend.ReceivedLogs) {
+		assert.Fail(tc.t, "Log body was changed.", "%s", mismatch)
+	}
+}
+
+// LogBodyMismatch describes a received log record whose body differs from the body of
+// the sent record with the same sequence number.
+type LogBodyMismatch struct {
+	LogSeqNum int64
+	Sent      string
+	Received  string
+	// Offset of the first byte which differs, or the length of the shorter body if one
+	// is a prefix of the other.
+	Offset int
+}
+
+func (m LogBodyMismatch) String() string {
+	return fmt.Sprintf("log record %d: body differs at byte %d: sent %d bytes %s, received %d bytes %s",
+		m.LogSeqNum, m.Offset, len(m.Sent), quoteLogBody(m.Sent, m.Offset), len(m.Received), quoteLogBody(m.Received, m.Offset))
+}
+
+// logBodyQuoteBytes is the number of bytes of a body quoted in a LogBodyMismatch,
+// starting at the first difference.
+const logBodyQuoteBytes = 16
+
+// quoteLogBody quotes up to logBodyQuoteBytes of body starting at offset, escaping
+// non-ASCII and non-printable bytes.
+func quoteLogBody(body string, offset int) string {
+	if offset > len(body) {
+		offset = len(body)
+	}
+	end := offset + logBodyQuoteBytes
+	suffix := "..."
+	if end >= len(body) {
+		end = len(body)
+		suffix = ""
+	}
+	prefix := ""
+	if offset > 0 {
+		prefix = "..."
+	}
+	return prefix + strconv.QuoteToASCII(body[offset:end]) + suffix
+}
+
+// FindLogBodyMismatches compares the bodies of all log records in the received batches
+// with sentBodies and returns the mismatches in the order the records were received.
+// Records with sequence numbers not present in sentBodies are ignored.
+func FindLogBodyMismatches(sentBodies map[int64]string, received []pdata.Logs) []LogBodyMismatch {
+	var mismatches []LogBodyMismatch
+	for _, ld := range received {
+		forEachLogBody(ld, func(seqNum int64, body string) {
+			sent, ok := sentBodies[seqNum]
+			if !ok || sent == body {
+				return
+			}
+			offset := 0
+			for offset < len(sent) && offset < len(body) && sent[offset] == body[offset] {
+				offset++
+			}
+			mismatches = append(mismatches, LogBodyMismatch{LogSeqNum: seqNum, Sent: sent, Received: body, Offset: offset})
+		})
+	}
+	return mismatches
+}
+
+// forEachLogBody calls fn with the sequence number and the body of every log record in
+// ld which has a sequence number, either the LogSeqNumAttribute set by
+// LogCorrectnessDataProvider or the item_index attribute set by PerfTestDataProvider.
+// Bodies which are not strings are formatted with their type, so that a changed type is
+// reported as a mismatch.
+func forEachLogBody(ld pdata.Logs, fn func(seqNum int64, body string)) {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		ills := rls.At(i).InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			records := ills.At(j).Logs()
+			for k := 0; k < records.Len(); k++ {
+				record := records.At(k)
+				seqNum, ok := logRecordSeqNum(record)
+				if !ok {
+					continue
+				}
+				body := record.Body()
+				if body.Type() == pdata.AttributeValueSTRING {
+					fn(seqNum, body.StringVal())
+				} else {
+					fn(seqNum, fmt.Sprintf("<%s> %v", body.Type(), attributeValueToRaw(body)))
+				}
+			}
+		}
+	}
+}
+
+// logRecordSeqNum returns the sequence number of a log record generated by
+// LogCorrectnessDataProvider or PerfTestDataProvider.
+func logRecordSeqNum(record pdata.LogRecord) (int64, bool) {
+	attrs := record.Attributes()
+	if seqNumAttr, ok := attrs.Get(LogSeqNumAttribute); ok && seqNumAttr.Type() == pdata.AttributeValueINT {
+		return seqNumAttr.IntVal(), true
+	}
+	if itemIndexAttr, ok := attrs.Get("item_index"); ok && itemIndexAttr.Type() == pdata.AttributeValueSTRING {
+		seqNum, err := strconv.ParseInt(strings.TrimPrefix(itemIndexAttr.StringVal(), "item_"), 10, 64)
+		return seqNum, err == nil
+	}
+	return 0, false
+}
+
+// SpanContextValidator implements TestCaseValidator for trace tests where the collector
+// must propagate the span context unchanged. In addition to the checks done by
+// PerfTestValidator it verifies that every received span has byte-for-byte the same trace
+// ID and span ID as the sent span with the same sequence number, which catches truncated
+// or regenerated IDs. The sent IDs are recorded by the DataProvider returned from
+// NewRecordingDataProvider. Recording must be enabled on the MockBackend.
+type SpanContextValidator struct {
+	PerfTestValidator
+	sentIDs map[int64]SpanContextIDs
+}
+
+// SpanContextIDs holds the trace ID and span ID of a span.
+type SpanContextIDs struct {
+	TraceID pdata.TraceID
+	SpanID  pdata.SpanID
+}
+
+// String returns the hex encoded trace ID and span ID. Unlike HexString, empty IDs are
+// encoded as zeros so that cleared IDs are visible in mismatch reports.
+func (ids SpanContextIDs) String() string {
+	traceID := ids.TraceID.Bytes()
+	spanID := ids.SpanID.Bytes()
+	return hex.EncodeToString(traceID[:]) + "-" + hex.EncodeToString(spanID[:])
+}
+
+// spanContextIDs returns the trace ID and span ID of span.
+func spanContextIDs(span pdata.Span) SpanContextIDs {
+	return SpanContextIDs{TraceID: span.TraceID(), SpanID: span.SpanID()}
+}
+
+// spanLinkIDs returns the trace IDs and span IDs of the links of span, or nil if it has
+// no links.
+func spanLinkIDs(span pdata.Span) []SpanContextIDs {
+	var links []SpanContextIDs
+	for i := 0; i < span.Links().Len(); i++ {
+		link := span.Links().At(i)
+		links = append(links, SpanContextIDs{TraceID: link.TraceID(), SpanID: link.SpanID()})
+	}
+	return links
+}
+
+// NewSpanContextValidator creates a new SpanContextValidator.
+func NewSpanContextValidator() *SpanContextValidator {
+	return &SpanContextValidator{sentIDs: make(map[int64]SpanContextIDs)}
+}
+
+// RecordSentTraces records the trace ID and span ID of the spans in td keyed by the
+// span sequence number. Spans without a sequence number are ignored.
+func (v *SpanContextValidator) RecordSentTraces(td pdata.Traces) {
+	forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+		v.sentIDs[seqNum] = spanContextIDs(span)
+	})
+}
+
+func (v *SpanContextValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, mismatch := range FindSpanContextMismatches(v.sentIDs, tc.MockBackend.ReceivedTraces) {
+		assert.Fail(tc.t, "Span context was changed.", "%s", mismatch)
+	}
+}
+
+// SpanContextMismatch describes a received span whose trace ID or span ID differs from
+// the IDs of the sent span with the same sequence number.
+type SpanContextMismatch struct {
+	SpanSeqNum int64
+	Sent       SpanContextIDs
+	Received   SpanContextIDs
+}
+
+func (m SpanContextMismatch) String() string {
+	return fmt.Sprintf("span %d: sent %s, received %s", m.SpanSeqNum, m.Sent, m.Received)
+}
+
+// FindSpanContextMismatches compares the trace ID and span ID of all spans in the
+// received batches with sentIDs and returns the mismatches in the order the spans were
+// received. Spans with sequence numbers not present in sentIDs are ignored.
+func FindSpanContextMismatches(sentIDs map[int64]SpanContextIDs, received []pdata.Traces) []SpanContextMismatch {
+	var mismatches []SpanContextMismatch
+	for _, td := range received {
+		forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+			ids := spanContextIDs(span)
+			sent, ok := sentIDs[seqNum]
+			if !ok || sent == ids {
+				return
+			}
+			mismatches = append(mismatches, SpanContextMismatch{SpanSeqNum: seqNum, Sent: sent, Received: ids})
+		})
+	}
+	return mismatches
+}
+
+// SpanLinkValidator implements TestCaseValidator for trace tests with span links, see
+// LoadOptions.SpanLinksPerSpan. In addition to the checks done by PerfTestValidator it
+// verifies that every received span has the same links, with the same trace IDs and
+// span IDs in the same order, as the sent span with the same sequence number. The sent
+// links are recorded by the DataProvider returned from NewRecordingDataProvider.
+// Recording must be enabled on the MockBackend.
+type SpanLinkValidator struct {
+	PerfTestValidator
+	sentLinks map[int64][]SpanContextIDs
+}
+
+// NewSpanLinkValidator creates a new SpanLinkValidator.
+func NewSpanLinkValidator() *SpanLinkValidator {
+	return &SpanLinkValidator{sentLinks: make(map[int64][]SpanContextIDs)}
+}
+
+// RecordSentTraces records the links of the spans in td keyed by the span sequence
+// number. Spans without a sequence number are ignored.
+func (v *SpanLinkValidator) RecordSentTraces(td pdata.Traces) {
+	forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+		v.sentLinks[seqNum] = spanLinkIDs(span)
+	})
+}
+
+func (v *SpanLinkValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, mismatch := range FindSpanLinkMismatches(v.sentLinks, tc.MockBackend.ReceivedTraces) {
+		assert.Fail(tc.t, "Span links were changed.", "%s", mismatch)
+	}
+}
+
+// SpanLinkMismatch describes a received span whose links differ from the links of the
+// sent span with the same sequence number.
+type SpanLinkMismatch struct {
+	SpanSeqNum int64
+	Sent       []SpanContextIDs
+	Received   []SpanContextIDs
+}
+
+func (m SpanLinkMismatch) String() string {
+	return fmt.Sprintf("span %d: sent links %v, received links %v", m.SpanSeqNum, m.Sent, m.Received)
+}
+
+// FindSpanLinkMismatches compares the links of all spans in the received batches with
+// sentLinks and returns the mismatches in the order the spans were received. Spans with
+// sequence numbers not present in sentLinks are ignored.
+func FindSpanLinkMismatches(sentLinks map[int64][]SpanContextIDs, received []pdata.Traces) []SpanLinkMismatch {
+	var mismatches []SpanLinkMismatch
+	for _, td := range received {
+		forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+			links := spanLinkIDs(span)
+			sent, ok := sentLinks[seqNum]
+			if !ok || reflect.DeepEqual(sent, links) {
+				return
+			}
+			mismatches = append(mismatches, SpanLinkMismatch{SpanSeqNum: seqNum, Sent: sent, Received: links})
+		})
+	}
+	return mismatches
+}
+
+// ClockSkewValidator implements TestCaseValidator for trace tests with clock-skewed
+// spans, see LoadOptions.ClockSkewedSpanRate. In addition to the checks done by
+// PerfTestValidator, which verifies that no skewed span was dropped, it flags the
+// received spans whose end is before their start or which start in the future, and
+// verifies that exactly the spans which were sent with clock skew are flagged, i.e.
+// that the collector neither corrected nor introduced skew. The skew of the sent spans
+// is recorded by the DataProvider returned from NewRecordingDataProvider. Recording
+// must be enabled on the MockBackend.
+type ClockSkewValidator struct {
+	PerfTestValidator
+	sentSkews map[int64]ClockSkew
+	flagged   int
+}
+
+// ClockSkew is the kind of clock skew of a span.
+type ClockSkew string
+
+const (
+	// ClockSkewNone is the ClockSkew of a span with plausible timestamps.
+	ClockSkewNone ClockSkew = "none"
+	// ClockSkewNegativeDuration is the ClockSkew of a span which ends before it starts.
+	ClockSkewNegativeDuration ClockSkew = "negative_duration"
+	// ClockSkewFuture is the ClockSkew of a span which starts in the future.
+	ClockSkewFuture ClockSkew = "future"
+)
+
+// SpanClockSkew returns the ClockSkew of span, which starts in the future if it starts
+// after now.
+func SpanClockSkew(span pdata.Span, now time.Time) ClockSkew {
+	switch {
+	case span.EndTime() < span.StartTime():
+		return ClockSkewNegativeDuration
+	case span.StartTime() > pdata.TimestampFromTime(now):
+		return ClockSkewFuture
+	}
+	return ClockSkewNone
+}
+
+// NewClockSkewValidator creates a new ClockSkewValidator.
+func NewClockSkewValidator() *ClockSkewValidator {
+	return &ClockSkewValidator{sentSkews: make(map[int64]ClockSkew)}
+}
+
+// RecordSentTraces records the clock skew of the spans in td, as of now, keyed by the
+// span sequence number. Spans without a sequence number are ignored.
+func (v *ClockSkewValidator) RecordSentTraces(td pdata.Traces) {
+	now := time.Now()
+	forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+		v.sentSkews[seqNum] = SpanClockSkew(span, now)
+	})
+}
+
+func (v *ClockSkewValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	flagged := FindClockSkewedSpans(tc.MockBackend.ReceivedTraces, time.Now())
+	v.flagged = len(flagged)
+	log.Printf("Flagged %d clock-skewed spans.", len(flagged))
+	for _, mismatch := range FindClockSkewMismatches(v.sentSkews, tc.MockBackend.ReceivedTraces, time.Now()) {
+		assert.Fail(tc.t, "Span clock skew was changed.", "%s", mismatch)
+	}
+}
+
+// FlaggedSpans returns the number of received clock-skewed spans flagged by Validate.
+func (v *ClockSkewValidator) FlaggedSpans() int {
+	return v.flagged
+}
+
+// ClockSkewedSpan describes a received span with clock skew.
+type ClockSkewedSpan struct {
+	SpanSeqNum int64
+	Skew       ClockSkew
+	StartTime  pdata.Timestamp
+	EndTime    pdata.Timestamp
+}
+
+func (s ClockSkewedSpan) String() string {
+	return fmt.Sprintf("span %d: %s, start %d, end %d", s.SpanSeqNum, s.Skew, s.StartTime, s.EndTime)
+}
+
+// FindClockSkewedSpans returns the spans with a sequence number in the received batches
+// which end before they start or start after now, in the order they were received.
+func FindClockSkewedSpans(received []pdata.Traces, now time.Time) []ClockSkewedSpan {
+	var skewed []ClockSkewedSpan
+	for _, td := range received {
+		forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+			if skew := SpanClockSkew(span, now); skew != ClockSkewNone {
+				skewed = append(skewed, ClockSkewedSpan{
+					SpanSeqNum: seqNum,
+					Skew:       skew,
+					StartTime:  span.StartTime(),
+					EndTime:    span.EndTime(),
+				})
+			}
+		})
+	}
+	return skewed
+}
+
+// ClockSkewMismatch describes a received span whose clock skew differs from the clock
+// skew of the sent span with the same sequence number.
+type ClockSkewMismatch struct {
+	SpanSeqNum int64
+	Sent       ClockSkew
+	Received   ClockSkew
+}
+
+func (m ClockSkewMismatch) String() string {
+	return fmt.Sprintf("span %d: sent with clock skew %s, received with %s", m.SpanSeqNum, m.Sent, m.Received)
+}
+
+// FindClockSkewMismatches compares the clock skew, as of now, of all spans in the
+// received batches with sentSkews and returns the mismatches in the order the spans were
+// received. Spans with sequence numbers not present in sentSkews are ignored.
+func FindClockSkewMismatches(sentSkews map[int64]ClockSkew, received []pdata.Traces, now time.Time) []ClockSkewMismatch {
+	var mismatches []ClockSkewMismatch
+	for _, td := range received {
+		forEachSeqNumSpan(td, func(seqNum int64, span pdata.Span) {
+			sent, ok := sentSkews[seqNum]
+			if !ok {
+				return
+			}
+			if skew := SpanClockSkew(span, now); skew != sent {
+				mismatches = append(mismatches, ClockSkewMismatch{SpanSeqNum: seqNum, Sent: sent, Received: skew})
+			}
+		})
+	}
+	return mismatches
+}
+
+// forEachSeqNumSpan calls fn with the sequence number and the span of every span in td
+// which has a sequence number.
+func forEachSeqNumSpan(td pdata.Traces, fn func(seqNum int64, span pdata.Span)) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				if seqNumAttr, ok := span.Attributes().Get(SpanSeqNumAttribute); ok {
+					fn(seqNumAttr.IntVal(), span)
+				}
+			}
+		}
+	}
+}
+
+// AttributeLimitValidator implements TestCaseValidator for trace tests where the
+// collector limits the number of span attributes. In addition to the checks done by
+// PerfTestValidator it verifies that every received span has at most the configured
+// number of attributes, that it still has all attributes which the limit policy keeps
+// and that it has none of the attributes which the policy drops. Use
+// LoadOptions.AttributeValueTypes to generate spans exceeding the limit. Recording must
+// be enabled on the MockBackend.
+type AttributeLimitValidator struct {
+	PerfTestValidator
+	limit   int
+	kept    []string
+	dropped []string
+}
+
+// NewAttributeLimitValidator creates an AttributeLimitValidator for a limit of limit
+// attributes per span which keeps the attributes with the keys in kept and drops the
+// attributes with the keys in dropped.
+func NewAttributeLimitValidator(limit int, kept []string, dropped []string) *AttributeLimitValidator {
+	return &AttributeLimitValidator{limit: limit, kept: kept, dropped: dropped}
+}
+
+func (v *AttributeLimitValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, violation := range FindAttributeLimitViolations(v.limit, v.kept, v.dropped, tc.MockBackend.ReceivedTraces) {
+		assert.Fail(tc.t, "Attribute limit was not applied.", "%s", violation)
+	}
+}
+
+// AttributeLimitViolation describes a received span whose attributes do not conform to
+// the attribute limit policy.
+type AttributeLimitViolation struct {
+	SpanID pdata.SpanID
+	// Number of attributes of the span.
+	Count int
+	// Keys which should have been kept but are missing.
+	Missing []string
+	// Keys which should have been dropped but are present.
+	Unexpected []string
+}
+
+func (v AttributeLimitViolation) String() string {
+	return fmt.Sprintf("span %s: %d attributes, missing %v, unexpected %v",
+		v.SpanID.HexString(), v.Count, v.Missing, v.Unexpected)
+}
+
+// FindAttributeLimitViolations checks the attributes of all spans in the received
+// batches against the limit and the kept and dropped keys, and returns the violations
+// in the order the spans were received.
+func FindAttributeLimitViolations(limit int, kept []string, dropped []string, received []pdata.Traces) []AttributeLimitViolation {
+	var violations []AttributeLimitViolation
+	for _, td := range received {
+		rss := td.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			ilss := rss.At(i).InstrumentationLibrarySpans()
+			for j := 0; j < ilss.Len(); j++ {
+				spans := ilss.At(j).Spans()
+				for k := 0; k < spans.Len(); k++ {
+					span := spans.At(k)
+					attrs := span.Attributes()
+					violation := AttributeLimitViolation{SpanID: span.SpanID(), Count: attrs.Len()}
+					for _, key := range kept {
+						if _, ok := attrs.Get(key); !ok {
+							violation.Missing = append(violation.Missing, key)
+						}
+					}
+					for _, key := range dropped {
+						if _, ok := attrs.Get(key); ok {
+							violation.Unexpected = append(violation.Unexpected, key)
+						}
+					}
+					if violation.Count > limit || len(violation.Missing) > 0 || len(violation.Unexpected) > 0 {
+						violations = append(violations, violation)
+					}
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// CounterResetValidator implements TestCaseValidator for metric tests where the counters
+// are reset, see LoadOptions.CounterResetInterval. In addition to the checks done by
+// PerfTestValidator it verifies that every reset received by MockBackend can be
+// detected, i.e. that whenever the value of a cumulative int sum series drops its start
+// timestamp advances. The data points of each series must be received in the order they
+// were generated. Recording must be enabled on the MockBackend.
+type CounterResetValidator struct {
+	PerfTestValidator
+
+	// MinResets is the minimum number of resets which must be received. Can be 0.
+	MinResets int
+}
+
+func (v *CounterResetValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	resets := FindCounterResets(tc.MockBackend.ReceivedMetrics)
+	for _, reset := range resets {
+		if !reset.Detectable() {
+			assert.Fail(tc.t, "Counter reset without start timestamp change.", "%s", reset)
+		}
+	}
+	assert.GreaterOrEqual(tc.t, len(resets), v.MinResets, "Too few counter resets received.")
+}
+
+// CounterReset describes a drop of the value of a cumulative sum series between two
+// consecutively received data points.
+type CounterReset struct {
+	MetricName    string
+	Labels        string
+	PrevValue     int64
+	Value         int64
+	PrevStartTime pdata.Timestamp
+	StartTime     pdata.Timestamp
+}
+
+// Detectable returns true if the start timestamp advanced with the reset, which allows
+// consumers to tell the reset apart from a decreasing counter.
+func (r CounterReset) Detectable() bool {
+	return r.StartTime > r.PrevStartTime
+}
+
+func (r CounterReset) String() string {
+	return fmt.Sprintf("metric %q {%s}: value dropped from %d to %d, start time %d -> %d",
+		r.MetricName, r.Labels, r.PrevValue, r.Value, r.PrevStartTime, r.StartTime)
+}
+
+// FindCounterResets returns the value drops of all cumulative int sum series in the
+// received batches in the order they were received. A series is identified by the
+// metric name and the data point labels.
+func FindCounterResets(received []pdata.Metrics) []CounterReset {
+	var resets []CounterReset
+	last := make(map[string]pdata.IntDataPoint)
+	for _, md := range received {
+		rms := md.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			ilms := rms.At(i).InstrumentationLibraryMetrics()
+			for j := 0; j < ilms.Len(); j++ {
+				metrics := ilms.At(j).Metrics()
+				for k := 0; k < metrics.Len(); k++ {
+					metric := metrics.At(k)
+					if metric.DataType() != pdata.MetricDataTypeIntSum ||
+						metric.IntSum().AggregationTemporality() != pdata.AggregationTemporalityCumulative {
+						continue
+					}
+					dps := metric.IntSum().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						dp := dps.At(l)
+						labels := formatLabels(dp.LabelsMap())
+						key := metric.Name() + "{" + labels + "}"
+						if prev, ok := last[key]; ok && dp.Value() < prev.Value() {
+							resets = append(resets, CounterReset{
+								MetricName:    metric.Name(),
+								Labels:        labels,
+								PrevValue:     prev.Value(),
+								Value:         dp.Value(),
+								PrevStartTime: prev.StartTime(),
+								StartTime:     dp.StartTime(),
+							})
+						}
+						last[key] = dp
+					}
+				}
+			}
+		}
+	}
+	return resets
+}
+
+// formatLabels formats labels as comma separated key=value pairs sorted by key.
+func formatLabels(labels pdata.StringMap) string {
+	var pairs []string
+	labels.ForEach(func(k string, v string) {
+		pairs = append(pairs, k+"="+v)
+	})
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// MonotonicTimestampValidator implements TestCaseValidator for metric tests where the
+// collector must not reorder data points. In addition to the checks done by
+// PerfTestValidator it verifies that the timestamps of every series received by
+// MockBackend never go backward. A series is identified by the metric name and the data
+// point labels. The collector must export the batches in order, e.g. with a single
+// sending queue consumer. Recording must be enabled on the MockBackend.
+type MonotonicTimestampValidator struct {
+	PerfTestValidator
+}
+
+func (v *MonotonicTimestampValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	for _, regression := range FindTimestampRegressions(tc.MockBackend.ReceivedMetrics) {
+		assert.Fail(tc.t, "Series timestamp went backward.", "%s", regression)
+	}
+}
+
+// TimestampRegression describes a data point received after a data point of the same
+// series with a later timestamp.
+type TimestampRegression struct {
+	MetricName    string
+	Labels        string
+	PrevTimestamp pdata.Timestamp
+	Timestamp     pdata.Timestamp
+}
+
+func (r TimestampRegression) String() string {
+	return fmt.Sprintf("metric %q {%s}: timestamp went backward from %d to %d",
+		r.MetricName, r.Labels, r.PrevTimestamp, r.Timestamp)
+}
+
+// FindTimestampRegressions returns the data points of all series in the received batches
+// whose timestamp is earlier than the timestamp of the previously received data point of
+// the same series, in the order they were received.
+func FindTimestampRegressions(received []pdata.Metrics) []TimestampRegression {
+	var regressions []TimestampRegression
+	last := make(map[string]pdata.Timestamp)
+	for _, md := range received {
+		forEachMetric(md, func(metric pdata.Metric) {
+			forEachDataPoint(metric, func(dp dataPoint) {
+				labels := formatLabels(dp.LabelsMap())
+				ts := dp.Timestamp()
+				key := metric.Name() + "{" + labels + "}"
+				if prev, ok := last[key]; ok && ts < prev {
+					regressions = append(regressions, TimestampRegression{
+						MetricName:    metric.Name(),
+						Labels:        labels,
+						PrevTimestamp: prev,
+						Timestamp:     ts,
+					})
+				}
+				last[key] = ts
+			})
+		})
+	}
+	return regressions
+}
+
+// SamplingValidator implements TestCaseValidator for trace tests where the collector
+// samples traces. It expects the traces to be generated by PerfTestDataProvider, one
+// trace per batch, and verifies that the fraction of received traces is within
+// statistical tolerance of the configured sampling probability and that every received
+// trace is complete, i.e. that all spans of a trace are kept or dropped together.
+// Traces are identified by the "load_generator.trace_seq_num" span attribute. The spans
+// of the sent traces are counted by the DataProvider returned from
+// NewRecordingDataProvider. Recording must be enabled on the MockBackend.
+type SamplingValidator struct {
+	PerfTestValidator
+	probability float64
+	sentSpans   map[int64]int
+}
+
+// NewSamplingValidator creates a SamplingValidator for a sampler configured with the
+// given probability in the [0, 1] range.
+func NewSamplingValidator(probability float64) *SamplingValidator {
+	return &SamplingValidator{probability: probability, sentSpans: make(map[int64]int)}
+}
+
+// RecordSentTraces counts the spans of each trace in td.
+func (v *SamplingValidator) RecordSentTraces(td pdata.Traces) {
+	addSpansByTraceSeqNum(v.sentSpans, td)
+}
+
+func (v *SamplingValidator) Validate(tc *TestCase) {
+	sentSpans := SentSpansByTrace(v.sentSpans, tc.LoadGenerator.DataItemsSent())
+	sentTraces := uint64(len(sentSpans))
+	if !assert.NotZero(tc.t, sentTraces, "No traces were sent.") {
+		return
+	}
+
+	for _, trace := range FindPartiallySampledTraces(sentSpans, tc.MockBackend.ReceivedTraces) {
+		assert.Fail(tc.t, "Trace was sampled partially.", "%s", trace)
+	}
 
-	tc.resultsSummary.Add(tc.t.Name(), &PerformanceTestResult{
-		testName:          testName,
-		result:            result,
-		receivedSpanCount: tc.MockBackend.DataItemsReceived(),
-		sentSpanCount:     tc.LoadGenerator.DataItemsSent(),
-		duration:          time.Since(tc.startTime),
-		cpuPercentageAvg:  rc.CPUPercentAvg,
-		cpuPercentageMax:  rc.CPUPercentMax,
-		ramMibAvg:         rc.RAMMiBAvg,
-		ramMibMax:         rc.RAMMiBMax,
-		errorCause:        tc.errorCause,
+	receivedTraces := uint64(len(countSpansByTraceSeqNum(tc.MockBackend.ReceivedTraces)))
+	fraction := float64(receivedTraces) / float64(sentTraces)
+	tolerance := SamplingTolerance(v.probability, sentTraces)
+	log.Printf("Sampled %d of %d traces (%.4f), expected %.4f +/- %.4f.",
+		receivedTraces, sentTraces, fraction, v.probability, tolerance)
+	assert.InDelta(tc.t, v.probability, fraction, tolerance,
+		"Sampled fraction of %d traces is out of tolerance.", sentTraces)
+}
+
+// FindPartiallySampledTraces returns the received traces, given the number of sent spans
+// of each trace keyed by its sequence number, of which a different number of spans was
+// received than sent, ordered by sequence number.
+func FindPartiallySampledTraces(sentSpans map[int64]int, received []pdata.Traces) []IncompleteTrace {
+	var partial []IncompleteTrace
+	for seqNum, count := range countSpansByTraceSeqNum(received) {
+		if count != sentSpans[seqNum] {
+			partial = append(partial, IncompleteTrace{
+				TraceSeqNum: seqNum,
+				Received:    count,
+				Expected:    sentSpans[seqNum],
+			})
+		}
+	}
+	sort.Slice(partial, func(i, j int) bool { return partial[i].TraceSeqNum < partial[j].TraceSeqNum })
+	return partial
+}
+
+// SamplingTolerance returns the allowed deviation of the sampled fraction from the
+// sampling probability for the given number of sampled traces. It is 4 standard
+// deviations of the binomial distribution, which makes a false failure very unlikely.
+func SamplingTolerance(probability float64, traces uint64) float64 {
+	if traces == 0 {
+		return 1
+	}
+	return 4 * math.Sqrt(probability*(1-probability)/float64(traces))
+}
+
+// TraceCompletenessValidator implements TestCaseValidator for trace tests where the
+// collector holds whole traces, e.g. for tail-based sampling. It expects the traces to
+// be generated by PerfTestDataProvider, one trace per batch, and in addition to the
+// checks done by PerfTestValidator verifies that every sent trace was received with all
+// of its spans. Traces are identified by the "load_generator.trace_seq_num" span
+// attribute. The spans of the sent traces are counted by the DataProvider returned from
+// NewRecordingDataProvider. Recording must be enabled on the MockBackend.
+type TraceCompletenessValidator struct {
+	PerfTestValidator
+	sentSpans map[int64]int
+}
+
+// NewTraceCompletenessValidator creates a new TraceCompletenessValidator.
+func NewTraceCompletenessValidator() *TraceCompletenessValidator {
+	return &TraceCompletenessValidator{sentSpans: make(map[int64]int)}
+}
+
+// RecordSentTraces counts the spans of each trace in td.
+func (v *TraceCompletenessValidator) RecordSentTraces(td pdata.Traces) {
+	addSpansByTraceSeqNum(v.sentSpans, td)
+}
+
+func (v *TraceCompletenessValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	sentSpans := SentSpansByTrace(v.sentSpans, tc.LoadGenerator.DataItemsSent())
+	for _, trace := range FindIncompleteTraces(sentSpans, tc.MockBackend.ReceivedTraces) {
+		assert.Fail(tc.t, "Trace was received incomplete.", "%s", trace)
+	}
+}
+
+// SentSpansByTrace returns the number of spans of each generated trace which were sent,
+// given the span counts of the generated traces and the number of sent spans. Once the
+// count requested by LoadGenerator.StartCount is reached the load generator stops
+// sending in the middle of the last trace, so the generated spans in excess of sentSpans
+// are removed from the trace with the highest sequence number, and the trace is removed
+// if none of its spans were sent.
+func SentSpansByTrace(generated map[int64]int, sentSpans uint64) map[int64]int {
+	sent := make(map[int64]int, len(generated))
+	var total uint64
+	var last int64
+	for seqNum, count := range generated {
+		sent[seqNum] = count
+		total += uint64(count)
+		if seqNum > last {
+			last = seqNum
+		}
+	}
+	if total > sentSpans {
+		sent[last] -= int(total - sentSpans)
+		if sent[last] <= 0 {
+			delete(sent, last)
+		}
+	}
+	return sent
+}
+
+// IncompleteTrace describes a sent trace of which a different number of spans was
+// received than sent, see FindIncompleteTraces and FindPartiallySampledTraces.
+type IncompleteTrace struct {
+	TraceSeqNum int64
+	Received    int
+	Expected    int
+}
+
+func (it IncompleteTrace) String() string {
+	return fmt.Sprintf("trace_seq_num=%d: received %d of %d spans", it.TraceSeqNum, it.Received, it.Expected)
+}
+
+// FindIncompleteTraces returns the sent traces, given the number of sent spans of each
+// trace keyed by its sequence number, of which fewer spans were received than sent,
+// ordered by sequence number. Traces which were not received at all are included with 0
+// received spans.
+func FindIncompleteTraces(sentSpans map[int64]int, received []pdata.Traces) []IncompleteTrace {
+	counts := countSpansByTraceSeqNum(received)
+	var incomplete []IncompleteTrace
+	for seqNum, sent := range sentSpans {
+		if count := counts[seqNum]; count < sent {
+			incomplete = append(incomplete, IncompleteTrace{
+				TraceSeqNum: seqNum,
+				Received:    count,
+				Expected:    sent,
+			})
+		}
+	}
+	sort.Slice(incomplete, func(i, j int) bool { return incomplete[i].TraceSeqNum < incomplete[j].TraceSeqNum })
+	return incomplete
+}
+
+// countSpansByTraceSeqNum returns the number of spans of each trace identified by the
+// "load_generator.trace_seq_num" attribute.
+func countSpansByTraceSeqNum(tracesList []pdata.Traces) map[int64]int {
+	counts := make(map[int64]int)
+	for _, td := range tracesList {
+		addSpansByTraceSeqNum(counts, td)
+	}
+	return counts
+}
+
+// addSpansByTraceSeqNum adds the number of spans of each trace in td identified by the
+// "load_generator.trace_seq_num" attribute to counts.
+func addSpansByTraceSeqNum(counts map[int64]int, td pdata.Traces) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				seqNum, ok := spans.At(k).Attributes().Get("load_generator.trace_seq_num")
+				if !ok {
+					continue
+				}
+				counts[seqNum.IntVal()]++
+			}
+		}
+	}
+}
+
+// CPUDriftValidator implements TestCaseValidator for soak tests. In addition to the
+// checks done by PerfTestValidator it fits a least squares trend line to the CPU usage
+// of the agent measured after the warmup and fails if the slope exceeds the maximum,
+// which indicates a slow CPU leak that the ExpectedMaxCPU check does not catch until
+// the peak is exceeded. The CPU usage is only measured for a ChildProcess with
+// resource limits set, see TestCase.SetResourceLimits.
+type CPUDriftValidator struct {
+	PerfTestValidator
+	maxSlope float64
+	warmup   time.Duration
+}
+
+// NewCPUDriftValidator creates a CPUDriftValidator allowing the CPU usage to grow by at
+// most maxSlope percentage points per minute. The samples measured within warmup of
+// the start of the agent are ignored.
+func NewCPUDriftValidator(maxSlope float64, warmup time.Duration) *CPUDriftValidator {
+	return &CPUDriftValidator{maxSlope: maxSlope, warmup: warmup}
+}
+
+func (v *CPUDriftValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	cp, ok := tc.agentProc.(*ChildProcess)
+	if !ok {
+		log.Printf("CPU drift is only validated for agents running as ChildProcess.")
+		return
+	}
+	v.validateSamples(tc.t, cp.CPUSamples())
+}
+
+func (v *CPUDriftValidator) validateSamples(t TestingT, samples []CPUSample) {
+	trend, ok := FindCPUTrend(samples, v.warmup)
+	if !ok {
+		log.Printf("Not enough CPU samples after the warmup of %s to compute the trend.", v.warmup)
+		return
+	}
+	log.Printf("CPU %s, allowed at most %.3f%% per minute.", trend, v.maxSlope)
+	if trend.Slope > v.maxSlope {
+		assert.Fail(t, "CPU usage is drifting upward.", "%s", trend)
+	}
+}
+
+// CPUTrend is the trend line fitted to CPU usage samples.
+type CPUTrend struct {
+	// Change of the CPU usage in percentage points per minute.
+	Slope float64
+	// Number of samples the trend was fitted to.
+	Samples int
+}
+
+func (ct CPUTrend) String() string {
+	return fmt.Sprintf("usage changed by %+.3f%% per minute over %d samples", ct.Slope, ct.Samples)
+}
+
+// FindCPUTrend fits a least squares trend line to the samples measured after warmup.
+// ok is false if there are fewer than two such samples.
+func FindCPUTrend(samples []CPUSample, warmup time.Duration) (trend CPUTrend, ok bool) {
+	var xs, ys []float64
+	for _, sample := range samples {
+		if sample.Elapsed < warmup {
+			continue
+		}
+		xs = append(xs, sample.Elapsed.Minutes())
+		ys = append(ys, sample.Percent)
+	}
+	slope, ok := leastSquaresSlope(xs, ys)
+	if !ok {
+		return CPUTrend{}, false
+	}
+	return CPUTrend{Slope: slope, Samples: len(xs)}, true
+}
+
+// leastSquaresSlope returns the slope of the least squares line fitted to the points
+// (xs[i], ys[i]). ok is false if there are fewer than two points with distinct xs.
+func leastSquaresSlope(xs, ys []float64) (slope float64, ok bool) {
+	if len(xs) < 2 {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+
+	var covariance, variance float64
+	for i := range xs {
+		covariance += (xs[i] - meanX) * (ys[i] - meanY)
+		variance += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	if variance == 0 {
+		return 0, false
+	}
+	return covariance / variance, true
+}
+
+// RSSBaselineValidator implements TestCaseValidator for soak tests of agents whose
+// memory usage is a sawtooth, e.g. because the garbage collector or the batch
+// processor periodically frees memory. The peaks of such a sawtooth hide a slow leak
+// from a check of the maximum RAM, and a trend line fitted to all samples is dominated
+// by the teeth. Instead the validator splits the RSS samples of the agent into
+// consecutive windows, takes the minimum of every window as the baseline the memory
+// returns to, and fails if the trend line fitted to these troughs grows faster than
+// allowed. The RSS is only measured for a ChildProcess with resource limits set, see
+// TestCase.SetResourceLimits.
+type RSSBaselineValidator struct {
+	PerfTestValidator
+	window   time.Duration
+	maxSlope float64
+}
+
+// NewRSSBaselineValidator creates a RSSBaselineValidator using windows of the given
+// length, which should span at least one tooth of the sawtooth, and allowing the
+// baseline to grow by at most maxSlope MiB per minute.
+func NewRSSBaselineValidator(window time.Duration, maxSlope float64) *RSSBaselineValidator {
+	return &RSSBaselineValidator{window: window, maxSlope: maxSlope}
+}
+
+func (v *RSSBaselineValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	cp, ok := tc.agentProc.(*ChildProcess)
+	if !ok {
+		log.Printf("RSS baseline is only validated for agents running as ChildProcess.")
+		return
+	}
+	v.validateSamples(tc.t, cp.RAMSamples())
+}
+
+func (v *RSSBaselineValidator) validateSamples(t TestingT, samples []RAMSample) {
+	trend, ok := FindRSSBaselineTrend(samples, v.window)
+	if !ok {
+		log.Printf("Not enough RSS samples to compute the baseline trend over windows of %s.", v.window)
+		return
+	}
+	log.Printf("RSS %s, allowed at most %.3f MiB per minute.", trend, v.maxSlope)
+	if trend.Slope > v.maxSlope {
+		assert.Fail(t, "RSS baseline is drifting upward.", "%s", trend)
+	}
+}
+
+// RSSBaselineTrend is the trend line fitted to the troughs of the RSS of a process.
+type RSSBaselineTrend struct {
+	// Change of the baseline RSS in MiB per minute.
+	Slope float64
+	// Number of windows, and so of troughs, the trend was fitted to.
+	Windows int
+	// Baseline RSS in MiB of the first and of the last window.
+	FirstBaseline float64
+	LastBaseline  float64
+	// Largest difference in MiB between the peak and the trough of a window, i.e. the
+	// height of the highest tooth of the sawtooth.
+	MaxPeakDelta float64
+}
+
+func (rt RSSBaselineTrend) String() string {
+	return fmt.Sprintf("baseline changed by %+.3f MiB per minute over %d windows (%.1f MiB to %.1f MiB), peak delta up to %.1f MiB",
+		rt.Slope, rt.Windows, rt.FirstBaseline, rt.LastBaseline, rt.MaxPeakDelta)
+}
+
+// FindRSSBaselineTrend splits the samples into consecutive windows of the given length
+// and fits a least squares trend line to the minimum of every window, placed at the
+// time it was measured. ok is false if the samples span fewer than two windows.
+func FindRSSBaselineTrend(samples []RAMSample, window time.Duration) (trend RSSBaselineTrend, ok bool) {
+	if window <= 0 {
+		return RSSBaselineTrend{}, false
+	}
+
+	var troughs, peaks []RAMSample
+	lastWindow := int64(-1)
+	for _, sample := range samples {
+		w := int64(sample.Elapsed / window)
+		if w != lastWindow {
+			troughs = append(troughs, sample)
+			peaks = append(peaks, sample)
+			lastWindow = w
+			continue
+		}
+		if sample.MiB < troughs[len(troughs)-1].MiB {
+			troughs[len(troughs)-1] = sample
+		}
+		if sample.MiB > peaks[len(peaks)-1].MiB {
+			peaks[len(peaks)-1] = sample
+		}
+	}
+
+	xs := make([]float64, len(troughs))
+	ys := make([]float64, len(troughs))
+	for i, trough := range troughs {
+		xs[i] = trough.Elapsed.Minutes()
+		ys[i] = trough.MiB
+		if delta := peaks[i].MiB - trough.MiB; delta > trend.MaxPeakDelta {
+			trend.MaxPeakDelta = delta
+		}
+	}
+	slope, ok := leastSquaresSlope(xs, ys)
+	if !ok {
+		return RSSBaselineTrend{}, false
+	}
+	trend.Slope = slope
+	trend.Windows = len(troughs)
+	trend.FirstBaseline = ys[0]
+	trend.LastBaseline = ys[len(ys)-1]
+	return trend, true
+}
+
+// LossAccountingValidator implements TestCaseValidator for tests where the collector is
+// expected to lose data, e.g. because a processor filters it. Instead of requiring all
+// sent data items to be received it verifies that every data item which was sent but
+// not received is accounted for by the internal metrics of the agent, i.e. that it was
+// refused by a receiver, dropped by a processor or failed to be sent by an exporter.
+// An unexplained gap means that data was lost silently. The internal metrics are only
+// scraped from a ChildProcess with MetricsPort set, see ChildProcess.LostItems.
+type LossAccountingValidator struct {
+	PerfTestValidator
+}
+
+func (v *LossAccountingValidator) Validate(tc *TestCase) {
+	cp, ok := tc.agentProc.(*ChildProcess)
+	if !ok || cp.MetricsPort == 0 {
+		assert.Fail(tc.t, "Lost data items can only be accounted for a ChildProcess with MetricsPort set.")
+		return
+	}
+	validateLossAccounting(tc.t, LossAccounting{
+		Sent:     tc.LoadGenerator.DataItemsSent(),
+		Received: tc.MockBackend.DataItemsReceived(),
+		Reported: cp.LostItems(),
+	})
+}
+
+func validateLossAccounting(t TestingT, accounting LossAccounting) {
+	log.Printf("Lost data items: %s.", accounting)
+	if accounting.Unexplained() > 0 {
+		assert.Fail(t, "Data items were lost silently.", "%s", accounting)
+	}
+}
+
+// LossAccounting compares the data items lost between the load generator and the
+// MockBackend with the data items which the agent reported as lost.
+type LossAccounting struct {
+	Sent     uint64
+	Received uint64
+	Reported LostItemCounts
+}
+
+// Lost returns the number of sent data items which were not received.
+func (la LossAccounting) Lost() uint64 {
+	if la.Received >= la.Sent {
+		return 0
+	}
+	return la.Sent - la.Received
+}
+
+// Unexplained returns the number of lost data items which were not reported as lost.
+// Reporting more items than were lost is not an error, because failed send attempts
+// are reported even if a retry succeeded.
+func (la LossAccounting) Unexplained() uint64 {
+	if lost, reported := la.Lost(), la.Reported.Total(); lost > reported {
+		return lost - reported
+	}
+	return 0
+}
+
+func (la LossAccounting) String() string {
+	return fmt.Sprintf("sent %d, received %d, lost %d, reported %d (refused %d, dropped %d, send failed %d), unexplained %d",
+		la.Sent, la.Received, la.Lost(), la.Reported.Total(),
+		la.Reported.Refused, la.Reported.Dropped, la.Reported.SendFailed, la.Unexplained())
+}
+
+// RoutingValidator implements TestCaseValidator for tests where the collector routes
+// data to several MockBackends based on the value of a resource attribute. It verifies
+// that all sent data items were received by one of the backends and that the items with
+// each routing value were received only by the backend of that value. Recording must be
+// enabled on all backends.
+type RoutingValidator struct {
+	PerfTestValidator
+	attributeKey string
+	backends     map[string]*MockBackend
+}
+
+// NewRoutingValidator creates a RoutingValidator which expects the data items with value
+// v of the resource attribute attributeKey to be received by backends[v] only.
+func NewRoutingValidator(attributeKey string, backends map[string]*MockBackend) *RoutingValidator {
+	return &RoutingValidator{attributeKey: attributeKey, backends: backends}
+}
+
+func (v *RoutingValidator) Validate(tc *TestCase) {
+	var received uint64
+	for _, mb := range v.backends {
+		received += mb.DataItemsReceived()
+	}
+	assert.EqualValues(tc.t, tc.LoadGenerator.DataItemsSent(), received,
+		"Received and sent counters do not match.")
+	for _, mismatch := range FindRoutingMismatches(v.attributeKey, v.backends) {
+		assert.Fail(tc.t, "Data items were misrouted.", "%s", mismatch)
+	}
+}
+
+// RoutingMismatch describes data items with a routing attribute value which were
+// received by the backend of another value.
+type RoutingMismatch struct {
+	Backend string
+	Value   string
+	Count   uint64
+}
+
+func (m RoutingMismatch) String() string {
+	return fmt.Sprintf("backend %q received %d items with value %q", m.Backend, m.Count, m.Value)
+}
+
+// FindRoutingMismatches counts the data items recorded by each of the backends by the
+// value of the resource attribute attributeKey and returns the counts of items with a
+// value other than the key of the backend, sorted by backend and value. Items without
+// the attribute are reported with an empty value.
+func FindRoutingMismatches(attributeKey string, backends map[string]*MockBackend) []RoutingMismatch {
+	var mismatches []RoutingMismatch
+	for backend, mb := range backends {
+		for value, count := range countItemsByResourceAttribute(attributeKey, mb) {
+			if value != backend {
+				mismatches = append(mismatches, RoutingMismatch{Backend: backend, Value: value, Count: count})
+			}
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		if mismatches[i].Backend != mismatches[j].Backend {
+			return mismatches[i].Backend < mismatches[j].Backend
+		}
+		return mismatches[i].Value < mismatches[j].Value
 	})
+	return mismatches
+}
+
+func countItemsByResourceAttribute(attributeKey string, mb *MockBackend) map[string]uint64 {
+	counts := make(map[string]uint64)
+	valueOf := func(resource pdata.Resource) string {
+		if value, ok := resource.Attributes().Get(attributeKey); ok {
+			return value.StringVal()
+		}
+		return ""
+	}
+	for _, td := range mb.ReceivedTraces {
+		rss := td.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			ilss := rss.At(i).InstrumentationLibrarySpans()
+			for j := 0; j < ilss.Len(); j++ {
+				counts[valueOf(rss.At(i).Resource())] += uint64(ilss.At(j).Spans().Len())
+			}
+		}
+	}
+	for _, md := range mb.ReceivedMetrics {
+		rms := md.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			ilms := rms.At(i).InstrumentationLibraryMetrics()
+			for j := 0; j < ilms.Len(); j++ {
+				metrics := ilms.At(j).Metrics()
+				for k := 0; k < metrics.Len(); k++ {
+					counts[valueOf(rms.At(i).Resource())] += uint64(metricDataPointCount(metrics.At(k)))
+				}
+			}
+		}
+	}
+	for _, ld := range mb.ReceivedLogs {
+		rls := ld.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			ills := rls.At(i).InstrumentationLibraryLogs()
+			for j := 0; j < ills.Len(); j++ {
+				counts[valueOf(rls.At(i).Resource())] += uint64(ills.At(j).Logs().Len())
+			}
+		}
+	}
+	return counts
+}
+
+// ResourceDistributionValidator implements TestCaseValidator for tests where the
+// collector splits, merges or regroups data by resource. In addition to the checks done
+// by PerfTestValidator it verifies that the share of the received data items of each
+// value of a resource attribute matches the expected distribution within a tolerance.
+// Use ExpectedResourceDistribution for the distribution generated via
+// LoadOptions.ResourceAttributeValues. Recording must be enabled on the MockBackend.
+type ResourceDistributionValidator struct {
+	PerfTestValidator
+	attributeKey string
+	expected     map[string]float64
+	tolerance    float64
+}
+
+// NewResourceDistributionValidator creates a ResourceDistributionValidator which expects
+// the share expected[v] of the received data items to have the value v of the resource
+// attribute attributeKey. The shares may deviate by up to tolerance, e.g. 0.01 for one
+// percentage point.
+func NewResourceDistributionValidator(attributeKey string, expected map[string]float64, tolerance float64) *ResourceDistributionValidator {
+	return &ResourceDistributionValidator{attributeKey: attributeKey, expected: expected, tolerance: tolerance}
+}
+
+func (v *ResourceDistributionValidator) Validate(tc *TestCase) {
+	v.PerfTestValidator.Validate(tc)
+	counts := countItemsByResourceAttribute(v.attributeKey, tc.MockBackend)
+	for _, deviation := range FindResourceDistributionDeviations(v.expected, counts, v.tolerance) {
+		assert.Fail(tc.t, "Resource distribution was changed.", "%s", deviation)
+	}
+}
+
+// ExpectedResourceDistribution returns the share of the generated data items of each
+// value of the resource attribute attributeKey set via LoadOptions.ResourceAttributeValues.
+// The batches cycle through the values, so each value gets the same share unless it is
+// listed more than once. Returns nil if the attribute is not generated.
+func ExpectedResourceDistribution(options LoadOptions, attributeKey string) map[string]float64 {
+	values := options.ResourceAttributeValues[attributeKey]
+	if len(values) == 0 {
+		return nil
+	}
+	distribution := make(map[string]float64)
+	for _, value := range values {
+		distribution[value] += 1 / float64(len(values))
+	}
+	return distribution
+}
+
+// ResourceDistributionDeviation describes a resource attribute value whose share of the
+// received data items deviates from the expected share.
+type ResourceDistributionDeviation struct {
+	Value    string
+	Count    uint64
+	Share    float64
+	Expected float64
+}
+
+func (d ResourceDistributionDeviation) String() string {
+	return fmt.Sprintf("value %q: received %d items (%.4f), expected %.4f", d.Value, d.Count, d.Share, d.Expected)
+}
+
+// FindResourceDistributionDeviations compares the share of each resource attribute value
+// in counts with the expected share and returns the deviations larger than tolerance,
+// sorted by value. Values missing from expected are expected to have no items, values
+// missing from counts are expected to have received none. Items without the attribute
+// are counted with an empty value.
+func FindResourceDistributionDeviations(expected map[string]float64, counts map[string]uint64, tolerance float64) []ResourceDistributionDeviation {
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	values := make(map[string]bool)
+	for value := range expected {
+		values[value] = true
+	}
+	for value := range counts {
+		values[value] = true
+	}
+
+	var deviations []ResourceDistributionDeviation
+	for value := range values {
+		var share float64
+		if total > 0 {
+			share = float64(counts[value]) / float64(total)
+		}
+		if math.Abs(share-expected[value]) > tolerance {
+			deviations = append(deviations, ResourceDistributionDeviation{
+				Value:    value,
+				Count:    counts[value],
+				Share:    share,
+				Expected: expected[value],
+			})
+		}
+	}
+	sort.Slice(deviations, func(i, j int) bool { return deviations[i].Value < deviations[j].Value })
+	return deviations
 }
 
 // CorrectnessTestValidator implements TestCaseValidator for test suites using CorrectnessResults for summarizing results.
@@ -96,6 +1832,8 @@ func (v *CorrectnessTestValidator) Validate(tc *TestCase) {
 	}
 	if len(tc.MockBackend.ReceivedTraces) > 0 {
 		v.assertSentRecdTracingDataEqual(tc.MockBackend.ReceivedTraces)
+		sent := tc.LoadGenerator.DataItemsSent()
+		v.assertNoUnexpectedSpans(sent, sent, tc.MockBackend.ReceivedTraces)
 	}
 	assert.EqualValues(tc.t, 0, len(v.assertionFailures), "There are span data mismatches.")
 }
@@ -109,7 +1847,56 @@ func (v *CorrectnessTestValidator) RecordResults(tc *TestCase) {
 	}
 
 	// Remove "Test" prefix from test name.
-	testName := tc.t.Name()[4:]
+	testName := strings.TrimPrefix(tc.t.Name(), "Test")
+	tc.resultsSummary.Add(tc.t.Name(), &CorrectnessTestResult{
+		testName:                   testName,
+		result:                     result,
+		duration:                   time.Since(tc.startTime),
+		receivedSpanCount:          tc.MockBackend.DataItemsReceived(),
+		sentSpanCount:              tc.LoadGenerator.DataItemsSent(),
+		traceAssertionFailureCount: uint64(len(v.assertionFailures)),
+		traceAssertionFailures:     v.assertionFailures,
+	})
+}
+
+// LogCorrectnessTestValidator implements TestCaseValidator for log correctness tests using
+// CorrectnessResults for summarizing results. It expects the logs to be generated by
+// LogCorrectnessDataProvider and compares every received log record with the record
+// expected for its sequence number, reporting missing and corrupted records.
+type LogCorrectnessTestValidator struct {
+	expectedTransform func(record pdata.LogRecord)
+	assertionFailures []*TraceAssertionFailure
+}
+
+// NewLogCorrectnessTestValidator creates a LogCorrectnessTestValidator. If the pipeline
+// under test is expected to modify the log records, expectedTransform must apply the
+// same modification to the expected record before it is compared. It may be nil.
+func NewLogCorrectnessTestValidator(expectedTransform func(record pdata.LogRecord)) *LogCorrectnessTestValidator {
+	return &LogCorrectnessTestValidator{
+		expectedTransform: expectedTransform,
+		assertionFailures: make([]*TraceAssertionFailure, 0),
+	}
+}
+
+func (v *LogCorrectnessTestValidator) Validate(tc *TestCase) {
+	if assert.EqualValues(tc.t, tc.LoadGenerator.DataItemsSent(), tc.MockBackend.DataItemsReceived(),
+		"Received and sent counters do not match.") {
+		log.Printf("Sent and received data counters match.")
+	}
+	v.assertSentRecdLogDataEqual(tc.LoadGenerator.DataItemsSent(), tc.MockBackend.ReceivedLogs)
+	assert.EqualValues(tc.t, 0, len(v.assertionFailures), "There are log data mismatches.")
+}
+
+func (v *LogCorrectnessTestValidator) RecordResults(tc *TestCase) {
+	var result string
+	if tc.t.Failed() {
+		result = "FAIL"
+	} else {
+		result = "PASS"
+	}
+
+	// Remove "Test" prefix from test name.
+	testName := strings.TrimPrefix(tc.t.Name(), "Test")
 	tc.resultsSummary.Add(tc.t.Name(), &CorrectnessTestResult{
 		testName:                   testName,
 		result:                     result,
@@ -121,6 +1908,124 @@ func (v *CorrectnessTestValidator) RecordResults(tc *TestCase) {
 	})
 }
 
+// assertSentRecdLogDataEqual compares the received log records with the expected ones.
+// Sequence numbers 1..sentCount are expected to be received.
+func (v *LogCorrectnessTestValidator) assertSentRecdLogDataEqual(sentCount uint64, logsList []pdata.Logs) {
+	received := make(map[int64]bool)
+	for _, ld := range logsList {
+		rls := ld.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			ills := rls.At(i).InstrumentationLibraryLogs()
+			for j := 0; j < ills.Len(); j++ {
+				records := ills.At(j).Logs()
+				for k := 0; k < records.Len(); k++ {
+					recdRecord := records.At(k)
+					seqNumAttr, ok := recdRecord.Attributes().Get(LogSeqNumAttribute)
+					if !ok || seqNumAttr.Type() != pdata.AttributeValueINT {
+						var actual interface{}
+						if ok {
+							actual = attributeValueToRaw(seqNumAttr)
+						}
+						v.assertionFailures = append(v.assertionFailures, &TraceAssertionFailure{
+							typeName:      "LogRecord",
+							dataComboName: recdRecord.Name(),
+							fieldPath:     "Attributes[" + LogSeqNumAttribute + "]",
+							expectedValue: "int sequence number",
+							actualValue:   actual,
+							sumCount:      1,
+						})
+						continue
+					}
+					seqNum := seqNumAttr.IntVal()
+					received[seqNum] = true
+
+					sentRecord := pdata.NewLogRecord()
+					GenerateSeqNumLogRecord(seqNum, sentRecord)
+					if v.expectedTransform != nil {
+						v.expectedTransform(sentRecord)
+					}
+					v.diffLogRecord(seqNum, sentRecord, recdRecord)
+				}
+			}
+		}
+	}
+
+	for seqNum := int64(1); seqNum <= int64(sentCount); seqNum++ {
+		if !received[seqNum] {
+			v.assertionFailures = append(v.assertionFailures, &TraceAssertionFailure{
+				typeName:      "LogRecord",
+				dataComboName: seqNumComboName(seqNum),
+				expectedValue: "received",
+				actualValue:   "missing",
+				sumCount:      1,
+			})
+		}
+	}
+}
+
+func (v *LogCorrectnessTestValidator) diffLogRecord(seqNum int64, sentRecord pdata.LogRecord, recdRecord pdata.LogRecord) {
+	comboName := seqNumComboName(seqNum)
+	addFailure := func(fieldPath string, expected interface{}, actual interface{}) {
+		v.assertionFailures = append(v.assertionFailures, &TraceAssertionFailure{
+			typeName:      "LogRecord",
+			dataComboName: comboName,
+			fieldPath:     fieldPath,
+			expectedValue: expected,
+			actualValue:   actual,
+			sumCount:      1,
+		})
+	}
+
+	if sentRecord.SeverityNumber() != recdRecord.SeverityNumber() {
+		addFailure("SeverityNumber", sentRecord.SeverityNumber(), recdRecord.SeverityNumber())
+	}
+	if sentRecord.SeverityText() != recdRecord.SeverityText() {
+		addFailure("SeverityText", sentRecord.SeverityText(), recdRecord.SeverityText())
+	}
+	if !sentRecord.Body().Equal(recdRecord.Body()) {
+		addFailure("Body", attributeValueToRaw(sentRecord.Body()), attributeValueToRaw(recdRecord.Body()))
+	}
+
+	sentAttrs := sentRecord.Attributes()
+	recdAttrs := recdRecord.Attributes()
+	sentAttrs.ForEach(func(k string, sentVal pdata.AttributeValue) {
+		recdVal, ok := recdAttrs.Get(k)
+		if !ok {
+			addFailure("Attributes["+k+"]", attributeValueToRaw(sentVal), nil)
+			return
+		}
+		if !sentVal.Equal(recdVal) {
+			addFailure("Attributes["+k+"]", attributeValueToRaw(sentVal), attributeValueToRaw(recdVal))
+		}
+	})
+	recdAttrs.ForEach(func(k string, recdVal pdata.AttributeValue) {
+		if _, ok := sentAttrs.Get(k); !ok {
+			addFailure("Attributes["+k+"]", nil, attributeValueToRaw(recdVal))
+		}
+	})
+}
+
+func seqNumComboName(seqNum int64) string {
+	return "seqnum=" + strconv.FormatInt(seqNum, 10)
+}
+
+func attributeValueToRaw(value pdata.AttributeValue) interface{} {
+	switch value.Type() {
+	case pdata.AttributeValueSTRING:
+		return value.StringVal()
+	case pdata.AttributeValueINT:
+		return value.IntVal()
+	case pdata.AttributeValueDOUBLE:
+		return value.DoubleVal()
+	case pdata.AttributeValueBOOL:
+		return value.BoolVal()
+	case pdata.AttributeValueNULL:
+		return nil
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
 func (v *CorrectnessTestValidator) assertSentRecdTracingDataEqual(tracesList []pdata.Traces) {
 	for _, td := range tracesList {
 		resourceSpansList := pdata.TracesToOtlp(td)
@@ -136,6 +2041,42 @@ func (v *CorrectnessTestValidator) assertSentRecdTracingDataEqual(tracesList []p
 	}
 }
 
+// assertNoUnexpectedSpans reports received spans with sequence numbers which were never
+// sent, e.g. because a processor duplicated or replayed them. See FindUnexpectedSpanSeqNums.
+func (v *CorrectnessTestValidator) assertNoUnexpectedSpans(lastSeqNum uint64, sentCount uint64, tracesList []pdata.Traces) {
+	for _, seqNum := range FindUnexpectedSpanSeqNums(lastSeqNum, sentCount, tracesList) {
+		v.assertionFailures = append(v.assertionFailures, &TraceAssertionFailure{
+			typeName:      "Span",
+			dataComboName: seqNumComboName(seqNum),
+			fieldPath:     "Attributes[load_generator.span_seq_num]",
+			expectedValue: "sent",
+			actualValue:   "unexpected",
+			sumCount:      1,
+		})
+	}
+}
+
+// FindUnexpectedSpanSeqNums returns the sequence numbers of received spans which were
+// not sent, in the order they were received. The sent spans are expected to have the
+// sentCount sequence numbers ending with lastSeqNum. The range is computed modulo 2^64 so
+// that it stays correct if the sequence numbers wrapped around. A sequence number
+// received more than once is reported for every extra occurrence. Spans without a
+// sequence number are ignored.
+func FindUnexpectedSpanSeqNums(lastSeqNum uint64, sentCount uint64, received []pdata.Traces) []int64 {
+	var unexpected []int64
+	seen := make(map[int64]bool)
+	for _, td := range received {
+		forEachSeqNumSpan(td, func(seqNum int64, _ pdata.Span) {
+			if lastSeqNum-uint64(seqNum) >= sentCount || seen[seqNum] {
+				unexpected = append(unexpected, seqNum)
+				return
+			}
+			seen[seqNum] = true
+		})
+	}
+	return unexpected
+}
+
 func (v *CorrectnessTestValidator) diffSpan(sentSpan *otlptrace.Span, recdSpan *otlptrace.Span) {
 	if sentSpan == nil {
 		af := &TraceAssertionFailure{
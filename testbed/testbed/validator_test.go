@@ -0,0 +1,884 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testbed
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.opentelemetry.io/collector/translator/trace/jaeger"
+)
+
+// genScrapeBatch generates a metrics batch with an int gauge and a double sum data
+// point for each of the given timestamps.
+func genScrapeBatch(timestamps ...pdata.Timestamp) pdata.Metrics {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	ilms := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics()
+	ilms.Resize(1)
+	metrics := ilms.At(0).Metrics()
+	metrics.Resize(2)
+
+	gauge := metrics.At(0)
+	gauge.SetName("int_gauge")
+	gauge.SetDataType(pdata.MetricDataTypeIntGauge)
+	gauge.IntGauge().DataPoints().Resize(len(timestamps))
+
+	sum := metrics.At(1)
+	sum.SetName("double_sum")
+	sum.SetDataType(pdata.MetricDataTypeDoubleSum)
+	sum.DoubleSum().DataPoints().Resize(len(timestamps))
+
+	for i, ts := range timestamps {
+		gauge.IntGauge().DataPoints().At(i).SetTimestamp(ts)
+		sum.DoubleSum().DataPoints().At(i).SetTimestamp(ts)
+	}
+	return md
+}
+
+func TestFindMixedScrapeCycleBatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		batches  []pdata.Metrics
+		expected []MixedScrapeCycleBatch
+	}{
+		{
+			name: "correctly batched",
+			batches: []pdata.Metrics{
+				genScrapeBatch(1000, 1000),
+				genScrapeBatch(2000),
+				genScrapeBatch(3000, 3000, 3000),
+			},
+		},
+		{
+			name: "incorrectly batched",
+			batches: []pdata.Metrics{
+				genScrapeBatch(1000),
+				genScrapeBatch(2000, 3000),
+				genScrapeBatch(4000),
+				genScrapeBatch(6000, 5000, 6000),
+			},
+			expected: []MixedScrapeCycleBatch{
+				{Index: 1, Timestamps: []pdata.Timestamp{2000, 3000}},
+				{Index: 3, Timestamps: []pdata.Timestamp{5000, 6000}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, FindMixedScrapeCycleBatches(test.batches))
+		})
+	}
+}
+
+func TestMixedScrapeCycleBatchString(t *testing.T) {
+	batch := MixedScrapeCycleBatch{Index: 2, Timestamps: []pdata.Timestamp{10, 20}}
+	require.Equal(t, "batch 2 contains timestamps [10, 20]", batch.String())
+}
+
+func genSeqNumLogs(t *testing.T, count int) pdata.Logs {
+	dp := NewLogCorrectnessDataProvider(LoadOptions{ItemsPerBatch: count})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	logs, done := dp.GenerateLogs()
+	require.False(t, done)
+	return logs
+}
+
+func TestLogCorrectnessTestValidator(t *testing.T) {
+	insertEnv := func(record pdata.LogRecord) {
+		record.Attributes().InsertString("env", "test")
+	}
+
+	tests := []struct {
+		name              string
+		expectedTransform func(record pdata.LogRecord)
+		modify            func(records pdata.LogSlice)
+		expected          []string
+	}{
+		{
+			name: "unmodified",
+		},
+		{
+			name:              "expected transformation",
+			expectedTransform: insertEnv,
+			modify: func(records pdata.LogSlice) {
+				for i := 0; i < records.Len(); i++ {
+					insertEnv(records.At(i))
+				}
+			},
+		},
+		{
+			name: "unexpected transformation",
+			modify: func(records pdata.LogSlice) {
+				insertEnv(records.At(0))
+			},
+			expected: []string{"seqnum=1/Attributes[env]"},
+		},
+		{
+			name:              "missing transformation",
+			expectedTransform: insertEnv,
+			modify: func(records pdata.LogSlice) {
+				for i := 1; i < records.Len(); i++ {
+					insertEnv(records.At(i))
+				}
+			},
+			expected: []string{"seqnum=1/Attributes[env]"},
+		},
+		{
+			name: "corrupted records",
+			modify: func(records pdata.LogSlice) {
+				records.At(1).Body().SetStringVal("corrupted")
+				records.At(2).SetSeverityNumber(pdata.SeverityNumberFATAL)
+				records.At(2).SetSeverityText("FATAL")
+				records.At(3).Attributes().UpsertString("seq_str", "corrupted")
+			},
+			expected: []string{
+				"seqnum=2/Body",
+				"seqnum=3/SeverityNumber",
+				"seqnum=3/SeverityText",
+				"seqnum=4/Attributes[seq_str]",
+			},
+		},
+		{
+			name: "missing records",
+			modify: func(records pdata.LogSlice) {
+				records.Resize(3)
+			},
+			expected: []string{"seqnum=4/", "seqnum=5/"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs := genSeqNumLogs(t, 5)
+			if test.modify != nil {
+				test.modify(logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs())
+			}
+
+			v := NewLogCorrectnessTestValidator(test.expectedTransform)
+			v.assertSentRecdLogDataEqual(5, []pdata.Logs{logs})
+
+			var failures []string
+			for _, af := range v.assertionFailures {
+				failures = append(failures, af.dataComboName+"/"+af.fieldPath)
+			}
+			assert.Equal(t, test.expected, failures)
+		})
+	}
+}
+
+// genHistogramBatch generates a metrics batch with an int histogram and a double
+// histogram which have the given explicit bounds.
+func genHistogramBatch(bounds []float64) pdata.Metrics {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	ilms := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics()
+	ilms.Resize(1)
+	metrics := ilms.At(0).Metrics()
+	metrics.Resize(2)
+
+	intHistogram := metrics.At(0)
+	intHistogram.SetName("int_histogram")
+	intHistogram.SetDataType(pdata.MetricDataTypeIntHistogram)
+	intHistogram.IntHistogram().DataPoints().Resize(1)
+	intHistogram.IntHistogram().DataPoints().At(0).SetExplicitBounds(bounds)
+	intHistogram.IntHistogram().DataPoints().At(0).SetBucketCounts(make([]uint64, len(bounds)+1))
+
+	doubleHistogram := metrics.At(1)
+	doubleHistogram.SetName("double_histogram")
+	doubleHistogram.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	doubleHistogram.DoubleHistogram().DataPoints().Resize(1)
+	doubleHistogram.DoubleHistogram().DataPoints().At(0).SetExplicitBounds(bounds)
+	doubleHistogram.DoubleHistogram().DataPoints().At(0).SetBucketCounts(make([]uint64, len(bounds)+1))
+	return md
+}
+
+// metricsBatchDataProvider generates copies of the same metrics batch.
+type metricsBatchDataProvider struct {
+	DataProvider
+	md pdata.Metrics
+}
+
+func (dp *metricsBatchDataProvider) GenerateMetrics() (pdata.Metrics, bool) {
+	return dp.md.Clone(), false
+}
+
+func TestHistogramBoundsValidator(t *testing.T) {
+	bounds := []float64{1, 5, 10, 50}
+
+	noop := func(md pdata.Metrics) pdata.Metrics {
+		return md.Clone()
+	}
+	// rebucket merges every two adjacent buckets of the double histogram.
+	rebucket := func(md pdata.Metrics) pdata.Metrics {
+		out := md.Clone()
+		metric := out.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(1)
+		dp := metric.DoubleHistogram().DataPoints().At(0)
+		var merged []float64
+		for i := 1; i < len(dp.ExplicitBounds()); i += 2 {
+			merged = append(merged, dp.ExplicitBounds()[i])
+		}
+		dp.SetExplicitBounds(merged)
+		dp.SetBucketCounts(make([]uint64, len(merged)+1))
+		return out
+	}
+
+	v := NewHistogramBoundsValidator()
+	dp := NewRecordingDataProvider(&metricsBatchDataProvider{md: genHistogramBatch(bounds)}, v)
+	_, _ = dp.GenerateMetrics()
+	// Bounds seen later for the same metric names are ignored.
+	v.RecordSentMetrics(genHistogramBatch([]float64{100}))
+
+	sent := genHistogramBatch(bounds)
+	assert.Empty(t, FindHistogramBoundsMismatches(v.sentBounds, []pdata.Metrics{noop(sent), noop(sent)}))
+
+	mismatches := FindHistogramBoundsMismatches(v.sentBounds, []pdata.Metrics{rebucket(sent), rebucket(sent)})
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, HistogramBoundsMismatch{
+		MetricName: "double_histogram",
+		Sent:       bounds,
+		Received:   []float64{5, 50},
+	}, mismatches[0])
+	assert.Equal(t, `metric "double_histogram": sent bounds [1 5 10 50], received bounds [5 50]`, mismatches[0].String())
+}
+
+func TestMetricMetadataValidator(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch:      3,
+		MetricUnits:        []string{"ms", "By"},
+		MetricDescriptions: []string{"Request latency"},
+	}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	v := NewMetricMetadataValidator()
+	wrapped := NewRecordingDataProvider(dp, v)
+	sent, _ := wrapped.GenerateMetrics()
+	assert.Equal(t, map[string]MetricMetadata{
+		"load_generator_0": {Unit: "ms", Description: "Request latency"},
+		"load_generator_1": {Unit: "By", Description: "Request latency"},
+		"load_generator_2": {Unit: "ms", Description: "Request latency"},
+	}, v.sentMetadata)
+
+	assert.Empty(t, FindMetricMetadataMismatches(v.sentMetadata, []pdata.Metrics{sent.Clone()}))
+
+	// strip removes the metadata of the second metric like a faulty transform would.
+	strip := func(md pdata.Metrics) pdata.Metrics {
+		out := md.Clone()
+		metric := out.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(1)
+		metric.SetUnit("")
+		metric.SetDescription("")
+		return out
+	}
+	mismatches := FindMetricMetadataMismatches(v.sentMetadata, []pdata.Metrics{strip(sent), strip(sent)})
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, MetricMetadataMismatch{
+		MetricName: "load_generator_1",
+		Sent:       MetricMetadata{Unit: "By", Description: "Request latency"},
+	}, mismatches[0])
+	assert.Equal(t, `metric "load_generator_1": sent unit "By", description "Request latency", received unit "", description ""`,
+		mismatches[0].String())
+}
+
+func TestFindInstrumentationLibraryMismatches(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch:                 3,
+		InstrumentationLibraryName:    "io.opentelemetry.testbed",
+		InstrumentationLibraryVersion: "1.2.3",
+	}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	sent, _ := dp.GenerateMetrics()
+
+	v := NewInstrumentationLibraryValidator(options)
+	assert.Equal(t, InstrumentationLibrary{Name: "io.opentelemetry.testbed", Version: "1.2.3"}, v.expected)
+	assert.Empty(t, FindInstrumentationLibraryMismatches(v.expected, []pdata.Metrics{sent.Clone()}))
+
+	// strip removes the version like a faulty exporter would.
+	strip := func(md pdata.Metrics) pdata.Metrics {
+		out := md.Clone()
+		out.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).InstrumentationLibrary().SetVersion("")
+		return out
+	}
+	mismatches := FindInstrumentationLibraryMismatches(v.expected, []pdata.Metrics{sent.Clone(), strip(sent), strip(sent)})
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, InstrumentationLibraryMismatch{
+		Expected:    InstrumentationLibrary{Name: "io.opentelemetry.testbed", Version: "1.2.3"},
+		Received:    InstrumentationLibrary{Name: "io.opentelemetry.testbed"},
+		MetricCount: 6,
+	}, mismatches[0])
+	assert.Equal(t, `6 metrics: expected name "io.opentelemetry.testbed", version "1.2.3", received name "io.opentelemetry.testbed", version ""`,
+		mismatches[0].String())
+}
+
+func TestLogBodyValidator(t *testing.T) {
+	options := LoadOptions{ItemsPerBatch: 3, LogBodyFormat: LogBodyFormatBinary}
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	v := NewLogBodyValidator()
+	wrapped := NewRecordingDataProvider(dp, v)
+	sent, _ := wrapped.GenerateLogs()
+	require.Len(t, v.sentBodies, 3)
+	assert.Len(t, v.sentBodies[1], 256)
+
+	assert.Empty(t, FindLogBodyMismatches(v.sentBodies, []pdata.Logs{sent.Clone()}))
+
+	// sanitize replaces the invalid UTF-8 of the second body like a faulty processor
+	// re-encoding the body would.
+	sanitize := func(ld pdata.Logs) pdata.Logs {
+		out := ld.Clone()
+		body := out.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(1).Body()
+		body.SetStringVal(strings.ToValidUTF8(body.StringVal(), "\uFFFD"))
+		return out
+	}
+	mismatches := FindLogBodyMismatches(v.sentBodies, []pdata.Logs{sanitize(sent)})
+	require.Len(t, mismatches, 1)
+	assert.EqualValues(t, 2, mismatches[0].LogSeqNum)
+	assert.Equal(t, v.sentBodies[2], mismatches[0].Sent)
+	assert.Equal(t, 0x7f, mismatches[0].Offset)
+	assert.Equal(t, `log record 2: body differs at byte 127: `+
+		`sent 256 bytes ..."\x80\x81\x82\x83\x84\x85\x86\x87\x88\x89\x8a\x8b\x8c\x8d\x8e\x8f"..., `+
+		`received 131 bytes ..."\ufffd\x00"`,
+		mismatches[0].String())
+
+	// A body which is no longer a string is reported with its type.
+	retyped := sent.Clone()
+	retyped.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(0).Body().SetIntVal(1)
+	mismatches = FindLogBodyMismatches(v.sentBodies, []pdata.Logs{retyped})
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, "<INT> 1", mismatches[0].Received)
+}
+
+func TestSamplingValidatorHelpers(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 3})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var tracesList []pdata.Traces
+	for i := 0; i < 4; i++ {
+		td, _ := dp.GenerateTraces()
+		tracesList = append(tracesList, td)
+	}
+	// Drop one span of the last trace.
+	tracesList[3].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().Resize(2)
+
+	assert.Equal(t, map[int64]int{1: 3, 2: 3, 3: 3, 4: 2}, countSpansByTraceSeqNum(tracesList))
+
+	assert.InDelta(t, 0.02, SamplingTolerance(0.5, 10000), 1e-9)
+	assert.Equal(t, float64(1), SamplingTolerance(0.5, 0))
+}
+
+func TestFindPartiallySampledTraces(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 3})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var tracesList []pdata.Traces
+	for i := 0; i < 3; i++ {
+		td, _ := dp.GenerateTraces()
+		tracesList = append(tracesList, td)
+	}
+	generated := countSpansByTraceSeqNum(tracesList)
+
+	// The load generator stopped after the first span of the last trace, which was
+	// received completely as far as it was sent.
+	tracesList[2].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().Resize(1)
+	sentSpans := SentSpansByTrace(generated, 7)
+	assert.Empty(t, FindPartiallySampledTraces(sentSpans, tracesList))
+
+	// Only the spans of a trace which were received are counted, the sampled out traces
+	// are not reported.
+	tracesList[1].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().Resize(2)
+	assert.Equal(t, []IncompleteTrace{{TraceSeqNum: 2, Received: 2, Expected: 3}},
+		FindPartiallySampledTraces(sentSpans, tracesList[1:]))
+}
+
+func TestFindIncompleteTraces(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 3})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var tracesList []pdata.Traces
+	for i := 0; i < 3; i++ {
+		td, _ := dp.GenerateTraces()
+		tracesList = append(tracesList, td)
+	}
+	sentSpans := countSpansByTraceSeqNum(tracesList)
+	assert.Empty(t, FindIncompleteTraces(sentSpans, tracesList))
+
+	// Drop one span of the second trace.
+	tracesList[1].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().Resize(2)
+	incomplete := FindIncompleteTraces(sentSpans, tracesList)
+	assert.Equal(t, []IncompleteTrace{{TraceSeqNum: 2, Received: 2, Expected: 3}}, incomplete)
+	assert.Equal(t, "trace_seq_num=2: received 2 of 3 spans", incomplete[0].String())
+
+	// A trace which was sent but never received is reported as well.
+	sentSpans[4] = 3
+	assert.Equal(t, []IncompleteTrace{
+		{TraceSeqNum: 2, Received: 2, Expected: 3},
+		{TraceSeqNum: 4, Received: 0, Expected: 3},
+	}, FindIncompleteTraces(sentSpans, tracesList))
+}
+
+func TestSentSpansByTrace(t *testing.T) {
+	generated := map[int64]int{1: 3, 2: 3, 3: 3}
+	assert.Equal(t, generated, SentSpansByTrace(generated, 9))
+
+	// StartCount stopped the load generator in the middle of the last trace.
+	assert.Equal(t, map[int64]int{1: 3, 2: 3, 3: 1}, SentSpansByTrace(generated, 7))
+	assert.Equal(t, map[int64]int{1: 3, 2: 3}, SentSpansByTrace(generated, 6))
+	// The generated counts are not modified.
+	assert.Equal(t, map[int64]int{1: 3, 2: 3, 3: 3}, generated)
+
+	// Traces of varying size, e.g. with LoadOptions.MaxBatchBytes, are kept as generated.
+	assert.Equal(t, map[int64]int{1: 5, 2: 2}, SentSpansByTrace(map[int64]int{1: 5, 2: 2}, 7))
+}
+
+// genCPUSamples generates a CPU sample every 10 seconds for 10 minutes, growing by
+// slope percentage points per minute from 50% with some noise.
+func genCPUSamples(slope float64) []CPUSample {
+	var samples []CPUSample
+	for elapsed := 10 * time.Second; elapsed <= 10*time.Minute; elapsed += 10 * time.Second {
+		noise := 2 * math.Sin(float64(elapsed/time.Second))
+		samples = append(samples, CPUSample{
+			Elapsed: elapsed,
+			Percent: 50 + slope*elapsed.Minutes() + noise,
+		})
+	}
+	return samples
+}
+
+func TestCPUDriftValidator(t *testing.T) {
+	v := NewCPUDriftValidator(0.5, time.Minute)
+
+	stable := genCPUSamples(0)
+	trend, ok := FindCPUTrend(stable, time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, 55, trend.Samples)
+	assert.InDelta(t, 0, trend.Slope, 0.1)
+	ht := NewHeadlessT("stable")
+	v.validateSamples(ht, stable)
+	assert.NoError(t, ht.Err())
+
+	drifting := genCPUSamples(2)
+	trend, ok = FindCPUTrend(drifting, time.Minute)
+	require.True(t, ok)
+	assert.InDelta(t, 2, trend.Slope, 0.1)
+	ht = NewHeadlessT("drifting")
+	v.validateSamples(ht, drifting)
+	require.Error(t, ht.Err())
+	assert.Contains(t, ht.Err().Error(), "CPU usage is drifting upward")
+
+	// Samples measured during the warmup are ignored.
+	warmupSpike := append([]CPUSample{{Elapsed: 5 * time.Second, Percent: 400}}, stable...)
+	ht = NewHeadlessT("warmup")
+	v.validateSamples(ht, warmupSpike)
+	assert.NoError(t, ht.Err())
+
+	_, ok = FindCPUTrend(stable[:6], time.Minute)
+	assert.False(t, ok, "only one sample after the warmup")
+}
+
+// genSawtoothRAMSamples generates RSS samples every 5 seconds for 20 minutes which
+// climb by 40 MiB and drop back to the baseline every minute, the baseline growing by
+// drift MiB per minute.
+func genSawtoothRAMSamples(drift float64) []RAMSample {
+	var samples []RAMSample
+	for elapsed := time.Duration(0); elapsed <= 20*time.Minute; elapsed += 5 * time.Second {
+		tooth := 40 * float64(elapsed%time.Minute) / float64(time.Minute)
+		samples = append(samples, RAMSample{
+			Elapsed: elapsed,
+			MiB:     100 + drift*elapsed.Minutes() + tooth,
+		})
+	}
+	return samples
+}
+
+func TestRSSBaselineValidator(t *testing.T) {
+	v := NewRSSBaselineValidator(time.Minute, 0.5)
+
+	sawtooth := genSawtoothRAMSamples(0)
+	trend, ok := FindRSSBaselineTrend(sawtooth, time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, 21, trend.Windows)
+	assert.InDelta(t, 0, trend.Slope, 0.01)
+	assert.InDelta(t, 100, trend.FirstBaseline, 0.01)
+	assert.InDelta(t, 100, trend.LastBaseline, 0.01)
+	assert.InDelta(t, 36.7, trend.MaxPeakDelta, 0.1)
+	ht := NewHeadlessT("sawtooth")
+	v.validateSamples(ht, sawtooth)
+	assert.NoError(t, ht.Err())
+
+	drifting := genSawtoothRAMSamples(2)
+	trend, ok = FindRSSBaselineTrend(drifting, time.Minute)
+	require.True(t, ok)
+	assert.InDelta(t, 2, trend.Slope, 0.1)
+	assert.Greater(t, trend.LastBaseline, trend.FirstBaseline+30)
+	ht = NewHeadlessT("drifting")
+	v.validateSamples(ht, drifting)
+	require.Error(t, ht.Err())
+	assert.Contains(t, ht.Err().Error(), "RSS baseline is drifting upward")
+
+	// A single window has no trend.
+	_, ok = FindRSSBaselineTrend(sawtooth[:10], time.Minute)
+	assert.False(t, ok)
+}
+
+func TestLossAccountingValidator(t *testing.T) {
+	accounted := LossAccounting{Sent: 1000, Received: 700, Reported: LostItemCounts{Refused: 100, Dropped: 200}}
+	assert.EqualValues(t, 300, accounted.Lost())
+	assert.EqualValues(t, 0, accounted.Unexplained())
+	ht := NewHeadlessT("accounted")
+	validateLossAccounting(ht, accounted)
+	assert.NoError(t, ht.Err())
+
+	// Failed send attempts which succeeded on retry are over-reported.
+	retried := LossAccounting{Sent: 1000, Received: 1000, Reported: LostItemCounts{SendFailed: 50}}
+	assert.EqualValues(t, 0, retried.Unexplained())
+	ht = NewHeadlessT("retried")
+	validateLossAccounting(ht, retried)
+	assert.NoError(t, ht.Err())
+
+	silent := LossAccounting{Sent: 1000, Received: 700, Reported: LostItemCounts{Dropped: 250}}
+	assert.EqualValues(t, 50, silent.Unexplained())
+	ht = NewHeadlessT("silent")
+	validateLossAccounting(ht, silent)
+	require.Error(t, ht.Err())
+	assert.Contains(t, ht.Err().Error(), "Data items were lost silently")
+	assert.Contains(t, ht.Err().Error(), "unexplained 50")
+}
+
+func TestFindRoutingMismatches(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:           2,
+		ResourceAttributeValues: map[string][]string{"route": {"a", "b"}},
+	})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+
+	backendA := &MockBackend{}
+	backendB := &MockBackend{}
+	// Batches alternate between the routing values starting with "a".
+	for i := 0; i < 4; i++ {
+		md, _ := dp.GenerateMetrics()
+		if i%2 == 0 {
+			backendA.ReceivedMetrics = append(backendA.ReceivedMetrics, md)
+		} else {
+			backendB.ReceivedMetrics = append(backendB.ReceivedMetrics, md)
+		}
+	}
+	backends := map[string]*MockBackend{"a": backendA, "b": backendB}
+	assert.Empty(t, FindRoutingMismatches("route", backends))
+
+	// A batch with value "a" and one without the attribute reach backend "b".
+	misrouted, _ := dp.GenerateMetrics()
+	backendB.ReceivedMetrics = append(backendB.ReceivedMetrics, misrouted, genScrapeBatch(1))
+
+	mismatches := FindRoutingMismatches("route", backends)
+	assert.Equal(t, []RoutingMismatch{
+		{Backend: "b", Value: "", Count: 2},
+		{Backend: "b", Value: "a", Count: 14},
+	}, mismatches)
+	assert.Equal(t, `backend "b" received 14 items with value "a"`, mismatches[1].String())
+}
+
+func TestFindResourceDistributionDeviations(t *testing.T) {
+	options := LoadOptions{
+		ItemsPerBatch:           2,
+		ResourceAttributeValues: map[string][]string{"tenant": {"a", "b", "a", "c"}},
+	}
+	expected := ExpectedResourceDistribution(options, "tenant")
+	assert.Equal(t, map[string]float64{"a": 0.5, "b": 0.25, "c": 0.25}, expected)
+	assert.Nil(t, ExpectedResourceDistribution(options, "other"))
+
+	dp := NewPerfTestDataProvider(options)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	mb := &MockBackend{}
+	for i := 0; i < 8; i++ {
+		td, _ := dp.GenerateTraces()
+		mb.ReceivedTraces = append(mb.ReceivedTraces, td)
+	}
+	counts := countItemsByResourceAttribute("tenant", mb)
+	assert.Equal(t, map[string]uint64{"a": 8, "b": 4, "c": 4}, counts)
+	assert.Empty(t, FindResourceDistributionDeviations(expected, counts, 0.01))
+
+	// All items of "c" were regrouped under "b" and some lost their resource.
+	deviations := FindResourceDistributionDeviations(expected, map[string]uint64{"a": 8, "b": 6, "": 2}, 0.01)
+	assert.Equal(t, []ResourceDistributionDeviation{
+		{Value: "", Count: 2, Share: 0.125, Expected: 0},
+		{Value: "b", Count: 6, Share: 0.375, Expected: 0.25},
+		{Value: "c", Count: 0, Share: 0, Expected: 0.25},
+	}, deviations)
+	assert.Equal(t, `value "b": received 6 items (0.3750), expected 0.2500`, deviations[1].String())
+
+	// Within tolerance.
+	assert.Empty(t, FindResourceDistributionDeviations(expected, map[string]uint64{"a": 9, "b": 4, "c": 4}, 0.05))
+}
+
+func TestSpanContextValidator(t *testing.T) {
+	// jaegerRoundTrip translates td to Jaeger and back. If dropTraceIDHigh is set the
+	// high 64 bits of the trace IDs are dropped like a backend supporting only 64 bit
+	// trace IDs would do.
+	jaegerRoundTrip := func(td pdata.Traces, dropTraceIDHigh bool) pdata.Traces {
+		batches, err := jaeger.InternalTracesToJaegerProto(td)
+		require.NoError(t, err)
+		if dropTraceIDHigh {
+			for _, batch := range batches {
+				for _, span := range batch.Spans {
+					span.TraceID.High = 0
+				}
+			}
+		}
+		return jaeger.ProtoBatchesToInternalTraces(batches)
+	}
+
+	v := NewSpanContextValidator()
+	dp := NewRecordingDataProvider(NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 2}), v)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	td, _ := dp.GenerateTraces()
+	require.Len(t, v.sentIDs, 2)
+
+	assert.Empty(t, FindSpanContextMismatches(v.sentIDs, []pdata.Traces{jaegerRoundTrip(td, false)}))
+
+	mismatches := FindSpanContextMismatches(v.sentIDs, []pdata.Traces{jaegerRoundTrip(td, true)})
+	require.Len(t, mismatches, 2)
+	assert.Equal(t, int64(1), mismatches[0].SpanSeqNum)
+	assert.Equal(t, v.sentIDs[1], mismatches[0].Sent)
+	assert.Equal(t, v.sentIDs[1].SpanID, mismatches[0].Received.SpanID)
+	assert.NotEqual(t, v.sentIDs[1].TraceID, mismatches[0].Received.TraceID)
+	assert.Equal(t,
+		"span 1: sent 01000000000000000000000000000000-0100000000000000, received 00000000000000000000000000000000-0100000000000000",
+		mismatches[0].String())
+}
+
+func TestSpanLinkValidator(t *testing.T) {
+	v := NewSpanLinkValidator()
+	dp := NewRecordingDataProvider(NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 2, SpanLinksPerSpan: 2}), v)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var sent []pdata.Traces
+	for i := 0; i < 3; i++ {
+		td, _ := dp.GenerateTraces()
+		sent = append(sent, td)
+	}
+	require.Len(t, v.sentLinks, 6)
+
+	// The first batch has no earlier traces to link to, the second one links to the first.
+	assert.Empty(t, v.sentLinks[1])
+	assert.Equal(t, []SpanContextIDs{
+		{TraceID: GenerateSequentialTraceID(1), SpanID: GenerateSequentialSpanID(2)},
+	}, v.sentLinks[4])
+	assert.Equal(t, []SpanContextIDs{
+		{TraceID: GenerateSequentialTraceID(2), SpanID: GenerateSequentialSpanID(3)},
+		{TraceID: GenerateSequentialTraceID(1), SpanID: GenerateSequentialSpanID(1)},
+	}, v.sentLinks[5])
+
+	assert.Empty(t, FindSpanLinkMismatches(v.sentLinks, sent))
+
+	// Drop the last link of span 5.
+	received := sent[2].Clone()
+	links := received.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).Links()
+	links.Resize(1)
+	mismatches := FindSpanLinkMismatches(v.sentLinks, []pdata.Traces{sent[0], sent[1], received})
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, int64(5), mismatches[0].SpanSeqNum)
+	assert.Equal(t, v.sentLinks[5], mismatches[0].Sent)
+	assert.Equal(t, v.sentLinks[5][:1], mismatches[0].Received)
+	assert.Equal(t,
+		"span 5: sent links [02000000000000000000000000000000-0300000000000000 01000000000000000000000000000000-0100000000000000], "+
+			"received links [02000000000000000000000000000000-0300000000000000]",
+		mismatches[0].String())
+}
+
+func TestClockSkewValidator(t *testing.T) {
+	v := NewClockSkewValidator()
+	dp := NewRecordingDataProvider(NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 5, ClockSkewedSpanRate: 0.2}), v)
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var sent []pdata.Traces
+	for i := 0; i < 2; i++ {
+		td, _ := dp.GenerateTraces()
+		sent = append(sent, td)
+	}
+	require.Len(t, v.sentSkews, 10)
+	assert.Equal(t, ClockSkewNegativeDuration, v.sentSkews[5])
+	assert.Equal(t, ClockSkewFuture, v.sentSkews[10])
+	assert.Equal(t, ClockSkewNone, v.sentSkews[1])
+
+	now := time.Now()
+	skewed := FindClockSkewedSpans(sent, now)
+	require.Len(t, skewed, 2)
+	assert.Equal(t, int64(5), skewed[0].SpanSeqNum)
+	assert.Equal(t, ClockSkewNegativeDuration, skewed[0].Skew)
+	assert.Less(t, uint64(skewed[0].EndTime), uint64(skewed[0].StartTime))
+	assert.Equal(t, int64(10), skewed[1].SpanSeqNum)
+	assert.Equal(t, ClockSkewFuture, skewed[1].Skew)
+	assert.Empty(t, FindClockSkewMismatches(v.sentSkews, sent, now))
+
+	// Correct the negative duration of span 5.
+	received := sent[0].Clone()
+	span := received.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(4)
+	span.SetStartTime(span.EndTime())
+	mismatches := FindClockSkewMismatches(v.sentSkews, []pdata.Traces{received, sent[1]}, now.Add(time.Second))
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, ClockSkewMismatch{SpanSeqNum: 5, Sent: ClockSkewNegativeDuration, Received: ClockSkewNone}, mismatches[0])
+	assert.Equal(t, "span 5: sent with clock skew negative_duration, received with none", mismatches[0].String())
+}
+
+func TestFindAttributeLimitViolations(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{
+		ItemsPerBatch:       3,
+		AttributeValueTypes: map[pdata.AttributeValueType]int{pdata.AttributeValueSTRING: 4},
+	})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	td, _ := dp.GenerateTraces()
+	spans := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+
+	kept := []string{SpanSeqNumAttribute, "load_generator.trace_seq_num", "load_generator.string_0", "load_generator.string_1"}
+	dropped := []string{"load_generator.string_2", "load_generator.string_3"}
+
+	// Nothing was truncated yet.
+	assert.Len(t, FindAttributeLimitViolations(4, kept, dropped, []pdata.Traces{td}), 3)
+
+	for i := 0; i < spans.Len(); i++ {
+		spans.At(i).Attributes().Delete("load_generator.string_2")
+		spans.At(i).Attributes().Delete("load_generator.string_3")
+	}
+	assert.Empty(t, FindAttributeLimitViolations(4, kept, dropped, []pdata.Traces{td}))
+
+	// Dropping a kept attribute instead of the configured one.
+	spans.At(1).Attributes().Delete("load_generator.string_1")
+	spans.At(1).Attributes().UpsertString("load_generator.string_3", "value")
+	assert.Equal(t, []AttributeLimitViolation{{
+		SpanID:     spans.At(1).SpanID(),
+		Count:      4,
+		Missing:    []string{"load_generator.string_1"},
+		Unexpected: []string{"load_generator.string_3"},
+	}}, FindAttributeLimitViolations(4, kept, dropped, []pdata.Traces{td}))
+
+	// Exceeding the limit.
+	spans.At(2).Attributes().UpsertString("extra", "value")
+	violations := FindAttributeLimitViolations(4, kept, dropped, []pdata.Traces{td})
+	require.Len(t, violations, 2)
+	assert.Equal(t, AttributeLimitViolation{SpanID: spans.At(2).SpanID(), Count: 5}, violations[1])
+}
+
+func TestCorrectnessTestValidatorUnexpectedSpans(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 5})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	td, _ := dp.GenerateTraces()
+
+	v := NewCorrectTestValidator(dp)
+	v.assertNoUnexpectedSpans(5, 5, []pdata.Traces{td})
+	assert.Empty(t, v.assertionFailures)
+
+	// Inject a spurious span which was never sent and duplicate one which was.
+	spurious := td.Clone()
+	spans := spurious.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+	spans.At(0).Attributes().UpsertInt(SpanSeqNumAttribute, 6)
+	spans.Resize(2)
+	v.assertNoUnexpectedSpans(5, 5, []pdata.Traces{td, spurious})
+
+	var failures []string
+	for _, af := range v.assertionFailures {
+		failures = append(failures, af.dataComboName+"/"+af.fieldPath)
+	}
+	assert.Equal(t, []string{
+		"seqnum=6/Attributes[load_generator.span_seq_num]",
+		"seqnum=2/Attributes[load_generator.span_seq_num]",
+	}, failures)
+}
+
+func TestFindUnexpectedSpanSeqNumsWraparound(t *testing.T) {
+	genSpans := func(seqNums ...int64) pdata.Traces {
+		td := pdata.NewTraces()
+		td.ResourceSpans().Resize(1)
+		td.ResourceSpans().At(0).InstrumentationLibrarySpans().Resize(1)
+		spans := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+		spans.Resize(len(seqNums))
+		for i, seqNum := range seqNums {
+			spans.At(i).Attributes().UpsertInt(SpanSeqNumAttribute, seqNum)
+		}
+		return td
+	}
+
+	// The sequence numbers wrapped around after math.MaxUint64, which is -1 as int64.
+	received := []pdata.Traces{genSpans(-2, -1, 0, 1, 2, 3, -3)}
+	assert.Equal(t, []int64{3, -3}, FindUnexpectedSpanSeqNums(2, 5, received))
+}
+
+func TestFindCounterResets(t *testing.T) {
+	dp := NewPerfTestDataProvider(LoadOptions{ItemsPerBatch: 1, DataPointsPerMetric: 1, CounterResetInterval: 2})
+	dp.SetLoadGeneratorCounters(atomic.NewUint64(0), atomic.NewUint64(0))
+	var received []pdata.Metrics
+	for i := 0; i < 4; i++ {
+		md, _ := dp.GenerateMetrics()
+		received = append(received, md)
+	}
+
+	resets := FindCounterResets(received)
+	require.Len(t, resets, 1)
+	assert.True(t, resets[0].Detectable())
+	assert.Equal(t, "load_generator_0", resets[0].MetricName)
+	assert.Equal(t, "item_index=item_0", resets[0].Labels)
+	assert.Equal(t, int64(2), resets[0].PrevValue)
+	assert.Equal(t, int64(1), resets[0].Value)
+
+	// A reset without start timestamp change cannot be told apart from a decreasing counter.
+	third := received[2].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0)
+	third.IntSum().DataPoints().At(0).SetStartTime(resets[0].PrevStartTime)
+	resets = FindCounterResets(received)
+	require.Len(t, resets, 1)
+	assert.False(t, resets[0].Detectable())
+	assert.Equal(t, fmt.Sprintf(`metric "load_generator_0" {item_index=item_0}: value dropped from 2 to 1, start time %d -> %d`,
+		resets[0].PrevStartTime, resets[0].PrevStartTime), resets[0].String())
+}
+
+func TestFindTimestampRegressions(t *testing.T) {
+	// gauge returns a batch with a data point of "cpu" per core, all with timestamp ts.
+	gauge := func(ts pdata.Timestamp, cores ...string) pdata.Metrics {
+		md := pdata.NewMetrics()
+		md.ResourceMetrics().Resize(1)
+		md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().Resize(1)
+		metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+		metrics.Resize(1)
+		metric := metrics.At(0)
+		metric.SetName("cpu")
+		metric.SetDataType(pdata.MetricDataTypeDoubleGauge)
+		dps := metric.DoubleGauge().DataPoints()
+		dps.Resize(len(cores))
+		for i, core := range cores {
+			dps.At(i).LabelsMap().Insert("core", core)
+			dps.At(i).SetTimestamp(ts)
+		}
+		return md
+	}
+
+	received := []pdata.Metrics{
+		gauge(100, "0", "1"),
+		gauge(200, "0", "1"),
+		gauge(200, "0"),
+		gauge(300, "1"),
+	}
+	assert.Empty(t, FindTimestampRegressions(received))
+
+	// An out of order point of core 1 is detected, core 0 is unaffected.
+	received = append(received, gauge(250, "1"), gauge(400, "0", "1"))
+	regressions := FindTimestampRegressions(received)
+	require.Len(t, regressions, 1)
+	assert.Equal(t, TimestampRegression{MetricName: "cpu", Labels: "core=1", PrevTimestamp: 300, Timestamp: 250}, regressions[0])
+	assert.Equal(t, `metric "cpu" {core=1}: timestamp went backward from 300 to 250`, regressions[0].String())
+}
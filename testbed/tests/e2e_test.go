@@ -18,12 +18,23 @@
 package tests
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"go.opentelemetry.io/collector/testbed/testbed"
 )
@@ -89,3 +100,472 @@ func TestBallastMemory(t *testing.T) {
 		tc.Stop()
 	}
 }
+
+func TestBaselineMemory(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{ExpectedMaxCPU: 50, ExpectedMaxRAM: 100})
+	tc.StartBackend()
+	tc.StartAgent()
+
+	// Warmup window during which the idle agent settles.
+	tc.Sleep(time.Second)
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+
+	baseline := tc.BaselineRAMMiB()
+	peak := agentProc.GetTotalConsumption().RAMMiBMax
+	assert.NotZero(t, baseline, "baseline RAM must be reported")
+	assert.NotZero(t, peak, "peak RAM must be reported")
+	assert.LessOrEqual(t, baseline, peak)
+
+	tc.ValidateData()
+}
+
+func TestScheduleLoad(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+
+	// Truncate to whole seconds like a start time agreed between machines.
+	start := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	stop := start.Add(2 * time.Second)
+	tc.ScheduleLoad(start, stop, options)
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+
+	const tolerance = 100 * time.Millisecond
+	assert.False(t, tc.LoadStartTime().Before(start), "load started at %v before %v", tc.LoadStartTime(), start)
+	assert.WithinDuration(t, start, tc.LoadStartTime(), tolerance)
+	assert.False(t, tc.LoadStopTime().Before(stop), "load stopped at %v before %v", tc.LoadStopTime(), stop)
+	assert.WithinDuration(t, stop, tc.LoadStopTime(), tolerance)
+	assert.False(t, tc.MockBackend.FirstItemReceivedAt().Before(start))
+	assert.True(t, tc.MockBackend.LastItemReceivedAt().Before(stop.Add(tolerance)),
+		"data item received at %v after the load stopped", tc.MockBackend.LastItemReceivedAt())
+	assert.InDelta(t, 2*options.DataItemsPerSecond, tc.MockBackend.DataItemsReceived(), 0.1*2*float64(options.DataItemsPerSecond))
+
+	tc.ValidateData()
+}
+
+func TestScheduleLoadInThePast(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+
+	now := time.Now()
+	tc.ScheduleLoad(now.Add(-time.Minute), now.Add(time.Second), options)
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	assert.WithinDuration(t, now, tc.LoadStartTime(), 100*time.Millisecond, "load did not start immediately")
+	assert.NotZero(t, tc.MockBackend.DataItemsReceived())
+
+	tc.ValidateData()
+}
+
+func TestControlServer(t *testing.T) {
+	cs := testbed.NewControlServer("localhost:0", testbed.ScenarioParams{
+		DataItemsPerSecond: 10_000,
+		ItemsPerBatch:      100,
+		Duration:           15 * time.Second,
+		Sender:             "otlp",
+		Receiver:           "otlp",
+	}, RunScenarioFromParams)
+	require.NoError(t, cs.Start())
+	defer cs.Shutdown(context.Background())
+
+	spec := `{"sender":"otlp","receiver":"otlp","items_per_second":5000,"duration":"2s"}`
+	resp, err := http.Post("http://"+cs.Addr()+testbed.ControlServerPath, "application/json", strings.NewReader(spec))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response struct {
+		testbed.ScenarioResponse
+		Results ScenarioResults `json:"results"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Empty(t, response.Error)
+	assert.Equal(t, "2s", response.Spec.Duration)
+	assert.Equal(t, 5000, response.Spec.DataItemsPerSecond)
+	results := response.Results
+	assert.NotZero(t, results.DataItemsSent)
+	assert.Equal(t, results.DataItemsSent, results.DataItemsReceived)
+	assert.InDelta(t, 10_000, results.DataItemsSent, 2_000)
+	assert.GreaterOrEqual(t, int64(results.Duration), int64(2*time.Second))
+	assert.NotZero(t, results.RAMMiBMax)
+	assert.DirExists(t, path.Join("results", response.Name))
+}
+
+func TestCustomAgentExecutable(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	// Simulate a custom distribution by running the default executable from another path.
+	defaultExe, err := filepath.Abs(strings.NewReplacer(
+		"{{.GOOS}}", runtime.GOOS, "{{.GOARCH}}", runtime.GOARCH,
+	).Replace(testbed.GlobalConfig.DefaultAgentExeRelativeFile))
+	require.NoError(t, err)
+	customExe := filepath.Join(resultDir, "otelcol-custom")
+	os.Remove(customExe)
+	require.NoError(t, os.MkdirAll(resultDir, os.ModePerm))
+	require.NoError(t, os.Symlink(defaultExe, customExe))
+
+	agentProc := &testbed.ChildProcess{
+		AgentExePath: customExe,
+		Components:   []string{"otlp", "pprof"},
+	}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+	assert.Equal(t, "latest", agentProc.AgentVersion())
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{ExpectedMaxCPU: 50, ExpectedMaxRAM: 100})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.ValidateData()
+}
+
+func TestCustomAgentExecutableMissingComponents(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewJaegerDataReceiver(testbed.GetAvailablePort(t))
+
+	// A distribution without the jaeger exporter cannot run the scenario.
+	agentProc := &testbed.ChildProcess{Components: []string{"otlp", "pprof"}}
+	_, err := agentProc.PrepareConfig(createConfigYaml(t, sender, receiver, "results", nil, nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `exporters "jaeger"`)
+}
+
+func TestAgentErrorLogs(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	// The ballast is not passed on the command line so memory_limiter warns about
+	// the misconfiguration once it checks the memory usage.
+	processors := map[string]string{
+		"memory_limiter": `
+  memory_limiter:
+    check_interval: 100ms
+    limit_mib: 4000
+    ballast_size_mib: 1000
+`,
+	}
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(time.Second)
+	tc.StopLoad()
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+
+	lines, err := agentProc.ErrorLogLines()
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "memory_limiter is likely incorrectly configured")
+
+	agentProc.AllowedLogPatterns = []*regexp.Regexp{regexp.MustCompile(`memory_limiter is likely incorrectly configured`)}
+	assert.True(t, agentProc.AssertNoErrorLogs(t))
+}
+
+// capturingReporter is a ResultReporter which keeps the reported results.
+type capturingReporter struct {
+	results []testbed.TestResult
+}
+
+func (r *capturingReporter) Report(result testbed.TestResult) {
+	r.results = append(r.results, result)
+}
+
+func TestResultReporter(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	reporter := &capturingReporter{}
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+		testbed.WithResultReporters(reporter),
+	)
+	tc.SetRunMetadata(map[string]string{"commit": "abc123"})
+
+	tc.SetResourceLimits(testbed.ResourceSpec{
+		ExpectedMaxCPU:      200,
+		ExpectedMaxRAM:      500,
+		ResourceCheckPeriod: 200 * time.Millisecond,
+	})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(time.Second)
+	tc.StopLoad()
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.ValidateData()
+	tc.Stop()
+
+	require.Len(t, reporter.results, 1)
+	result := reporter.results[0]
+	assert.Equal(t, "ResultReporter", result.TestName)
+	assert.Equal(t, "PASS", result.Result)
+	assert.NotZero(t, result.SentItems)
+	assert.Equal(t, result.SentItems, result.ReceivedItems)
+	assert.NotZero(t, result.RAMMiBMax)
+	assert.GreaterOrEqual(t, result.Duration, time.Second)
+	assert.Equal(t, map[string]string{"commit": "abc123"}, result.Metadata)
+}
+
+func TestTestCaseTimeout(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	// The test case is expected to fail so run it with a HeadlessT.
+	ht := testbed.NewHeadlessT(t.Name())
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		ht,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetTimeout(2 * time.Second)
+	tc.StartBackend()
+	tc.StartAgent()
+
+	// Hang until the timeout signals an error.
+	start := time.Now()
+	assert.False(t, tc.WaitForN(func() bool { return false }, time.Minute, "never"))
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+
+	assert.True(t, tc.TimedOut())
+	require.Error(t, ht.Err())
+	assert.Contains(t, ht.Err().Error(), "timed out after 2s")
+
+	testDump, err := ioutil.ReadFile(path.Join(resultDir, "goroutines-test.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(testDump), "TestTestCaseTimeout")
+
+	agentDump, err := ioutil.ReadFile(path.Join(resultDir, "goroutines-agent.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(agentDump), "SIGQUIT")
+	assert.Contains(t, string(agentDump), "go.opentelemetry.io/collector/service")
+}
+
+func TestAllSignalsSingleConnection(t *testing.T) {
+	sender := testbed.NewOTLPDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10, DataPointsPerMetric: 2}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(time.Second)
+	tc.StopLoad()
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+
+	// Every tick sends a batch of each signal, metrics have two data points each.
+	spans := tc.MockBackend.SpansReceived()
+	assert.NotZero(t, spans)
+	assert.Equal(t, spans, tc.MockBackend.LogRecordsReceived())
+	assert.Equal(t, 2*spans, tc.MockBackend.MetricsReceived())
+	assert.Zero(t, tc.LoadGenerator.SendErrors())
+}
+
+func TestSearchMaxRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		expected int
+	}{
+		{name: "within range", capacity: 37_000},
+		{name: "below minimum", capacity: 500, expected: 0},
+		{name: "above maximum", capacity: 1_000_000, expected: maxThroughputMaxRate},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The mock collector sustains every rate up to its capacity.
+			var probes []int
+			maxRate := searchMaxRate(maxThroughputMinRate, maxThroughputMaxRate, func(rate int) bool {
+				probes = append(probes, rate)
+				return rate <= test.capacity
+			})
+
+			if test.capacity >= maxThroughputMinRate && test.capacity <= maxThroughputMaxRate {
+				assert.LessOrEqual(t, maxRate, test.capacity)
+				assert.GreaterOrEqual(t, float64(maxRate), (1-maxThroughputPrecision)*float64(test.capacity))
+			} else {
+				assert.Equal(t, test.expected, maxRate)
+			}
+			assert.LessOrEqual(t, len(probes), 12, "probed rates: %v", probes)
+		})
+	}
+}
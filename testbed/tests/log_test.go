@@ -18,8 +18,12 @@ package tests
 // coded in this file or use scenarios from perf_scenarios.go.
 
 import (
+	"path"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"go.opentelemetry.io/collector/testbed/testbed"
 )
 
@@ -82,3 +86,84 @@ func TestLog10kDPS(t *testing.T) {
 		})
 	}
 }
+
+func TestLogBodyPreserved(t *testing.T) {
+	tests := []struct {
+		name     string
+		sender   testbed.DataSender
+		receiver testbed.DataReceiver
+		format   string
+	}{
+		{
+			name:     "OTLP-Binary",
+			sender:   testbed.NewOTLPLogsDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+			receiver: testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+			format:   testbed.LogBodyFormatBinary,
+		},
+		{
+			name:     "OTLP-HTTP-Multiline",
+			sender:   testbed.NewOTLPHTTPLogsDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+			receiver: testbed.NewOTLPHTTPDataReceiver(testbed.GetAvailablePort(t)),
+			format:   testbed.LogBodyFormatMultiline,
+		},
+	}
+
+	// The attributes processor rewrites the log records, but must leave the bodies intact.
+	processors := map[string]string{
+		"attributes": `
+  attributes:
+    actions:
+    - key: a
+      value: transformed
+      action: update
+    - key: d
+      action: delete
+`,
+		"batch": `
+  batch:
+`,
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+			require.NoError(t, err)
+
+			agentProc := &testbed.ChildProcess{}
+			configStr := createConfigYaml(t, test.sender, test.receiver, resultDir, processors, nil)
+			configCleanup, err := agentProc.PrepareConfig(configStr)
+			require.NoError(t, err)
+			defer configCleanup()
+
+			options := testbed.LoadOptions{
+				DataItemsPerSecond: 1_000,
+				ItemsPerBatch:      10,
+				LogBodyFormat:      test.format,
+				LogBodyBytes:       512,
+			}
+			validator := testbed.NewLogBodyValidator()
+			tc := testbed.NewTestCase(
+				t,
+				testbed.NewRecordingDataProvider(testbed.NewPerfTestDataProvider(options), validator),
+				test.sender,
+				test.receiver,
+				agentProc,
+				validator,
+				performanceResultsSummary,
+			)
+			defer tc.Stop()
+
+			tc.EnableRecording()
+			tc.StartBackend()
+			tc.StartAgent()
+			tc.StartLoad(options)
+			tc.Sleep(tc.Duration)
+			tc.StopLoad()
+
+			tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+				"all data items received")
+			tc.StopAgent()
+			tc.ValidateData()
+		})
+	}
+}
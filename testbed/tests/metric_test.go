@@ -18,8 +18,18 @@ package tests
 // coded in this file or use scenarios from perf_scenarios.go.
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/testbed/testbed"
 )
 
@@ -96,3 +106,429 @@ func TestMetric10kDPS(t *testing.T) {
 	}
 
 }
+
+func TestMetricRoutingByResourceAttribute(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiverA := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+	receiverB := testbed.NewOTLPHTTPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createRoutingConfigYaml(t, sender, "route", map[string]testbed.DataReceiver{
+		"a": receiverA,
+		"b": receiverB,
+	}, resultDir)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:      1_000,
+		ItemsPerBatch:           10,
+		ResourceAttributeValues: map[string][]string{"route": {"a", "b"}},
+	}
+	backends := make(map[string]*testbed.MockBackend)
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiverA,
+		agentProc,
+		testbed.NewRoutingValidator("route", backends),
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+	backends["a"] = tc.MockBackend
+	backends["b"] = tc.AddMockBackend(receiverB)
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	tc.Sleep(tc.Duration)
+
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool {
+		return tc.LoadGenerator.DataItemsSent() == backends["a"].DataItemsReceived()+backends["b"].DataItemsReceived()
+	}, "all data items received")
+
+	// The batches alternate between the routing values so both backends receive data.
+	assert.NotZero(t, backends["a"].DataItemsReceived())
+	assert.NotZero(t, backends["b"].DataItemsReceived())
+	tc.ValidateData()
+}
+
+func TestMetricCardinalityRamp(t *testing.T) {
+	// Every agent uses more than 1 MiB, so the ramp stops at the first step.
+	result := ScenarioCardinalityRamp(
+		t,
+		testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		nil,
+		"k8s.pod.name",
+		testbed.CardinalityRamp{
+			Steps:        []int{10, 100, 1_000},
+			StepDuration: time.Second,
+			ThresholdMiB: 1,
+		},
+	)
+
+	require.Len(t, result.Steps, 1)
+	assert.Equal(t, 10, result.Steps[0].Cardinality)
+	assert.Greater(t, result.Steps[0].RAMMiB, uint32(1))
+	assert.Equal(t, 10, result.CrossingCardinality)
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "cardinality_ramp.json"))
+	require.NoError(t, err)
+	var written testbed.CardinalityRampResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, result, written)
+}
+
+func TestMetricFilterDropsAccounted(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	// Two of the ten metrics of every batch are filtered out.
+	processors := map[string]string{
+		"filter": `
+  filter:
+    metrics:
+      exclude:
+        match_type: strict
+        metric_names:
+          - load_generator_0
+          - load_generator_1
+`,
+	}
+	agentProc := &testbed.ChildProcess{MetricsPort: testbed.GetAvailablePort(t)}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.LossAccountingValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{
+		ExpectedMaxCPU:      200,
+		ExpectedMaxRAM:      500,
+		ResourceCheckPeriod: 200 * time.Millisecond,
+	})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool {
+		return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived()+agentProc.LostItems().Dropped
+	}, "all data items received or dropped")
+	tc.StopAgent()
+
+	lost := agentProc.LostItems()
+	assert.InDelta(t, 0.2, float64(lost.Dropped)/float64(tc.LoadGenerator.DataItemsSent()), 0.01)
+	assert.Zero(t, lost.Refused)
+	tc.ValidateData()
+}
+
+func TestMetricMetadataPreserved(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"resource": `
+  resource:
+    attributes:
+    - key: deployment.environment
+      value: testbed
+      action: insert
+`,
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond: 1_000,
+		ItemsPerBatch:      10,
+		MetricUnits:        []string{"ms", "By", "{requests}"},
+		MetricDescriptions: []string{"Request latency", "Payload size"},
+	}
+	validator := testbed.NewMetricMetadataValidator()
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewRecordingDataProvider(testbed.NewPerfTestDataProvider(options), validator),
+		sender,
+		receiver,
+		agentProc,
+		validator,
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+// TestMetricTimestampsMonotonic verifies that the timestamps of every series go forward
+// through a pipeline with a batch processor and a single sending queue consumer. The
+// counters are reset rarely so that every batch continues the same series.
+func TestMetricTimestampsMonotonic(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)).WithNumConsumers(1)
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:   1_000,
+		ItemsPerBatch:        10,
+		Parallel:             1,
+		CounterResetInterval: 1_000_000,
+	}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.MonotonicTimestampValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+// TestMetricCounterResets verifies that counter resets remain detectable, i.e. that the
+// start timestamp advances whenever a counter drops, through a pipeline with a batch
+// processor and a single sending queue consumer, which keeps the data points of every
+// series in order.
+func TestMetricCounterResets(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)).WithNumConsumers(1)
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	// About 5 resets per second.
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:   1_000,
+		ItemsPerBatch:        10,
+		Parallel:             1,
+		CounterResetInterval: 20,
+	}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.CounterResetValidator{MinResets: 2},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestMetricInstrumentationLibraryPreserved(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:            1_000,
+		ItemsPerBatch:                 10,
+		InstrumentationLibraryName:    "io.opentelemetry.testbed",
+		InstrumentationLibraryVersion: "1.2.3",
+	}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		testbed.NewInstrumentationLibraryValidator(options),
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestMetricNaNGaugeValues(t *testing.T) {
+	sender := testbed.NewOTLPMetricDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond: 1_000,
+		ItemsPerBatch:      10,
+		EdgeCaseMetrics:    []string{testbed.EdgeCaseMetricNaNGauge},
+		EdgeCaseMetricRate: 0.1,
+	}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	// A crash of the agent fails the test case, so receiving all data shows that the
+	// pipeline stayed healthy.
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	assert.NotZero(t, countNaNDataPoints(tc.MockBackend.ReceivedMetrics))
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+// countNaNDataPoints returns the number of double gauge data points with NaN values.
+func countNaNDataPoints(metricsList []pdata.Metrics) int {
+	count := 0
+	for _, md := range metricsList {
+		rms := md.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			ilms := rms.At(i).InstrumentationLibraryMetrics()
+			for j := 0; j < ilms.Len(); j++ {
+				metrics := ilms.At(j).Metrics()
+				for k := 0; k < metrics.Len(); k++ {
+					if metrics.At(k).DataType() != pdata.MetricDataTypeDoubleGauge {
+						continue
+					}
+					dps := metrics.At(k).DoubleGauge().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						if math.IsNaN(dps.At(l).Value()) {
+							count++
+						}
+					}
+				}
+			}
+		}
+	}
+	return count
+}
@@ -18,15 +18,26 @@ package tests
 // also used by tests in custom builds of Collector (e.g. Collector Contrib).
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
 	"math/rand"
+	"os"
 	"path"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/testbed/testbed"
 )
 
@@ -34,19 +45,71 @@ var (
 	performanceResultsSummary testbed.TestResultsSummary = &testbed.PerformanceResults{}
 )
 
+// ProcessorNameAndConfigBody is a processor of a pipeline together with its config.
+type ProcessorNameAndConfigBody struct {
+	// Name of the processor in the pipeline, for example "batch".
+	Name string
+	// Body is the config of the processor in YAML, indented by 2 spaces.
+	Body string
+}
+
 // createConfigYaml creates a collector config file that corresponds to the
 // sender and receiver used in the test and returns the config file name.
 // Map of processor names to their configs. Config is in YAML and must be
-// indented by 2 spaces. Processors will be placed between batch and queue for traces
-// pipeline. For metrics pipeline these will be sole processors.
+// indented by 2 spaces. Processors are placed in the pipelines in the order of
+// their names, use createOrderedConfigYaml for a specific order.
 func createConfigYaml(
-	t *testing.T,
+	t testbed.TestingT,
 	sender testbed.DataSender,
 	receiver testbed.DataReceiver,
 	resultDir string,
 	processors map[string]string,
 	extensions map[string]string,
 ) string {
+	return createOrderedConfigYaml(t, sender, receiver, resultDir, processorsInNameOrder(processors), extensions)
+}
+
+// processorsInNameOrder returns the processors of the map ordered by name.
+func processorsInNameOrder(processors map[string]string) []ProcessorNameAndConfigBody {
+	var ordered []ProcessorNameAndConfigBody
+	for name, body := range processors {
+		ordered = append(ordered, ProcessorNameAndConfigBody{Name: name, Body: body})
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Name < ordered[j].Name
+	})
+	return ordered
+}
+
+// pipelineTypesOf returns the types of the pipelines receiving from sender, a sender of
+// several signals gets a pipeline for each of them.
+func pipelineTypesOf(t testbed.TestingT, sender testbed.DataSender) []string {
+	var pipelines []string
+	if _, ok := sender.(testbed.TraceDataSender); ok {
+		pipelines = append(pipelines, "traces")
+	}
+	if _, ok := sender.(testbed.MetricDataSender); ok {
+		pipelines = append(pipelines, "metrics")
+	}
+	if _, ok := sender.(testbed.LogDataSender); ok {
+		pipelines = append(pipelines, "logs")
+	}
+	if len(pipelines) == 0 {
+		t.Error("Invalid DataSender type")
+	}
+	return pipelines
+}
+
+// createOrderedConfigYaml creates a collector config like createConfigYaml, with the
+// processors placed in the pipelines in the given order.
+func createOrderedConfigYaml(
+	t testbed.TestingT,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resultDir string,
+	processors []ProcessorNameAndConfigBody,
+	extensions map[string]string,
+) string {
 
 	// Create a config. Note that our DataSender is used to generate a config for Collector's
 	// receiver and our DataReceiver is used to generate a config for Collector's exporter.
@@ -57,16 +120,12 @@ func createConfigYaml(
 	// names to use in corresponding "processors" settings.
 	processorsSections := ""
 	processorsList := ""
-	if len(processors) > 0 {
-		first := true
-		for name, cfg := range processors {
-			processorsSections += cfg + "\n"
-			if !first {
-				processorsList += ","
-			}
-			processorsList += name
-			first = false
+	for i, processor := range processors {
+		processorsSections += processor.Body + "\n"
+		if i > 0 {
+			processorsList += ","
 		}
+		processorsList += processor.Name
 	}
 
 	// Prepare extra extension config section and comma-separated list of extra extension
@@ -85,17 +144,19 @@ func createConfigYaml(
 		}
 	}
 
-	// Set pipeline based on DataSender type
-	var pipeline string
-	switch sender.(type) {
-	case testbed.TraceDataSender:
-		pipeline = "traces"
-	case testbed.MetricDataSender:
-		pipeline = "metrics"
-	case testbed.LogDataSender:
-		pipeline = "logs"
-	default:
-		t.Error("Invalid DataSender type")
+	// Set pipelines based on DataSender type.
+	pipelinesSection := ""
+	for _, pipeline := range pipelineTypesOf(t, sender) {
+		pipelinesSection += fmt.Sprintf(`
+    %s:
+      receivers: [%v]
+      processors: [%s]
+      exporters: [%v]`,
+			pipeline,
+			sender.ProtocolName(),
+			processorsList,
+			receiver.ProtocolName(),
+		)
 	}
 
 	format := `
@@ -111,11 +172,7 @@ extensions:
 
 service:
   extensions: [pprof, %s]
-  pipelines:
-    %s:
-      receivers: [%v]
-      processors: [%s]
-      exporters: [%v]
+  pipelines:%s
 `
 
 	// Put corresponding elements into the config template to generate the final config.
@@ -127,10 +184,77 @@ service:
 		resultDir,
 		extensionsSections,
 		extensionsList,
-		pipeline,
-		sender.ProtocolName(),
-		processorsList,
-		receiver.ProtocolName(),
+		pipelinesSection,
+	)
+}
+
+// createRoutingConfigYaml creates a collector config which routes the metrics received
+// from sender to the receivers in routes by the value of the resource attribute
+// attributeKey: for each routing value there is a pipeline with a filter processor which
+// only includes metrics with that value and exports to the receiver of the value. The
+// receivers must use distinct protocols, e.g. OTLP over gRPC and over HTTP, so that
+// their exporters have distinct names.
+func createRoutingConfigYaml(
+	t testbed.TestingT,
+	sender testbed.MetricDataSender,
+	attributeKey string,
+	routes map[string]testbed.DataReceiver,
+	resultDir string,
+) string {
+	values := make([]string, 0, len(routes))
+	for value := range routes {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	exportersSections := ""
+	processorsSections := ""
+	pipelines := ""
+	exporterNames := make(map[string]bool)
+	for _, value := range values {
+		receiver := routes[value]
+		if exporterNames[receiver.ProtocolName()] {
+			t.Errorf("Routes must use receivers with distinct protocols, %q is used twice", receiver.ProtocolName())
+		}
+		exporterNames[receiver.ProtocolName()] = true
+
+		exportersSections += receiver.GenConfigYAMLStr()
+		processorsSections += fmt.Sprintf(`
+  filter/%s:
+    metrics:
+      include:
+        match_type: regexp
+        metric_names: [".*"]
+        resource_attributes:
+          - Key: %s
+            Value: %s`, value, attributeKey, value)
+		pipelines += fmt.Sprintf(`
+    metrics/%s:
+      receivers: [%s]
+      processors: [filter/%s]
+      exporters: [%s]`, value, sender.ProtocolName(), value, receiver.ProtocolName())
+	}
+
+	format := `
+receivers:%v
+exporters:%v
+processors:%v
+
+extensions:
+  pprof:
+    save_to_file: %v/cpu.prof
+
+service:
+  extensions: [pprof]
+  pipelines:%v
+`
+	return fmt.Sprintf(
+		format,
+		sender.GenConfigYAMLStr(),
+		exportersSections,
+		processorsSections,
+		resultDir,
+		pipelines,
 	)
 }
 
@@ -144,50 +268,1182 @@ func Scenario10kItemsPerSecond(
 	processors map[string]string,
 	extensions map[string]string,
 ) {
-	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
-	require.NoError(t, err)
+	runScenario10kItemsPerSecond(
+		context.Background(),
+		t,
+		sender,
+		receiver,
+		resourceSpec,
+		resultsSummary,
+		processorsInNameOrder(processors),
+		extensions,
+	)
+}
 
+// ScenarioResults holds the results of a scenario run programmatically.
+type ScenarioResults struct {
+	DataItemsSent     uint64        `json:"data_items_sent"`
+	DataItemsReceived uint64        `json:"data_items_received"`
+	Duration          time.Duration `json:"duration_ns"`
+	CPUPercentAvg     float64       `json:"cpu_percent_avg"`
+	CPUPercentMax     float64       `json:"cpu_percent_max"`
+	RAMMiBAvg         uint32        `json:"ram_mib_avg"`
+	RAMMiBMax         uint32        `json:"ram_mib_max"`
+}
+
+// RunScenario10kItemsPerSecond runs the same test as Scenario10kItemsPerSecond without
+// requiring *testing.T, e.g. from a custom benchmark binary. Logs and results are written
+// to the "results/<name>" directory. Cancelling ctx ends the load phase early. The
+// returned error combines all failures which occurred during the run, in which case the
+// results may be incomplete.
+func RunScenario10kItemsPerSecond(
+	ctx context.Context,
+	name string,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resourceSpec testbed.ResourceSpec,
+	processors map[string]string,
+	extensions map[string]string,
+) (*ScenarioResults, error) {
+	ht := testbed.NewHeadlessT(name)
+	results := &ScenarioResults{}
+
+	// HeadlessT.FailNow exits the goroutine, so run the scenario in a goroutine of its own.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		*results = runScenario10kItemsPerSecond(
+			ctx,
+			ht,
+			sender,
+			receiver,
+			resourceSpec,
+			nil,
+			processorsInNameOrder(processors),
+			extensions,
+		)
+	}()
+	<-done
+
+	return results, ht.Err()
+}
+
+func runScenario10kItemsPerSecond(
+	ctx context.Context,
+	t testbed.TestingT,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resourceSpec testbed.ResourceSpec,
+	resultsSummary testbed.TestResultsSummary,
+	processors []ProcessorNameAndConfigBody,
+	extensions map[string]string,
+) ScenarioResults {
 	options := testbed.LoadOptions{
 		DataItemsPerSecond: 10_000,
 		ItemsPerBatch:      100,
 		Parallel:           1,
 	}
-	agentProc := &testbed.ChildProcess{}
+	return runScenarioItemsPerSecond(ctx, t, options, sender, receiver, resourceSpec, resultsSummary, processors, extensions)
+}
+
+// ScenarioFromParams runs the items per second scenario with trace data, using the rate,
+// batch size, sender and receiver from the scenario parameters, see
+// testbed.ScenarioParams. By default 10k spans/sec are sent via OTLP.
+func ScenarioFromParams(
+	t *testing.T,
+	resourceSpec testbed.ResourceSpec,
+	resultsSummary testbed.TestResultsSummary,
+) {
+	params, err := testbed.ReadScenarioParams(testbed.ScenarioParams{
+		DataItemsPerSecond: 10_000,
+		ItemsPerBatch:      100,
+		Sender:             "otlp",
+		Receiver:           "otlp",
+	})
+	require.NoError(t, err)
+	sender, err := testbed.NewTraceDataSender(params.Sender, testbed.GetAvailablePort(t))
+	require.NoError(t, err)
+	receiver, err := testbed.NewTraceDataReceiver(params.Receiver, testbed.GetAvailablePort(t))
+	require.NoError(t, err)
+
+	options := params.LoadOptions()
+	options.Parallel = 1
+	runScenarioItemsPerSecond(context.Background(), t, options, sender, receiver, resourceSpec, resultsSummary, nil, nil)
+}
+
+// monitoringResourceSpec is the resource spec of the scenarios which compare several
+// runs. Limits are generous, they only enable resource consumption monitoring.
+var monitoringResourceSpec = testbed.ResourceSpec{ExpectedMaxCPU: 400, ExpectedMaxRAM: 1000}
+
+// RunScenarioFromParams runs the items per second scenario with trace data like
+// ScenarioFromParams, using the rate, batch size, duration, sender and receiver of
+// params, without requiring *testing.T. It is the testbed.ScenarioRunner of the
+// testbed.ControlServer. Logs and results are written to the "results/<name>" directory.
+// Cancelling ctx ends the load phase early. The returned error combines all failures
+// which occurred during the run, in which case the results may be incomplete.
+func RunScenarioFromParams(ctx context.Context, name string, params testbed.ScenarioParams) (interface{}, error) {
+	senderPort, err := testbed.FindAvailablePort()
+	if err != nil {
+		return nil, err
+	}
+	sender, err := testbed.NewTraceDataSender(params.Sender, senderPort)
+	if err != nil {
+		return nil, err
+	}
+	receiverPort, err := testbed.FindAvailablePort()
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := testbed.NewTraceDataReceiver(params.Receiver, receiverPort)
+	if err != nil {
+		return nil, err
+	}
 
-	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, extensions)
+	ht := testbed.NewHeadlessT(name)
+	results := &ScenarioResults{}
+	options := params.LoadOptions()
+	options.Parallel = 1
+
+	// HeadlessT.FailNow exits the goroutine, so run the scenario in a goroutine of its own.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		*results = runScenarioItemsPerSecond(ctx, ht, options, sender, receiver, monitoringResourceSpec, nil, nil, nil,
+			testbed.WithDuration(params.Duration))
+	}()
+	<-done
+
+	return results, ht.Err()
+}
+
+// runScenarioItemsPerSecond runs the load described by options through a fresh agent and
+// backend for the test case duration, validates that all sent items were received and
+// returns the results.
+func runScenarioItemsPerSecond(
+	ctx context.Context,
+	t testbed.TestingT,
+	options testbed.LoadOptions,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resourceSpec testbed.ResourceSpec,
+	resultsSummary testbed.TestResultsSummary,
+	processors []ProcessorNameAndConfigBody,
+	extensions map[string]string,
+	opts ...testbed.TestCaseOption,
+) ScenarioResults {
+	return runScenario(t, scenarioRun{
+		sender:         sender,
+		receiver:       receiver,
+		options:        options,
+		resourceSpec:   resourceSpec,
+		processors:     processors,
+		extensions:     extensions,
+		resultsSummary: resultsSummary,
+		opts:           opts,
+		agentArgs:      []string{"--log-level=debug"},
+		load: func(tc *testbed.TestCase) {
+			select {
+			case <-time.After(tc.Duration):
+			case <-tc.ErrorSignal:
+			case <-ctx.Done():
+			}
+		},
+	}).ScenarioResults
+}
+
+// scenarioRun describes a run of the load through a fresh agent, see runScenario.
+type scenarioRun struct {
+	sender       testbed.DataSender
+	receiver     testbed.DataReceiver
+	options      testbed.LoadOptions
+	resourceSpec testbed.ResourceSpec
+	// Processors and extensions of the config created by createOrderedConfigYaml.
+	processors []ProcessorNameAndConfigBody
+	extensions map[string]string
+	// createConfig replaces createOrderedConfigYaml for agents with other pipelines.
+	createConfig func(resultDir string) string
+	// dataProvider defaults to a PerfTestDataProvider for options.
+	dataProvider testbed.DataProvider
+	// resultsSummary is nil to skip recording the results of the test case.
+	resultsSummary testbed.TestResultsSummary
+	opts           []testbed.TestCaseOption
+	agentArgs      []string
+	// setup is called before the backend and the agent start, e.g. to add backends.
+	setup func(tc *testbed.TestCase)
+	// skipBackend does not start the backend, for agents which do not export to receiver.
+	skipBackend bool
+	// load is called once the load started and returns when the load should stop. By
+	// default it sleeps for the test case duration.
+	load func(tc *testbed.TestCase)
+	// drain is called after the load stopped and returns once the sent data items were
+	// received. By default it waits until the backend received all of them, or returns
+	// at once if the backend was skipped.
+	drain func(tc *testbed.TestCase)
+	// skipValidation does not validate the data, e.g. when data items may be lost.
+	skipValidation bool
+	// finish is called after the agent stopped, e.g. to verify the received data.
+	finish func(tc *testbed.TestCase)
+}
+
+// scenarioRunResults holds the results of runScenario.
+type scenarioRunResults struct {
+	ScenarioResults
+	// LoadDuration is the time the load was sent, Duration includes draining.
+	LoadDuration time.Duration
+}
+
+// runScenario runs the load described by run through a fresh agent and backend. The test
+// case is stopped when it returns, but its backends still hold the received data.
+func runScenario(t testbed.TestingT, run scenarioRun) scenarioRunResults {
+	resultDir := scenarioResultDir(t)
+
+	agentProc := &testbed.ChildProcess{}
+	var configStr string
+	if run.createConfig != nil {
+		configStr = run.createConfig(resultDir)
+	} else {
+		configStr = createOrderedConfigYaml(t, run.sender, run.receiver, resultDir, run.processors, run.extensions)
+	}
 	configCleanup, err := agentProc.PrepareConfig(configStr)
 	require.NoError(t, err)
 	defer configCleanup()
 
-	dataProvider := testbed.NewPerfTestDataProvider(options)
+	dataProvider := run.dataProvider
+	if dataProvider == nil {
+		dataProvider = testbed.NewPerfTestDataProvider(run.options)
+	}
+	opts := run.opts
+	if run.resultsSummary == nil {
+		opts = append(opts, testbed.WithSkipResults())
+	}
 	tc := testbed.NewTestCase(
 		t,
 		dataProvider,
-		sender,
-		receiver,
+		run.sender,
+		run.receiver,
 		agentProc,
 		&testbed.PerfTestValidator{},
-		resultsSummary,
+		run.resultsSummary,
+		opts...,
 	)
 	defer tc.Stop()
 
-	tc.SetResourceLimits(resourceSpec)
-	tc.StartBackend()
-	tc.StartAgent("--log-level=debug")
-
-	tc.StartLoad(options)
-
-	tc.Sleep(tc.Duration)
+	tc.SetResourceLimits(run.resourceSpec)
+	if run.setup != nil {
+		run.setup(tc)
+	}
+	if !run.skipBackend {
+		tc.StartBackend()
+	}
+	tc.StartAgent(run.agentArgs...)
 
+	startTime := time.Now()
+	tc.StartLoad(run.options)
+	if run.load != nil {
+		run.load(tc)
+	} else {
+		tc.Sleep(tc.Duration)
+	}
 	tc.StopLoad()
+	loadDuration := time.Since(startTime)
 
-	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() > 0 }, "load generator started")
-	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
-		"all data items received")
+	switch {
+	case run.drain != nil:
+		run.drain(tc)
+	case !run.skipBackend:
+		tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() > 0 }, "load generator started")
+		tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+			"all data items received")
+	}
+	duration := time.Since(startTime)
 
 	tc.StopAgent()
+	if !run.skipValidation {
+		tc.ValidateData()
+	}
+	if run.finish != nil {
+		run.finish(tc)
+	}
+
+	rc := agentProc.GetTotalConsumption()
+	return scenarioRunResults{
+		ScenarioResults: ScenarioResults{
+			DataItemsSent:     tc.LoadGenerator.DataItemsSent(),
+			DataItemsReceived: tc.MockBackend.DataItemsReceived(),
+			Duration:          duration,
+			CPUPercentAvg:     rc.CPUPercentAvg,
+			CPUPercentMax:     rc.CPUPercentMax,
+			RAMMiBAvg:         rc.RAMMiBAvg,
+			RAMMiBMax:         rc.RAMMiBMax,
+		},
+		LoadDuration: loadDuration,
+	}
+}
+
+// itemsPerSecond returns the received data items per second of the run.
+func (r ScenarioResults) itemsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.DataItemsReceived) / r.Duration.Seconds()
+}
+
+// cpuSecondsPerMillionItems returns the CPU time the agent used per million sent data
+// items.
+func (r ScenarioResults) cpuSecondsPerMillionItems() float64 {
+	if r.DataItemsSent == 0 {
+		return 0
+	}
+	cpuSeconds := r.CPUPercentAvg / 100 * r.Duration.Seconds()
+	return cpuSeconds / (float64(r.DataItemsSent) / 1e6)
+}
+
+// scenarioResultDir returns the absolute results directory of the test.
+func scenarioResultDir(t testbed.TestingT) string {
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+	return resultDir
+}
+
+// writeResultsJSON writes results as indented JSON to fileName in the results directory
+// of the test.
+func writeResultsJSON(t testbed.TestingT, fileName string, results interface{}) {
+	resultDir := scenarioResultDir(t)
+	require.NoError(t, os.MkdirAll(resultDir, 0755))
+	data, err := json.MarshalIndent(results, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path.Join(resultDir, fileName), data, 0644))
+}
+
+// SenderComparisonResult holds the results of one sender compared by CompareSenders.
+type SenderComparisonResult struct {
+	Rank              int     `json:"rank"`
+	Sender            string  `json:"sender"`
+	DataItemsSent     uint64  `json:"data_items_sent"`
+	DataItemsReceived uint64  `json:"data_items_received"`
+	ItemsPerSecond    float64 `json:"items_per_second"`
+	CPUPercentAvg     float64 `json:"cpu_percent_avg"`
+	CPUPercentMax     float64 `json:"cpu_percent_max"`
+	RAMMiBAvg         uint32  `json:"ram_mib_avg"`
+	RAMMiBMax         uint32  `json:"ram_mib_max"`
+}
+
+// CompareSenders runs the 10k data items/sec scenario with the same receiver once for
+// every sender, sequentially and each with a fresh agent, and returns the results ranked
+// by received items per second, highest first, with ties broken by lower average CPU.
+// The ranked comparison is logged and written to "comparison.json" in the results
+// directory of the test.
+func CompareSenders(t *testing.T, receiver testbed.DataReceiver, senders []testbed.DataSender) []SenderComparisonResult {
+	results := make([]SenderComparisonResult, 0, len(senders))
+	for i, sender := range senders {
+		name := fmt.Sprintf("%d_%s", i, senderName(sender))
+		t.Run(name, func(t *testing.T) {
+			r := runScenario10kItemsPerSecond(context.Background(), t, sender, receiver, monitoringResourceSpec, nil, nil, nil)
+			results = append(results, SenderComparisonResult{
+				Sender:            name,
+				DataItemsSent:     r.DataItemsSent,
+				DataItemsReceived: r.DataItemsReceived,
+				ItemsPerSecond:    r.itemsPerSecond(),
+				CPUPercentAvg:     r.CPUPercentAvg,
+				CPUPercentMax:     r.CPUPercentMax,
+				RAMMiBAvg:         r.RAMMiBAvg,
+				RAMMiBMax:         r.RAMMiBMax,
+			})
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].ItemsPerSecond != results[j].ItemsPerSecond {
+			return results[i].ItemsPerSecond > results[j].ItemsPerSecond
+		}
+		return results[i].CPUPercentAvg < results[j].CPUPercentAvg
+	})
+	table := fmt.Sprintf("%-4s|%-40s|%12s|%8s|%8s|%11s|%11s\n",
+		"Rank", "Sender", "Items/sec", "CPU Avg%", "CPU Max%", "RAM Avg MiB", "RAM Max MiB")
+	for i := range results {
+		results[i].Rank = i + 1
+		r := results[i]
+		table += fmt.Sprintf("%4d|%-40s|%12.1f|%8.1f|%8.1f|%11d|%11d\n",
+			r.Rank, r.Sender, r.ItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.RAMMiBAvg, r.RAMMiBMax)
+	}
+	log.Printf("Sender comparison:\n%s", table)
+
+	writeResultsJSON(t, "comparison.json", results)
+	return results
+}
+
+// RateSweepResult holds the results of one target rate run by SweepRates.
+type RateSweepResult struct {
+	TargetItemsPerSecond int     `json:"target_items_per_second"`
+	DataItemsSent        uint64  `json:"data_items_sent"`
+	DataItemsReceived    uint64  `json:"data_items_received"`
+	ItemsPerSecond       float64 `json:"items_per_second"`
+	CPUPercentAvg        float64 `json:"cpu_percent_avg"`
+	CPUPercentMax        float64 `json:"cpu_percent_max"`
+	RAMMiBAvg            uint32  `json:"ram_mib_avg"`
+	RAMMiBMax            uint32  `json:"ram_mib_max"`
+}
+
+// SweepRates runs the same scenario as Scenario10kItemsPerSecond once for every target
+// rate in data items per second, sequentially and each with a fresh agent and backend,
+// and returns the results in the order of rates. The results are logged as a table and
+// written to "sweep.json" in the results directory of the test, e.g. to plot the
+// throughput against the CPU usage.
+func SweepRates(t *testing.T, sender testbed.DataSender, receiver testbed.DataReceiver, rates []int) []RateSweepResult {
+	results := make([]RateSweepResult, 0, len(rates))
+	for _, rate := range rates {
+		t.Run(fmt.Sprintf("%dItemsPerSecond", rate), func(t *testing.T) {
+			options := testbed.LoadOptions{
+				DataItemsPerSecond: rate,
+				ItemsPerBatch:      sweepItemsPerBatch(rate),
+				Parallel:           1,
+			}
+			r := runScenarioItemsPerSecond(context.Background(), t, options, sender, receiver, monitoringResourceSpec, nil, nil, nil)
+			results = append(results, RateSweepResult{
+				TargetItemsPerSecond: rate,
+				DataItemsSent:        r.DataItemsSent,
+				DataItemsReceived:    r.DataItemsReceived,
+				ItemsPerSecond:       r.itemsPerSecond(),
+				CPUPercentAvg:        r.CPUPercentAvg,
+				CPUPercentMax:        r.CPUPercentMax,
+				RAMMiBAvg:            r.RAMMiBAvg,
+				RAMMiBMax:            r.RAMMiBMax,
+			})
+		})
+	}
+
+	table := fmt.Sprintf("%14s|%12s|%8s|%8s|%11s|%11s\n",
+		"Target items/s", "Items/sec", "CPU Avg%", "CPU Max%", "RAM Avg MiB", "RAM Max MiB")
+	for _, r := range results {
+		table += fmt.Sprintf("%14d|%12.1f|%8.1f|%8.1f|%11d|%11d\n",
+			r.TargetItemsPerSecond, r.ItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.RAMMiBAvg, r.RAMMiBMax)
+	}
+	log.Printf("Rate sweep:\n%s", table)
+
+	writeResultsJSON(t, "sweep.json", results)
+	return results
+}
+
+// sweepItemsPerBatch returns the batch size used by SweepRates for the given rate: 100
+// items like Scenario10kItemsPerSecond, but at least 10 batches per second so that low
+// rates are sent smoothly.
+func sweepItemsPerBatch(rate int) int {
+	itemsPerBatch := rate / 10
+	if itemsPerBatch > 100 {
+		itemsPerBatch = 100
+	}
+	if itemsPerBatch < 1 {
+		itemsPerBatch = 1
+	}
+	return itemsPerBatch
+}
+
+// CompressionNone is the codec name which makes SweepCompression send uncompressed.
+const CompressionNone = "none"
+
+// CompressionSweepResult holds the results of one codec run by SweepCompression.
+type CompressionSweepResult struct {
+	Codec             string  `json:"codec"`
+	DataItemsSent     uint64  `json:"data_items_sent"`
+	DataItemsReceived uint64  `json:"data_items_received"`
+	ItemsPerSecond    float64 `json:"items_per_second"`
+	CPUPercentAvg     float64 `json:"cpu_percent_avg"`
+	CPUPercentMax     float64 `json:"cpu_percent_max"`
+	RAMMiBAvg         uint32  `json:"ram_mib_avg"`
+	RAMMiBMax         uint32  `json:"ram_mib_max"`
+	// NetworkBytes is the number of bytes sent by the agent to the receiver over the
+	// network, i.e. after compression.
+	NetworkBytes uint64 `json:"network_bytes"`
+	// BytesPerItem is NetworkBytes divided by DataItemsReceived.
+	BytesPerItem float64 `json:"bytes_per_item"`
+}
+
+// SweepCompression runs the same scenario as Scenario10kItemsPerSecond once for every
+// codec with which the agent compresses the data it exports to receiver, sequentially and
+// each with a fresh agent and backend, and returns the results in the order of codecs.
+// CompressionNone disables compression, the other codecs must be supported by the OTLP
+// exporters, see configgrpc.GetGRPCCompressionKey. The receiver must be able to count
+// the received bytes, which only OTLP over gRPC does, see
+// BaseOTLPDataReceiver.WithConnectionStats, and is left configured with the last codec.
+// The results are logged as a table and written to "compression_sweep.json" in the
+// results directory of the test.
+func SweepCompression(
+	t *testing.T,
+	sender testbed.DataSender,
+	receiver *testbed.BaseOTLPDataReceiver,
+	codecs []string,
+) []CompressionSweepResult {
+	for _, codec := range codecs {
+		if codec != CompressionNone && configgrpc.GetGRPCCompressionKey(codec) == configgrpc.CompressionUnsupported {
+			require.FailNowf(t, "Unsupported codec.", "compression %q is not supported by the OTLP exporters", codec)
+		}
+	}
+	_, ok := receiver.WithConnectionStats().ConnectionStats()
+	require.True(t, ok, "receiver %s does not count received bytes", receiver.ProtocolName())
+
+	results := make([]CompressionSweepResult, 0, len(codecs))
+	for _, codec := range codecs {
+		t.Run(codec, func(t *testing.T) {
+			compression := codec
+			if codec == CompressionNone {
+				compression = ""
+			}
+			receiver.WithCompression(compression)
+			r := runScenario10kItemsPerSecond(context.Background(), t, sender, receiver, monitoringResourceSpec, nil, nil, nil)
+			// The counters are reset when the receiver starts, so they only cover this run.
+			stats, _ := receiver.ConnectionStats()
+			result := CompressionSweepResult{
+				Codec:             codec,
+				DataItemsSent:     r.DataItemsSent,
+				DataItemsReceived: r.DataItemsReceived,
+				ItemsPerSecond:    r.itemsPerSecond(),
+				CPUPercentAvg:     r.CPUPercentAvg,
+				CPUPercentMax:     r.CPUPercentMax,
+				RAMMiBAvg:         r.RAMMiBAvg,
+				RAMMiBMax:         r.RAMMiBMax,
+				NetworkBytes:      stats.ReceivedWireBytes,
+			}
+			if r.DataItemsReceived > 0 {
+				result.BytesPerItem = float64(stats.ReceivedWireBytes) / float64(r.DataItemsReceived)
+			}
+			results = append(results, result)
+		})
+	}
+
+	table := fmt.Sprintf("%-10s|%12s|%8s|%8s|%11s|%11s|%14s|%10s\n",
+		"Codec", "Items/sec", "CPU Avg%", "CPU Max%", "RAM Avg MiB", "RAM Max MiB", "Network bytes", "Bytes/item")
+	for _, r := range results {
+		table += fmt.Sprintf("%-10s|%12.1f|%8.1f|%8.1f|%11d|%11d|%14d|%10.1f\n",
+			r.Codec, r.ItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.RAMMiBAvg, r.RAMMiBMax, r.NetworkBytes, r.BytesPerItem)
+	}
+	log.Printf("Compression sweep:\n%s", table)
+
+	writeResultsJSON(t, "compression_sweep.json", results)
+	return results
+}
+
+// ProcessorOrderResult holds the results of one processor order run by
+// CompareProcessorOrders.
+type ProcessorOrderResult struct {
+	// Order is the comma separated list of the processor names in pipeline order.
+	Order             string  `json:"order"`
+	DataItemsSent     uint64  `json:"data_items_sent"`
+	DataItemsReceived uint64  `json:"data_items_received"`
+	ItemsPerSecond    float64 `json:"items_per_second"`
+	CPUPercentAvg     float64 `json:"cpu_percent_avg"`
+	CPUPercentMax     float64 `json:"cpu_percent_max"`
+	RAMMiBAvg         uint32  `json:"ram_mib_avg"`
+	RAMMiBMax         uint32  `json:"ram_mib_max"`
+}
+
+// CompareProcessorOrders runs the 10k data items/sec scenario once for every order of
+// processors, sequentially and each with a fresh agent and backend, and returns the
+// results in the order of orders. The orders usually hold the same processors, for
+// example a filter before and after the batch processor. The results are logged as a
+// table and written to "processor_orders.json" in the results directory of the test.
+func CompareProcessorOrders(
+	t *testing.T,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	orders [][]ProcessorNameAndConfigBody,
+) []ProcessorOrderResult {
+	results := make([]ProcessorOrderResult, 0, len(orders))
+	for _, processors := range orders {
+		names := make([]string, len(processors))
+		for i, processor := range processors {
+			names[i] = processor.Name
+		}
+		t.Run(strings.Join(names, "_"), func(t *testing.T) {
+			r := runScenario10kItemsPerSecond(context.Background(), t, sender, receiver, monitoringResourceSpec, nil, processors, nil)
+			results = append(results, ProcessorOrderResult{
+				Order:             strings.Join(names, ","),
+				DataItemsSent:     r.DataItemsSent,
+				DataItemsReceived: r.DataItemsReceived,
+				ItemsPerSecond:    r.itemsPerSecond(),
+				CPUPercentAvg:     r.CPUPercentAvg,
+				CPUPercentMax:     r.CPUPercentMax,
+				RAMMiBAvg:         r.RAMMiBAvg,
+				RAMMiBMax:         r.RAMMiBMax,
+			})
+		})
+	}
+
+	table := fmt.Sprintf("%-40s|%12s|%8s|%8s|%11s|%11s\n",
+		"Processor order", "Items/sec", "CPU Avg%", "CPU Max%", "RAM Avg MiB", "RAM Max MiB")
+	for _, r := range results {
+		table += fmt.Sprintf("%-40s|%12.1f|%8.1f|%8.1f|%11d|%11d\n",
+			r.Order, r.ItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.RAMMiBAvg, r.RAMMiBMax)
+	}
+	log.Printf("Processor order comparison:\n%s", table)
+
+	writeResultsJSON(t, "processor_orders.json", results)
+	return results
+}
+
+// ProcessorCostRun holds the results of one run of CompareProcessorCost or
+// CompareDecodeCost.
+type ProcessorCostRun struct {
+	// Name is the name of the processor, or "baseline" for the run without processors.
+	// For CompareDecodeCost it is "decode_only" or "round_trip".
+	Name                      string  `json:"name"`
+	DataItemsSent             uint64  `json:"data_items_sent"`
+	DataItemsReceived         uint64  `json:"data_items_received"`
+	ItemsPerSecond            float64 `json:"items_per_second"`
+	CPUPercentAvg             float64 `json:"cpu_percent_avg"`
+	CPUPercentMax             float64 `json:"cpu_percent_max"`
+	CPUSecondsPerMillionItems float64 `json:"cpu_seconds_per_million_items"`
+}
+
+// ProcessorCostResult holds the results of CompareProcessorCost.
+type ProcessorCostResult struct {
+	Baseline  ProcessorCostRun `json:"baseline"`
+	Processor ProcessorCostRun `json:"processor"`
+	// CPU time per million data items the processor adds to the baseline.
+	OverheadCPUSecondsPerMillionItems float64 `json:"overhead_cpu_seconds_per_million_items"`
+}
+
+// CompareProcessorCost runs the 10k data items/sec scenario once without processors as
+// the no-op baseline and once with the given processor, sequentially and each with a
+// fresh agent and backend, and returns the throughput and CPU cost of both. This is
+// meant for CPU-heavy processors such as transforms. verify is called with the backend
+// of the processor run, which records the received data, to check that the processor
+// actually transformed it. The results are logged and written to "processor_cost.json"
+// in the results directory of the test.
+func CompareProcessorCost(
+	t *testing.T,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	processor ProcessorNameAndConfigBody,
+	verify func(t testbed.TestingT, backend *testbed.MockBackend),
+) ProcessorCostResult {
+	var result ProcessorCostResult
+	t.Run("baseline", func(t *testing.T) {
+		result.Baseline = runProcessorCostScenario(t, sender, receiver, nil, nil)
+		result.Baseline.Name = "baseline"
+	})
+	t.Run(processor.Name, func(t *testing.T) {
+		result.Processor = runProcessorCostScenario(t, sender, receiver, []ProcessorNameAndConfigBody{processor}, verify)
+		result.Processor.Name = processor.Name
+	})
+	result.OverheadCPUSecondsPerMillionItems =
+		result.Processor.CPUSecondsPerMillionItems - result.Baseline.CPUSecondsPerMillionItems
+
+	table := fmt.Sprintf("%-40s|%12s|%8s|%8s|%15s\n", "Processor", "Items/sec", "CPU Avg%", "CPU Max%", "CPU s/M items")
+	for _, r := range []ProcessorCostRun{result.Baseline, result.Processor} {
+		table += fmt.Sprintf("%-40s|%12.1f|%8.1f|%8.1f|%15.3f\n",
+			r.Name, r.ItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.CPUSecondsPerMillionItems)
+	}
+	log.Printf("Processor cost, overhead %.3f CPU seconds per million items:\n%s",
+		result.OverheadCPUSecondsPerMillionItems, table)
+
+	writeResultsJSON(t, "processor_cost.json", result)
+	return result
+}
+
+func runProcessorCostScenario(
+	t testbed.TestingT,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	processors []ProcessorNameAndConfigBody,
+	verify func(t testbed.TestingT, backend *testbed.MockBackend),
+) ProcessorCostRun {
+	run := scenarioRun{
+		sender:       sender,
+		receiver:     receiver,
+		options:      testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 100, Parallel: 1},
+		resourceSpec: monitoringResourceSpec,
+		processors:   processors,
+	}
+	if verify != nil {
+		run.setup = func(tc *testbed.TestCase) { tc.EnableRecording() }
+		run.finish = func(tc *testbed.TestCase) { verify(t, tc.MockBackend) }
+	}
+	return newProcessorCostRun(runScenario(t, run).ScenarioResults)
+}
+
+// newProcessorCostRun returns the ProcessorCostRun of the results of a run.
+func newProcessorCostRun(r ScenarioResults) ProcessorCostRun {
+	return ProcessorCostRun{
+		DataItemsSent:             r.DataItemsSent,
+		DataItemsReceived:         r.DataItemsReceived,
+		ItemsPerSecond:            r.itemsPerSecond(),
+		CPUPercentAvg:             r.CPUPercentAvg,
+		CPUPercentMax:             r.CPUPercentMax,
+		CPUSecondsPerMillionItems: r.cpuSecondsPerMillionItems(),
+	}
+}
+
+// createDecodeOnlyConfigYaml creates a collector config with the receiver of sender and
+// pipelines without processors which export to a logging exporter that only logs
+// warnings, so that the received data is decoded and then dropped without being sent
+// anywhere.
+func createDecodeOnlyConfigYaml(t testbed.TestingT, sender testbed.DataSender, resultDir string) string {
+	pipelinesSection := ""
+	for _, pipeline := range pipelineTypesOf(t, sender) {
+		pipelinesSection += fmt.Sprintf(`
+    %s:
+      receivers: [%s]
+      exporters: [logging]`, pipeline, sender.ProtocolName())
+	}
+
+	format := `
+receivers:%v
+exporters:
+  logging:
+    loglevel: warn
+
+extensions:
+  pprof:
+    save_to_file: %v/cpu.prof
 
-	tc.ValidateData()
+service:
+  extensions: [pprof]
+  pipelines:%v
+`
+	return fmt.Sprintf(
+		format,
+		sender.GenConfigYAMLStr(),
+		resultDir,
+		pipelinesSection,
+	)
+}
+
+// DecodeCostResult holds the results of CompareDecodeCost.
+type DecodeCostResult struct {
+	DecodeOnly ProcessorCostRun `json:"decode_only"`
+	RoundTrip  ProcessorCostRun `json:"round_trip"`
+	// CPU time per million data items the export to the backend adds to the decoding.
+	ExportCPUSecondsPerMillionItems float64 `json:"export_cpu_seconds_per_million_items"`
+}
+
+// CompareDecodeCost measures the CPU cost of the receiver of sender in isolation. It runs
+// the 10k data items/sec scenario once with a pipeline which decodes the received data
+// and drops it in a logging exporter that only logs warnings, without a round-trip to a
+// MockBackend, and once with a pipeline exporting to receiver as the full round-trip,
+// sequentially and each with a fresh agent. The CPU cost of the decode-only run is per
+// sent data item, as nothing is received. The results are logged and written to
+// "decode_cost.json" in the results directory of the test.
+func CompareDecodeCost(t *testing.T, sender testbed.DataSender, receiver testbed.DataReceiver) DecodeCostResult {
+	var result DecodeCostResult
+	t.Run("decode_only", func(t *testing.T) {
+		result.DecodeOnly = runDecodeOnlyScenario(t, sender, receiver)
+		result.DecodeOnly.Name = "decode_only"
+	})
+	t.Run("round_trip", func(t *testing.T) {
+		result.RoundTrip = runProcessorCostScenario(t, sender, receiver, nil, nil)
+		result.RoundTrip.Name = "round_trip"
+	})
+	result.ExportCPUSecondsPerMillionItems =
+		result.RoundTrip.CPUSecondsPerMillionItems - result.DecodeOnly.CPUSecondsPerMillionItems
+
+	table := fmt.Sprintf("%-12s|%12s|%8s|%8s|%15s\n", "Run", "Items/sec", "CPU Avg%", "CPU Max%", "CPU s/M items")
+	for _, r := range []ProcessorCostRun{result.DecodeOnly, result.RoundTrip} {
+		table += fmt.Sprintf("%-12s|%12.1f|%8.1f|%8.1f|%15.3f\n",
+			r.Name, r.ItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.CPUSecondsPerMillionItems)
+	}
+	log.Printf("Decode cost, export adds %.3f CPU seconds per million items:\n%s",
+		result.ExportCPUSecondsPerMillionItems, table)
+
+	writeResultsJSON(t, "decode_cost.json", result)
+	return result
+}
+
+// runDecodeOnlyScenario runs the load through a fresh agent which drops all data after
+// decoding it. The backend of receiver is not started. Requests are only acknowledged
+// once the pipeline consumed them, so all data items were decoded when the load stops
+// without send errors.
+func runDecodeOnlyScenario(t testbed.TestingT, sender testbed.DataSender, receiver testbed.DataReceiver) ProcessorCostRun {
+	r := runScenario(t, scenarioRun{
+		sender:       sender,
+		receiver:     receiver,
+		options:      testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 100, Parallel: 1},
+		resourceSpec: monitoringResourceSpec,
+		createConfig: func(resultDir string) string {
+			return createDecodeOnlyConfigYaml(t, sender, resultDir)
+		},
+		skipBackend:    true,
+		skipValidation: true,
+		finish: func(tc *testbed.TestCase) {
+			assert.Zero(t, tc.LoadGenerator.SendErrors(), "Data items were not accepted by the receiver.")
+		},
+	})
+	// Nothing is received, so the throughput is the one of the sent data items.
+	run := newProcessorCostRun(r.ScenarioResults)
+	run.ItemsPerSecond = float64(r.DataItemsSent) / r.Duration.Seconds()
+	return run
+}
+
+// createFanOutConfigYaml creates a collector config like createConfigYaml, with the
+// pipelines exporting to all receivers. The exporter of each receiver is named after its
+// protocol and its position, e.g. "otlp/1", so that receivers of the same protocol can
+// be used.
+func createFanOutConfigYaml(
+	t testbed.TestingT,
+	sender testbed.DataSender,
+	receivers []testbed.DataReceiver,
+	resultDir string,
+) string {
+	exportersSections := ""
+	var exporterNames []string
+	for i, receiver := range receivers {
+		name := fmt.Sprintf("%s/%d", receiver.ProtocolName(), i+1)
+		exportersSections += strings.Replace(receiver.GenConfigYAMLStr(),
+			"\n  "+receiver.ProtocolName()+":", "\n  "+name+":", 1)
+		exporterNames = append(exporterNames, name)
+	}
+
+	pipelinesSection := ""
+	for _, pipeline := range pipelineTypesOf(t, sender) {
+		pipelinesSection += fmt.Sprintf(`
+    %s:
+      receivers: [%s]
+      exporters: [%s]`, pipeline, sender.ProtocolName(), strings.Join(exporterNames, ","))
+	}
+
+	format := `
+receivers:%v
+exporters:%v
+
+extensions:
+  pprof:
+    save_to_file: %v/cpu.prof
+
+service:
+  extensions: [pprof]
+  pipelines:%v
+`
+	return fmt.Sprintf(
+		format,
+		sender.GenConfigYAMLStr(),
+		exportersSections,
+		resultDir,
+		pipelinesSection,
+	)
+}
+
+// FanOutRun holds the results of one run of CompareFanOut.
+type FanOutRun struct {
+	Exporters     int    `json:"exporters"`
+	DataItemsSent uint64 `json:"data_items_sent"`
+	// Data items received and throughput of the backend of each exporter.
+	DataItemsReceived []uint64  `json:"data_items_received"`
+	ItemsPerSecond    []float64 `json:"items_per_second"`
+	// Sum of the throughput of all exporters.
+	AggregateItemsPerSecond   float64 `json:"aggregate_items_per_second"`
+	CPUPercentAvg             float64 `json:"cpu_percent_avg"`
+	CPUPercentMax             float64 `json:"cpu_percent_max"`
+	CPUSecondsPerMillionItems float64 `json:"cpu_seconds_per_million_items"`
+}
+
+// FanOutResult holds the results of CompareFanOut.
+type FanOutResult struct {
+	SingleExporter FanOutRun `json:"single_exporter"`
+	FanOut         FanOutRun `json:"fan_out"`
+	// CPU time per million sent data items the fan-out adds to the single exporter.
+	OverheadCPUSecondsPerMillionItems float64 `json:"overhead_cpu_seconds_per_million_items"`
+}
+
+// CompareFanOut runs the 10k data items/sec scenario once with a pipeline exporting to
+// the first receiver only and once with a pipeline fanning out to all receivers, each
+// with its own exporter and MockBackend, sequentially and each with a fresh agent. It
+// verifies that every backend received all sent data items and returns the per-exporter
+// and aggregate throughput and the CPU cost of both runs. The results are logged and
+// written to "fan_out.json" in the results directory of the test.
+func CompareFanOut(t *testing.T, sender testbed.DataSender, receivers []testbed.DataReceiver) FanOutResult {
+	require.NotEmpty(t, receivers)
+
+	var result FanOutResult
+	t.Run("single_exporter", func(t *testing.T) {
+		result.SingleExporter = runFanOutScenario(t, sender, receivers[:1])
+	})
+	t.Run(fmt.Sprintf("%d_exporters", len(receivers)), func(t *testing.T) {
+		result.FanOut = runFanOutScenario(t, sender, receivers)
+	})
+	result.OverheadCPUSecondsPerMillionItems =
+		result.FanOut.CPUSecondsPerMillionItems - result.SingleExporter.CPUSecondsPerMillionItems
+
+	table := fmt.Sprintf("%-10s|%18s|%8s|%8s|%15s\n", "Exporters", "Items/sec (total)", "CPU Avg%", "CPU Max%", "CPU s/M items")
+	for _, r := range []FanOutRun{result.SingleExporter, result.FanOut} {
+		table += fmt.Sprintf("%-10d|%18.1f|%8.1f|%8.1f|%15.3f\n",
+			r.Exporters, r.AggregateItemsPerSecond, r.CPUPercentAvg, r.CPUPercentMax, r.CPUSecondsPerMillionItems)
+	}
+	log.Printf("Fan-out cost, overhead %.3f CPU seconds per million items:\n%s",
+		result.OverheadCPUSecondsPerMillionItems, table)
+
+	writeResultsJSON(t, "fan_out.json", result)
+	return result
+}
+
+func runFanOutScenario(t testbed.TestingT, sender testbed.DataSender, receivers []testbed.DataReceiver) FanOutRun {
+	var backends []*testbed.MockBackend
+	r := runScenario(t, scenarioRun{
+		sender:       sender,
+		receiver:     receivers[0],
+		options:      testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 100, Parallel: 1},
+		resourceSpec: monitoringResourceSpec,
+		createConfig: func(resultDir string) string {
+			return createFanOutConfigYaml(t, sender, receivers, resultDir)
+		},
+		setup: func(tc *testbed.TestCase) {
+			backends = []*testbed.MockBackend{tc.MockBackend}
+			for _, receiver := range receivers[1:] {
+				backends = append(backends, tc.AddMockBackend(receiver))
+			}
+		},
+		drain: func(tc *testbed.TestCase) {
+			tc.WaitFor(func() bool {
+				for _, mb := range backends {
+					if mb.DataItemsReceived() != tc.LoadGenerator.DataItemsSent() {
+						return false
+					}
+				}
+				return true
+			}, "all data items received by every backend")
+		},
+	})
+
+	run := FanOutRun{
+		Exporters:                 len(receivers),
+		DataItemsSent:             r.DataItemsSent,
+		CPUPercentAvg:             r.CPUPercentAvg,
+		CPUPercentMax:             r.CPUPercentMax,
+		CPUSecondsPerMillionItems: r.cpuSecondsPerMillionItems(),
+	}
+	for i, mb := range backends {
+		assert.EqualValues(t, run.DataItemsSent, mb.DataItemsReceived(), "Backend %d did not receive all data items.", i+1)
+		itemsPerSecond := float64(mb.DataItemsReceived()) / r.Duration.Seconds()
+		run.DataItemsReceived = append(run.DataItemsReceived, mb.DataItemsReceived())
+		run.ItemsPerSecond = append(run.ItemsPerSecond, itemsPerSecond)
+		run.AggregateItemsPerSecond += itemsPerSecond
+	}
+	return run
+}
+
+// ScenarioCardinalityRamp sends metrics at 10k data points/sec through a fresh agent with
+// the given processors while ramping up the cardinality of the resource attribute
+// attributeKey through the steps of ramp, see testbed.CardinalityRamp. The RSS of the
+// agent is measured at the end of every step and the ramp stops once it exceeds the
+// threshold of ramp, the agent is not failed for its memory. The memory growth against
+// the cardinality and the cardinality at which the threshold was crossed are logged and
+// written to "cardinality_ramp.json" in the results directory of the test.
+func ScenarioCardinalityRamp(
+	t *testing.T,
+	sender testbed.MetricDataSender,
+	receiver testbed.DataReceiver,
+	processors []ProcessorNameAndConfigBody,
+	attributeKey string,
+	ramp testbed.CardinalityRamp,
+) testbed.CardinalityRampResult {
+	require.NotEmpty(t, ramp.Steps)
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:           10_000,
+		ItemsPerBatch:                100,
+		Parallel:                     1,
+		ResourceAttributeCardinality: map[string]int{attributeKey: ramp.Steps[0]},
+	}
+	dataProvider := testbed.NewPerfTestDataProvider(options)
+	var result testbed.CardinalityRampResult
+	runScenario(t, scenarioRun{
+		sender:       sender,
+		receiver:     receiver,
+		options:      options,
+		dataProvider: dataProvider,
+		// Only the CPU is limited, the memory is expected to grow.
+		resourceSpec: testbed.ResourceSpec{ExpectedMaxCPU: 400},
+		processors:   processors,
+		load: func(tc *testbed.TestCase) {
+			var err error
+			result, err = ramp.Run(
+				func(cardinality int) {
+					dataProvider.SetResourceAttributeCardinality(map[string]int{attributeKey: cardinality})
+				},
+				tc.Sleep,
+				func() (uint32, error) {
+					rss, _, err := tc.AgentMemoryInfo()
+					return rss, err
+				},
+			)
+			require.NoError(t, err)
+		},
+	})
+
+	table := fmt.Sprintf("%-12s|%8s\n", "Cardinality", "RAM MiB")
+	for _, step := range result.Steps {
+		table += fmt.Sprintf("%-12d|%8d\n", step.Cardinality, step.RAMMiB)
+	}
+	if result.CrossingCardinality > 0 {
+		log.Printf("Cardinality ramp, RAM crossed %d MiB at cardinality %d:\n%s",
+			result.ThresholdMiB, result.CrossingCardinality, table)
+	} else {
+		log.Printf("Cardinality ramp, RAM stayed within %d MiB:\n%s", result.ThresholdMiB, table)
+	}
+
+	writeResultsJSON(t, "cardinality_ramp.json", result)
+	return result
+}
+
+// ThroughputProbeResult holds the results of one probe run by FindMaxThroughput.
+type ThroughputProbeResult struct {
+	TargetItemsPerSecond int     `json:"target_items_per_second"`
+	DataItemsSent        uint64  `json:"data_items_sent"`
+	DataItemsReceived    uint64  `json:"data_items_received"`
+	ItemsPerSecond       float64 `json:"items_per_second"`
+	LossFraction         float64 `json:"loss_fraction"`
+	CPUPercentMax        float64 `json:"cpu_percent_max"`
+	Sustained            bool    `json:"sustained"`
+}
+
+const (
+	// Range of rates in data items per second searched by FindMaxThroughput.
+	maxThroughputMinRate = 1_000
+	maxThroughputMaxRate = 256_000
+	// FindMaxThroughput stops once the highest sustained and the lowest unsustained
+	// rate are within this fraction of each other.
+	maxThroughputPrecision = 0.05
+	// Fraction of the sent data items a sustained probe may lose.
+	maxThroughputLoss = 0.001
+	// Fraction of the target rate a sustained probe must deliver. Senders block when
+	// the collector cannot keep up, so an overloaded collector shows as a low rate
+	// rather than as loss.
+	maxThroughputMinDelivered = 0.95
+	// How long a probe waits for the sent data items to arrive after the load stopped.
+	maxThroughputDrainTimeout = 5 * time.Second
+)
+
+// FindMaxThroughput binary searches the highest rate in data items per second which the
+// collector sustains, i.e. delivers at 95% or more of the rate with at most 0.1% of the
+// data items lost, while its CPU usage stays at or below cpuCap percent. Every probe
+// runs for the test case duration with a fresh agent and backend, so short durations
+// should be configured via TESTCASE_DURATION. Failed probes do not fail the test. The
+// discovered rate is logged together with the results of all probes, which are also
+// written to "throughput.json" in the results directory of the test. Returns 0 if not
+// even the lowest rate of 1k items/sec is sustained.
+func FindMaxThroughput(t *testing.T, sender testbed.DataSender, receiver testbed.DataReceiver, cpuCap float64) int {
+	resourceSpec := monitoringResourceSpec
+	resourceSpec.ExpectedMaxCPU = uint32(math.Max(float64(resourceSpec.ExpectedMaxCPU), 2*cpuCap))
+
+	var probes []ThroughputProbeResult
+	maxRate := searchMaxRate(maxThroughputMinRate, maxThroughputMaxRate, func(rate int) bool {
+		name := fmt.Sprintf("%s/%dItemsPerSecond", t.Name(), rate)
+		probe := probeThroughput(name, rate, sender, receiver, resourceSpec, cpuCap)
+		probes = append(probes, probe)
+		return probe.Sustained
+	})
+
+	table := fmt.Sprintf("%14s|%12s|%8s|%8s|%9s\n", "Target items/s", "Items/sec", "Loss%", "CPU Max%", "Sustained")
+	for _, p := range probes {
+		table += fmt.Sprintf("%14d|%12.1f|%8.3f|%8.1f|%9t\n",
+			p.TargetItemsPerSecond, p.ItemsPerSecond, 100*p.LossFraction, p.CPUPercentMax, p.Sustained)
+	}
+	log.Printf("Max throughput below %.0f%% CPU: %d items/sec. Probes:\n%s", cpuCap, maxRate, table)
+
+	writeResultsJSON(t, "throughput.json", probes)
+	return maxRate
+}
+
+// searchMaxRate returns the highest rate between minRate and maxRate for which sustained
+// returns true, assuming that all rates below a sustained rate are sustained as well.
+// The search stops once the result is within maxThroughputPrecision of the lowest rate
+// found not to be sustained. Returns 0 if minRate is not sustained.
+func searchMaxRate(minRate, maxRate int, sustained func(rate int) bool) int {
+	if !sustained(minRate) {
+		return 0
+	}
+	if sustained(maxRate) {
+		return maxRate
+	}
+	low, high := minRate, maxRate
+	for float64(high-low) > maxThroughputPrecision*float64(low) {
+		mid := (low + high) / 2
+		if sustained(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// probeThroughput runs the load at the given rate through a fresh agent and backend for
+// the test case duration. It runs the test case with a HeadlessT so that the failures of
+// an overloaded collector do not fail the test.
+func probeThroughput(
+	name string,
+	rate int,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resourceSpec testbed.ResourceSpec,
+	cpuCap float64,
+) ThroughputProbeResult {
+	ht := testbed.NewHeadlessT(name)
+	result := ThroughputProbeResult{TargetItemsPerSecond: rate}
+
+	// HeadlessT.FailNow exits the goroutine, so run the probe in a goroutine of its own.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runThroughputProbe(ht, rate, sender, receiver, resourceSpec, &result)
+	}()
+	<-done
+
+	if err := ht.Err(); err != nil {
+		log.Printf("Probe of %d items/sec failed: %v", rate, err)
+		return result
+	}
+	result.Sustained = result.LossFraction <= maxThroughputLoss &&
+		result.ItemsPerSecond >= maxThroughputMinDelivered*float64(rate) &&
+		result.CPUPercentMax <= cpuCap
+	return result
+}
+
+func runThroughputProbe(
+	t testbed.TestingT,
+	rate int,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resourceSpec testbed.ResourceSpec,
+	result *ThroughputProbeResult,
+) {
+	r := runScenario(t, scenarioRun{
+		sender:   sender,
+		receiver: receiver,
+		options: testbed.LoadOptions{
+			DataItemsPerSecond: rate,
+			ItemsPerBatch:      sweepItemsPerBatch(rate),
+			Parallel:           1,
+		},
+		resourceSpec: resourceSpec,
+		// Unlike WaitFor this does not fail the probe if items were lost.
+		drain: func(tc *testbed.TestCase) {
+			deadline := time.Now().Add(maxThroughputDrainTimeout)
+			for tc.MockBackend.DataItemsReceived() < tc.LoadGenerator.DataItemsSent() && time.Now().Before(deadline) {
+				select {
+				case <-time.After(50 * time.Millisecond):
+				case <-tc.ErrorSignal:
+					return
+				}
+			}
+		},
+		skipValidation: true,
+	})
+
+	result.DataItemsSent = r.DataItemsSent
+	result.DataItemsReceived = r.DataItemsReceived
+	result.ItemsPerSecond = float64(r.DataItemsReceived) / r.LoadDuration.Seconds()
+	if r.DataItemsSent > 0 && r.DataItemsReceived < r.DataItemsSent {
+		result.LossFraction = float64(r.DataItemsSent-r.DataItemsReceived) / float64(r.DataItemsSent)
+	}
+	result.CPUPercentMax = r.CPUPercentMax
+}
+
+// senderName returns the type name of the sender.
+func senderName(sender testbed.DataSender) string {
+	typ := reflect.TypeOf(sender)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
 }
 
 // TestCase for Scenario1kSPSWithAttrs func.
@@ -320,6 +1576,190 @@ func ScenarioTestTraceNoBackend10kSPS(
 	assert.Less(t, configuration.ExpectedMinFinalRAM, rss)
 }
 
+// ScenarioColdStart measures the time from the start of the agent until the first data
+// item is received by the backend and returns it. The load is started before the agent,
+// so data sent while the agent is not ready yet is lost. For that reason sent and
+// received counters are not compared.
+func ScenarioColdStart(
+	t *testing.T,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resultsSummary testbed.TestResultsSummary,
+) time.Duration {
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	dataProvider := testbed.NewPerfTestDataProvider(options)
+	tc := testbed.NewTestCase(
+		t,
+		dataProvider,
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		resultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartLoad(options)
+	tc.StartAgent()
+
+	tc.WaitFor(func() bool { return tc.MockBackend.DataItemsReceived() > 0 }, "first data item received")
+	tc.StopLoad()
+
+	timeToFirstItem := tc.TimeToFirstItem()
+	log.Printf("Time to first item: %v", timeToFirstItem)
+	return timeToFirstItem
+}
+
+// batchTimeoutLatencyTolerance is how much the end-to-end latency measured by
+// ScenarioBatchTimeoutLatency may exceed the batch timeout.
+const batchTimeoutLatencyTolerance = 100 * time.Millisecond
+
+// ScenarioBatchTimeoutLatency sends traces through a batch processor with the given
+// timeout at a rate far below the batch size, so that every batch is sent because the
+// timeout expired. It asserts that the maximum end-to-end latency of the spans is close
+// to the timeout, i.e. at least half of it and at most batchTimeoutLatencyTolerance
+// above it, and returns the maximum latency.
+func ScenarioBatchTimeoutLatency(
+	t *testing.T,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resultsSummary testbed.TestResultsSummary,
+	batchTimeout time.Duration,
+) time.Duration {
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": fmt.Sprintf(`
+  batch:
+    timeout: %s
+    send_batch_size: 100000
+`, batchTimeout),
+	}
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 100, ItemsPerBatch: 1}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	dataProvider := testbed.NewPerfTestDataProvider(options)
+	tc := testbed.NewTestCase(
+		t,
+		dataProvider,
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		resultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.MockBackend.EnableSpanLatencyRecording()
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	tc.Sleep(tc.Duration)
+
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+
+	var maxLatency time.Duration
+	for _, latency := range tc.MockBackend.SpanLatencies() {
+		if latency > maxLatency {
+			maxLatency = latency
+		}
+	}
+	log.Printf("Batch timeout %v, max latency %v", batchTimeout, maxLatency)
+	assert.GreaterOrEqual(t, int64(maxLatency), int64(batchTimeout/2),
+		"latency is not governed by the batch timeout")
+	assert.LessOrEqual(t, int64(maxLatency), int64(batchTimeout+batchTimeoutLatencyTolerance),
+		"latency is much higher than the batch timeout")
+	return maxLatency
+}
+
+// shutdownBackendDelay is how long the backend takes to consume every request in
+// ScenarioShutdownDrain, so that the exporter queue of the agent fills up.
+const shutdownBackendDelay = 200 * time.Millisecond
+
+// ScenarioShutdownDrain sends load to the agent which exports to a slow backend, so
+// that data is still queued in the agent when the load is stopped. Then it stops the
+// agent gracefully or, if kill is true, kills it and returns the number of data items
+// lost to the shutdown, i.e. sent but never received. A graceful stop must drain the
+// exporter queue and lose nothing.
+func ScenarioShutdownDrain(
+	t *testing.T,
+	sender testbed.DataSender,
+	receiver testbed.DataReceiver,
+	resultsSummary testbed.TestResultsSummary,
+	kill bool,
+) uint64 {
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		resultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.MockBackend.SetConsumeDelayDistribution(shutdownBackendDelay, 0)
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	tc.Sleep(time.Second)
+
+	// The sender is synchronous, so once the load is stopped all sent data was accepted
+	// by the agent and whatever was not received yet is in flight in the agent.
+	tc.StopLoad()
+	sent := tc.LoadGenerator.DataItemsSent()
+	inFlight := sent - tc.MockBackend.DataItemsReceived()
+	require.NotZero(t, inFlight, "no data in flight at shutdown")
+
+	if kill {
+		agentProc.Kill()
+		// Requests which reached the backend before the kill are still consumed.
+		time.Sleep(2 * shutdownBackendDelay)
+	} else {
+		tc.StopAgent()
+	}
+
+	lost := sent - tc.MockBackend.DataItemsReceived()
+	log.Printf("%d items in flight at shutdown, %d lost to shutdown", inFlight, lost)
+	if !kill {
+		assert.Zero(t, lost, "graceful shutdown lost in-flight items")
+		tc.ValidateData()
+	}
+	return lost
+}
+
 func constructLoadOptions(test TestCase) testbed.LoadOptions {
 	options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
 	options.Attributes = make(map[string]string)
@@ -22,22 +22,31 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
 	"path"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.opentelemetry.io/collector/config/configauth"
 	"go.opentelemetry.io/collector/config/configmodels"
 	"go.opentelemetry.io/collector/consumer/pdata"
+	"go.opentelemetry.io/collector/exporter/otlphttpexporter"
 	"go.opentelemetry.io/collector/testbed/testbed"
 	"go.opentelemetry.io/collector/translator/conventions"
 )
 
 // TestMain is used to initiate setup, execution and tear down of testbed.
 func TestMain(m *testing.M) {
-	testbed.DoTestMain(m, performanceResultsSummary)
+	testbed.DoTestMainWithControlServer(m, performanceResultsSummary, RunScenarioFromParams)
 }
 
 func TestTrace10kSPS(t *testing.T) {
@@ -101,6 +110,15 @@ func TestTrace10kSPS(t *testing.T) {
 				ExpectedMaxRAM: 100,
 			},
 		},
+		{
+			"OTLP-HTTP-JSON",
+			testbed.NewOTLPHTTPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)).WithHTTPEncoding(otlphttpexporter.EncodingJSON),
+			testbed.NewOTLPHTTPDataReceiver(testbed.GetAvailablePort(t)).WithHTTPEncoding(otlphttpexporter.EncodingJSON),
+			testbed.ResourceSpec{
+				ExpectedMaxCPU: 70,
+				ExpectedMaxRAM: 100,
+			},
+		},
 		{
 			"Zipkin",
 			testbed.NewZipkinDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
@@ -110,6 +128,24 @@ func TestTrace10kSPS(t *testing.T) {
 				ExpectedMaxRAM: 80,
 			},
 		},
+		{
+			"ZipkinV1-Thrift",
+			testbed.NewZipkinV1DataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+			testbed.NewZipkinDataReceiver(testbed.GetAvailablePort(t)),
+			testbed.ResourceSpec{
+				ExpectedMaxCPU: 80,
+				ExpectedMaxRAM: 80,
+			},
+		},
+		{
+			"ZipkinV1-JSON",
+			testbed.NewZipkinV1DataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)).WithEncoding(testbed.ZipkinV1JSON),
+			testbed.NewZipkinDataReceiver(testbed.GetAvailablePort(t)),
+			testbed.ResourceSpec{
+				ExpectedMaxCPU: 80,
+				ExpectedMaxRAM: 80,
+			},
+		},
 	}
 
 	processors := map[string]string{
@@ -133,6 +169,960 @@ func TestTrace10kSPS(t *testing.T) {
 	}
 }
 
+// TestTraceSendingQueueConsumers runs the same load with a different number of
+// consumers of the exporter sending queue. The throughput for each consumer count
+// is reported in the results summary.
+func TestTraceSendingQueueConsumers(t *testing.T) {
+	tests := []struct {
+		name         string
+		numConsumers int
+	}{
+		{"1Consumer", 1},
+		{"10Consumers", 10},
+	}
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Scenario10kItemsPerSecond(
+				t,
+				testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+				testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)).WithNumConsumers(test.numConsumers),
+				testbed.ResourceSpec{
+					ExpectedMaxCPU: 30,
+					ExpectedMaxRAM: 80,
+				},
+				performanceResultsSummary,
+				processors,
+				nil,
+			)
+		})
+	}
+}
+
+func TestTraceIPv6(t *testing.T) {
+	host := testbed.IPv6.LoopbackHost()
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Skip("IPv6 loopback is not available:", err)
+	}
+	ln.Close()
+
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePortForFamily(t, testbed.IPv6))
+	receiver.Host = host
+
+	Scenario10kItemsPerSecond(
+		t,
+		testbed.NewOTLPTraceDataSender(host, testbed.GetAvailablePortForFamily(t, testbed.IPv6)),
+		receiver,
+		testbed.ResourceSpec{
+			ExpectedMaxCPU: 20,
+			ExpectedMaxRAM: 70,
+		},
+		performanceResultsSummary,
+		nil,
+		nil,
+	)
+}
+
+// TestTraceFromParams runs a load configured by the scenario parameters, e.g.
+// TESTBED_SENDER=jaeger TESTBED_ITEMS_PER_SECOND=20000 RUN_TESTBED=1 go test -run TestTraceFromParams
+func TestTraceFromParams(t *testing.T) {
+	// Limits are generous, they only enable resource consumption monitoring.
+	ScenarioFromParams(
+		t,
+		testbed.ResourceSpec{
+			ExpectedMaxCPU: 400,
+			ExpectedMaxRAM: 1000,
+		},
+		performanceResultsSummary,
+	)
+}
+
+func TestTraceSendImmediatelyAfterAgentStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		sender   testbed.DataSender
+		receiver testbed.DataReceiver
+	}{
+		{
+			"OTLP-gRPC",
+			testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+			testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		},
+		{
+			"OTLP-HTTP",
+			testbed.NewOTLPHTTPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+			testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+			require.NoError(t, err)
+
+			agentProc := &testbed.ChildProcess{}
+			configStr := createConfigYaml(t, test.sender, test.receiver, resultDir, nil, nil)
+			configCleanup, err := agentProc.PrepareConfig(configStr)
+			require.NoError(t, err)
+			defer configCleanup()
+
+			options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
+			tc := testbed.NewTestCase(
+				t,
+				testbed.NewPerfTestDataProvider(options),
+				test.sender,
+				test.receiver,
+				agentProc,
+				&testbed.PerfTestValidator{},
+				performanceResultsSummary,
+				testbed.WithAgentStartTimeout(30*time.Second),
+			)
+			defer tc.Stop()
+
+			tc.StartBackend()
+			tc.StartAgent()
+			// No sleep, the agent must accept data as soon as StartAgent returns.
+			tc.StartLoad(options)
+
+			tc.Sleep(time.Second)
+
+			tc.StopLoad()
+
+			tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+				"all data items received")
+			assert.Zero(t, tc.LoadGenerator.SendErrors())
+			tc.ValidateData()
+		})
+	}
+}
+
+func TestTraceColdStart(t *testing.T) {
+	timeToFirstItem := ScenarioColdStart(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		performanceResultsSummary,
+	)
+	assert.Greater(t, int64(timeToFirstItem), int64(0))
+	assert.Less(t, int64(timeToFirstItem), int64(10*time.Second))
+}
+
+func TestTraceBatchTimeoutLatency(t *testing.T) {
+	const batchTimeout = 500 * time.Millisecond
+	maxLatency := ScenarioBatchTimeoutLatency(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		performanceResultsSummary,
+		batchTimeout,
+	)
+	assert.InDelta(t, int64(batchTimeout), int64(maxLatency), float64(batchTimeoutLatencyTolerance))
+}
+
+func TestTraceShutdownDrain(t *testing.T) {
+	tests := []struct {
+		name string
+		kill bool
+	}{
+		{name: "GracefulStop"},
+		{name: "Kill", kill: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lost := ScenarioShutdownDrain(
+				t,
+				testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+				testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+				performanceResultsSummary,
+				test.kill,
+			)
+			if test.kill {
+				assert.NotZero(t, lost, "kill did not lose in-flight items")
+			} else {
+				assert.Zero(t, lost)
+			}
+		})
+	}
+}
+
+func TestTraceThrottledBackend(t *testing.T) {
+	const throttleDelay = 200 * time.Millisecond
+	const minRetries = 6
+
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)).WithRetry(100*time.Millisecond, 2*time.Second)
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.MockBackend.SetErrorMode(testbed.ErrorThrottle, throttleDelay)
+	tc.StartAgent()
+
+	require.NoError(t, sender.Start())
+
+	// Send one span so that all throttled requests are retries of the same request.
+	td := pdata.NewTraces()
+	td.ResourceSpans().Resize(1)
+	td.ResourceSpans().At(0).InstrumentationLibrarySpans().Resize(1)
+	spans := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans()
+	spans.Resize(1)
+	spans.At(0).SetTraceID(testbed.GenerateSequentialTraceID(1))
+	spans.At(0).SetSpanID(testbed.GenerateSequentialSpanID(1))
+	spans.At(0).SetName("throttled-span")
+	require.NoError(t, sender.ConsumeTraces(context.Background(), td))
+	tc.LoadGenerator.IncDataItemsSent()
+
+	tc.WaitFor(func() bool { return len(tc.MockBackend.ThrottleRetryIntervals()) >= minRetries },
+		"exporter retried throttled request")
+	tc.MockBackend.SetErrorMode(testbed.ErrorNone, 0)
+	tc.WaitFor(func() bool { return tc.MockBackend.DataItemsReceived() == 1 }, "span received")
+
+	intervals := tc.MockBackend.ThrottleRetryIntervals()
+	require.GreaterOrEqual(t, len(intervals), minRetries)
+	for _, interval := range intervals {
+		// The exporter must wait at least for the delay requested by the backend.
+		assert.GreaterOrEqual(t, int64(interval), int64(throttleDelay*9/10), "retry intervals: %v", intervals)
+	}
+	// Once the exponential backoff exceeds the requested delay the intervals grow.
+	assert.Greater(t, int64(intervals[minRetries-1]), int64(intervals[0]), "retry intervals: %v", intervals)
+
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTracePanickingBackend(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)).WithRetry(100*time.Millisecond, time.Second)
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.MockBackend.SetPanicRate(0.05)
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(2 * time.Second)
+	tc.StopLoad()
+
+	// The exporter retries the data rejected by the panicking calls, so everything is
+	// received while the backend keeps panicking.
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	assert.NotZero(t, tc.MockBackend.RecoveredPanics())
+
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceExporterQueueGrows(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{MetricsPort: testbed.GetAvailablePort(t)}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	// 100 batches/sec are sent while the 10 queue consumers of the exporter can export
+	// only about 50 batches/sec to the slow backend, so the queue fills up.
+	options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{
+		ExpectedMaxCPU:      200,
+		ExpectedMaxRAM:      500,
+		ResourceCheckPeriod: 200 * time.Millisecond,
+	})
+	tc.StartBackend()
+	tc.MockBackend.SetConsumeDelayDistribution(200*time.Millisecond, 0)
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(2 * time.Second)
+	tc.StopLoad()
+
+	queueSizes := agentProc.ExporterQueueSizes()
+	require.GreaterOrEqual(t, len(queueSizes), 5, "queue sizes: %v", queueSizes)
+	last := queueSizes[len(queueSizes)-1]
+	assert.Greater(t, last.Size, queueSizes[0].Size, "queue sizes: %v", queueSizes)
+	assert.Greater(t, last.Size, int64(20), "queue sizes: %v", queueSizes)
+
+	tc.MockBackend.SetConsumeDelayDistribution(0, 0)
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceTimeToDrain(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{MetricsPort: testbed.GetAvailablePort(t)}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	// 100 batches/sec are sent while the 10 queue consumers of the exporter can export
+	// only about 50 batches/sec to the slow backend, so the queue fills up and takes
+	// about queue size / 50 seconds to drain after the load stops.
+	const exportedBatchesPerSecond = 50
+	options := testbed.LoadOptions{DataItemsPerSecond: 1000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{
+		ExpectedMaxCPU:      200,
+		ExpectedMaxRAM:      500,
+		ResourceCheckPeriod: 100 * time.Millisecond,
+	})
+	tc.StartBackend()
+	tc.MockBackend.SetConsumeDelayDistribution(200*time.Millisecond, 0)
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(2 * time.Second)
+	tc.StopLoad()
+
+	queueSizes := agentProc.ExporterQueueSizes()
+	require.NotEmpty(t, queueSizes)
+	queueSize := queueSizes[len(queueSizes)-1].Size
+	require.Greater(t, queueSize, int64(20), "queue sizes: %v", queueSizes)
+
+	require.True(t, tc.WaitForDrain(time.Second, 30*time.Second))
+	assert.False(t, tc.DrainTimedOut())
+	expected := time.Duration(queueSize) * time.Second / exportedBatchesPerSecond
+	assert.Greater(t, int64(tc.TimeToDrain()), int64(expected/2), "queue size %d", queueSize)
+	assert.Less(t, int64(tc.TimeToDrain()), int64(expected*2), "queue size %d", queueSize)
+	assert.EqualValues(t, tc.LoadGenerator.DataItemsSent(), tc.MockBackend.DataItemsReceived())
+
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceAllocationRate(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{MetricsPort: testbed.GetAvailablePort(t)}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 100}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{
+		ExpectedMaxCPU:      200,
+		ExpectedMaxRAM:      500,
+		ResourceCheckPeriod: time.Second,
+	})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	// The agent refreshes its runtime metrics every 5 seconds, at least two refreshes
+	// are needed to derive a rate.
+	tc.WaitForN(func() bool {
+		_, ok := agentProc.GCStats()
+		return ok
+	}, 30*time.Second, "GC stats scraped twice")
+	tc.StopLoad()
+
+	stats, ok := agentProc.GCStats()
+	require.True(t, ok)
+	assert.Greater(t, stats.AllocBytesPerSec, float64(0), "%+v", stats)
+	assert.Greater(t, stats.NumGC, uint64(0), "%+v", stats)
+	assert.Greater(t, int64(stats.GCPause), int64(0), "%+v", stats)
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceThroughProxy(t *testing.T) {
+	tests := []struct {
+		name string
+		// setProxy routes the sender or the receiver through a proxy and returns the
+		// address the proxy forwards to.
+		setProxy func(sender *testbed.OTLPTraceDataSender, receiver *testbed.BaseOTLPDataReceiver, proxyEndpoint string) string
+	}{
+		{
+			name: "Sender",
+			setProxy: func(sender *testbed.OTLPTraceDataSender, _ *testbed.BaseOTLPDataReceiver, proxyEndpoint string) string {
+				sender.ProxyEndpoint = proxyEndpoint
+				return sender.GetEndpoint()
+			},
+		},
+		{
+			name: "Exporter",
+			setProxy: func(_ *testbed.OTLPTraceDataSender, receiver *testbed.BaseOTLPDataReceiver, proxyEndpoint string) string {
+				receiver.ProxyEndpoint = proxyEndpoint
+				return receiver.GetEndpoint()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+			receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+			proxyEndpoint := net.JoinHostPort(testbed.DefaultHost, strconv.Itoa(testbed.GetAvailablePort(t)))
+			proxy := testbed.NewTCPProxy(proxyEndpoint, test.setProxy(sender, receiver, proxyEndpoint))
+			require.NoError(t, proxy.Start())
+			defer proxy.Stop()
+
+			Scenario10kItemsPerSecond(
+				t,
+				sender,
+				receiver,
+				testbed.ResourceSpec{
+					ExpectedMaxCPU: 20,
+					ExpectedMaxRAM: 70,
+				},
+				performanceResultsSummary,
+				nil,
+				nil,
+			)
+
+			assert.NotZero(t, proxy.Connections())
+			assert.NotZero(t, proxy.BytesForwarded())
+		})
+	}
+}
+
+func TestTraceProbabilisticSampling(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"probabilistic_sampler": `
+  probabilistic_sampler:
+    sampling_percentage: 50
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10000, ItemsPerBatch: 10}
+	validator := testbed.NewSamplingValidator(0.5)
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewRecordingDataProvider(testbed.NewPerfTestDataProvider(options), validator),
+		sender,
+		receiver,
+		agentProc,
+		validator,
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.EnableRecording()
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	tc.Sleep(tc.Duration)
+
+	tc.StopLoad()
+
+	// The number of received items is not known in advance, give the agent time to
+	// export the remaining data.
+	tc.Sleep(time.Second)
+
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+// TestTraceHeldTracesCompleteness verifies that every trace passing a processor which
+// holds whole traces, like tail-based sampling does, is received with all of its spans.
+// The tail sampling processors are not part of this repository, so the batch processor
+// holds the traces with a timeout much longer than the interval between them. The load
+// stops in the middle of the last trace, whose sent spans must be received as well.
+func TestTraceHeldTracesCompleteness(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+    timeout: 1s
+    send_batch_size: 100000
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	const totalItems = 5005
+	options := testbed.LoadOptions{DataItemsPerSecond: 5000, ItemsPerBatch: 10}
+	validator := testbed.NewTraceCompletenessValidator()
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewRecordingDataProvider(testbed.NewPerfTestDataProvider(options), validator),
+		sender,
+		receiver,
+		agentProc,
+		validator,
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.EnableRecording()
+	tc.StartAgent()
+	tc.StartLoadCount(options, totalItems)
+
+	tc.WaitFor(tc.LoadGenerator.CountReached, "all data items sent")
+	tc.WaitFor(func() bool { return tc.MockBackend.DataItemsReceived() == totalItems }, "all data items received")
+	tc.StopLoad()
+
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceCorruptedPayloads(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewCorruptingDataReceiver(
+		testbed.GetAvailablePort(t),
+		testbed.NewOTLPHTTPDataReceiver(testbed.GetAvailablePort(t)),
+		0.2,
+	)
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{ExpectedMaxCPU: 50, ExpectedMaxRAM: 100})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	tc.Sleep(tc.Duration)
+
+	tc.StopLoad()
+	// Rejected payloads are dropped by the agent, give it time to export the rest.
+	tc.Sleep(time.Second)
+
+	// The agent must keep running and keep delivering the payloads which were not rejected.
+	running, err := agentProc.GetProcessMon().IsRunning()
+	require.NoError(t, err)
+	assert.True(t, running)
+	assert.NotZero(t, receiver.CorruptedPayloads())
+	assert.NotZero(t, receiver.RejectedPayloads(), "corrupted payloads must be reported as decode errors")
+	assert.NotZero(t, tc.MockBackend.DataItemsReceived())
+	assert.Less(t, tc.MockBackend.DataItemsReceived(), tc.LoadGenerator.DataItemsSent())
+	assert.LessOrEqual(t, receiver.RejectedPayloads(), receiver.CorruptedPayloads())
+}
+
+func TestTraceClientBatching(t *testing.T) {
+	tests := []struct {
+		name                  string
+		disableClientBatching bool
+	}{
+		{name: "Batched"},
+		{name: "Unbatched", disableClientBatching: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+			receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+			resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+			require.NoError(t, err)
+
+			agentProc := &testbed.ChildProcess{}
+			configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+			configCleanup, err := agentProc.PrepareConfig(configStr)
+			require.NoError(t, err)
+			defer configCleanup()
+
+			options := testbed.LoadOptions{
+				DataItemsPerSecond:    1000,
+				ItemsPerBatch:         10,
+				DisableClientBatching: test.disableClientBatching,
+			}
+			tc := testbed.NewTestCase(
+				t,
+				testbed.NewPerfTestDataProvider(options),
+				sender,
+				receiver,
+				agentProc,
+				&testbed.PerfTestValidator{},
+				performanceResultsSummary,
+			)
+			defer tc.Stop()
+
+			tc.SetResourceLimits(testbed.ResourceSpec{ExpectedMaxCPU: 60, ExpectedMaxRAM: 100})
+			tc.StartBackend()
+			tc.StartAgent()
+			tc.StartLoad(options)
+
+			tc.Sleep(tc.Duration)
+
+			tc.StopLoad()
+
+			tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+				"all data items received")
+
+			tc.ValidateData()
+		})
+	}
+}
+
+func TestTraceFixedItemCount(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10000, ItemsPerBatch: 30}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{ExpectedMaxCPU: 60, ExpectedMaxRAM: 100})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoadCount(options, 10000)
+
+	tc.WaitFor(tc.LoadGenerator.CountReached, "all data items sent")
+	tc.WaitFor(func() bool { return tc.MockBackend.DataItemsReceived() == 10000 }, "all data items received")
+	tc.StopLoad()
+
+	assert.EqualValues(t, 10000, tc.LoadGenerator.DataItemsSent())
+	assert.EqualValues(t, 10000, tc.MockBackend.DataItemsReceived())
+	tc.ValidateData()
+}
+
+func TestTraceCompareOTLPSenders(t *testing.T) {
+	results := CompareSenders(
+		t,
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		[]testbed.DataSender{
+			testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+			testbed.NewOTLPHTTPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		},
+	)
+
+	require.Len(t, results, 2)
+	senders := []string{results[0].Sender, results[1].Sender}
+	assert.ElementsMatch(t, []string{"0_OTLPTraceDataSender", "1_OTLPHTTPTraceDataSender"}, senders)
+	for i, result := range results {
+		assert.Equal(t, i+1, result.Rank)
+		assert.NotZero(t, result.DataItemsReceived)
+		assert.NotZero(t, result.ItemsPerSecond)
+	}
+	assert.GreaterOrEqual(t, results[0].ItemsPerSecond, results[1].ItemsPerSecond)
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "comparison.json"))
+	require.NoError(t, err)
+	var written []SenderComparisonResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, results, written)
+}
+
+func TestTraceSweepCompression(t *testing.T) {
+	codecs := []string{CompressionNone, "gzip"}
+	results := SweepCompression(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		codecs,
+	)
+
+	require.Len(t, results, 2)
+	for i, codec := range codecs {
+		assert.Equal(t, codec, results[i].Codec)
+		assert.NotZero(t, results[i].DataItemsReceived)
+		assert.NotZero(t, results[i].ItemsPerSecond)
+		assert.NotZero(t, results[i].NetworkBytes)
+		assert.NotZero(t, results[i].BytesPerItem)
+	}
+	// The generated spans compress well.
+	assert.Less(t, results[1].BytesPerItem, results[0].BytesPerItem)
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "compression_sweep.json"))
+	require.NoError(t, err)
+	var written []CompressionSweepResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, results, written)
+}
+
+func TestTraceSweepRates(t *testing.T) {
+	results := SweepRates(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		[]int{1000, 5000},
+	)
+
+	require.Len(t, results, 2)
+	for i, rate := range []int{1000, 5000} {
+		assert.Equal(t, rate, results[i].TargetItemsPerSecond)
+		assert.NotZero(t, results[i].DataItemsReceived)
+		assert.NotZero(t, results[i].ItemsPerSecond)
+	}
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "sweep.json"))
+	require.NoError(t, err)
+	var written []RateSweepResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, results, written)
+}
+
+func TestTraceCompareProcessorCost(t *testing.T) {
+	// Copy one attribute into a new one and hash another, a transform touching every span.
+	attributes := ProcessorNameAndConfigBody{
+		Name: "attributes",
+		Body: `
+  attributes:
+    actions:
+      - action: insert
+        key: "copied_seq_num"
+        from_attribute: "load_generator.span_seq_num"
+      - action: hash
+        key: "load_generator.trace_seq_num"
+`,
+	}
+	result := CompareProcessorCost(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		attributes,
+		func(t testbed.TestingT, backend *testbed.MockBackend) {
+			var spans int
+			for _, td := range backend.ReceivedTraces {
+				rss := td.ResourceSpans()
+				for i := 0; i < rss.Len(); i++ {
+					ilss := rss.At(i).InstrumentationLibrarySpans()
+					for j := 0; j < ilss.Len(); j++ {
+						for k := 0; k < ilss.At(j).Spans().Len(); k++ {
+							attrs := ilss.At(j).Spans().At(k).Attributes()
+							seqNum, _ := attrs.Get(testbed.SpanSeqNumAttribute)
+							copied, ok := attrs.Get("copied_seq_num")
+							require.True(t, ok, "span was not transformed")
+							require.Equal(t, seqNum.IntVal(), copied.IntVal())
+							hashed, _ := attrs.Get("load_generator.trace_seq_num")
+							require.Equal(t, pdata.AttributeValueSTRING, hashed.Type())
+							spans++
+						}
+					}
+				}
+			}
+			require.NotZero(t, spans)
+		},
+	)
+
+	assert.Equal(t, "baseline", result.Baseline.Name)
+	assert.Equal(t, "attributes", result.Processor.Name)
+	for _, run := range []ProcessorCostRun{result.Baseline, result.Processor} {
+		assert.NotZero(t, run.DataItemsReceived)
+		assert.NotZero(t, run.ItemsPerSecond)
+	}
+	assert.Equal(t, result.Processor.CPUSecondsPerMillionItems-result.Baseline.CPUSecondsPerMillionItems,
+		result.OverheadCPUSecondsPerMillionItems)
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "processor_cost.json"))
+	require.NoError(t, err)
+	var written ProcessorCostResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, result, written)
+}
+
+func TestTraceCompareProcessorOrders(t *testing.T) {
+	attributes := ProcessorNameAndConfigBody{
+		Name: "attributes",
+		Body: `
+  attributes:
+    actions:
+      - action: insert
+        key: "new_attr"
+        value: "string value"
+`,
+	}
+	batch := ProcessorNameAndConfigBody{
+		Name: "batch",
+		Body: `
+  batch:
+`,
+	}
+	results := CompareProcessorOrders(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		[][]ProcessorNameAndConfigBody{
+			{attributes, batch},
+			{batch, attributes},
+		},
+	)
+
+	require.Len(t, results, 2)
+	for i, order := range []string{"attributes,batch", "batch,attributes"} {
+		assert.Equal(t, order, results[i].Order)
+		assert.NotZero(t, results[i].DataItemsReceived)
+		assert.NotZero(t, results[i].ItemsPerSecond)
+	}
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "processor_orders.json"))
+	require.NoError(t, err)
+	var written []ProcessorOrderResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, results, written)
+
+	// The processors are placed in the pipeline in the requested order.
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+	config := createOrderedConfigYaml(t, sender, receiver, "results", []ProcessorNameAndConfigBody{batch, attributes}, nil)
+	assert.Contains(t, config, "processors: [batch,attributes]")
+}
+
+func TestTraceProgrammaticScenario(t *testing.T) {
+	results, err := RunScenario10kItemsPerSecond(
+		context.Background(),
+		t.Name(),
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		testbed.ResourceSpec{
+			ExpectedMaxCPU: 20,
+			ExpectedMaxRAM: 70,
+		},
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+	require.NotNil(t, results)
+	assert.NotZero(t, results.DataItemsSent)
+	assert.Equal(t, results.DataItemsSent, results.DataItemsReceived)
+	assert.NotZero(t, results.Duration)
+	assert.NotZero(t, results.RAMMiBMax)
+}
+
 func TestTraceNoBackend10kSPS(t *testing.T) {
 
 	limitProcessors := map[string]string{
@@ -395,85 +1385,547 @@ func TestTraceAttributesProcessor(t *testing.T) {
 			resultDir, err := filepath.Abs(path.Join("results", t.Name()))
 			require.NoError(t, err)
 
-			// Use processor to add attributes to certain spans.
-			processors := map[string]string{
-				"batch": `
-  batch:
-`,
-				"attributes": `
-  attributes:
-    include:
-      match_type: regexp
-      services: ["service-to-add.*"]
-      span_names: ["span-to-add-.*"]
-    actions:
-      - action: insert
-        key: "new_attr"
-        value: "string value"
-`,
-			}
-
+			// Use processor to add attributes to certain spans.
+			processors := map[string]string{
+				"batch": `
+  batch:
+`,
+				"attributes": `
+  attributes:
+    include:
+      match_type: regexp
+      services: ["service-to-add.*"]
+      span_names: ["span-to-add-.*"]
+    actions:
+      - action: insert
+        key: "new_attr"
+        value: "string value"
+`,
+			}
+
+			agentProc := &testbed.ChildProcess{}
+			configStr := createConfigYaml(t, test.sender, test.receiver, resultDir, processors, nil)
+			configCleanup, err := agentProc.PrepareConfig(configStr)
+			require.NoError(t, err)
+			defer configCleanup()
+
+			options := testbed.LoadOptions{DataItemsPerSecond: 10000, ItemsPerBatch: 10}
+			dataProvider := testbed.NewPerfTestDataProvider(options)
+			tc := testbed.NewTestCase(
+				t,
+				dataProvider,
+				test.sender,
+				test.receiver,
+				agentProc,
+				&testbed.PerfTestValidator{},
+				performanceResultsSummary,
+			)
+			defer tc.Stop()
+
+			tc.StartBackend()
+			tc.StartAgent()
+			defer tc.StopAgent()
+
+			tc.EnableRecording()
+
+			require.NoError(t, test.sender.Start())
+
+			// Create a span that matches "include" filter.
+			spanToInclude := "span-to-add-attr"
+			// Create a service name that matches "include" filter.
+			nodeToInclude := "service-to-add-attr"
+
+			// verifySpan verifies that attributes was added to the internal data span.
+			verifySpan := func(span pdata.Span) {
+				require.NotNil(t, span)
+				require.Equal(t, span.Attributes().Len(), 1)
+				attrVal, ok := span.Attributes().Get("new_attr")
+				assert.True(t, ok)
+				assert.EqualValues(t, "string value", attrVal.StringVal())
+			}
+
+			verifySingleSpan(t, tc, nodeToInclude, spanToInclude, verifySpan)
+
+			// Create a service name that does not match "include" filter.
+			nodeToExclude := "service-not-to-add-attr"
+
+			verifySingleSpan(t, tc, nodeToExclude, spanToInclude, func(span pdata.Span) {
+				// Verify attributes was not added to the new internal data span.
+				assert.Equal(t, span.Attributes().Len(), 0)
+			})
+
+			// Create another span that does not match "include" filter.
+			spanToExclude := "span-not-to-add-attr"
+			verifySingleSpan(t, tc, nodeToInclude, spanToExclude, func(span pdata.Span) {
+				// Verify attributes was not added to the new internal data span.
+				assert.Equal(t, span.Attributes().Len(), 0)
+			})
+		})
+	}
+}
+
+func TestTraceRemoteConfig(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{ServeConfigOverHTTP: true}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+
+	// The agent fetched its config before it could receive anything.
+	fetchLatency := agentProc.ConfigFetchLatency()
+	assert.NotZero(t, fetchLatency)
+	assert.Less(t, int64(fetchLatency), int64(tc.TimeToFirstItem()))
+	log.Printf("Config fetched %s after the agent start", fetchLatency)
+}
+
+func TestTraceSpanLinksPreserved(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10, SpanLinksPerSpan: 3}
+	validator := testbed.NewSpanLinkValidator()
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewRecordingDataProvider(testbed.NewPerfTestDataProvider(options), validator),
+		sender,
+		receiver,
+		agentProc,
+		validator,
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceClockSkewedSpans(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"batch": `
+  batch:
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10, ClockSkewedSpanRate: 0.1}
+	validator := testbed.NewClockSkewValidator()
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewRecordingDataProvider(testbed.NewPerfTestDataProvider(options), validator),
+		sender,
+		receiver,
+		agentProc,
+		validator,
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	assert.Zero(t, tc.LoadGenerator.SendErrors())
+	tc.StopAgent()
+	tc.ValidateData()
+	assert.InDelta(t, 0.1*float64(tc.MockBackend.DataItemsReceived()), validator.FlaggedSpans(), 1)
+}
+
+func TestTraceAttributeLimit(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	// Every span has the 2 sequence number attributes and 8 string attributes. The
+	// processor limits them to 6 attributes by dropping the last 4 string attributes.
+	const limit = 6
+	kept := []string{testbed.SpanSeqNumAttribute, "load_generator.trace_seq_num"}
+	var dropped []string
+	actions := ""
+	for i := 0; i < 8; i++ {
+		key := fmt.Sprintf("load_generator.string_%d", i)
+		if i < 4 {
+			kept = append(kept, key)
+			continue
+		}
+		dropped = append(dropped, key)
+		actions += fmt.Sprintf("      - action: delete\n        key: %q\n", key)
+	}
+	processors := map[string]string{
+		"attributes": `
+  attributes:
+    actions:
+` + actions,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:  10_000,
+		ItemsPerBatch:       100,
+		AttributeValueTypes: map[pdata.AttributeValueType]int{pdata.AttributeValueSTRING: 8},
+	}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		testbed.NewAttributeLimitValidator(limit, kept, dropped),
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.EnableRecording()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceResourceDistribution(t *testing.T) {
+	options := testbed.LoadOptions{
+		DataItemsPerSecond:      10_000,
+		ItemsPerBatch:           10,
+		ResourceAttributeValues: map[string][]string{"tenant": {"a", "b", "a", "c"}},
+	}
+
+	tests := []struct {
+		name       string
+		processors map[string]string
+		expected   map[string]float64
+	}{
+		{
+			// The batch processor merges the batches of different resources into one
+			// request, which must not change the number of spans per resource.
+			name: "Merged",
+			processors: map[string]string{
+				"batch": `
+  batch:
+    send_batch_size: 1000
+`,
+			},
+			expected: testbed.ExpectedResourceDistribution(options, "tenant"),
+		},
+		{
+			// The resource processor regroups all spans under a single tenant.
+			name: "Regrouped",
+			processors: map[string]string{
+				"resource": `
+  resource:
+    attributes:
+      - key: tenant
+        value: shared
+        action: upsert
+`,
+			},
+			expected: map[string]float64{"shared": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+			receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+			resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+			require.NoError(t, err)
+
 			agentProc := &testbed.ChildProcess{}
-			configStr := createConfigYaml(t, test.sender, test.receiver, resultDir, processors, nil)
+			configStr := createConfigYaml(t, sender, receiver, resultDir, test.processors, nil)
 			configCleanup, err := agentProc.PrepareConfig(configStr)
 			require.NoError(t, err)
 			defer configCleanup()
 
-			options := testbed.LoadOptions{DataItemsPerSecond: 10000, ItemsPerBatch: 10}
-			dataProvider := testbed.NewPerfTestDataProvider(options)
 			tc := testbed.NewTestCase(
 				t,
-				dataProvider,
-				test.sender,
-				test.receiver,
+				testbed.NewPerfTestDataProvider(options),
+				sender,
+				receiver,
 				agentProc,
-				&testbed.PerfTestValidator{},
+				testbed.NewResourceDistributionValidator("tenant", test.expected, 0.01),
 				performanceResultsSummary,
 			)
 			defer tc.Stop()
 
+			tc.EnableRecording()
 			tc.StartBackend()
 			tc.StartAgent()
-			defer tc.StopAgent()
+			tc.StartLoad(options)
+			tc.Sleep(tc.Duration)
+			tc.StopLoad()
 
-			tc.EnableRecording()
+			tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+				"all data items received")
+			tc.StopAgent()
+			tc.ValidateData()
+		})
+	}
+}
 
-			require.NoError(t, test.sender.Start())
+func TestTraceSlowReadingBackend(t *testing.T) {
+	const bytesPerSecond = 100_000
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewSlowReadingDataReceiver(
+		testbed.GetAvailablePort(t),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		bytesPerSecond,
+	)
 
-			// Create a span that matches "include" filter.
-			spanToInclude := "span-to-add-attr"
-			// Create a service name that matches "include" filter.
-			nodeToInclude := "service-to-add-attr"
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
 
-			// verifySpan verifies that attributes was added to the internal data span.
-			verifySpan := func(span pdata.Span) {
-				require.NotNil(t, span)
-				require.Equal(t, span.Attributes().Len(), 1)
-				attrVal, ok := span.Attributes().Get("new_attr")
-				assert.True(t, ok)
-				assert.EqualValues(t, "string value", attrVal.StringVal())
-			}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
 
-			verifySingleSpan(t, tc, nodeToInclude, spanToInclude, verifySpan)
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
 
-			// Create a service name that does not match "include" filter.
-			nodeToExclude := "service-not-to-add-attr"
+	start := time.Now()
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
 
-			verifySingleSpan(t, tc, nodeToExclude, spanToInclude, func(span pdata.Span) {
-				// Verify attributes was not added to the new internal data span.
-				assert.Equal(t, span.Attributes().Len(), 0)
-			})
+	// The exporter must buffer while the backend reads slowly and deliver everything once
+	// the backend catches up.
+	tc.WaitForN(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		tc.Duration, "all data items received")
+	log.Printf("Backend read %d bytes at most %d bytes/sec", receiver.BytesRead(), bytesPerSecond)
+	assert.LessOrEqual(t, float64(receiver.BytesRead()), float64(bytesPerSecond)*time.Since(start).Seconds()*1.1)
+	tc.ValidateData()
+}
 
-			// Create another span that does not match "include" filter.
-			spanToExclude := "span-not-to-add-attr"
-			verifySingleSpan(t, tc, nodeToInclude, spanToExclude, func(span pdata.Span) {
-				// Verify attributes was not added to the new internal data span.
-				assert.Equal(t, span.Attributes().Len(), 0)
-			})
+func TestTraceReceiverAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       configauth.Authentication
+		authHeader string
+	}{
+		{
+			name:       "BearerToken",
+			auth:       configauth.Authentication{BearerToken: "testbed-token"},
+			authHeader: configauth.BearerTokenHeader("testbed-token"),
+		},
+		{
+			name:       "Basic",
+			auth:       configauth.Authentication{Basic: &configauth.BasicAuth{Username: "testbed", Password: "secret"}},
+			authHeader: configauth.BasicAuthHeader("testbed", "secret"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)).
+				WithAuth(test.auth, test.authHeader)
+			Scenario10kItemsPerSecond(
+				t,
+				sender,
+				testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+				testbed.ResourceSpec{ExpectedMaxCPU: 30, ExpectedMaxRAM: 100},
+				performanceResultsSummary,
+				nil,
+				nil,
+			)
 		})
 	}
 }
 
+func TestTraceReceiverAuthRejected(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)).
+		WithAuth(configauth.Authentication{BearerToken: "testbed-token"}, configauth.BearerTokenHeader("wrong-token"))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	// Every request carried the wrong token, so the agent must reject all of them.
+	assert.NotZero(t, tc.LoadGenerator.DataItemsSent())
+	assert.NotZero(t, tc.LoadGenerator.SendErrors())
+	assert.Zero(t, tc.MockBackend.DataItemsReceived())
+}
+
+func TestTraceComponentCPUBreakdown(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	processors := map[string]string{
+		"attributes": `
+  attributes:
+    actions:
+      - action: insert
+        key: "new_attr"
+        value: "string value"
+      - action: hash
+        key: "load_generator.span_seq_num"
+`,
+		"span": `
+  span:
+    name:
+      to_attributes:
+        rules:
+          - ^(?P<span_source>[a-z]+)-generator-span$
+`,
+	}
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, processors, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 20_000, ItemsPerBatch: 100}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+	// The profile is sampled at 100 Hz, short runs have too few samples for both processors.
+	duration := tc.Duration
+	if duration < 10*time.Second {
+		duration = 10 * time.Second
+	}
+	tc.Sleep(duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+
+	// The CPU profile is written when the agent shuts down.
+	tc.StopAgent()
+	shares, err := testbed.FindComponentCPUShares(filepath.Join(resultDir, "cpu.prof"))
+	require.NoError(t, err)
+
+	components := make(map[string]bool)
+	for _, share := range shares {
+		components[share.Component] = true
+	}
+	assert.True(t, components["processor/attributesprocessor"], "attributes processor missing in %v", shares)
+	assert.True(t, components["processor/spanprocessor"], "span processor missing in %v", shares)
+}
+
 func TestMetricsFromFile(t *testing.T) {
 	// This test demonstrates usage of NewFileDataProvider to generate load using
 	// previously recorded data.
@@ -533,3 +1985,161 @@ func TestMetricsFromFile(t *testing.T) {
 
 	tc.ValidateData()
 }
+
+func TestTraceLoadGeneratorProcess(t *testing.T) {
+	sender := testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t))
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 10_000, ItemsPerBatch: 100}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.SetResourceLimits(testbed.ResourceSpec{
+		ExpectedMaxCPU:      100,
+		ExpectedMaxRAM:      200,
+		ResourceCheckPeriod: 500 * time.Millisecond,
+	})
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoadProcess(options)
+
+	tc.WaitFor(func() bool { return tc.MockBackend.DataItemsReceived() > 0 }, "first data items received")
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	assert.NotZero(t, tc.LoadGenerator.DataItemsSent())
+
+	// The agent and the load generator process are measured separately.
+	generatorResources := tc.LoadGenerator.ProcessResourceConsumption()
+	require.NotNil(t, generatorResources)
+	assert.NotZero(t, generatorResources.RAMMiBMax)
+	assert.Greater(t, generatorResources.CPUPercentAvg, 0.0)
+	assert.NotZero(t, agentProc.GetTotalConsumption().RAMMiBMax)
+
+	tc.StopAgent()
+	tc.ValidateData()
+}
+
+func TestTraceFanOut(t *testing.T) {
+	receivers := []testbed.DataReceiver{
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+	}
+	result := CompareFanOut(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		receivers,
+	)
+
+	assert.Equal(t, 1, result.SingleExporter.Exporters)
+	assert.Equal(t, 3, result.FanOut.Exporters)
+	require.Len(t, result.FanOut.DataItemsReceived, 3)
+	for i, received := range result.FanOut.DataItemsReceived {
+		assert.NotZero(t, received)
+		assert.Equal(t, result.FanOut.DataItemsSent, received, "exporter %d", i+1)
+	}
+	assert.InDelta(t, 3*result.FanOut.ItemsPerSecond[0], result.FanOut.AggregateItemsPerSecond, 1e-6)
+	assert.Equal(t, result.FanOut.CPUSecondsPerMillionItems-result.SingleExporter.CPUSecondsPerMillionItems,
+		result.OverheadCPUSecondsPerMillionItems)
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "fan_out.json"))
+	require.NoError(t, err)
+	var written FanOutResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, result, written)
+}
+
+func TestTraceDecodeOnlyCost(t *testing.T) {
+	result := CompareDecodeCost(
+		t,
+		testbed.NewOTLPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)),
+		testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t)),
+	)
+
+	assert.Equal(t, "decode_only", result.DecodeOnly.Name)
+	assert.Equal(t, "round_trip", result.RoundTrip.Name)
+	assert.NotZero(t, result.DecodeOnly.DataItemsSent)
+	assert.Zero(t, result.DecodeOnly.DataItemsReceived)
+	assert.NotZero(t, result.RoundTrip.DataItemsReceived)
+	assert.Greater(t, result.DecodeOnly.CPUSecondsPerMillionItems, 0.0)
+	assert.Less(t, result.DecodeOnly.CPUSecondsPerMillionItems, result.RoundTrip.CPUSecondsPerMillionItems)
+
+	data, err := ioutil.ReadFile(path.Join("results", t.Name(), "decode_cost.json"))
+	require.NoError(t, err)
+	var written DecodeCostResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, result, written)
+}
+
+func TestTraceReceiverReadTimeout(t *testing.T) {
+	const readTimeout = 500 * time.Millisecond
+	sender := testbed.NewOTLPHTTPTraceDataSender(testbed.DefaultHost, testbed.GetAvailablePort(t)).
+		WithReceiverTimeouts(readTimeout, 0)
+	receiver := testbed.NewOTLPDataReceiver(testbed.GetAvailablePort(t))
+
+	resultDir, err := filepath.Abs(path.Join("results", t.Name()))
+	require.NoError(t, err)
+
+	agentProc := &testbed.ChildProcess{}
+	configStr := createConfigYaml(t, sender, receiver, resultDir, nil, nil)
+	configCleanup, err := agentProc.PrepareConfig(configStr)
+	require.NoError(t, err)
+	defer configCleanup()
+
+	options := testbed.LoadOptions{DataItemsPerSecond: 1_000, ItemsPerBatch: 10}
+	tc := testbed.NewTestCase(
+		t,
+		testbed.NewPerfTestDataProvider(options),
+		sender,
+		receiver,
+		agentProc,
+		&testbed.PerfTestValidator{},
+		performanceResultsSummary,
+	)
+	defer tc.Stop()
+
+	tc.StartBackend()
+	tc.StartAgent()
+	tc.StartLoad(options)
+
+	// A slow client which never completes its request headers is disconnected by the
+	// receiver once the read timeout expires, while the load keeps flowing.
+	conn, err := net.Dial("tcp", sender.GetEndpoint())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST /v1/traces HTTP/1.1\r\nHost: " + sender.GetEndpoint() + "\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*readTimeout)))
+	start := time.Now()
+	_, err = ioutil.ReadAll(conn)
+	assert.NoError(t, err, "slow client was not disconnected")
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(readTimeout/2))
+
+	tc.Sleep(tc.Duration)
+	tc.StopLoad()
+
+	tc.WaitFor(func() bool { return tc.LoadGenerator.DataItemsSent() == tc.MockBackend.DataItemsReceived() },
+		"all data items received")
+	tc.StopAgent()
+	tc.ValidateData()
+}